@@ -0,0 +1,10 @@
+package admission
+
+import (
+	"github.com/giantswarm/kvm-operator/flag/service/admission/tls"
+)
+
+type Admission struct {
+	Address string
+	TLS     tls.TLS
+}
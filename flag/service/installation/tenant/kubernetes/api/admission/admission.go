@@ -0,0 +1,6 @@
+package admission
+
+type Admission struct {
+	DisablePlugins string
+	EnablePlugins  string
+}
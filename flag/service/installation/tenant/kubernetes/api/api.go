@@ -1,9 +1,17 @@
 package api
 
 import (
+	"github.com/giantswarm/kvm-operator/flag/service/installation/tenant/kubernetes/api/admission"
+	"github.com/giantswarm/kvm-operator/flag/service/installation/tenant/kubernetes/api/audit"
 	"github.com/giantswarm/kvm-operator/flag/service/installation/tenant/kubernetes/api/auth"
+	"github.com/giantswarm/kvm-operator/flag/service/installation/tenant/kubernetes/api/encryption"
 )
 
 type API struct {
-	Auth auth.Auth
+	Admission    admission.Admission
+	Audit        audit.Audit
+	Auth         auth.Auth
+	Encryption   encryption.Encryption
+	ExtraSANs    string
+	FeatureGates string
 }
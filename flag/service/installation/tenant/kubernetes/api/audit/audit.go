@@ -0,0 +1,7 @@
+package audit
+
+type Audit struct {
+	LogFormat  string
+	LogMode    string
+	PolicyFile string
+}
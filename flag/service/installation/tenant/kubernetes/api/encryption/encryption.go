@@ -0,0 +1,5 @@
+package encryption
+
+type Encryption struct {
+	Providers string
+}
@@ -3,12 +3,14 @@ package service
 import (
 	"github.com/giantswarm/operatorkit/flag/service/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/flag/service/admission"
 	"github.com/giantswarm/kvm-operator/flag/service/crd"
 	"github.com/giantswarm/kvm-operator/flag/service/installation"
 	"github.com/giantswarm/kvm-operator/flag/service/tenant"
 )
 
 type Service struct {
+	Admission    admission.Admission
 	CRD          crd.CRD
 	Installation installation.Installation
 	Kubernetes   kubernetes.Kubernetes
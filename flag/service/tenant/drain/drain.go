@@ -0,0 +1,8 @@
+package drain
+
+type Drain struct {
+	Builtin             string
+	EvictionGracePeriod string
+	ForceAfterTimeout   string
+	Timeout             string
+}
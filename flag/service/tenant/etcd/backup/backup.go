@@ -0,0 +1,11 @@
+package backup
+
+import (
+	"github.com/giantswarm/kvm-operator/flag/service/tenant/etcd/backup/s3"
+)
+
+type Backup struct {
+	Interval string
+	S3       s3.S3
+	Target   string
+}
@@ -0,0 +1,8 @@
+package s3
+
+type S3 struct {
+	AccessKeyID     string
+	Endpoint        string
+	Region          string
+	SecretAccessKey string
+}
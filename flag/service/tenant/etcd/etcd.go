@@ -0,0 +1,9 @@
+package etcd
+
+import (
+	"github.com/giantswarm/kvm-operator/flag/service/tenant/etcd/backup"
+)
+
+type Etcd struct {
+	Backup backup.Backup
+}
@@ -1,5 +1,7 @@
 package ignition
 
 type Ignition struct {
-	Path string
+	Format  string
+	MaxSize string
+	Path    string
 }
@@ -0,0 +1,6 @@
+package nodeindex
+
+type NodeIndex struct {
+	MaxIndex         string
+	QuarantinePeriod string
+}
@@ -0,0 +1,5 @@
+package resize
+
+type Resize struct {
+	CapacityCheck string
+}
@@ -1,13 +1,21 @@
 package tenant
 
 import (
+	"github.com/giantswarm/kvm-operator/flag/service/tenant/drain"
+	"github.com/giantswarm/kvm-operator/flag/service/tenant/etcd"
 	"github.com/giantswarm/kvm-operator/flag/service/tenant/ignition"
+	"github.com/giantswarm/kvm-operator/flag/service/tenant/nodeindex"
+	"github.com/giantswarm/kvm-operator/flag/service/tenant/resize"
 	"github.com/giantswarm/kvm-operator/flag/service/tenant/ssh"
 	"github.com/giantswarm/kvm-operator/flag/service/tenant/update"
 )
 
 type Tenant struct {
-	Ignition ignition.Ignition
-	SSH      ssh.SSH
-	Update   update.Update
+	Drain     drain.Drain
+	Etcd      etcd.Etcd
+	Ignition  ignition.Ignition
+	NodeIndex nodeindex.NodeIndex
+	Resize    resize.Resize
+	SSH       ssh.SSH
+	Update    update.Update
 }
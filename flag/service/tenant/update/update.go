@@ -1,5 +1,11 @@
 package update
 
 type Update struct {
-	Enabled string
+	BatchSize             string
+	BatchWait             string
+	Enabled               string
+	MastersFirst          string
+	MaxClusters           string
+	MaxUnavailableMasters string
+	MaxUnavailableWorkers string
 }
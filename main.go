@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/giantswarm/kvm-operator/flag"
 	"github.com/giantswarm/microerror"
@@ -71,6 +72,7 @@ func mainError() error {
 		var newServer microserver.Server
 		{
 			c := server.Config{
+				Flag:    f,
 				Logger:  newLogger,
 				Service: newService,
 				Viper:   v,
@@ -82,6 +84,9 @@ func mainError() error {
 			if err != nil {
 				panic(fmt.Sprintf("%#v", err))
 			}
+			// microkit only uses the config of the custom server, so the admission
+			// webhook listener of the custom server has to be booted here.
+			go newServer.Boot()
 		}
 
 		return newServer
@@ -111,11 +116,23 @@ func mainError() error {
 
 	daemonCommand.PersistentFlags().String(f.Service.Installation.DNS.Servers, "", "Comma separated list of DNS servers.")
 
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Admission.DisablePlugins, "", "Comma separated list of admission plugins disabled in tenant cluster API servers. Plugins enabled by default cannot be disabled.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Admission.EnablePlugins, "", "Comma separated list of admission plugins enabled in tenant cluster API servers in addition to the default ones.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Audit.LogFormat, "", "Format of the tenant cluster API server audit log, either json or legacy. Empty means the API server default.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Audit.LogMode, "", "Mode of the tenant cluster API server audit log backend, either batch or blocking. Empty means the API server default.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Audit.PolicyFile, "", "Path of the audit policy file replacing the default audit policy of tenant cluster API servers.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Encryption.Providers, "", "Comma separated list of encryption providers of tenant cluster API servers, e.g. aescbc,identity. The first provider encrypts new data. Empty means aescbc,identity.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.ExtraSANs, "", "Comma separated list of additional host names and IPs tenant cluster API servers are reached with. Only checked against the API server certificates.")
+	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.FeatureGates, "", "Comma separated list of feature gates of tenant cluster API servers, e.g. TTLAfterFinished=true.")
 	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.ClientID, "", "OIDC authorization provider ClientID.")
 	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.IssuerURL, "", "OIDC authorization provider IssuerURL.")
 	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.UsernameClaim, "", "OIDC authorization provider UsernameClaim.")
 	daemonCommand.PersistentFlags().String(f.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.GroupsClaim, "", "OIDC authorization provider GroupsClaim.")
 
+	daemonCommand.PersistentFlags().String(f.Service.Admission.Address, "0.0.0.0:8443", "Address the admission webhook for KVMConfigs listens on using TLS.")
+	daemonCommand.PersistentFlags().String(f.Service.Admission.TLS.CrtFile, "", "Certificate file path of the admission webhook. The webhook is only served when certificate and key are given.")
+	daemonCommand.PersistentFlags().String(f.Service.Admission.TLS.KeyFile, "", "Key file path of the admission webhook. The webhook is only served when certificate and key are given.")
+
 	daemonCommand.PersistentFlags().String(f.Service.CRD.LabelSelector, "", "Label selector for CRD informer ListOptions.")
 
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.Address, "http://127.0.0.1:6443", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
@@ -125,9 +142,30 @@ func mainError() error {
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.CrtFile, "", "Certificate file path to use to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.TLS.KeyFile, "", "Key file path to use to authenticate with Kubernetes.")
 
+	daemonCommand.PersistentFlags().Bool(f.Service.Tenant.Drain.Builtin, false, "Whether tenant cluster nodes are drained by the operator itself instead of the node-operator.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Tenant.Drain.EvictionGracePeriod, 0, "Grace period given to pods evicted from tenant cluster nodes being drained. Zero means the grace periods of the pods are used.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Tenant.Drain.ForceAfterTimeout, true, "Whether tenant cluster VMs are deleted once draining their nodes timed out.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Tenant.Drain.Timeout, 0, "Maximum duration to wait for tenant cluster nodes to be drained. Zero means only the timeout of the node-operator applies and is not allowed for the builtin drainer.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Tenant.Etcd.Backup.Interval, 0, "Interval in which etcd snapshots of tenant cluster masters are taken. Zero disables etcd backups while still allowing restores.")
+	daemonCommand.PersistentFlags().String(f.Service.Tenant.Etcd.Backup.S3.AccessKeyID, "", "Access key ID used to authenticate against the S3 compatible etcd backup target.")
+	daemonCommand.PersistentFlags().String(f.Service.Tenant.Etcd.Backup.S3.Endpoint, "", "Endpoint of the S3 compatible etcd backup target, e.g. a local MinIO. Defaults to AWS S3.")
+	daemonCommand.PersistentFlags().String(f.Service.Tenant.Etcd.Backup.S3.Region, "", "Region of the S3 compatible etcd backup target. Defaults to us-east-1.")
+	daemonCommand.PersistentFlags().String(f.Service.Tenant.Etcd.Backup.S3.SecretAccessKey, "", "Secret access key used to authenticate against the S3 compatible etcd backup target.")
+	daemonCommand.PersistentFlags().String(f.Service.Tenant.Etcd.Backup.Target, "", "URL of the etcd backup target, either file:///path/to/dir or s3://bucket/prefix. Directories must be available on all hosts for restores. Empty disables etcd backups and restores.")
+	daemonCommand.PersistentFlags().String(f.Service.Tenant.Ignition.Format, "", "Overrides the format tenant cluster cloud configs are rendered in, which is declared per version bundle. Either base64 for gzip compressed and base64 encoded Ignition JSON or ignition for plain Ignition JSON. Only honoured by version bundles supporting it.")
+	daemonCommand.PersistentFlags().Int(f.Service.Tenant.Ignition.MaxSize, 960*1024, "Maximum size of rendered tenant cluster cloud configs in bytes, leaving headroom below the 1 MiB ConfigMap limit. Zero means there is no size budget.")
 	daemonCommand.PersistentFlags().String(f.Service.Tenant.Ignition.Path, "/opt/ignition", "Default path for the ignition base directory.")
+	daemonCommand.PersistentFlags().Int(f.Service.Tenant.NodeIndex.MaxIndex, 0, "Highest node index allocated to tenant cluster nodes. Clusters exceeding it are not reconciled until indexes become available. Zero means there is no upper bound.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Tenant.NodeIndex.QuarantinePeriod, 15*time.Minute, "Duration a node index of a removed tenant cluster node is not reused, so that new nodes do not inherit the iSCSI initiator name of removed nodes right away.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Tenant.Resize.CapacityCheck, false, "Whether resizes of tenant cluster VMs are rejected in case the resized VMs do not fit the allocatable capacity of any host node. Resizing a VM drains its node and recreates the VM with the new CPUs and memory.")
 	daemonCommand.PersistentFlags().String(f.Service.Tenant.SSH.SSOPublicKey, "", "Public key for trusted SSO CA.")
+	daemonCommand.PersistentFlags().Int(f.Service.Tenant.Update.BatchSize, 1, "Maximum number of tenant cluster nodes being updated within a single reconciliation loop.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Tenant.Update.BatchWait, 0, "Minimum duration to wait after a batch of tenant cluster nodes got updated before the next batch is processed.")
 	daemonCommand.PersistentFlags().Bool(f.Service.Tenant.Update.Enabled, false, "Whether updates of tenant cluster nodes are allowed to be processed upon reconciliation.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Tenant.Update.MastersFirst, true, "Whether tenant cluster masters are updated before or after the workers.")
+	daemonCommand.PersistentFlags().Int(f.Service.Tenant.Update.MaxClusters, 0, "Maximum number of tenant clusters being updated at the same time. Zero means no limit.")
+	daemonCommand.PersistentFlags().Int(f.Service.Tenant.Update.MaxUnavailableMasters, 1, "Maximum number of tenant cluster masters being updated within a single batch.")
+	daemonCommand.PersistentFlags().Int(f.Service.Tenant.Update.MaxUnavailableWorkers, 1, "Maximum number of tenant cluster workers being updated within a single batch.")
 
 	newCommand.CobraCommand().Execute()
 
@@ -0,0 +1,249 @@
+// Package admission implements a validating admission webhook for KVMConfig
+// custom objects. It has to be registered with a
+// ValidatingWebhookConfiguration for kvmconfigs.provider.giantswarm.io and
+// rejects creates and updates the controller would not be able to reconcile.
+// The endpoint is served on the separate TLS listener of the server because
+// the Kubernetes API server only calls webhooks using TLS.
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/giantswarm/versionbundle"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/validation"
+)
+
+const (
+	// Method is the HTTP method this endpoint is register for.
+	Method = "POST"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "admission"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/admission/kvmconfig"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+
+	// ValidatedVersions are the versions of the version bundles implementing
+	// the spec validation rules checked by the webhook. Custom objects of other
+	// version bundles are only checked for changes to immutable fields and for
+	// downgrades.
+	ValidatedVersions []string
+	// VersionBundles are the version bundles served by the operator. Custom
+	// objects must not be downgraded to versions not contained here.
+	VersionBundles []versionbundle.Bundle
+}
+
+type Endpoint struct {
+	logger micrologger.Logger
+
+	servedVersions    map[string]bool
+	validatedVersions map[string]bool
+}
+
+func New(config Config) (*Endpoint, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if len(config.ValidatedVersions) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ValidatedVersions must not be empty", config)
+	}
+	if len(config.VersionBundles) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.VersionBundles must not be empty", config)
+	}
+
+	servedVersions := map[string]bool{}
+	for _, b := range config.VersionBundles {
+		servedVersions[b.Version] = true
+	}
+	validatedVersions := map[string]bool{}
+	for _, v := range config.ValidatedVersions {
+		validatedVersions[v] = true
+	}
+
+	e := &Endpoint{
+		logger: config.Logger,
+
+		servedVersions:    servedVersions,
+		validatedVersions: validatedVersions,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		var review Review
+		err := json.NewDecoder(r.Body).Decode(&review)
+		if err != nil {
+			return nil, microerror.Maskf(invalidRequestError, "decoding admission review: %s", err)
+		}
+		if review.Request == nil {
+			return nil, microerror.Maskf(invalidRequestError, "admission review must contain a request")
+		}
+
+		return review, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		return json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		review, ok := request.(Review)
+		if !ok {
+			return nil, microerror.Maskf(wrongTypeError, "expected '%T' got '%T'", Review{}, request)
+		}
+
+		return e.Review(ctx, review), nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
+
+// Review returns the given admission review completed with the response
+// deciding whether the reviewed request is allowed.
+func (e *Endpoint) Review(ctx context.Context, review Review) Review {
+	violations, err := e.violations(*review.Request)
+	if err != nil {
+		violations = []string{err.Error()}
+	}
+
+	response := &Response{
+		UID:     review.Request.UID,
+		Allowed: len(violations) == 0,
+	}
+	if !response.Allowed {
+		message := fmt.Sprintf("invalid %s: %s", review.Request.Kind.Kind, strings.Join(violations, ", "))
+
+		response.Result = &metav1.Status{
+			Code:    http.StatusUnprocessableEntity,
+			Message: message,
+			Reason:  metav1.StatusReasonInvalid,
+			Status:  metav1.StatusFailure,
+		}
+
+		e.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("rejecting %s of %s '%s/%s'", strings.ToLower(review.Request.Operation), review.Request.Kind.Kind, review.Request.Namespace, review.Request.Name), "reason", message)
+	}
+
+	return Review{
+		TypeMeta: review.TypeMeta,
+		Response: response,
+	}
+}
+
+func (e *Endpoint) violations(request Request) ([]string, error) {
+	switch request.Operation {
+	case OperationCreate:
+		newCR, err := toCustomObject(request.Object.Raw)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if !e.validatedVersions[key.VersionBundleVersion(newCR)] {
+			return nil, nil
+		}
+
+		return validation.Violations(newCR), nil
+
+	case OperationUpdate:
+		newCR, err := toCustomObject(request.Object.Raw)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		oldCR, err := toCustomObject(request.OldObject.Raw)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		// Custom objects being deleted only get their finalizers removed and
+		// updates not touching the spec or the annotations, e.g. of the status
+		// or the finalizers, cannot introduce violations. Rejecting them would
+		// block the deletion or the reconciliation of custom objects which
+		// became invalid before the webhook was registered.
+		if newCR.GetDeletionTimestamp() != nil {
+			return nil, nil
+		}
+		if reflect.DeepEqual(oldCR.Spec, newCR.Spec) && reflect.DeepEqual(oldCR.GetAnnotations(), newCR.GetAnnotations()) {
+			return nil, nil
+		}
+
+		var violations []string
+
+		if e.validatedVersions[key.VersionBundleVersion(newCR)] {
+			violations = append(violations, validation.Violations(newCR)...)
+		}
+		violations = append(violations, validation.ImmutabilityViolations(oldCR, newCR)...)
+
+		oldVersion := key.VersionBundleVersion(oldCR)
+		newVersion := key.VersionBundleVersion(newCR)
+		if isDowngrade(oldVersion, newVersion) && !e.servedVersions[newVersion] {
+			violations = append(violations, fmt.Sprintf("spec.versionBundle.version must not be downgraded from '%s' to '%s' because version bundle '%s' is not served", oldVersion, newVersion, newVersion))
+		}
+
+		return violations, nil
+	}
+
+	return nil, nil
+}
+
+// isDowngrade returns whether the new version is lower than the old version.
+// Versions which are no semantic versions are not considered to be downgrades
+// because they cannot be compared.
+func isDowngrade(oldVersion, newVersion string) bool {
+	o, err := semver.NewVersion(oldVersion)
+	if err != nil {
+		return false
+	}
+	n, err := semver.NewVersion(newVersion)
+	if err != nil {
+		return false
+	}
+
+	return n.LessThan(*o)
+}
+
+func toCustomObject(raw []byte) (v1alpha1.KVMConfig, error) {
+	var cr v1alpha1.KVMConfig
+	err := json.Unmarshal(raw, &cr)
+	if err != nil {
+		return v1alpha1.KVMConfig{}, microerror.Maskf(invalidRequestError, "decoding KVMConfig: %s", err)
+	}
+
+	return cr, nil
+}
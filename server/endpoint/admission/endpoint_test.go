@@ -0,0 +1,194 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/versionbundle"
+)
+
+func Test_Endpoint(t *testing.T) {
+	testCases := []struct {
+		name            string
+		fixture         string
+		expectedAllowed bool
+		expectedMessage []string
+	}{
+		{
+			name:            "case 0: valid create is allowed",
+			fixture:         "create_valid.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 1: create with mismatching worker arrays is rejected",
+			fixture:         "create_mismatching_workers.json",
+			expectedAllowed: false,
+			expectedMessage: []string{
+				"spec.kvm.workers has 1 entries but spec.cluster.workers has 2",
+			},
+		},
+		{
+			name:            "case 2: adding workers, upgrading and growing etcd storage is allowed",
+			fixture:         "update_valid.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 3: shrinking masters is rejected",
+			fixture:         "update_shrinking_masters.json",
+			expectedAllowed: false,
+			expectedMessage: []string{
+				"spec.cluster.masters must not shrink from 3 to 1 entries",
+			},
+		},
+		{
+			name:            "case 4: changing cluster ID and VNI is rejected",
+			fixture:         "update_immutable_fields.json",
+			expectedAllowed: false,
+			expectedMessage: []string{
+				"spec.cluster.id must not be changed from 'al9qy' to 'x7k2p'",
+				"spec.kvm.network.flannel.vni must not be changed from 12 to 13",
+			},
+		},
+		{
+			name:            "case 5: downgrading to an unserved version bundle is rejected",
+			fixture:         "update_unserved_downgrade.json",
+			expectedAllowed: false,
+			expectedMessage: []string{
+				"spec.versionBundle.version must not be downgraded from '3.6.0' to '1.0.0'",
+			},
+		},
+		{
+			name:            "case 6: reducing etcd storage is rejected",
+			fixture:         "update_reducing_etcd_storage.json",
+			expectedAllowed: false,
+			expectedMessage: []string{
+				"etcd storage size must not be reduced from 15Gi to 10Gi",
+			},
+		},
+		{
+			name:            "case 7: invalid create of version bundle without validation rules is allowed",
+			fixture:         "create_unvalidated_version.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 8: status update of invalid custom object is allowed",
+			fixture:         "update_status_only.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 9: update of custom object being deleted is allowed",
+			fixture:         "update_deleting.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 10: upgrading to an unserved version bundle is allowed",
+			fixture:         "update_unserved_upgrade.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 11: downgrading to a served version bundle is allowed",
+			fixture:         "update_served_downgrade.json",
+			expectedAllowed: true,
+		},
+		{
+			name:            "case 12: changing VNI of version bundle without validation rules is rejected",
+			fixture:         "update_unvalidated_immutable_fields.json",
+			expectedAllowed: false,
+			expectedMessage: []string{
+				"spec.kvm.network.flannel.vni must not be changed from 12 to 13",
+			},
+		},
+	}
+
+	var e *Endpoint
+	{
+		c := Config{
+			Logger:            microloggertest.New(),
+			ValidatedVersions: []string{"3.6.0"},
+			VersionBundles: []versionbundle.Bundle{
+				{Version: "3.5.0"},
+				{Version: "3.6.0"},
+			},
+		}
+
+		var err error
+		e, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tc.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			ctx := context.Background()
+
+			request, err := e.Decoder()(ctx, httptest.NewRequest(Method, Path, f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := e.Endpoint()(ctx, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			err = e.Encoder()(ctx, w, response)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var review Review
+			err = json.NewDecoder(w.Body).Decode(&review)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if review.Response == nil {
+				t.Fatalf("expected response got nil")
+			}
+			if review.Response.UID != request.(Review).Request.UID {
+				t.Fatalf("expected UID %#q got %#q", request.(Review).Request.UID, review.Response.UID)
+			}
+			if review.Response.Allowed != tc.expectedAllowed {
+				t.Fatalf("expected allowed %t got %t", tc.expectedAllowed, review.Response.Allowed)
+			}
+
+			var message string
+			if review.Response.Result != nil {
+				message = review.Response.Result.Message
+			}
+			for _, m := range tc.expectedMessage {
+				if !strings.Contains(message, m) {
+					t.Fatalf("expected message to contain %#q got %#q", m, message)
+				}
+			}
+		})
+	}
+}
+
+func Test_Decoder_InvalidRequest(t *testing.T) {
+	e, err := New(Config{
+		Logger:            microloggertest.New(),
+		ValidatedVersions: []string{"3.6.0"},
+		VersionBundles:    []versionbundle.Bundle{{Version: "3.6.0"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = e.Decoder()(context.Background(), httptest.NewRequest(Method, Path, strings.NewReader(`{"kind":"AdmissionReview"}`)))
+	if !IsInvalidRequest(err) {
+		t.Fatalf("error == %#v, want invalidRequestError", err)
+	}
+}
@@ -0,0 +1,37 @@
+package admission
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The types below mirror the admission.k8s.io/v1beta1 AdmissionReview API
+// as far as the webhook needs it.
+
+const (
+	OperationCreate = "CREATE"
+	OperationUpdate = "UPDATE"
+)
+
+type Review struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *Request  `json:"request,omitempty"`
+	Response        *Response `json:"response,omitempty"`
+}
+
+type Request struct {
+	UID       string                      `json:"uid"`
+	Kind      metav1.GroupVersionKind     `json:"kind"`
+	Resource  metav1.GroupVersionResource `json:"resource"`
+	Name      string                      `json:"name,omitempty"`
+	Namespace string                      `json:"namespace,omitempty"`
+	Operation string                      `json:"operation"`
+	Object    runtime.RawExtension        `json:"object,omitempty"`
+	OldObject runtime.RawExtension        `json:"oldObject,omitempty"`
+}
+
+type Response struct {
+	UID     string         `json:"uid"`
+	Allowed bool           `json:"allowed"`
+	Result  *metav1.Status `json:"status,omitempty"`
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
+	"github.com/giantswarm/kvm-operator/server/endpoint/admission"
+	"github.com/giantswarm/kvm-operator/server/endpoint/shutdown"
 	"github.com/giantswarm/kvm-operator/service"
 )
 
@@ -16,13 +18,29 @@ type Config struct {
 
 // Endpoint is the endpoint collection.
 type Endpoint struct {
-	Healthz *healthz.Endpoint
-	Version *versionendpoint.Endpoint
+	Admission *admission.Endpoint
+	Healthz   *healthz.Endpoint
+	Shutdown  *shutdown.Endpoint
+	Version   *versionendpoint.Endpoint
 }
 
 func New(config Config) (*Endpoint, error) {
 	var err error
 
+	var admissionEndpoint *admission.Endpoint
+	{
+		c := admission.Config{
+			Logger:            config.Logger,
+			ValidatedVersions: service.NewValidatedVersions(),
+			VersionBundles:    service.NewVersionBundles(),
+		}
+
+		admissionEndpoint, err = admission.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var healthzEndpoint *healthz.Endpoint
 	{
 		c := healthz.Config{
@@ -35,6 +53,19 @@ func New(config Config) (*Endpoint, error) {
 		}
 	}
 
+	var shutdownEndpoint *shutdown.Endpoint
+	{
+		c := shutdown.Config{
+			Logger:  config.Logger,
+			Service: config.Service.Shutdown,
+		}
+
+		shutdownEndpoint, err = shutdown.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionEndpoint *versionendpoint.Endpoint
 	{
 		c := versionendpoint.Config{
@@ -49,8 +80,10 @@ func New(config Config) (*Endpoint, error) {
 	}
 
 	newEndpoint := &Endpoint{
-		Healthz: healthzEndpoint,
-		Version: versionEndpoint,
+		Admission: admissionEndpoint,
+		Healthz:   healthzEndpoint,
+		Shutdown:  shutdownEndpoint,
+		Version:   versionEndpoint,
 	}
 
 	return newEndpoint, nil
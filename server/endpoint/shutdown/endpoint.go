@@ -0,0 +1,118 @@
+// Package shutdown implements the endpoint VM pods ask whether QEMU may shut
+// down. The response is 200 OK in case the VM may shut down and 423 Locked in
+// case the shut down has to be deferred, so that sidecars and preStop hooks
+// can simply poll the endpoint until it succeeds. The body contains the
+// decision, the reason and the drain state of the pod.
+package shutdown
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/giantswarm/kvm-operator/service/shutdown"
+)
+
+const (
+	// Method is the HTTP method this endpoint is register for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "shutdown"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/v1/shutdown/{namespace}/{pod}/"
+)
+
+type Config struct {
+	Logger  micrologger.Logger
+	Service *shutdown.Service
+}
+
+type Endpoint struct {
+	logger  micrologger.Logger
+	service *shutdown.Service
+}
+
+func New(config Config) (*Endpoint, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+	}
+
+	e := &Endpoint{
+		logger:  config.Logger,
+		service: config.Service,
+	}
+
+	return e, nil
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		vars := mux.Vars(r)
+
+		request := shutdown.Request{
+			Namespace: vars["namespace"],
+			Pod:       vars["pod"],
+		}
+
+		return request, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		r, ok := response.(*shutdown.Response)
+		if !ok {
+			return microerror.Maskf(wrongTypeError, "expected '%T' got '%T'", &shutdown.Response{}, response)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.MayShutDown {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusLocked)
+		}
+
+		return json.NewEncoder(w).Encode(r)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r, ok := request.(shutdown.Request)
+		if !ok {
+			return nil, microerror.Maskf(wrongTypeError, "expected '%T' got '%T'", shutdown.Request{}, request)
+		}
+
+		response, err := e.service.Get(ctx, r)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return response, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
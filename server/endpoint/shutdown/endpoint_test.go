@@ -0,0 +1,114 @@
+package shutdown
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/shutdown"
+)
+
+func Test_Endpoint(t *testing.T) {
+	deleted := metav1.Now()
+
+	testCases := []struct {
+		name               string
+		path               string
+		expectedStatusCode int
+		expectedReason     string
+	}{
+		{
+			name:               "case 0: pod not being deleted may shut down",
+			path:               "/v1/shutdown/al9qy/worker-1/",
+			expectedStatusCode: http.StatusOK,
+			expectedReason:     shutdown.ReasonPodNotDeleted,
+		},
+		{
+			name:               "case 1: deleted pod being drained defers shut down",
+			path:               "/v1/shutdown/al9qy/worker-2/",
+			expectedStatusCode: http.StatusLocked,
+			expectedReason:     shutdown.ReasonDrainInProgress,
+		},
+		{
+			name:               "case 2: missing pod may shut down",
+			path:               "/v1/shutdown/al9qy/worker-3/",
+			expectedStatusCode: http.StatusOK,
+			expectedReason:     shutdown.ReasonPodNotFound,
+		},
+	}
+
+	k8sClient := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "worker-1",
+				Namespace: "al9qy",
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "worker-2",
+				Namespace:         "al9qy",
+				DeletionTimestamp: &deleted,
+			},
+		},
+	)
+
+	var service *shutdown.Service
+	{
+		c := shutdown.Config{
+			K8sClient: k8sClient,
+			Logger:    microloggertest.New(),
+		}
+
+		var err error
+		service, err = shutdown.New(c)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	var e *Endpoint
+	{
+		c := Config{
+			Logger:  microloggertest.New(),
+			Service: service,
+		}
+
+		var err error
+		e, err = New(c)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	router := mux.NewRouter()
+	router.Methods(e.Method()).Path(e.Path()).Handler(kithttp.NewServer(e.Endpoint(), e.Decoder(), e.Encoder()))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(Method, tc.path, nil))
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("expected status code %d got %d", tc.expectedStatusCode, w.Code)
+			}
+
+			var response shutdown.Response
+			err := json.NewDecoder(w.Body).Decode(&response)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if response.Reason != tc.expectedReason {
+				t.Fatalf("expected reason %#q got %#q", tc.expectedReason, response.Reason)
+			}
+		})
+	}
+}
@@ -4,20 +4,26 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	microserver "github.com/giantswarm/microkit/server"
 	"github.com/giantswarm/micrologger"
+	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/spf13/viper"
 
+	"github.com/giantswarm/kvm-operator/flag"
 	"github.com/giantswarm/kvm-operator/server/endpoint"
 	"github.com/giantswarm/kvm-operator/service"
 )
 
 // Config represents the configuration used to create a new server object.
 type Config struct {
+	Flag    *flag.Flag
 	Logger  micrologger.Logger
 	Service *service.Service
 	Viper   *viper.Viper
@@ -30,13 +36,23 @@ type Server struct {
 	logger micrologger.Logger
 
 	// Internals.
-	bootOnce     sync.Once
-	config       microserver.Config
-	shutdownOnce sync.Once
+	admissionServer *http.Server
+	admissionTLS    admissionTLS
+	bootOnce        sync.Once
+	config          microserver.Config
+	shutdownOnce    sync.Once
+}
+
+type admissionTLS struct {
+	CrtFile string
+	KeyFile string
 }
 
 // New creates a new configured server object.
 func New(config Config) (*Server, error) {
+	if config.Flag == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Flag must not be empty", config)
+	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
@@ -65,12 +81,48 @@ func New(config Config) (*Server, error) {
 		}
 	}
 
+	// The admission webhook is called by the Kubernetes API server, which only
+	// talks to webhooks using TLS. It is therefore served on its own TLS
+	// listener instead of the plain HTTP listener of the other endpoints.
+	var admissionServer *http.Server
+	var tlsFiles admissionTLS
+	{
+		tlsFiles = admissionTLS{
+			CrtFile: config.Viper.GetString(config.Flag.Service.Admission.TLS.CrtFile),
+			KeyFile: config.Viper.GetString(config.Flag.Service.Admission.TLS.KeyFile),
+		}
+
+		if tlsFiles.CrtFile == "" && tlsFiles.KeyFile != "" {
+			return nil, microerror.Maskf(invalidConfigError, "%s must not be empty when %s is given", config.Flag.Service.Admission.TLS.CrtFile, config.Flag.Service.Admission.TLS.KeyFile)
+		}
+		if tlsFiles.CrtFile != "" && tlsFiles.KeyFile == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%s must not be empty when %s is given", config.Flag.Service.Admission.TLS.KeyFile, config.Flag.Service.Admission.TLS.CrtFile)
+		}
+
+		if tlsFiles.CrtFile != "" {
+			e := endpointCollection.Admission
+
+			mux := http.NewServeMux()
+			mux.Handle(e.Path(), kithttp.NewServer(e.Endpoint(), e.Decoder(), e.Encoder()))
+
+			admissionServer = &http.Server{
+				Addr:    config.Viper.GetString(config.Flag.Service.Admission.Address),
+				Handler: mux,
+				TLSConfig: &tls.Config{
+					MinVersion: tls.VersionTLS12,
+				},
+			}
+		}
+	}
+
 	s := &Server{
 		// Dependencies.
 		logger: config.Logger,
 
 		// Internals.
-		bootOnce: sync.Once{},
+		admissionServer: admissionServer,
+		admissionTLS:    tlsFiles,
+		bootOnce:        sync.Once{},
 		config: microserver.Config{
 			Logger:      config.Logger,
 			ServiceName: config.ProjectName,
@@ -78,6 +130,7 @@ func New(config Config) (*Server, error) {
 
 			Endpoints: []microserver.Endpoint{
 				endpointCollection.Healthz,
+				endpointCollection.Shutdown,
 				endpointCollection.Version,
 			},
 			ErrorEncoder: errorEncoder,
@@ -90,8 +143,22 @@ func New(config Config) (*Server, error) {
 
 func (s *Server) Boot() {
 	s.bootOnce.Do(func() {
-		// Here goes your custom boot logic for your server/endpoint/middleware, if
-		// any.
+		if s.admissionServer == nil {
+			s.logger.Log("level", "debug", "message", "not running admission server because no TLS certificate is given")
+			return
+		}
+
+		go func() {
+			s.logger.Log("level", "debug", "message", fmt.Sprintf("running admission server at https://%s", s.admissionServer.Addr))
+
+			err := s.admissionServer.ListenAndServeTLS(s.admissionTLS.CrtFile, s.admissionTLS.KeyFile)
+			if err == http.ErrServerClosed {
+				// We get a closed error in case the server is shutting down. We expect
+				// this at times so we just fall through here.
+			} else if err != nil {
+				panic(err)
+			}
+		}()
 	})
 }
 
@@ -101,8 +168,17 @@ func (s *Server) Config() microserver.Config {
 
 func (s *Server) Shutdown() {
 	s.shutdownOnce.Do(func() {
-		// Here goes your custom shutdown logic for your server/endpoint/middleware,
-		// if any.
+		if s.admissionServer == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		err := s.admissionServer.Shutdown(ctx)
+		if err != nil {
+			s.logger.Log("level", "error", "message", "shutting down admission server failed", "stack", fmt.Sprintf("%#v", err))
+		}
 	})
 }
 
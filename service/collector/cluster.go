@@ -0,0 +1,348 @@
+// Package collector implements Prometheus collectors exposing the state of
+// the tenant clusters managed by the operator.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/metric"
+)
+
+const (
+	prometheusNamespace = "kvm_operator"
+	prometheusSubsystem = "cluster"
+)
+
+const (
+	labelCluster       = "cluster_id"
+	labelDrainerConfig = "drainer_config"
+	labelRole          = "role"
+)
+
+const (
+	// resyncPeriod is the interval in which the caches of KVMConfigs, pods and
+	// DrainerConfigs are resynced in addition to the watch events.
+	resyncPeriod = 5 * time.Minute
+)
+
+var (
+	desiredVMsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "desired_vms"),
+		"Number of VMs defined in the KVMConfig of the tenant cluster.",
+		[]string{labelCluster, labelRole},
+		nil,
+	)
+	readyVMsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "ready_vms"),
+		"Number of VM pods of the tenant cluster being ready.",
+		[]string{labelCluster, labelRole},
+		nil,
+	)
+	drainingPodsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "draining_pods"),
+		"Number of VM pods of the tenant cluster waiting for their node to be drained.",
+		[]string{labelCluster},
+		nil,
+	)
+	drainerConfigAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "drainer_config_age_seconds"),
+		"Age of the DrainerConfigs of the tenant cluster which did not finish yet.",
+		[]string{labelCluster, labelDrainerConfig},
+		nil,
+	)
+	updatingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "updating"),
+		"Whether the tenant cluster is being updated, 1 if so, 0 otherwise.",
+		[]string{labelCluster},
+		nil,
+	)
+	nodeIndexesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "node_indexes"),
+		"Number of node indexes allocated in the status of the tenant cluster.",
+		[]string{labelCluster},
+		nil,
+	)
+	lastReconciliationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(prometheusNamespace, prometheusSubsystem, "last_reconciliation_seconds"),
+		"Time since the last successful reconciliation of the tenant cluster.",
+		[]string{labelCluster},
+		nil,
+	)
+)
+
+type ClusterConfig struct {
+	G8sClient versioned.Interface
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// CRDLabelSelector restricts the reported KVMConfigs to the ones matching
+	// the label selector the controllers watch KVMConfigs with.
+	CRDLabelSelector string
+}
+
+// Cluster is a Prometheus collector reporting the state of the VMs and the
+// reconciliation of each tenant cluster, labeled by cluster ID. KVMConfigs,
+// pods and DrainerConfigs are watched and read from caches, so that scrapes do
+// not put load on the Kubernetes API proportional to the number of tenant
+// clusters.
+type Cluster struct {
+	logger micrologger.Logger
+
+	bootOnce              sync.Once
+	drainerConfigInformer cache.SharedIndexInformer
+	kvmConfigInformer     cache.SharedIndexInformer
+	podInformer           cache.SharedIndexInformer
+}
+
+func NewCluster(config ClusterConfig) (*Cluster, error) {
+	if config.G8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.G8sClient must not be empty", config)
+	}
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	indexers := cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+	}
+
+	var drainerConfigInformer cache.SharedIndexInformer
+	{
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return config.G8sClient.CoreV1alpha1().DrainerConfigs("").List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return config.G8sClient.CoreV1alpha1().DrainerConfigs("").Watch(options)
+			},
+		}
+
+		drainerConfigInformer = cache.NewSharedIndexInformer(lw, &corev1alpha1.DrainerConfig{}, resyncPeriod, indexers)
+	}
+
+	var kvmConfigInformer cache.SharedIndexInformer
+	{
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = config.CRDLabelSelector
+				return config.G8sClient.ProviderV1alpha1().KVMConfigs("").List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = config.CRDLabelSelector
+				return config.G8sClient.ProviderV1alpha1().KVMConfigs("").Watch(options)
+			},
+		}
+
+		kvmConfigInformer = cache.NewSharedIndexInformer(lw, &v1alpha1.KVMConfig{}, resyncPeriod, cache.Indexers{})
+	}
+
+	var podInformer cache.SharedIndexInformer
+	{
+		// Only VM pods are of interest, which keeps the cache small.
+		selector := fmt.Sprintf("%s in (%s,%s)", key.LabelApp, key.MasterID, key.WorkerID)
+
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return config.K8sClient.CoreV1().Pods("").List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return config.K8sClient.CoreV1().Pods("").Watch(options)
+			},
+		}
+
+		podInformer = cache.NewSharedIndexInformer(lw, &corev1.Pod{}, resyncPeriod, indexers)
+	}
+
+	c := &Cluster{
+		logger: config.Logger,
+
+		bootOnce:              sync.Once{},
+		drainerConfigInformer: drainerConfigInformer,
+		kvmConfigInformer:     kvmConfigInformer,
+		podInformer:           podInformer,
+	}
+
+	return c, nil
+}
+
+// Boot starts the caches of the collector and registers the collector with
+// the default Prometheus registry, which is served by the /metrics endpoint of
+// the operator's server.
+func (c *Cluster) Boot(ctx context.Context) {
+	c.bootOnce.Do(func() {
+		c.runInformers(ctx.Done())
+
+		c.logger.LogCtx(ctx, "level", "debug", "message", "registering cluster collector")
+
+		err := prometheus.Register(prometheus.Collector(c))
+		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			c.logger.LogCtx(ctx, "level", "debug", "message", "cluster collector already registered")
+		} else if err != nil {
+			c.logger.LogCtx(ctx, "level", "error", "message", "registering cluster collector failed", "stack", fmt.Sprintf("%#v", err))
+		} else {
+			c.logger.LogCtx(ctx, "level", "debug", "message", "registered cluster collector")
+		}
+	})
+}
+
+// runInformers starts filling the caches of KVMConfigs, pods and
+// DrainerConfigs until the given channel is closed.
+func (c *Cluster) runInformers(stop <-chan struct{}) {
+	go c.drainerConfigInformer.Run(stop)
+	go c.kvmConfigInformer.Run(stop)
+	go c.podInformer.Run(stop)
+}
+
+func (c *Cluster) Collect(ch chan<- prometheus.Metric) {
+	// Without the KVMConfigs there are no tenant clusters to report about.
+	if !c.kvmConfigInformer.HasSynced() {
+		c.logger.Log("level", "debug", "message", "cache of KVMConfigs of cluster collector not synced yet")
+		return
+	}
+
+	now := time.Now()
+
+	// Until the caches are filled, the metrics derived from them would report
+	// wrong values. They are omitted instead.
+	synced := c.drainerConfigInformer.HasSynced() && c.podInformer.HasSynced()
+	if !synced {
+		c.logger.Log("level", "debug", "message", "caches of cluster collector not synced yet")
+	}
+
+	for _, obj := range c.kvmConfigInformer.GetStore().List() {
+		cr, ok := obj.(*v1alpha1.KVMConfig)
+		if !ok {
+			c.logger.Log("level", "error", "message", fmt.Sprintf("expected '%T' got '%T'", &v1alpha1.KVMConfig{}, obj))
+			continue
+		}
+
+		err := c.collectCluster(ch, *cr, now, synced)
+		if err != nil {
+			c.logger.Log("level", "error", "message", fmt.Sprintf("collecting metrics of cluster '%s' failed", key.ClusterID(*cr)), "stack", fmt.Sprintf("%#v", err))
+		}
+	}
+}
+
+func (c *Cluster) Describe(ch chan<- *prometheus.Desc) {
+	ch <- desiredVMsDesc
+	ch <- readyVMsDesc
+	ch <- drainingPodsDesc
+	ch <- drainerConfigAgeDesc
+	ch <- updatingDesc
+	ch <- nodeIndexesDesc
+	ch <- lastReconciliationDesc
+}
+
+func (c *Cluster) collectCluster(ch chan<- prometheus.Metric, cr v1alpha1.KVMConfig, now time.Time, synced bool) error {
+	clusterID := key.ClusterID(cr)
+	namespace := key.ClusterNamespace(cr)
+
+	{
+		ch <- prometheus.MustNewConstMetric(desiredVMsDesc, prometheus.GaugeValue, float64(len(cr.Spec.Cluster.Masters)), clusterID, key.MasterID)
+		ch <- prometheus.MustNewConstMetric(desiredVMsDesc, prometheus.GaugeValue, float64(len(cr.Spec.Cluster.Workers)), clusterID, key.WorkerID)
+	}
+
+	if synced {
+		pods, err := c.podInformer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		ready := map[string]int{
+			key.MasterID: 0,
+			key.WorkerID: 0,
+		}
+		for _, obj := range pods {
+			p, ok := obj.(*corev1.Pod)
+			if !ok {
+				return microerror.Maskf(wrongTypeError, "expected '%T' got '%T'", &corev1.Pod{}, obj)
+			}
+
+			role := p.GetLabels()[key.LabelApp]
+			if _, ok := ready[role]; ok && isPodReady(*p) {
+				ready[role]++
+			}
+		}
+
+		ch <- prometheus.MustNewConstMetric(readyVMsDesc, prometheus.GaugeValue, float64(ready[key.MasterID]), clusterID, key.MasterID)
+		ch <- prometheus.MustNewConstMetric(readyVMsDesc, prometheus.GaugeValue, float64(ready[key.WorkerID]), clusterID, key.WorkerID)
+	}
+
+	if synced {
+		drainerConfigs, err := c.drainerConfigInformer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		var draining int
+		for _, obj := range drainerConfigs {
+			d, ok := obj.(*corev1alpha1.DrainerConfig)
+			if !ok {
+				return microerror.Maskf(wrongTypeError, "expected '%T' got '%T'", &corev1alpha1.DrainerConfig{}, obj)
+			}
+
+			if d.Status.HasDrainedCondition() || d.Status.HasTimeoutCondition() {
+				continue
+			}
+
+			draining++
+			age := now.Sub(d.GetCreationTimestamp().Time)
+			ch <- prometheus.MustNewConstMetric(drainerConfigAgeDesc, prometheus.GaugeValue, age.Seconds(), clusterID, d.GetName())
+		}
+
+		ch <- prometheus.MustNewConstMetric(drainingPodsDesc, prometheus.GaugeValue, float64(draining), clusterID)
+	}
+
+	{
+		var updating float64
+		if cr.Status.Cluster.HasUpdatingCondition() {
+			updating = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(updatingDesc, prometheus.GaugeValue, updating, clusterID)
+		ch <- prometheus.MustNewConstMetric(nodeIndexesDesc, prometheus.GaugeValue, float64(len(cr.Status.KVM.NodeIndexes)), clusterID)
+	}
+
+	{
+		// Only clusters of version bundles recording their reconciliations are
+		// reported, since there is nothing to tell about the others.
+		t, ok := metric.Reconciliations.Get(clusterID)
+		if ok {
+			ch <- prometheus.MustNewConstMetric(lastReconciliationDesc, prometheus.GaugeValue, now.Sub(t).Seconds(), clusterID)
+		}
+	}
+
+	return nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
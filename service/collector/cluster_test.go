@@ -0,0 +1,180 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/giantswarm/kvm-operator/service/metric"
+)
+
+func Test_Cluster_Collect(t *testing.T) {
+	now := time.Now()
+
+	cr := &v1alpha1.KVMConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "al9qy",
+			Namespace: "default",
+			Labels: map[string]string{
+				"operator": "kvm",
+			},
+		},
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID:      "al9qy",
+				Masters: []v1alpha1.ClusterNode{{ID: "m1"}},
+				Workers: []v1alpha1.ClusterNode{{ID: "w1"}, {ID: "w2"}, {ID: "w3"}},
+			},
+		},
+		Status: v1alpha1.KVMConfigStatus{
+			Cluster: v1alpha1.StatusCluster{
+				Conditions: []v1alpha1.StatusClusterCondition{
+					{Status: v1alpha1.StatusClusterStatusTrue, Type: v1alpha1.StatusClusterTypeUpdating},
+				},
+			},
+			KVM: v1alpha1.KVMConfigStatusKVM{
+				NodeIndexes: map[string]int{"m1": 1, "w1": 2, "w2": 3, "w3": 4},
+			},
+		},
+	}
+
+	// KVMConfigs not matching the CRD label selector are not reported.
+	unselectedCR := &v1alpha1.KVMConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "x7k2p",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID:      "x7k2p",
+				Masters: []v1alpha1.ClusterNode{{ID: "m1"}},
+			},
+		},
+	}
+
+	drainerConfigs := []*corev1alpha1.DrainerConfig{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "worker-w1",
+				Namespace:         "al9qy",
+				CreationTimestamp: metav1.NewTime(now.Add(-10 * time.Minute)),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "worker-w2",
+				Namespace: "al9qy",
+			},
+			Status: corev1alpha1.DrainerConfigStatus{
+				Conditions: []corev1alpha1.DrainerConfigStatusCondition{
+					{Status: corev1alpha1.DrainerConfigStatusStatusTrue, Type: corev1alpha1.DrainerConfigStatusTypeDrained},
+				},
+			},
+		},
+	}
+
+	pods := []*corev1.Pod{
+		newPod("master-m1", "master", corev1.ConditionTrue),
+		newPod("worker-w1", "worker", corev1.ConditionFalse),
+		newPod("worker-w2", "worker", corev1.ConditionTrue),
+		newPod("worker-w3", "worker", corev1.ConditionTrue),
+	}
+
+	var c *Cluster
+	{
+		g8sClient := fake.NewSimpleClientset(cr, unselectedCR, drainerConfigs[0], drainerConfigs[1])
+		k8sClient := k8sfake.NewSimpleClientset(pods[0], pods[1], pods[2], pods[3])
+
+		var err error
+		c, err = NewCluster(ClusterConfig{
+			G8sClient: g8sClient,
+			K8sClient: k8sClient,
+			Logger:    microloggertest.New(),
+
+			CRDLabelSelector: "operator=kvm",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	c.runInformers(stop)
+	if !cache.WaitForCacheSync(stop, c.drainerConfigInformer.HasSynced, c.kvmConfigInformer.HasSynced, c.podInformer.HasSynced) {
+		t.Fatalf("expected caches to be synced")
+	}
+
+	metric.Reconciliations.Set("al9qy", now.Add(-time.Minute))
+	defer metric.Reconciliations.Delete("al9qy")
+
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+
+	got := map[string]float64{}
+	for m := range ch {
+		var d dto.Metric
+		err := m.Write(&d)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := m.Desc().String()
+		for _, l := range d.GetLabel() {
+			name += "," + l.GetName() + "=" + l.GetValue()
+		}
+		got[name] = d.GetGauge().GetValue()
+	}
+
+	expected := map[string]float64{
+		desiredVMsDesc.String() + ",cluster_id=al9qy,role=master":                    1,
+		desiredVMsDesc.String() + ",cluster_id=al9qy,role=worker":                    3,
+		readyVMsDesc.String() + ",cluster_id=al9qy,role=master":                      1,
+		readyVMsDesc.String() + ",cluster_id=al9qy,role=worker":                      2,
+		drainingPodsDesc.String() + ",cluster_id=al9qy":                              1,
+		drainerConfigAgeDesc.String() + ",cluster_id=al9qy,drainer_config=worker-w1": 600,
+		updatingDesc.String() + ",cluster_id=al9qy":                                  1,
+		nodeIndexesDesc.String() + ",cluster_id=al9qy":                               4,
+		lastReconciliationDesc.String() + ",cluster_id=al9qy":                        60,
+	}
+
+	// Durations are computed from the time of collection, so they are
+	// rounded to seconds before comparing.
+	for k, v := range got {
+		got[k] = float64(int(v))
+	}
+
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Fatalf("metrics not met after Collect(): (-got +expected)\n%s\n", diff)
+	}
+}
+
+func newPod(name, role string, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "al9qy",
+			Labels: map[string]string{
+				"app": role,
+			},
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: ready},
+			},
+		},
+	}
+}
@@ -0,0 +1,433 @@
+// Package registry collects the version bundles the operator reconciles. Each
+// version bundle package registers itself upon import, declaring its version
+// bundle and how to build its cluster, deleter and drainer resource sets from
+// the version independent Config. The controllers build their resource sets by
+// iterating the registry, so adding a release only requires a new version
+// bundle package registering itself and an import in the controller package.
+//
+// Releases which only change images, cloud config templates or single
+// resources of a previous release do not copy its package. They extend the
+// previous version bundle and declare what they change as Delta.
+package registry
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/certs"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/giantswarm/operatorkit/controller"
+	"github.com/giantswarm/randomkeys"
+	"github.com/giantswarm/tenantcluster"
+	"github.com/giantswarm/versionbundle"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/rollout"
+)
+
+const (
+	ImageEtcd               = "etcd"
+	ImageK8sEndpointUpdater = "k8s-endpoint-updater"
+	ImageK8sKVM             = "k8s-kvm"
+	ImageK8sKVMHealth       = "k8s-kvm-health"
+	ImageShutdownDeferrer   = "shutdown-deferrer"
+)
+
+var (
+	mutex   sync.Mutex
+	bundles = map[string]Bundle{}
+)
+
+// Config holds the version independent dependencies and settings resource
+// sets of all version bundles are built from. Settings only known to certain
+// version bundles are provided using Tenant, which the version bundles not
+// knowing about them ignore. Controllers only provide the parts their resource
+// sets need, e.g. the drainer does not provide a CertsSearcher.
+type Config struct {
+	CertsSearcher      certs.Interface
+	G8sClient          versioned.Interface
+	K8sClient          kubernetes.Interface
+	Logger             micrologger.Logger
+	RandomkeysSearcher randomkeys.Interface
+	TenantCluster      tenantcluster.Interface
+
+	DNSServers         string
+	GuestUpdateEnabled bool
+	// GuestUpdateLimiter is shared by the resource sets of all version bundles
+	// so that the maximum number of tenant clusters being updated at the same
+	// time applies across version bundles.
+	GuestUpdateLimiter *rollout.Limiter
+	IgnitionPath       string
+	OIDC               OIDC
+	ProjectName        string
+	SSOPublicKey       string
+	Tenant             Tenant
+
+	// Version and Delta are set by the registry when building the resource
+	// sets of a version bundle. Version is the version of the version bundle
+	// the resource sets are built for, which differs from the version of the
+	// implementing package in case the version bundle extends another one.
+	// Delta is what the version bundle changes compared to the version bundle
+	// it extends.
+	Version string
+	Delta   Delta
+}
+
+// OIDC represents the configuration of the OIDC authorization provider.
+type OIDC struct {
+	ClientID      string
+	IssuerURL     string
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+// Tenant represents the settings of tenant clusters resolved from the operator
+// flags.
+type Tenant struct {
+	APIServer  TenantAPIServer
+	Drain      TenantDrain
+	EtcdBackup TenantEtcdBackup
+	Ignition   TenantIgnition
+	NodeIndex  TenantNodeIndex
+	Resize     TenantResize
+	Update     TenantUpdate
+}
+
+// TenantAPIServer represents the configuration of the API servers of tenant
+// clusters.
+type TenantAPIServer struct {
+	AdmissionPlugins TenantAPIServerAdmissionPlugins
+	Audit            TenantAPIServerAudit
+	// EncryptionProviders are the providers of the encryption config in the
+	// order they are listed in the config.
+	EncryptionProviders []string
+	ExtraSANs           []string
+	// FeatureGates are feature gates like Name=true.
+	FeatureGates []string
+}
+
+// TenantAPIServerAdmissionPlugins represents the admission plugins enabled and
+// disabled in addition to the defaults.
+type TenantAPIServerAdmissionPlugins struct {
+	Disable []string
+	Enable  []string
+}
+
+// TenantAPIServerAudit represents the audit logging configuration.
+type TenantAPIServerAudit struct {
+	LogFormat  string
+	LogMode    string
+	PolicyFile string
+}
+
+// TenantDrain represents the drain policy of tenant cluster nodes.
+type TenantDrain struct {
+	// Builtin enables the drainer of the operator instead of relying on an
+	// external one.
+	Builtin             bool
+	EvictionGracePeriod time.Duration
+	ForceAfterTimeout   bool
+	Timeout             time.Duration
+}
+
+// TenantEtcdBackup represents the etcd backup configuration. An empty Target
+// disables etcd backups and restores.
+type TenantEtcdBackup struct {
+	Interval time.Duration
+	S3       TenantEtcdBackupS3
+	Target   string
+}
+
+// TenantEtcdBackupS3 represents the configuration of S3 compatible etcd backup
+// targets.
+type TenantEtcdBackupS3 struct {
+	AccessKeyID     string
+	Endpoint        string
+	Region          string
+	SecretAccessKey string
+}
+
+// TenantIgnition represents the output of the cloud configs of tenant cluster
+// nodes. An empty Format keeps the format declared by the version bundle.
+type TenantIgnition struct {
+	Format  string
+	MaxSize int
+}
+
+// TenantNodeIndex represents the node index allocation policy.
+type TenantNodeIndex struct {
+	MaxIndex         int
+	QuarantinePeriod time.Duration
+}
+
+// TenantResize represents the resize policy of tenant cluster VMs.
+type TenantResize struct {
+	CapacityCheck bool
+}
+
+// TenantUpdate represents the rolling update policy of tenant cluster nodes.
+type TenantUpdate struct {
+	BatchSize             int
+	BatchWait             time.Duration
+	MastersFirst          bool
+	MaxUnavailableMasters int
+	MaxUnavailableWorkers int
+}
+
+// Delta describes what a version bundle changes compared to the version
+// bundle it extends. Empty fields are inherited from the extended version
+// bundle.
+type Delta struct {
+	// CloudConfigFormat is the output format the cloud configs of tenant
+	// cluster nodes are rendered in, e.g. base64 or ignition. Formats other
+	// than base64 require CloudConfigSecretSupport. Empty means base64.
+	CloudConfigFormat string
+	// CloudConfigSecretSupport declares that the k8s-kvm image of the version
+	// bundle reads the CLOUD_CONFIG_FORMAT and CLOUD_CONFIG_SECRET_PATH
+	// environment variables. Only then the secret part of the cloud configs is
+	// moved from the ConfigMaps into Secrets mounted into the VM pods. Once
+	// declared it is inherited by extending version bundles.
+	CloudConfigSecretSupport bool
+	// CloudConfigTemplates are the k8scloudconfig templates the cloud configs
+	// of tenant cluster nodes are rendered with.
+	CloudConfigTemplates CloudConfigTemplates
+	// Images maps image names like ImageK8sKVM to the images used for them.
+	Images map[string]string
+	// Resources maps resource names to functions building the resources which
+	// replace the equally named resources of the extended version bundle.
+	Resources map[string]ResourceFunc
+}
+
+// CloudConfigTemplates represents the k8scloudconfig templates of masters and
+// workers.
+type CloudConfigTemplates struct {
+	Master string
+	Worker string
+}
+
+// ResourceFunc builds a resource of a version bundle.
+type ResourceFunc func(config Config) (controller.Resource, error)
+
+// ResourceSetFunc builds a resource set of a version bundle.
+type ResourceSetFunc func(config Config) (*controller.ResourceSet, error)
+
+// Bundle is what a version bundle registers. Version bundles either implement
+// their resource sets themselves or extend another version bundle, in which
+// case they only declare their version bundle and what they change using
+// Delta. The extended version bundle has to be Extensible, which means its
+// resource sets apply Config.Delta.
+type Bundle struct {
+	VersionBundle func() versionbundle.Bundle
+
+	Delta      Delta
+	Extends    string
+	Extensible bool
+
+	NewClusterResourceSet ResourceSetFunc
+	NewDeleterResourceSet ResourceSetFunc
+	NewDrainerResourceSet ResourceSetFunc
+}
+
+// Register adds the given version bundle to the registry. It is meant to be
+// called from init functions of version bundle packages and therefore panics
+// in case the version bundle is incomplete or registered twice.
+func Register(b Bundle) {
+	err := validate(b)
+	if err != nil {
+		panic(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	version := b.VersionBundle().Version
+	_, ok := bundles[version]
+	if ok {
+		panic(microerror.Maskf(invalidConfigError, "version bundle %s registered twice", version))
+	}
+
+	bundles[version] = b
+}
+
+// Bundles returns all registered version bundles ordered by version. Version
+// bundles extending other version bundles are resolved, so that all returned
+// version bundles provide resource sets. The resource sets of the returned
+// version bundles are built with Config.Version and Config.Delta set for the
+// respective version bundle.
+func Bundles() ([]Bundle, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var list []Bundle
+	for _, vb := range sortedVersionBundles() {
+		b, err := resolve(bundles[vb.Version], nil)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		version := vb.Version
+		delta := b.Delta
+		withVersion := func(f ResourceSetFunc) ResourceSetFunc {
+			return func(config Config) (*controller.ResourceSet, error) {
+				config.Version = version
+				config.Delta = delta
+				return f(config)
+			}
+		}
+
+		b.NewClusterResourceSet = withVersion(b.NewClusterResourceSet)
+		b.NewDeleterResourceSet = withVersion(b.NewDeleterResourceSet)
+		b.NewDrainerResourceSet = withVersion(b.NewDrainerResourceSet)
+
+		list = append(list, b)
+	}
+
+	return list, nil
+}
+
+// VersionBundles returns the version bundles of all registered version bundles
+// ordered by version.
+func VersionBundles() []versionbundle.Bundle {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return sortedVersionBundles()
+}
+
+// VersionsBasedOn returns the given version and the versions of all version
+// bundles extending the version bundle of the given version, either directly
+// or through other version bundles. These version bundles share the resource
+// implementations of the given one.
+func VersionsBasedOn(version string) []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	versions := []string{version}
+	for _, vb := range sortedVersionBundles() {
+		b := bundles[vb.Version]
+		// The number of steps is bounded by the number of version bundles
+		// because cycles are only reported by Bundles.
+		for i := 0; i < len(bundles) && b.Extends != ""; i++ {
+			if b.Extends == version {
+				versions = append(versions, vb.Version)
+				break
+			}
+			b = bundles[b.Extends]
+		}
+	}
+
+	return versions
+}
+
+// resolve returns the given version bundle with the resource sets and the
+// delta of the version bundles it extends. seen holds the versions of the
+// version bundles extending the given one in order to detect cycles.
+func resolve(b Bundle, seen []string) (Bundle, error) {
+	if b.Extends == "" {
+		return b, nil
+	}
+
+	version := b.VersionBundle().Version
+	for _, s := range seen {
+		if s == version {
+			return Bundle{}, microerror.Maskf(invalidConfigError, "version bundle %s extends itself", version)
+		}
+	}
+
+	extended, ok := bundles[b.Extends]
+	if !ok {
+		return Bundle{}, microerror.Maskf(invalidConfigError, "version bundle %s extends version bundle %s which is not registered", version, b.Extends)
+	}
+
+	extended, err := resolve(extended, append(seen, version))
+	if err != nil {
+		return Bundle{}, microerror.Mask(err)
+	}
+	if !extended.Extensible {
+		return Bundle{}, microerror.Maskf(invalidConfigError, "version bundle %s extends version bundle %s which is not extensible", version, b.Extends)
+	}
+
+	b.Delta = mergeDelta(extended.Delta, b.Delta)
+	b.Extensible = true
+	if b.NewClusterResourceSet == nil {
+		b.NewClusterResourceSet = extended.NewClusterResourceSet
+	}
+	if b.NewDeleterResourceSet == nil {
+		b.NewDeleterResourceSet = extended.NewDeleterResourceSet
+	}
+	if b.NewDrainerResourceSet == nil {
+		b.NewDrainerResourceSet = extended.NewDrainerResourceSet
+	}
+
+	return b, nil
+}
+
+// mergeDelta returns the given base delta overridden by the given delta.
+func mergeDelta(base, delta Delta) Delta {
+	merged := Delta{
+		CloudConfigFormat:        base.CloudConfigFormat,
+		CloudConfigSecretSupport: base.CloudConfigSecretSupport || delta.CloudConfigSecretSupport,
+		CloudConfigTemplates:     base.CloudConfigTemplates,
+		Images:                   map[string]string{},
+		Resources:                map[string]ResourceFunc{},
+	}
+
+	if delta.CloudConfigFormat != "" {
+		merged.CloudConfigFormat = delta.CloudConfigFormat
+	}
+	if delta.CloudConfigTemplates.Master != "" {
+		merged.CloudConfigTemplates.Master = delta.CloudConfigTemplates.Master
+	}
+	if delta.CloudConfigTemplates.Worker != "" {
+		merged.CloudConfigTemplates.Worker = delta.CloudConfigTemplates.Worker
+	}
+
+	for _, d := range []Delta{base, delta} {
+		for k, v := range d.Images {
+			merged.Images[k] = v
+		}
+		for k, v := range d.Resources {
+			merged.Resources[k] = v
+		}
+	}
+
+	return merged
+}
+
+func sortedVersionBundles() []versionbundle.Bundle {
+	var versionBundles []versionbundle.Bundle
+	for _, b := range bundles {
+		versionBundles = append(versionBundles, b.VersionBundle())
+	}
+	sort.Sort(versionbundle.SortBundlesByVersion(versionBundles))
+
+	return versionBundles
+}
+
+func validate(b Bundle) error {
+	if b.VersionBundle == nil {
+		return microerror.Maskf(invalidConfigError, "%T.VersionBundle must not be empty", b)
+	}
+	if b.VersionBundle().Version == "" {
+		return microerror.Maskf(invalidConfigError, "%T.VersionBundle must define a version", b)
+	}
+	if b.Extends != "" {
+		// Missing resource sets are inherited from the extended version
+		// bundle, which might not be registered yet.
+		return nil
+	}
+	if b.NewClusterResourceSet == nil {
+		return microerror.Maskf(invalidConfigError, "%T.NewClusterResourceSet of version bundle %s must not be empty", b, b.VersionBundle().Version)
+	}
+	if b.NewDeleterResourceSet == nil {
+		return microerror.Maskf(invalidConfigError, "%T.NewDeleterResourceSet of version bundle %s must not be empty", b, b.VersionBundle().Version)
+	}
+	if b.NewDrainerResourceSet == nil {
+		return microerror.Maskf(invalidConfigError, "%T.NewDrainerResourceSet of version bundle %s must not be empty", b, b.VersionBundle().Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/operatorkit/controller"
+	"github.com/giantswarm/versionbundle"
+)
+
+func Test_Registry(t *testing.T) {
+	defer func() {
+		bundles = map[string]Bundle{}
+	}()
+
+	newResourceSet := func(config Config) (*controller.ResourceSet, error) {
+		return nil, nil
+	}
+	newBundle := func(version string) Bundle {
+		return Bundle{
+			VersionBundle: func() versionbundle.Bundle {
+				return versionbundle.Bundle{Version: version}
+			},
+
+			NewClusterResourceSet: newResourceSet,
+			NewDeleterResourceSet: newResourceSet,
+			NewDrainerResourceSet: newResourceSet,
+		}
+	}
+	expectPanic := func(t *testing.T, b Bundle) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				t.Fatalf("expected panic")
+			}
+			err, ok := r.(error)
+			if !ok || !IsInvalidConfig(err) {
+				t.Fatalf("expected invalidConfigError got %#v", r)
+			}
+		}()
+
+		Register(b)
+	}
+
+	Register(newBundle("3.10.0"))
+	Register(newBundle("3.2.0"))
+	Register(newBundle("3.2.1"))
+
+	t.Run("case 0: bundles are ordered by version", func(t *testing.T) {
+		var versions []string
+		for _, vb := range VersionBundles() {
+			versions = append(versions, vb.Version)
+		}
+
+		expected := []string{"3.2.0", "3.2.1", "3.10.0"}
+		if !reflect.DeepEqual(versions, expected) {
+			t.Fatalf("expected %#v got %#v", expected, versions)
+		}
+	})
+
+	t.Run("case 1: duplicated versions are rejected", func(t *testing.T) {
+		expectPanic(t, newBundle("3.2.0"))
+	})
+
+	t.Run("case 2: bundles without drainer resource set are rejected", func(t *testing.T) {
+		b := newBundle("3.3.0")
+		b.NewDrainerResourceSet = nil
+		expectPanic(t, b)
+	})
+
+	t.Run("case 3: bundles without version are rejected", func(t *testing.T) {
+		expectPanic(t, newBundle(""))
+	})
+}
+
+func Test_Registry_Extends(t *testing.T) {
+	defer func() {
+		bundles = map[string]Bundle{}
+	}()
+
+	var configs []Config
+	newResourceSet := func(config Config) (*controller.ResourceSet, error) {
+		configs = append(configs, config)
+		return nil, nil
+	}
+	newVersionBundle := func(version string) func() versionbundle.Bundle {
+		return func() versionbundle.Bundle {
+			return versionbundle.Bundle{Version: version}
+		}
+	}
+	newResource := func(config Config) (controller.Resource, error) {
+		return nil, nil
+	}
+
+	Register(Bundle{
+		VersionBundle: newVersionBundle("3.2.0"),
+
+		Extensible: true,
+
+		NewClusterResourceSet: newResourceSet,
+		NewDeleterResourceSet: newResourceSet,
+		NewDrainerResourceSet: newResourceSet,
+	})
+	Register(Bundle{
+		VersionBundle: newVersionBundle("3.2.1"),
+
+		Delta: Delta{
+			CloudConfigFormat:        "ignition",
+			CloudConfigSecretSupport: true,
+			Images: map[string]string{
+				ImageK8sKVM: "k8s-kvm:3.2.1",
+			},
+		},
+		Extends: "3.2.0",
+	})
+	Register(Bundle{
+		VersionBundle: newVersionBundle("3.2.2"),
+
+		Delta: Delta{
+			CloudConfigTemplates: CloudConfigTemplates{
+				Master: "master",
+			},
+			Images: map[string]string{
+				ImageEtcd: "etcd:3.2.2",
+			},
+			Resources: map[string]ResourceFunc{
+				"deploymentv22": newResource,
+			},
+		},
+		Extends: "3.2.1",
+	})
+
+	t.Run("case 0: extending bundles inherit resource sets and deltas", func(t *testing.T) {
+		list, err := Bundles()
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		for _, b := range list {
+			_, err := b.NewClusterResourceSet(Config{})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+		}
+
+		if len(configs) != 3 {
+			t.Fatalf("expected 3 resource sets got %d", len(configs))
+		}
+
+		var versions []string
+		for _, c := range configs {
+			versions = append(versions, c.Version)
+		}
+		expectedVersions := []string{"3.2.0", "3.2.1", "3.2.2"}
+		if !reflect.DeepEqual(versions, expectedVersions) {
+			t.Fatalf("expected %#v got %#v", expectedVersions, versions)
+		}
+
+		if len(configs[0].Delta.Images) != 0 {
+			t.Fatalf("expected no images of base bundle got %#v", configs[0].Delta.Images)
+		}
+		expectedImages := map[string]string{
+			ImageEtcd:   "etcd:3.2.2",
+			ImageK8sKVM: "k8s-kvm:3.2.1",
+		}
+		if !reflect.DeepEqual(configs[2].Delta.Images, expectedImages) {
+			t.Fatalf("expected %#v got %#v", expectedImages, configs[2].Delta.Images)
+		}
+		if configs[2].Delta.CloudConfigFormat != "ignition" {
+			t.Fatalf("expected inherited cloud config format got %#q", configs[2].Delta.CloudConfigFormat)
+		}
+		if configs[0].Delta.CloudConfigSecretSupport {
+			t.Fatalf("expected no cloud config secret support of base bundle")
+		}
+		if !configs[2].Delta.CloudConfigSecretSupport {
+			t.Fatalf("expected inherited cloud config secret support")
+		}
+		if configs[2].Delta.CloudConfigTemplates.Master != "master" {
+			t.Fatalf("expected master template got %#q", configs[2].Delta.CloudConfigTemplates.Master)
+		}
+		if _, ok := configs[2].Delta.Resources["deploymentv22"]; !ok {
+			t.Fatalf("expected replaced deployment resource")
+		}
+	})
+
+	t.Run("case 1: versions based on a bundle include extending bundles", func(t *testing.T) {
+		versions := VersionsBasedOn("3.2.0")
+
+		expected := []string{"3.2.0", "3.2.1", "3.2.2"}
+		if !reflect.DeepEqual(versions, expected) {
+			t.Fatalf("expected %#v got %#v", expected, versions)
+		}
+	})
+
+	t.Run("case 2: extending unknown bundles is rejected", func(t *testing.T) {
+		Register(Bundle{
+			VersionBundle: newVersionBundle("3.3.0"),
+
+			Extends: "3.2.9",
+		})
+		defer delete(bundles, "3.3.0")
+
+		_, err := Bundles()
+		if !IsInvalidConfig(err) {
+			t.Fatalf("expected invalidConfigError got %#v", err)
+		}
+	})
+
+	t.Run("case 3: extending not extensible bundles is rejected", func(t *testing.T) {
+		Register(Bundle{
+			VersionBundle: newVersionBundle("3.3.0"),
+
+			NewClusterResourceSet: newResourceSet,
+			NewDeleterResourceSet: newResourceSet,
+			NewDrainerResourceSet: newResourceSet,
+		})
+		Register(Bundle{
+			VersionBundle: newVersionBundle("3.3.1"),
+
+			Extends: "3.3.0",
+		})
+		defer delete(bundles, "3.3.0")
+		defer delete(bundles, "3.3.1")
+
+		_, err := Bundles()
+		if !IsInvalidConfig(err) {
+			t.Fatalf("expected invalidConfigError got %#v", err)
+		}
+	})
+}
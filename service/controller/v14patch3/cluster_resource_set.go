@@ -3,7 +3,6 @@ package v14patch3
 import (
 	"context"
 
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/certs"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
@@ -11,11 +10,10 @@ import (
 	"github.com/giantswarm/operatorkit/controller/context/updateallowedcontext"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"github.com/giantswarm/randomkeys"
 	"github.com/giantswarm/statusresource"
 	"github.com/giantswarm/tenantcluster"
-	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch3/cloudconfig"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch3/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch3/resource/clusterrolebinding"
@@ -28,20 +26,7 @@ import (
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch3/resource/serviceaccount"
 )
 
-type ClusterResourceSetConfig struct {
-	CertsSearcher      certs.Interface
-	G8sClient          versioned.Interface
-	K8sClient          kubernetes.Interface
-	Logger             micrologger.Logger
-	RandomkeysSearcher randomkeys.Interface
-
-	OIDC               cloudconfig.OIDCConfig
-	GuestUpdateEnabled bool
-	ProjectName        string
-	SSOPublicKey       string
-}
-
-func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.ResourceSet, error) {
+func NewClusterResourceSet(config registry.Config) (*controller.ResourceSet, error) {
 	var err error
 
 	if config.SSOPublicKey == "" {
@@ -53,7 +38,7 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		c := cloudconfig.Config{
 			Logger: config.Logger,
 
-			OIDC:         config.OIDC,
+			OIDC:         cloudconfig.OIDCConfig(config.OIDC),
 			SSOPublicKey: config.SSOPublicKey,
 		}
 
@@ -1,27 +1,17 @@
 package v14patch3
 
 import (
-	"github.com/giantswarm/certs"
 	"github.com/giantswarm/microerror"
-	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/controller"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch3/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch3/resource/node"
 )
 
-type DeleterResourceSetConfig struct {
-	CertsSearcher certs.Interface
-	K8sClient     kubernetes.Interface
-	Logger        micrologger.Logger
-
-	ProjectName string
-}
-
-func NewDeleterResourceSet(config DeleterResourceSetConfig) (*controller.ResourceSet, error) {
+func NewDeleterResourceSet(config registry.Config) (*controller.ResourceSet, error) {
 	var err error
 
 	handlesFunc := func(obj interface{}) bool {
@@ -2,8 +2,20 @@ package v14patch3
 
 import (
 	"github.com/giantswarm/versionbundle"
+
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 )
 
+func init() {
+	registry.Register(registry.Bundle{
+		VersionBundle: VersionBundle,
+
+		NewClusterResourceSet: NewClusterResourceSet,
+		NewDeleterResourceSet: NewDeleterResourceSet,
+		NewDrainerResourceSet: NewDrainerResourceSet,
+	})
+}
+
 func VersionBundle() versionbundle.Bundle {
 	return versionbundle.Bundle{
 		Changelogs: []versionbundle.Changelog{
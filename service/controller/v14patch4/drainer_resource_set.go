@@ -1,28 +1,18 @@
 package v14patch4
 
 import (
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
-	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/controller"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch4/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch4/resource/endpoint"
 	"github.com/giantswarm/kvm-operator/service/controller/v14patch4/resource/pod"
 )
 
-type DrainerResourceSetConfig struct {
-	G8sClient versioned.Interface
-	K8sClient kubernetes.Interface
-	Logger    micrologger.Logger
-
-	ProjectName string
-}
-
-func NewDrainerResourceSet(config DrainerResourceSetConfig) (*controller.ResourceSet, error) {
+func NewDrainerResourceSet(config registry.Config) (*controller.ResourceSet, error) {
 	var err error
 
 	handlesFunc := func(obj interface{}) bool {
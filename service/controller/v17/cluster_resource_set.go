@@ -3,8 +3,8 @@ package v17
 import (
 	"context"
 
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/certs"
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 	"github.com/giantswarm/kvm-operator/service/controller/v17/cloudconfig"
 	"github.com/giantswarm/kvm-operator/service/controller/v17/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v17/resource/clusterrolebinding"
@@ -21,26 +21,11 @@ import (
 	"github.com/giantswarm/operatorkit/controller/context/updateallowedcontext"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"github.com/giantswarm/randomkeys"
 	"github.com/giantswarm/statusresource"
 	"github.com/giantswarm/tenantcluster"
-	"k8s.io/client-go/kubernetes"
 )
 
-type ClusterResourceSetConfig struct {
-	CertsSearcher      certs.Interface
-	G8sClient          versioned.Interface
-	K8sClient          kubernetes.Interface
-	Logger             micrologger.Logger
-	RandomkeysSearcher randomkeys.Interface
-
-	OIDC               cloudconfig.OIDCConfig
-	GuestUpdateEnabled bool
-	ProjectName        string
-	SSOPublicKey       string
-}
-
-func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.ResourceSet, error) {
+func NewClusterResourceSet(config registry.Config) (*controller.ResourceSet, error) {
 	var err error
 
 	var cloudConfig *cloudconfig.CloudConfig
@@ -48,7 +33,7 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		c := cloudconfig.Config{
 			Logger: config.Logger,
 
-			OIDC:         config.OIDC,
+			OIDC:         cloudconfig.OIDCConfig(config.OIDC),
 			SSOPublicKey: config.SSOPublicKey,
 		}
 
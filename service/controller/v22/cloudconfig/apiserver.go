@@ -0,0 +1,484 @@
+package cloudconfig
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	AuditLogFormatJSON   = "json"
+	AuditLogFormatLegacy = "legacy"
+
+	AuditLogModeBatch    = "batch"
+	AuditLogModeBlocking = "blocking"
+
+	EncryptionProviderAESCBC    = "aescbc"
+	EncryptionProviderAESGCM    = "aesgcm"
+	EncryptionProviderIdentity  = "identity"
+	EncryptionProviderSecretbox = "secretbox"
+
+	auditPolicyAsset = "policies/audit-policy.yaml"
+)
+
+var (
+	apiServerArgRegexp       = regexp.MustCompile(`^--[a-z0-9][a-z0-9-]*(=.*)?$`)
+	admissionPluginRegexp    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+	featureGateRegexp        = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	encryptionProviderRegexp = regexp.MustCompile(`^(` + EncryptionProviderAESCBC + `|` + EncryptionProviderAESGCM + `|` + EncryptionProviderIdentity + `|` + EncryptionProviderSecretbox + `)$`)
+)
+
+// reservedAPIServerFlags are the flags hardcoded in the API server manifest of
+// k8scloudconfig. Extra args are put in front of them and the API server takes
+// the last value of a flag, so setting them via extra args would silently have
+// no effect. --enable-admission-plugins is not reserved, because its values
+// are appended to the ones of the manifest.
+var reservedAPIServerFlags = map[string]bool{
+	"--advertise-address":                  true,
+	"--allow-privileged":                   true,
+	"--anonymous-auth":                     true,
+	"--audit-log-maxage":                   true,
+	"--audit-log-maxbackup":                true,
+	"--audit-log-maxsize":                  true,
+	"--audit-log-path":                     true,
+	"--audit-policy-file":                  true,
+	"--authorization-mode":                 true,
+	"--bind-address":                       true,
+	"--client-ca-file":                     true,
+	"--cloud-provider":                     true,
+	"--encryption-provider-config":         true,
+	"--etcd-cafile":                        true,
+	"--etcd-certfile":                      true,
+	"--etcd-keyfile":                       true,
+	"--etcd-prefix":                        true,
+	"--etcd-servers":                       true,
+	"--insecure-port":                      true,
+	"--kubelet-https":                      true,
+	"--kubelet-preferred-address-types":    true,
+	"--logtostderr":                        true,
+	"--profiling":                          true,
+	"--proxy-client-cert-file":             true,
+	"--proxy-client-key-file":              true,
+	"--requestheader-allowed-names":        true,
+	"--requestheader-client-ca-file":       true,
+	"--requestheader-extra-headers-prefix": true,
+	"--requestheader-group-headers":        true,
+	"--requestheader-username-headers":     true,
+	"--runtime-config":                     true,
+	"--secure-port":                        true,
+	"--service-account-key-file":           true,
+	"--service-account-lookup":             true,
+	"--service-cluster-ip-range":           true,
+	"--tls-cert-file":                      true,
+	"--tls-private-key-file":               true,
+}
+
+// defaultAdmissionPlugins are the admission plugins enabled by the API server
+// manifest of k8scloudconfig. They cannot be disabled, because the API server
+// refuses to start with plugins being enabled and disabled at the same time.
+var defaultAdmissionPlugins = []string{
+	"DefaultStorageClass",
+	"DefaultTolerationSeconds",
+	"LimitRanger",
+	"MutatingAdmissionWebhook",
+	"NamespaceLifecycle",
+	"PersistentVolumeClaimResize",
+	"PodSecurityPolicy",
+	"Priority",
+	"ResourceQuota",
+	"ServiceAccount",
+	"ValidatingAdmissionWebhook",
+}
+
+// APIServerConfig represents the configuration of the API servers of tenant
+// clusters. The installation wide configuration is given by flags. It can be
+// overridden per tenant cluster with the YAML encoded configuration of the API
+// server config annotation of the KVMConfig.
+type APIServerConfig struct {
+	AdmissionPlugins APIServerAdmissionPlugins `json:"admissionPlugins,omitempty"`
+	Audit            APIServerAudit            `json:"audit,omitempty"`
+	// EncryptionProviders are the providers of the encryption config in the
+	// given order. The first provider encrypts new data, all of them decrypt
+	// existing data. aescbc must be part of the list, because existing data
+	// is encrypted with it. Empty means aescbc and identity.
+	EncryptionProviders []string `json:"encryptionProviders,omitempty"`
+	// ExtraArgs are additional API server flags of the form --name=value. They
+	// are only read from the API server config annotation.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	// ExtraSANs are additional host names and IPs the API servers are reached
+	// with. The API server certificates are issued by cert-operator, so the
+	// SANs are only checked against the certificates.
+	ExtraSANs    []string        `json:"extraSANs,omitempty"`
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// APIServerAdmissionPlugins holds the admission plugins enabled in addition to
+// the default ones and the plugins disabled.
+type APIServerAdmissionPlugins struct {
+	Disable []string `json:"disable,omitempty"`
+	Enable  []string `json:"enable,omitempty"`
+}
+
+// APIServerAudit represents the configuration of the audit log. Path and
+// retention of the log backend are managed by the API server manifest.
+type APIServerAudit struct {
+	LogFormat string `json:"logFormat,omitempty"`
+	LogMode   string `json:"logMode,omitempty"`
+	// Policy is the YAML encoded audit policy replacing the default one.
+	Policy string `json:"policy,omitempty"`
+}
+
+// ParseAPIServerConfig parses and validates the given YAML encoded API server
+// configuration.
+func ParseAPIServerConfig(data []byte) (APIServerConfig, error) {
+	var c APIServerConfig
+
+	err := yaml.Unmarshal(data, &c)
+	if err != nil {
+		return APIServerConfig{}, microerror.Maskf(invalidAPIServerConfigError, "%s", err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return APIServerConfig{}, microerror.Mask(err)
+	}
+
+	return c, nil
+}
+
+// ParseFeatureGates parses feature gates of the form Name=true or Name=false.
+func ParseFeatureGates(gates []string) (map[string]bool, error) {
+	if len(gates) == 0 {
+		return nil, nil
+	}
+
+	parsed := map[string]bool{}
+
+	for _, g := range gates {
+		split := strings.SplitN(g, "=", 2)
+		if len(split) != 2 {
+			return nil, microerror.Maskf(invalidAPIServerConfigError, "feature gate '%s' must have the form Name=true or Name=false", g)
+		}
+		enabled, err := strconv.ParseBool(split[1])
+		if err != nil {
+			return nil, microerror.Maskf(invalidAPIServerConfigError, "feature gate '%s' must have the form Name=true or Name=false", g)
+		}
+		if _, ok := parsed[split[0]]; ok {
+			return nil, microerror.Maskf(invalidAPIServerConfigError, "feature gate '%s' must not be given twice", split[0])
+		}
+
+		parsed[split[0]] = enabled
+	}
+
+	return parsed, nil
+}
+
+// Validate returns an invalidAPIServerConfigError in case the configuration
+// would break the API servers.
+func (c APIServerConfig) Validate() error {
+	enabled := map[string]bool{}
+	for _, p := range c.AdmissionPlugins.Enable {
+		if !admissionPluginRegexp.MatchString(p) {
+			return microerror.Maskf(invalidAPIServerConfigError, "admission plugin '%s' is invalid", p)
+		}
+		enabled[p] = true
+	}
+	for _, p := range c.AdmissionPlugins.Disable {
+		if !admissionPluginRegexp.MatchString(p) {
+			return microerror.Maskf(invalidAPIServerConfigError, "admission plugin '%s' is invalid", p)
+		}
+		if enabled[p] || containsString(defaultAdmissionPlugins, p) {
+			return microerror.Maskf(invalidAPIServerConfigError, "admission plugin '%s' must not be enabled and disabled", p)
+		}
+	}
+
+	switch c.Audit.LogFormat {
+	case "", AuditLogFormatJSON, AuditLogFormatLegacy:
+	default:
+		return microerror.Maskf(invalidAPIServerConfigError, "audit log format must be %q or %q", AuditLogFormatJSON, AuditLogFormatLegacy)
+	}
+	switch c.Audit.LogMode {
+	case "", AuditLogModeBatch, AuditLogModeBlocking:
+	default:
+		return microerror.Maskf(invalidAPIServerConfigError, "audit log mode must be %q or %q", AuditLogModeBatch, AuditLogModeBlocking)
+	}
+	if c.Audit.Policy != "" {
+		var policy struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		err := yaml.Unmarshal([]byte(c.Audit.Policy), &policy)
+		if err != nil {
+			return microerror.Maskf(invalidAPIServerConfigError, "audit policy: %s", err)
+		}
+		if !strings.HasPrefix(policy.APIVersion, "audit.k8s.io/") || policy.Kind != "Policy" {
+			return microerror.Maskf(invalidAPIServerConfigError, "audit policy must be of kind Policy of the audit.k8s.io API group")
+		}
+	}
+
+	if len(c.EncryptionProviders) != 0 {
+		seen := map[string]bool{}
+		for _, p := range c.EncryptionProviders {
+			if !encryptionProviderRegexp.MatchString(p) {
+				return microerror.Maskf(invalidAPIServerConfigError, "encryption provider '%s' is unknown", p)
+			}
+			if seen[p] {
+				return microerror.Maskf(invalidAPIServerConfigError, "encryption provider '%s' must not be given twice", p)
+			}
+			seen[p] = true
+		}
+		if !seen[EncryptionProviderAESCBC] {
+			return microerror.Maskf(invalidAPIServerConfigError, "encryption providers must contain %q to decrypt existing data", EncryptionProviderAESCBC)
+		}
+	}
+
+	for _, a := range c.ExtraArgs {
+		if !apiServerArgRegexp.MatchString(a) {
+			return microerror.Maskf(invalidAPIServerConfigError, "extra arg '%s' must have the form --name=value", a)
+		}
+		if reservedAPIServerFlags[apiServerFlagName(a)] {
+			return microerror.Maskf(invalidAPIServerConfigError, "extra arg '%s' is managed by the API server manifest", a)
+		}
+	}
+
+	for _, s := range c.ExtraSANs {
+		if len(validation.IsValidIP(s)) != 0 && len(validation.IsDNS1123Subdomain(s)) != 0 {
+			return microerror.Maskf(invalidAPIServerConfigError, "extra SAN '%s' must be a DNS name or an IP", s)
+		}
+	}
+
+	for g := range c.FeatureGates {
+		if !featureGateRegexp.MatchString(g) {
+			return microerror.Maskf(invalidAPIServerConfigError, "feature gate '%s' is invalid", g)
+		}
+	}
+
+	return nil
+}
+
+// merge returns the configuration overridden by the given one. Fields set in
+// the given configuration replace the ones of the receiver. Feature gates are
+// overridden one by one.
+func (c APIServerConfig) merge(o APIServerConfig) APIServerConfig {
+	merged := c
+
+	if len(o.AdmissionPlugins.Disable) != 0 {
+		merged.AdmissionPlugins.Disable = o.AdmissionPlugins.Disable
+	}
+	if len(o.AdmissionPlugins.Enable) != 0 {
+		merged.AdmissionPlugins.Enable = o.AdmissionPlugins.Enable
+	}
+	if o.Audit.LogFormat != "" {
+		merged.Audit.LogFormat = o.Audit.LogFormat
+	}
+	if o.Audit.LogMode != "" {
+		merged.Audit.LogMode = o.Audit.LogMode
+	}
+	if o.Audit.Policy != "" {
+		merged.Audit.Policy = o.Audit.Policy
+	}
+	if len(o.EncryptionProviders) != 0 {
+		merged.EncryptionProviders = o.EncryptionProviders
+	}
+	merged.ExtraArgs = append(append([]string{}, c.ExtraArgs...), o.ExtraArgs...)
+	if len(o.ExtraSANs) != 0 {
+		merged.ExtraSANs = o.ExtraSANs
+	}
+	if len(c.FeatureGates) != 0 || len(o.FeatureGates) != 0 {
+		merged.FeatureGates = map[string]bool{}
+		for g, enabled := range c.FeatureGates {
+			merged.FeatureGates[g] = enabled
+		}
+		for g, enabled := range o.FeatureGates {
+			merged.FeatureGates[g] = enabled
+		}
+	}
+
+	return merged
+}
+
+// apiServerConfig returns the API server configuration of the given tenant
+// cluster, which is the installation wide configuration overridden by the one
+// of the API server config annotation.
+func (c *CloudConfig) apiServerConfig(customObject v1alpha1.KVMConfig) (APIServerConfig, error) {
+	data := key.APIServerConfig(customObject)
+	if data == "" {
+		return c.apiServer, nil
+	}
+
+	o, err := ParseAPIServerConfig([]byte(data))
+	if err != nil {
+		return APIServerConfig{}, microerror.Mask(err)
+	}
+
+	merged := c.apiServer.merge(o)
+
+	err = merged.Validate()
+	if err != nil {
+		return APIServerConfig{}, microerror.Mask(err)
+	}
+
+	return merged, nil
+}
+
+// apiServerArgs returns the validated and deduplicated API server flags of the
+// given configuration and the OIDC configuration. The same flag given twice
+// with the same value is only passed once. The same flag given with different
+// values is rejected with an apiServerArgConflictError, because only one of
+// the values would take effect.
+func apiServerArgs(c APIServerConfig, oidc OIDCConfig) ([]string, error) {
+	type source struct {
+		name string
+		args []string
+	}
+
+	var sources []source
+	{
+		var args []string
+		if oidc.ClientID != "" {
+			args = append(args, fmt.Sprintf("--oidc-client-id=%s", oidc.ClientID))
+		}
+		if oidc.IssuerURL != "" {
+			args = append(args, fmt.Sprintf("--oidc-issuer-url=%s", oidc.IssuerURL))
+		}
+		if oidc.UsernameClaim != "" {
+			args = append(args, fmt.Sprintf("--oidc-username-claim=%s", oidc.UsernameClaim))
+		}
+		if oidc.GroupsClaim != "" {
+			args = append(args, fmt.Sprintf("--oidc-groups-claim=%s", oidc.GroupsClaim))
+		}
+		sources = append(sources, source{name: "OIDC config", args: args})
+	}
+	{
+		var args []string
+		if len(c.AdmissionPlugins.Enable) != 0 {
+			args = append(args, fmt.Sprintf("--enable-admission-plugins=%s", strings.Join(c.AdmissionPlugins.Enable, ",")))
+		}
+		if len(c.AdmissionPlugins.Disable) != 0 {
+			args = append(args, fmt.Sprintf("--disable-admission-plugins=%s", strings.Join(c.AdmissionPlugins.Disable, ",")))
+		}
+		if c.Audit.LogFormat != "" {
+			args = append(args, fmt.Sprintf("--audit-log-format=%s", c.Audit.LogFormat))
+		}
+		if c.Audit.LogMode != "" {
+			args = append(args, fmt.Sprintf("--audit-log-mode=%s", c.Audit.LogMode))
+		}
+		if len(c.FeatureGates) != 0 {
+			var gates []string
+			for g, enabled := range c.FeatureGates {
+				gates = append(gates, fmt.Sprintf("%s=%t", g, enabled))
+			}
+			sort.Strings(gates)
+			args = append(args, fmt.Sprintf("--feature-gates=%s", strings.Join(gates, ",")))
+		}
+		sources = append(sources, source{name: "API server config", args: args})
+	}
+	sources = append(sources, source{name: "extra args", args: c.ExtraArgs})
+
+	var args []string
+	{
+		seen := map[string]string{}
+		seenBy := map[string]string{}
+
+		for _, s := range sources {
+			for _, a := range s.args {
+				if !apiServerArgRegexp.MatchString(a) {
+					return nil, microerror.Maskf(invalidAPIServerConfigError, "arg '%s' of %s must have the form --name=value", a, s.name)
+				}
+
+				name := apiServerFlagName(a)
+				if reservedAPIServerFlags[name] {
+					return nil, microerror.Maskf(invalidAPIServerConfigError, "arg '%s' of %s is managed by the API server manifest", a, s.name)
+				}
+
+				existing, ok := seen[name]
+				if ok && existing == a {
+					continue
+				} else if ok {
+					return nil, microerror.Maskf(apiServerArgConflictError, "arg '%s' of %s conflicts with '%s' of %s", a, s.name, existing, seenBy[name])
+				}
+
+				seen[name] = a
+				seenBy[name] = s.name
+				args = append(args, a)
+			}
+		}
+	}
+
+	return args, nil
+}
+
+// apiServerFlagName returns the flag name of the given API server arg, e.g.
+// --feature-gates for --feature-gates=PodPriority=true.
+func apiServerFlagName(arg string) string {
+	return strings.SplitN(arg, "=", 2)[0]
+}
+
+// missingSANs returns the extra SANs not covered by the given PEM encoded
+// certificate.
+func missingSANs(crt []byte, sans []string) ([]string, error) {
+	b, _ := pem.Decode(crt)
+	if b == nil {
+		return nil, microerror.Maskf(invalidAPIServerConfigError, "certificate must be PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var missing []string
+	for _, s := range sans {
+		if cert.VerifyHostname(s) != nil {
+			missing = append(missing, s)
+		}
+	}
+
+	return missing, nil
+}
+
+// renderEncryptionConfig returns the API server encryption config using the
+// given providers in order. All providers but identity use the encryption key
+// of the tenant cluster.
+func renderEncryptionConfig(providers []string, encryptionKey string) string {
+	var b strings.Builder
+
+	b.WriteString("kind: EncryptionConfiguration\n")
+	b.WriteString("apiVersion: apiserver.config.k8s.io/v1\n")
+	b.WriteString("resources:\n")
+	b.WriteString("  - resources:\n")
+	b.WriteString("    - secrets\n")
+	b.WriteString("    providers:\n")
+	for _, p := range providers {
+		if p == EncryptionProviderIdentity {
+			b.WriteString("    - identity: {}\n")
+			continue
+		}
+		b.WriteString(fmt.Sprintf("    - %s:\n", p))
+		b.WriteString("        keys:\n")
+		b.WriteString("        - name: key1\n")
+		b.WriteString(fmt.Sprintf("          secret: %s\n", encryptionKey))
+	}
+
+	return b.String()
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,327 @@
+package cloudconfig
+
+import (
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_ParseAPIServerConfig(t *testing.T) {
+	testCases := []struct {
+		name           string
+		data           string
+		expectedConfig APIServerConfig
+		errorMatcher   func(error) bool
+	}{
+		{
+			name: "case 0: full configuration",
+			data: `
+admissionPlugins:
+  enable: [AlwaysPullImages]
+  disable: [StorageObjectInUseProtection]
+audit:
+  logFormat: json
+  logMode: batch
+  policy: |
+    apiVersion: audit.k8s.io/v1
+    kind: Policy
+encryptionProviders: [secretbox, aescbc, identity]
+extraArgs: [--request-timeout=2m]
+extraSANs: [api.example.com, 10.0.0.1]
+featureGates:
+  TTLAfterFinished: true
+`,
+			expectedConfig: APIServerConfig{
+				AdmissionPlugins: APIServerAdmissionPlugins{
+					Disable: []string{"StorageObjectInUseProtection"},
+					Enable:  []string{"AlwaysPullImages"},
+				},
+				Audit: APIServerAudit{
+					LogFormat: AuditLogFormatJSON,
+					LogMode:   AuditLogModeBatch,
+					Policy:    "apiVersion: audit.k8s.io/v1\nkind: Policy\n",
+				},
+				EncryptionProviders: []string{"secretbox", "aescbc", "identity"},
+				ExtraArgs:           []string{"--request-timeout=2m"},
+				ExtraSANs:           []string{"api.example.com", "10.0.0.1"},
+				FeatureGates: map[string]bool{
+					"TTLAfterFinished": true,
+				},
+			},
+		},
+		{
+			name:         "case 1: disabling a default admission plugin is invalid",
+			data:         `admissionPlugins: {disable: [PodSecurityPolicy]}`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 2: enabling and disabling the same admission plugin is invalid",
+			data:         `admissionPlugins: {enable: [AlwaysPullImages], disable: [AlwaysPullImages]}`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 3: unknown audit log mode is invalid",
+			data:         `audit: {logMode: async}`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 4: audit policy of other kind is invalid",
+			data:         `audit: {policy: "apiVersion: v1\nkind: ConfigMap\n"}`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 5: encryption providers without aescbc are invalid",
+			data:         `encryptionProviders: [secretbox, identity]`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 6: unknown encryption provider is invalid",
+			data:         `encryptionProviders: [kms, aescbc]`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 7: extra arg managed by the manifest is invalid",
+			data:         `extraArgs: [--authorization-mode=AlwaysAllow]`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 8: extra arg without dashes is invalid",
+			data:         `extraArgs: [request-timeout=2m]`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 9: invalid extra SAN is invalid",
+			data:         `extraSANs: [api_example.com]`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 10: invalid feature gate is invalid",
+			data:         `featureGates: {ttl-after-finished: true}`,
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseAPIServerConfig([]byte(tc.data))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if diff := cmp.Diff(c, tc.expectedConfig); diff != "" {
+				t.Fatalf("config not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func Test_ParseFeatureGates(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		gates                []string
+		expectedFeatureGates map[string]bool
+		errorMatcher         func(error) bool
+	}{
+		{
+			name: "case 0: no feature gates",
+		},
+		{
+			name:  "case 1: enabled and disabled feature gates",
+			gates: []string{"TTLAfterFinished=true", "PodShareProcessNamespace=false"},
+			expectedFeatureGates: map[string]bool{
+				"PodShareProcessNamespace": false,
+				"TTLAfterFinished":         true,
+			},
+		},
+		{
+			name:         "case 2: feature gate without value is invalid",
+			gates:        []string{"TTLAfterFinished"},
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+		{
+			name:         "case 3: feature gate given twice is invalid",
+			gates:        []string{"TTLAfterFinished=true", "TTLAfterFinished=false"},
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gates, err := ParseFeatureGates(tc.gates)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if diff := cmp.Diff(gates, tc.expectedFeatureGates); diff != "" {
+				t.Fatalf("feature gates not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func Test_CloudConfig_apiServerArgs(t *testing.T) {
+	installation := APIServerConfig{
+		AdmissionPlugins: APIServerAdmissionPlugins{
+			Enable: []string{"AlwaysPullImages"},
+		},
+		Audit: APIServerAudit{
+			LogFormat: AuditLogFormatJSON,
+		},
+		FeatureGates: map[string]bool{
+			"TTLAfterFinished":         true,
+			"PodShareProcessNamespace": false,
+		},
+	}
+
+	oidc := OIDCConfig{
+		ClientID:  "tenant",
+		IssuerURL: "https://dex.example.com",
+	}
+
+	newCustomObject := func(apiServerConfig string) v1alpha1.KVMConfig {
+		return v1alpha1.KVMConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					key.AnnotationAPIServerConfig: apiServerConfig,
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		customObject v1alpha1.KVMConfig
+		expectedArgs []string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: installation configuration only",
+			customObject: newCustomObject(""),
+			expectedArgs: []string{
+				"--oidc-client-id=tenant",
+				"--oidc-issuer-url=https://dex.example.com",
+				"--enable-admission-plugins=AlwaysPullImages",
+				"--audit-log-format=json",
+				"--feature-gates=PodShareProcessNamespace=false,TTLAfterFinished=true",
+			},
+		},
+		{
+			name:         "case 1: cluster configuration overrides the installation configuration",
+			customObject: newCustomObject(`{audit: {logFormat: legacy, logMode: blocking}, featureGates: {TTLAfterFinished: false}}`),
+			expectedArgs: []string{
+				"--oidc-client-id=tenant",
+				"--oidc-issuer-url=https://dex.example.com",
+				"--enable-admission-plugins=AlwaysPullImages",
+				"--audit-log-format=legacy",
+				"--audit-log-mode=blocking",
+				"--feature-gates=PodShareProcessNamespace=false,TTLAfterFinished=false",
+			},
+		},
+		{
+			name:         "case 2: extra args equal to generated args are deduplicated",
+			customObject: newCustomObject(`{extraArgs: [--oidc-client-id=tenant, --request-timeout=2m, --request-timeout=2m]}`),
+			expectedArgs: []string{
+				"--oidc-client-id=tenant",
+				"--oidc-issuer-url=https://dex.example.com",
+				"--enable-admission-plugins=AlwaysPullImages",
+				"--audit-log-format=json",
+				"--feature-gates=PodShareProcessNamespace=false,TTLAfterFinished=true",
+				"--request-timeout=2m",
+			},
+		},
+		{
+			name:         "case 3: extra arg conflicting with the OIDC config",
+			customObject: newCustomObject(`{extraArgs: [--oidc-client-id=other]}`),
+			errorMatcher: IsAPIServerArgConflict,
+		},
+		{
+			name:         "case 4: extra arg conflicting with the feature gates",
+			customObject: newCustomObject(`{extraArgs: [--feature-gates=TTLAfterFinished=true]}`),
+			errorMatcher: IsAPIServerArgConflict,
+		},
+		{
+			name:         "case 5: extra args conflicting with each other",
+			customObject: newCustomObject(`{extraArgs: [--request-timeout=2m, --request-timeout=1m]}`),
+			errorMatcher: IsAPIServerArgConflict,
+		},
+		{
+			name:         "case 6: invalid cluster configuration",
+			customObject: newCustomObject(`{encryptionProviders: [identity]}`),
+			errorMatcher: IsInvalidAPIServerConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &CloudConfig{
+				apiServer: installation,
+				oidc:      oidc,
+			}
+
+			var args []string
+			apiServer, err := c.apiServerConfig(tc.customObject)
+			if err == nil {
+				args, err = apiServerArgs(apiServer, c.oidc)
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if diff := cmp.Diff(args, tc.expectedArgs); diff != "" {
+				t.Fatalf("args not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func Test_renderEncryptionConfig(t *testing.T) {
+	expected := `kind: EncryptionConfiguration
+apiVersion: apiserver.config.k8s.io/v1
+resources:
+  - resources:
+    - secrets
+    providers:
+    - secretbox:
+        keys:
+        - name: key1
+          secret: c2VjcmV0
+    - aescbc:
+        keys:
+        - name: key1
+          secret: c2VjcmV0
+    - identity: {}
+`
+
+	got := renderEncryptionConfig([]string{EncryptionProviderSecretbox, EncryptionProviderAESCBC, EncryptionProviderIdentity}, "c2VjcmV0")
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Fatalf("encryption config not as expected: (-got +expected)\n%s\n", diff)
+	}
+}
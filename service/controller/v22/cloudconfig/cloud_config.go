@@ -1,8 +1,7 @@
 package cloudconfig
 
 import (
-	"fmt"
-
+	k8scloudconfig "github.com/giantswarm/k8scloudconfig/v_4_3_0"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 )
@@ -28,9 +27,22 @@ type Config struct {
 	// Dependencies.
 	Logger micrologger.Logger
 
+	APIServer    APIServerConfig
 	IgnitionPath string
 	OIDC         OIDCConfig
+	Output       OutputConfig
 	SSOPublicKey string
+	// Templates is optional. Empty templates default to the k8scloudconfig
+	// templates this package is written against. Other templates have to be
+	// compatible with the parameters of these.
+	Templates TemplatesConfig
+}
+
+// TemplatesConfig represents the k8scloudconfig templates the cloud configs of
+// masters and workers are rendered with.
+type TemplatesConfig struct {
+	Master string
+	Worker string
 }
 
 // DefaultConfig provides a default configuration to create a new cloud config
@@ -39,6 +51,15 @@ func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
 		Logger: nil,
+
+		Output: OutputConfig{
+			Format:  OutputFormatBase64,
+			MaxSize: DefaultOutputMaxSize,
+		},
+		Templates: TemplatesConfig{
+			Master: k8scloudconfig.MasterTemplate,
+			Worker: k8scloudconfig.WorkerTemplate,
+		},
 	}
 }
 
@@ -47,9 +68,12 @@ type CloudConfig struct {
 	// Dependencies.
 	logger micrologger.Logger
 
-	ignitionPath    string
-	k8sAPIExtraArgs []string
-	ssoPublicKey    string
+	apiServer    APIServerConfig
+	ignitionPath string
+	oidc         OIDCConfig
+	output       OutputConfig
+	ssoPublicKey string
+	templates    TemplatesConfig
 }
 
 // OIDCConfig represents the configuration of the OIDC authorization provider
@@ -70,30 +94,40 @@ func New(config Config) (*CloudConfig, error) {
 	if config.IgnitionPath == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.IgnitionPath must not be empty", config)
 	}
+	err := config.Output.validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-	var k8sAPIExtraArgs []string
-	{
-		if config.OIDC.ClientID != "" {
-			k8sAPIExtraArgs = append(k8sAPIExtraArgs, fmt.Sprintf("--oidc-client-id=%s", config.OIDC.ClientID))
-		}
-		if config.OIDC.IssuerURL != "" {
-			k8sAPIExtraArgs = append(k8sAPIExtraArgs, fmt.Sprintf("--oidc-issuer-url=%s", config.OIDC.IssuerURL))
-		}
-		if config.OIDC.UsernameClaim != "" {
-			k8sAPIExtraArgs = append(k8sAPIExtraArgs, fmt.Sprintf("--oidc-username-claim=%s", config.OIDC.UsernameClaim))
-		}
-		if config.OIDC.GroupsClaim != "" {
-			k8sAPIExtraArgs = append(k8sAPIExtraArgs, fmt.Sprintf("--oidc-groups-claim=%s", config.OIDC.GroupsClaim))
-		}
+	err = config.APIServer.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	// Conflicts between the installation wide API server config and the OIDC
+	// config are reported right away instead of for every tenant cluster.
+	_, err = apiServerArgs(config.APIServer, config.OIDC)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	templates := config.Templates
+	if templates.Master == "" {
+		templates.Master = k8scloudconfig.MasterTemplate
+	}
+	if templates.Worker == "" {
+		templates.Worker = k8scloudconfig.WorkerTemplate
 	}
 
 	newCloudConfig := &CloudConfig{
 		// Dependencies.
 		logger: config.Logger,
 
-		ignitionPath:    config.IgnitionPath,
-		k8sAPIExtraArgs: k8sAPIExtraArgs,
-		ssoPublicKey:    config.SSOPublicKey,
+		apiServer:    config.APIServer,
+		ignitionPath: config.IgnitionPath,
+		oidc:         config.OIDC,
+		output:       config.Output,
+		ssoPublicKey: config.SSOPublicKey,
+		templates:    templates,
 	}
 
 	return newCloudConfig, nil
@@ -19,3 +19,39 @@ var notFoundError = &microerror.Error{
 func IsNotFound(err error) bool {
 	return microerror.Cause(err) == notFoundError
 }
+
+var invalidExtensionError = &microerror.Error{
+	Kind: "invalidExtensionError",
+}
+
+// IsInvalidExtension asserts invalidExtensionError.
+func IsInvalidExtension(err error) bool {
+	return microerror.Cause(err) == invalidExtensionError
+}
+
+var tooLargeError = &microerror.Error{
+	Kind: "tooLargeError",
+}
+
+// IsTooLarge asserts tooLargeError.
+func IsTooLarge(err error) bool {
+	return microerror.Cause(err) == tooLargeError
+}
+
+var invalidAPIServerConfigError = &microerror.Error{
+	Kind: "invalidAPIServerConfigError",
+}
+
+// IsInvalidAPIServerConfig asserts invalidAPIServerConfigError.
+func IsInvalidAPIServerConfig(err error) bool {
+	return microerror.Cause(err) == invalidAPIServerConfigError
+}
+
+var apiServerArgConflictError = &microerror.Error{
+	Kind: "apiServerArgConflictError",
+}
+
+// IsAPIServerArgConflict asserts apiServerArgConflictError.
+func IsAPIServerArgConflict(err error) bool {
+	return microerror.Cause(err) == apiServerArgConflictError
+}
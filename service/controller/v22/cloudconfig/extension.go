@@ -0,0 +1,332 @@
+package cloudconfig
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	k8scloudconfig "github.com/giantswarm/k8scloudconfig/v_4_3_0"
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	// ExtensionDataKey is the key of the ConfigMap and Secret data holding the
+	// YAML encoded Extension.
+	ExtensionDataKey = "extension.yaml"
+
+	extensionRefConfigMap = "configmap"
+	extensionRefSecret    = "secret"
+)
+
+var (
+	ownerNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+	unitNameRegexp  = regexp.MustCompile(`^[a-zA-Z0-9:_.@-]+\.(automount|mount|path|service|socket|target|timer)$`)
+)
+
+// Extension holds the custom files and systemd units contributed to the cloud
+// configs of a tenant cluster per role. Extensions are read from the
+// ConfigMaps and Secrets referenced in the cloud config extensions annotation
+// of the KVMConfig, so that e.g. registry mirrors, audit policies or
+// additional units can be added without changing the operator.
+type Extension struct {
+	Master RoleExtension `json:"master,omitempty"`
+	Worker RoleExtension `json:"worker,omitempty"`
+}
+
+// RoleExtension holds the custom files and systemd units of a single role.
+type RoleExtension struct {
+	Files []ExtensionFile `json:"files,omitempty"`
+	Units []ExtensionUnit `json:"units,omitempty"`
+}
+
+// ExtensionFile is a custom file written to the VMs. The content is written
+// as is and not rendered as template. Owner user and group default to root.
+// Permissions are given as octal string, e.g. "0644". Files read from Secrets
+// are written by the secret part of the cloud config, so that their content
+// is not stored in ConfigMaps.
+type ExtensionFile struct {
+	Content     string             `json:"content"`
+	Owner       ExtensionFileOwner `json:"owner,omitempty"`
+	Path        string             `json:"path"`
+	Permissions string             `json:"permissions"`
+	Secret      bool               `json:"-"`
+}
+
+type ExtensionFileOwner struct {
+	Group string `json:"group,omitempty"`
+	User  string `json:"user,omitempty"`
+}
+
+// ExtensionUnit is a custom systemd unit. Units without content can be used
+// to enable units shipped with the OS.
+type ExtensionUnit struct {
+	Content string `json:"content,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name"`
+}
+
+// LoadExtension returns the merged extensions of the ConfigMaps and Secrets
+// referenced by the given KVMConfig. References have the form
+// configmap/<name> or secret/<name> and are looked up in the namespace of the
+// KVMConfig. Files and units are appended in the order of the references.
+// Files of Secrets are marked as secret.
+func LoadExtension(k8sClient kubernetes.Interface, customObject v1alpha1.KVMConfig) (Extension, error) {
+	var merged Extension
+
+	for _, ref := range key.CloudConfigExtensions(customObject) {
+		split := strings.SplitN(ref, "/", 2)
+		if len(split) != 2 || split[1] == "" {
+			return Extension{}, microerror.Maskf(invalidExtensionError, "reference '%s' must have the form configmap/<name> or secret/<name>", ref)
+		}
+
+		var data []byte
+		var secret bool
+		switch split[0] {
+		case extensionRefConfigMap:
+			cm, err := k8sClient.CoreV1().ConfigMaps(customObject.GetNamespace()).Get(split[1], metav1.GetOptions{})
+			if err != nil {
+				return Extension{}, microerror.Mask(err)
+			}
+			data = []byte(cm.Data[ExtensionDataKey])
+		case extensionRefSecret:
+			s, err := k8sClient.CoreV1().Secrets(customObject.GetNamespace()).Get(split[1], metav1.GetOptions{})
+			if err != nil {
+				return Extension{}, microerror.Mask(err)
+			}
+			data = s.Data[ExtensionDataKey]
+			secret = true
+		default:
+			return Extension{}, microerror.Maskf(invalidExtensionError, "reference '%s' must have the form configmap/<name> or secret/<name>", ref)
+		}
+
+		e, err := ParseExtension(data)
+		if err != nil {
+			return Extension{}, microerror.Maskf(invalidExtensionError, "reference '%s': %s", ref, err)
+		}
+		if secret {
+			e.Master.Files = withSecretFiles(e.Master.Files)
+			e.Worker.Files = withSecretFiles(e.Worker.Files)
+		}
+
+		merged.Master.Files = append(merged.Master.Files, e.Master.Files...)
+		merged.Master.Units = append(merged.Master.Units, e.Master.Units...)
+		merged.Worker.Files = append(merged.Worker.Files, e.Worker.Files...)
+		merged.Worker.Units = append(merged.Worker.Units, e.Worker.Units...)
+	}
+
+	err := merged.Master.Validate()
+	if err != nil {
+		return Extension{}, microerror.Mask(err)
+	}
+	err = merged.Worker.Validate()
+	if err != nil {
+		return Extension{}, microerror.Mask(err)
+	}
+
+	return merged, nil
+}
+
+func withSecretFiles(files []ExtensionFile) []ExtensionFile {
+	var secretFiles []ExtensionFile
+
+	for _, f := range files {
+		f.Secret = true
+		secretFiles = append(secretFiles, f)
+	}
+
+	return secretFiles
+}
+
+// ParseExtension parses and validates the given YAML encoded extension.
+func ParseExtension(data []byte) (Extension, error) {
+	var e Extension
+
+	err := yaml.Unmarshal(data, &e)
+	if err != nil {
+		return Extension{}, microerror.Maskf(invalidExtensionError, "%s", err)
+	}
+
+	err = e.Master.Validate()
+	if err != nil {
+		return Extension{}, microerror.Mask(err)
+	}
+	err = e.Worker.Validate()
+	if err != nil {
+		return Extension{}, microerror.Mask(err)
+	}
+
+	return e, nil
+}
+
+// Hash returns a hash of the role extension used to detect changes of the
+// extension. The hash is empty in case the role extension is empty, so that
+// tenant clusters without extensions are not affected.
+func (e RoleExtension) Hash() string {
+	if len(e.Files) == 0 && len(e.Units) == 0 {
+		return ""
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		// Marshalling plain strings and bools does not fail.
+		panic(err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+// Validate returns an invalidExtensionError in case any file or unit of the
+// role extension is invalid or declared twice.
+func (e RoleExtension) Validate() error {
+	paths := map[string]bool{}
+	for _, f := range e.Files {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || f.Path == "/" {
+			return microerror.Maskf(invalidExtensionError, "file path '%s' must be an absolute and clean file path", f.Path)
+		}
+		if paths[f.Path] {
+			return microerror.Maskf(invalidExtensionError, "file path '%s' must be unique", f.Path)
+		}
+		paths[f.Path] = true
+
+		if f.Owner.User != "" && !ownerNameRegexp.MatchString(f.Owner.User) {
+			return microerror.Maskf(invalidExtensionError, "owner user '%s' of file '%s' must match %s", f.Owner.User, f.Path, ownerNameRegexp)
+		}
+		if f.Owner.Group != "" && !ownerNameRegexp.MatchString(f.Owner.Group) {
+			return microerror.Maskf(invalidExtensionError, "owner group '%s' of file '%s' must match %s", f.Owner.Group, f.Path, ownerNameRegexp)
+		}
+
+		_, err := f.permissions()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, u := range e.Units {
+		if !unitNameRegexp.MatchString(u.Name) {
+			return microerror.Maskf(invalidExtensionError, "unit name '%s' must match %s", u.Name, unitNameRegexp)
+		}
+		if names[u.Name] {
+			return microerror.Maskf(invalidExtensionError, "unit name '%s' must be unique", u.Name)
+		}
+		names[u.Name] = true
+	}
+
+	return nil
+}
+
+// metadata returns the file metadata of the custom file with the owner
+// defaulted to root.
+func (f ExtensionFile) metadata() (k8scloudconfig.FileMetadata, error) {
+	permissions, err := f.permissions()
+	if err != nil {
+		return k8scloudconfig.FileMetadata{}, microerror.Mask(err)
+	}
+
+	owner := k8scloudconfig.Owner{
+		User:  FileOwnerUser,
+		Group: FileOwnerGroup,
+	}
+	if f.Owner.User != "" {
+		owner.User = f.Owner.User
+	}
+	if f.Owner.Group != "" {
+		owner.Group = f.Owner.Group
+	}
+
+	m := k8scloudconfig.FileMetadata{
+		AssetContent: f.Content,
+		Path:         f.Path,
+		Owner:        owner,
+		Permissions:  permissions,
+	}
+
+	return m, nil
+}
+
+func (f ExtensionFile) permissions() (int, error) {
+	p, err := strconv.ParseInt(f.Permissions, 8, 32)
+	if err != nil || p <= 0 || p > 0777 {
+		return 0, microerror.Maskf(invalidExtensionError, "permissions '%s' of file '%s' must be an octal file mode between 0001 and 0777", f.Permissions, f.Path)
+	}
+
+	return int(p), nil
+}
+
+// withExtensionFiles returns the given file assets followed by the custom files
+// of the given role extension. Custom files must not overwrite the files
+// managed by the operator, including the given secret files which might be
+// written by the secret part of the cloud config. Custom files read from
+// Secrets are written by the secret part of the cloud config as well, unless
+// embedSecrets is true.
+func withExtensionFiles(files []k8scloudconfig.FileAsset, secretFiles []k8scloudconfig.FileMetadata, e RoleExtension, embedSecrets bool) ([]k8scloudconfig.FileAsset, error) {
+	for _, f := range e.Files {
+		for _, a := range files {
+			if a.Metadata.Path == f.Path {
+				return nil, microerror.Maskf(invalidExtensionError, "file path '%s' is managed by the operator", f.Path)
+			}
+		}
+		for _, m := range secretFiles {
+			if m.Path == f.Path {
+				return nil, microerror.Maskf(invalidExtensionError, "file path '%s' is managed by the operator", f.Path)
+			}
+		}
+	}
+
+	for _, f := range e.Files {
+		if f.Secret && !embedSecrets {
+			continue
+		}
+
+		m, err := f.metadata()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		files = append(files, k8scloudconfig.FileAsset{
+			Metadata: m,
+			// The content is not rendered as template, because custom files
+			// like docker or audit configs may contain template delimiters.
+			Content: base64.StdEncoding.EncodeToString([]byte(f.Content)),
+		})
+	}
+
+	return files, nil
+}
+
+// withExtensionUnits returns the given unit assets followed by the custom units
+// of the given role extension. Custom units must not overwrite the units
+// managed by the operator.
+func withExtensionUnits(units []k8scloudconfig.UnitAsset, e RoleExtension) ([]k8scloudconfig.UnitAsset, error) {
+	for _, u := range e.Units {
+		for _, a := range units {
+			if a.Metadata.Name == u.Name {
+				return nil, microerror.Maskf(invalidExtensionError, "unit '%s' is managed by the operator", u.Name)
+			}
+		}
+	}
+
+	for _, u := range e.Units {
+		units = append(units, k8scloudconfig.UnitAsset{
+			Metadata: k8scloudconfig.UnitMetadata{
+				AssetContent: u.Content,
+				Name:         u.Name,
+				Enabled:      u.Enabled,
+			},
+			Content: strings.Split(u.Content, "\n"),
+		})
+	}
+
+	return units, nil
+}
@@ -0,0 +1,371 @@
+package cloudconfig
+
+import (
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_ParseExtension(t *testing.T) {
+	testCases := []struct {
+		name              string
+		data              string
+		expectedExtension Extension
+		errorMatcher      func(error) bool
+	}{
+		{
+			name: "case 0: files and units per role",
+			data: `
+master:
+  files:
+  - path: /etc/kubernetes/policies/audit-policy.yaml
+    permissions: "0600"
+    content: |
+      apiVersion: audit.k8s.io/v1beta1
+      kind: Policy
+worker:
+  files:
+  - path: /etc/docker/daemon.json
+    owner:
+      user: root
+      group: docker
+    permissions: "0644"
+    content: '{"registry-mirrors": ["https://mirror.example.com"]}'
+  units:
+  - name: node-problem-detector.service
+    enabled: true
+    content: |
+      [Service]
+      ExecStart=/opt/bin/node-problem-detector
+`,
+			expectedExtension: Extension{
+				Master: RoleExtension{
+					Files: []ExtensionFile{
+						{
+							Content:     "apiVersion: audit.k8s.io/v1beta1\nkind: Policy\n",
+							Path:        "/etc/kubernetes/policies/audit-policy.yaml",
+							Permissions: "0600",
+						},
+					},
+				},
+				Worker: RoleExtension{
+					Files: []ExtensionFile{
+						{
+							Content: `{"registry-mirrors": ["https://mirror.example.com"]}`,
+							Owner: ExtensionFileOwner{
+								Group: "docker",
+								User:  "root",
+							},
+							Path:        "/etc/docker/daemon.json",
+							Permissions: "0644",
+						},
+					},
+					Units: []ExtensionUnit{
+						{
+							Content: "[Service]\nExecStart=/opt/bin/node-problem-detector\n",
+							Enabled: true,
+							Name:    "node-problem-detector.service",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "case 1: relative file path is invalid",
+			data: `
+worker:
+  files:
+  - path: etc/docker/daemon.json
+    permissions: "0644"
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name: "case 2: unclean file path is invalid",
+			data: `
+worker:
+  files:
+  - path: /etc/docker/../shadow
+    permissions: "0644"
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name: "case 3: duplicate file path is invalid",
+			data: `
+worker:
+  files:
+  - path: /etc/docker/daemon.json
+    permissions: "0644"
+  - path: /etc/docker/daemon.json
+    permissions: "0600"
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name: "case 4: non octal permissions are invalid",
+			data: `
+worker:
+  files:
+  - path: /etc/docker/daemon.json
+    permissions: "644a"
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name: "case 5: setuid permissions are invalid",
+			data: `
+worker:
+  files:
+  - path: /opt/bin/tool
+    permissions: "4755"
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name: "case 6: invalid owner is invalid",
+			data: `
+worker:
+  files:
+  - path: /etc/docker/daemon.json
+    owner:
+      user: "root; rm -rf /"
+    permissions: "0644"
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name: "case 7: unit without unit type suffix is invalid",
+			data: `
+master:
+  units:
+  - name: node-problem-detector
+    enabled: true
+`,
+			errorMatcher: IsInvalidExtension,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := ParseExtension([]byte(tc.data))
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if diff := cmp.Diff(e, tc.expectedExtension); diff != "" {
+				t.Fatalf("extension not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func Test_LoadExtension(t *testing.T) {
+	newConfigMap := func(name string, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Data: map[string]string{
+				ExtensionDataKey: data,
+			},
+		}
+	}
+
+	newSecret := func(name string, data string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				ExtensionDataKey: []byte(data),
+			},
+		}
+	}
+
+	newCustomObject := func(refs string) v1alpha1.KVMConfig {
+		return v1alpha1.KVMConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "al9qy",
+				Namespace: "default",
+				Annotations: map[string]string{
+					key.AnnotationCloudConfigExtensions: refs,
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                string
+		customObject        v1alpha1.KVMConfig
+		objects             []runtime.Object
+		expectedWorkerFiles []string
+		expectedSecretFiles []string
+		expectedWorkerUnits []string
+		errorMatcher        func(error) bool
+	}{
+		{
+			name:         "case 0: no references",
+			customObject: newCustomObject(""),
+		},
+		{
+			name:         "case 1: config map and secret are merged in order",
+			customObject: newCustomObject("configmap/mirrors, secret/npd"),
+			objects: []runtime.Object{
+				newConfigMap("mirrors", "worker: {files: [{path: /etc/docker/daemon.json, permissions: '0644', content: '{}'}]}"),
+				newSecret("npd", "worker: {files: [{path: /etc/npd/config.json, permissions: '0600', content: '{}'}], units: [{name: npd.service, enabled: true}]}"),
+			},
+			expectedWorkerFiles: []string{"/etc/docker/daemon.json", "/etc/npd/config.json"},
+			expectedSecretFiles: []string{"/etc/npd/config.json"},
+			expectedWorkerUnits: []string{"npd.service"},
+		},
+		{
+			name:         "case 2: same path contributed twice is invalid",
+			customObject: newCustomObject("configmap/a,configmap/b"),
+			objects: []runtime.Object{
+				newConfigMap("a", "worker: {files: [{path: /etc/docker/daemon.json, permissions: '0644'}]}"),
+				newConfigMap("b", "worker: {files: [{path: /etc/docker/daemon.json, permissions: '0644'}]}"),
+			},
+			errorMatcher: IsInvalidExtension,
+		},
+		{
+			name:         "case 3: unknown reference kind is invalid",
+			customObject: newCustomObject("pod/mirrors"),
+			errorMatcher: IsInvalidExtension,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := LoadExtension(fake.NewSimpleClientset(tc.objects...), tc.customObject)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			var files []string
+			for _, f := range e.Worker.Files {
+				files = append(files, f.Path)
+			}
+			if diff := cmp.Diff(files, tc.expectedWorkerFiles); diff != "" {
+				t.Fatalf("worker files not as expected: (-got +expected)\n%s\n", diff)
+			}
+
+			var secretFiles []string
+			for _, f := range e.Worker.Files {
+				if f.Secret {
+					secretFiles = append(secretFiles, f.Path)
+				}
+			}
+			if diff := cmp.Diff(secretFiles, tc.expectedSecretFiles); diff != "" {
+				t.Fatalf("worker secret files not as expected: (-got +expected)\n%s\n", diff)
+			}
+
+			var units []string
+			for _, u := range e.Worker.Units {
+				units = append(units, u.Name)
+			}
+			if diff := cmp.Diff(units, tc.expectedWorkerUnits); diff != "" {
+				t.Fatalf("worker units not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func Test_withExtensionFiles(t *testing.T) {
+	e := RoleExtension{
+		Files: []ExtensionFile{
+			{
+				Content:     "InitiatorName=evil",
+				Path:        IscsiInitiatorNameFilePath,
+				Permissions: "0644",
+			},
+		},
+	}
+
+	files, err := (&workerExtension{}).Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = withExtensionFiles(files, nil, e, false)
+	if !IsInvalidExtension(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
+
+func Test_withExtensionFiles_Secret(t *testing.T) {
+	e := RoleExtension{
+		Files: []ExtensionFile{
+			{
+				Content:     "{}",
+				Path:        "/etc/docker/daemon.json",
+				Permissions: "0644",
+			},
+			{
+				Content:     "key",
+				Path:        "/etc/docker/certs.d/mirror/client.key",
+				Permissions: "0600",
+				Secret:      true,
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		embedSecrets  bool
+		expectedPaths []string
+	}{
+		{
+			name:          "case 0: secret files are left to the secret part of the cloud config",
+			embedSecrets:  false,
+			expectedPaths: []string{"/etc/docker/daemon.json"},
+		},
+		{
+			name:          "case 1: secret files are embedded for VMs not mounting the secret part",
+			embedSecrets:  true,
+			expectedPaths: []string{"/etc/docker/daemon.json", "/etc/docker/certs.d/mirror/client.key"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := withExtensionFiles(nil, nil, e, tc.embedSecrets)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			var paths []string
+			for _, f := range files {
+				paths = append(paths, f.Metadata.Path)
+			}
+			if diff := cmp.Diff(paths, tc.expectedPaths); diff != "" {
+				t.Fatalf("files not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package cloudconfig
 
 import (
+	"encoding/base64"
 	"fmt"
 
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
@@ -11,16 +12,44 @@ import (
 	"github.com/giantswarm/randomkeys"
 )
 
-// NewMasterTemplate generates a new worker cloud config template and returns it
-// as a base64 encoded string.
-func (c *CloudConfig) NewMasterTemplate(customObject v1alpha1.KVMConfig, certs certs.Cluster, node v1alpha1.ClusterNode, randomKeys randomkeys.Cluster, nodeIndex int) (string, error) {
-	var err error
+// NewMasterTemplate generates a new master cloud config template and returns it
+// in the configured output format. The files and units of the given extension
+// are added to the ones managed by the operator. Certificates and the API
+// server encryption config are only embedded in case embedSecrets is true,
+// which is the case for VMs not mounting the secret part of their cloud config
+// yet. See NewMasterSecretTemplate. The API server is configured according to
+// the API server config of the tenant cluster.
+func (c *CloudConfig) NewMasterTemplate(customObject v1alpha1.KVMConfig, certs certs.Cluster, node v1alpha1.ClusterNode, randomKeys randomkeys.Cluster, nodeIndex int, extension RoleExtension, embedSecrets bool) (string, error) {
+	apiServer, err := c.apiServerConfig(customObject)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	apiServerArgs, err := apiServerArgs(apiServer, c.oidc)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if len(apiServer.ExtraSANs) != 0 {
+		missing, err := missingSANs(certs.APIServer.Crt, apiServer.ExtraSANs)
+		if err != nil {
+			c.logger.Log("cluster", key.ClusterID(customObject), "level", "warning", "message", "failed checking the extra SANs of the API server certificate", "stack", fmt.Sprintf("%#v", err))
+		} else if len(missing) != 0 {
+			c.logger.Log("cluster", key.ClusterID(customObject), "level", "warning", "message", fmt.Sprintf("API server certificate does not cover the extra SANs %v", missing))
+		}
+	}
 
 	var params k8scloudconfig.Params
 	{
 		params = k8scloudconfig.DefaultParams()
 
-		params.APIServerEncryptionKey = string(randomKeys.APIServerEncryptionKey)
+		if embedSecrets {
+			params.APIServerEncryptionKey = string(randomKeys.APIServerEncryptionKey)
+		} else {
+			// The encryption config is written by the secret part of the
+			// cloud config. The API server manifest refers to it regardless.
+			params.DisableEncryptionAtREST = true
+		}
 		params.BaseDomain = key.BaseDomain(customObject)
 		params.Cluster = customObject.Spec.Cluster
 		// Ingress controller service remains in k8scloudconfig and will be
@@ -29,10 +58,12 @@ func (c *CloudConfig) NewMasterTemplate(customObject v1alpha1.KVMConfig, certs c
 		params.Extension = &masterExtension{
 			certs:        certs,
 			customObject: customObject,
+			embedSecrets: embedSecrets,
+			extension:    extension,
 			nodeIndex:    nodeIndex,
 		}
 		params.Node = node
-		params.Hyperkube.Apiserver.Pod.CommandExtraArgs = c.k8sAPIExtraArgs
+		params.Hyperkube.Apiserver.Pod.CommandExtraArgs = apiServerArgs
 		params.SSOPublicKey = c.ssoPublicKey
 
 		ignitionPath := k8scloudconfig.GetIgnitionPath(c.ignitionPath)
@@ -40,13 +71,21 @@ func (c *CloudConfig) NewMasterTemplate(customObject v1alpha1.KVMConfig, certs c
 		if err != nil {
 			return "", microerror.Mask(err)
 		}
+
+		if apiServer.Audit.Policy != "" {
+			params.Files[auditPolicyAsset] = base64.StdEncoding.EncodeToString([]byte(apiServer.Audit.Policy))
+		}
+		if embedSecrets && len(apiServer.EncryptionProviders) != 0 {
+			encryptionConfig := renderEncryptionConfig(apiServer.EncryptionProviders, string(randomKeys.APIServerEncryptionKey))
+			params.Files[encryptionConfigAsset] = base64.StdEncoding.EncodeToString([]byte(encryptionConfig))
+		}
 	}
 
 	var newCloudConfig *k8scloudconfig.CloudConfig
 	{
 		cloudConfigConfig := k8scloudconfig.DefaultCloudConfigConfig()
 		cloudConfigConfig.Params = params
-		cloudConfigConfig.Template = k8scloudconfig.MasterTemplate
+		cloudConfigConfig.Template = c.templates.Master
 
 		newCloudConfig, err = k8scloudconfig.NewCloudConfig(cloudConfigConfig)
 		if err != nil {
@@ -59,29 +98,28 @@ func (c *CloudConfig) NewMasterTemplate(customObject v1alpha1.KVMConfig, certs c
 		}
 	}
 
-	return newCloudConfig.Base64(), nil
+	rendered, err := c.render(newCloudConfig.String())
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rendered, nil
 }
 
 type masterExtension struct {
 	certs        certs.Cluster
 	customObject v1alpha1.KVMConfig
+	embedSecrets bool
+	extension    RoleExtension
 	nodeIndex    int
 }
 
 func (e *masterExtension) Files() ([]k8scloudconfig.FileAsset, error) {
 	var filesMeta []k8scloudconfig.FileMetadata
 
-	for _, f := range certs.NewFilesClusterMaster(e.certs) {
-		m := k8scloudconfig.FileMetadata{
-			AssetContent: string(f.Data),
-			Path:         f.AbsolutePath,
-			Owner: k8scloudconfig.Owner{
-				User:  FileOwnerUser,
-				Group: FileOwnerGroup,
-			},
-			Permissions: FilePermission,
-		}
-		filesMeta = append(filesMeta, m)
+	certFilesMeta := newCertFilesMeta(certs.NewFilesClusterMaster(e.certs))
+	if e.embedSecrets {
+		filesMeta = append(filesMeta, certFilesMeta...)
 	}
 
 	iscsiInitiatorFile := k8scloudconfig.FileMetadata{
@@ -123,6 +161,11 @@ func (e *masterExtension) Files() ([]k8scloudconfig.FileAsset, error) {
 		newFiles = append(newFiles, fileAsset)
 	}
 
+	newFiles, err := withExtensionFiles(newFiles, certFilesMeta, e.extension, e.embedSecrets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	return newFiles, nil
 }
 
@@ -240,6 +283,11 @@ WantedBy=multi-user.target`,
 		newUnits = append(newUnits, unitAsset)
 	}
 
+	newUnits, err := withExtensionUnits(newUnits, e.extension)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	return newUnits, nil
 }
 
@@ -0,0 +1,80 @@
+package cloudconfig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// OutputFormatBase64 renders the gzip compressed and base64 encoded
+	// Ignition JSON k8s-kvm reads by default.
+	OutputFormatBase64 = "base64"
+	// OutputFormatIgnition renders plain Ignition v2 JSON. k8s-kvm is told
+	// about the format via the CLOUD_CONFIG_FORMAT environment variable.
+	OutputFormatIgnition = "ignition"
+
+	// DefaultOutputMaxSize is the default size budget of rendered cloud
+	// configs in bytes. It leaves headroom below the 1 MiB limit of the
+	// ConfigMaps the cloud configs are stored in for their metadata.
+	DefaultOutputMaxSize = 960 * 1024
+)
+
+// OutputConfig represents the configuration of how cloud configs are
+// rendered.
+type OutputConfig struct {
+	// Format is either OutputFormatBase64 or OutputFormatIgnition.
+	Format string
+	// MaxSize is the maximum size of rendered cloud configs in bytes. Zero
+	// means there is no size budget.
+	MaxSize int
+}
+
+func (c OutputConfig) validate() error {
+	if c.Format != OutputFormatBase64 && c.Format != OutputFormatIgnition {
+		return microerror.Maskf(invalidConfigError, "%T.Format must be %q or %q", c, OutputFormatBase64, OutputFormatIgnition)
+	}
+	if c.MaxSize < 0 {
+		return microerror.Maskf(invalidConfigError, "%T.MaxSize must not be negative", c)
+	}
+
+	return nil
+}
+
+// OutputFormat returns the format cloud configs are rendered in.
+func (c *CloudConfig) OutputFormat() string {
+	return c.output.Format
+}
+
+// render returns the given Ignition JSON in the configured output format.
+// Rendered cloud configs exceeding the size budget are rejected, so that
+// large certificate bundles or extensions fail early instead of when writing
+// the ConfigMaps and Secrets.
+func (c *CloudConfig) render(ignitionJSON string) (string, error) {
+	var rendered string
+	switch c.output.Format {
+	case OutputFormatIgnition:
+		rendered = ignitionJSON
+	default:
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		_, err := w.Write([]byte(ignitionJSON))
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		err = w.Close()
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		rendered = base64.StdEncoding.EncodeToString(b.Bytes())
+	}
+
+	if c.output.MaxSize > 0 && len(rendered) > c.output.MaxSize {
+		return "", microerror.Maskf(tooLargeError, "%s cloud config of %d bytes exceeds the size budget of %d bytes", c.output.Format, len(rendered), c.output.MaxSize)
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,287 @@
+package cloudconfig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/certs"
+	k8scloudconfig "github.com/giantswarm/k8scloudconfig/v_4_3_0"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/randomkeys"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the cloud config tests")
+
+// Test_CloudConfig_OutputFormat renders master and worker cloud configs and
+// their secret parts in all output formats and compares them against the
+// golden files in testdata. Run the tests with -update after intended template
+// changes.
+func Test_CloudConfig_OutputFormat(t *testing.T) {
+	customObject := v1alpha1.KVMConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "al9qy",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+				Kubernetes: v1alpha1.ClusterKubernetes{
+					API: v1alpha1.ClusterKubernetesAPI{
+						Domain: "api.al9qy.k8s.gollum.westeurope.azure.gigantic.io",
+					},
+				},
+				Masters: []v1alpha1.ClusterNode{
+					{ID: "a"},
+				},
+				Workers: []v1alpha1.ClusterNode{
+					{ID: "b"},
+				},
+			},
+		},
+	}
+
+	extension := RoleExtension{
+		Files: []ExtensionFile{
+			{
+				Content:     `{"registry-mirrors": ["https://mirror.example.com"]}`,
+				Path:        "/etc/docker/daemon.json",
+				Permissions: "0644",
+			},
+			{
+				Content:     "mirror-client-key",
+				Path:        "/etc/docker/certs.d/mirror.example.com/client.key",
+				Permissions: "0600",
+				Secret:      true,
+			},
+		},
+	}
+
+	clusterCerts := certs.Cluster{
+		APIServer: certs.TLS{
+			CA:  []byte("api-ca"),
+			Crt: []byte("api-crt"),
+			Key: []byte("api-key"),
+		},
+		Worker: certs.TLS{
+			CA:  []byte("worker-ca"),
+			Crt: []byte("worker-crt"),
+			Key: []byte("worker-key"),
+		},
+	}
+
+	keys := randomkeys.Cluster{
+		APIServerEncryptionKey: randomkeys.RandomKey("4Yt3WoLuV3mT1l5WrgVZ9x6yB0fSxJkLr2QnDbqsa1w="),
+	}
+
+	testCases := []struct {
+		name         string
+		format       string
+		role         string
+		maxSize      int
+		golden       string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: master in base64 format",
+			format: OutputFormatBase64,
+			role:   "master",
+			golden: "master_base64.golden",
+		},
+		{
+			name:   "case 1: master in ignition format",
+			format: OutputFormatIgnition,
+			role:   "master",
+			golden: "master_ignition.golden",
+		},
+		{
+			name:   "case 2: worker in base64 format",
+			format: OutputFormatBase64,
+			role:   "worker",
+			golden: "worker_base64.golden",
+		},
+		{
+			name:   "case 3: worker in ignition format",
+			format: OutputFormatIgnition,
+			role:   "worker",
+			golden: "worker_ignition.golden",
+		},
+		{
+			name:   "case 4: master with embedded secrets in ignition format",
+			format: OutputFormatIgnition,
+			role:   "master-embedded",
+			golden: "master_embedded_ignition.golden",
+		},
+		{
+			name:   "case 5: master secret in ignition format",
+			format: OutputFormatIgnition,
+			role:   "master-secret",
+			golden: "master_secret_ignition.golden",
+		},
+		{
+			name:   "case 6: worker secret in base64 format",
+			format: OutputFormatBase64,
+			role:   "worker-secret",
+			golden: "worker_secret_base64.golden",
+		},
+		{
+			name:         "case 7: worker exceeding the size budget",
+			format:       OutputFormatIgnition,
+			role:         "worker",
+			maxSize:      1024,
+			errorMatcher: IsTooLarge,
+		},
+	}
+
+	packagePath, err := k8scloudconfig.GetPackagePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var cloudConfig *CloudConfig
+			{
+				c := DefaultConfig()
+
+				c.Logger = microloggertest.New()
+
+				c.IgnitionPath = packagePath
+				c.Output.Format = tc.format
+				c.Output.MaxSize = tc.maxSize
+
+				cloudConfig, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var rendered string
+			switch tc.role {
+			case "master":
+				rendered, err = cloudConfig.NewMasterTemplate(customObject, clusterCerts, customObject.Spec.Cluster.Masters[0], keys, 1, extension, false)
+			case "master-embedded":
+				rendered, err = cloudConfig.NewMasterTemplate(customObject, clusterCerts, customObject.Spec.Cluster.Masters[0], keys, 1, extension, true)
+			case "master-secret":
+				rendered, err = cloudConfig.NewMasterSecretTemplate(customObject, clusterCerts, keys, extension)
+			case "worker":
+				rendered, err = cloudConfig.NewWorkerTemplate(customObject, clusterCerts, customObject.Spec.Cluster.Workers[0], 2, extension, false)
+			case "worker-secret":
+				rendered, err = cloudConfig.NewWorkerSecretTemplate(clusterCerts, extension)
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.golden == "" {
+				return
+			}
+
+			p := filepath.Join("testdata", tc.golden)
+			if *update {
+				err := ioutil.WriteFile(p, []byte(rendered), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			golden, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := rendered
+			expected := string(golden)
+			// Compressed output may differ between Go versions, so base64
+			// cloud configs are compared by their decompressed content.
+			if tc.format == OutputFormatBase64 {
+				got = mustDecodeBase64(t, got)
+				expected = mustDecodeBase64(t, expected)
+			}
+
+			if diff := cmp.Diff(got, expected); diff != "" {
+				t.Fatalf("cloud config not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func Test_CloudConfig_NewWorkerSecretTemplate_Conflict(t *testing.T) {
+	clusterCerts := certs.Cluster{
+		Worker: certs.TLS{
+			CA:  []byte("worker-ca"),
+			Crt: []byte("worker-crt"),
+			Key: []byte("worker-key"),
+		},
+	}
+
+	extension := RoleExtension{
+		Files: []ExtensionFile{
+			{
+				Content:     "custom-crt",
+				Path:        "/etc/kubernetes/ssl/worker-crt.pem",
+				Permissions: "0600",
+				Secret:      true,
+			},
+		},
+	}
+
+	packagePath, err := k8scloudconfig.GetPackagePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cloudConfig *CloudConfig
+	{
+		c := DefaultConfig()
+
+		c.Logger = microloggertest.New()
+
+		c.IgnitionPath = packagePath
+		c.Output.Format = OutputFormatIgnition
+
+		cloudConfig, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = cloudConfig.NewWorkerSecretTemplate(clusterCerts, extension)
+	if !IsInvalidExtension(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
+
+func mustDecodeBase64(t *testing.T, s string) string {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(decoded)
+}
@@ -0,0 +1,172 @@
+package cloudconfig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/certs"
+	ignition "github.com/giantswarm/k8scloudconfig/ignition/v_2_2_0"
+	k8scloudconfig "github.com/giantswarm/k8scloudconfig/v_4_3_0"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/randomkeys"
+)
+
+const (
+	EncryptionConfigFilePath        = "/etc/kubernetes/encryption/k8s-encryption-config.yaml"
+	EncryptionConfigFilePermissions = 0600
+
+	encryptionConfigAsset = "k8s-resource/k8s-encryption-config.yaml"
+	ignitionVersion       = "2.2.0"
+)
+
+// NewMasterSecretTemplate generates the secret part of the master cloud
+// config and returns it in the configured output format. It holds the
+// certificate files, the API server encryption config and the custom files of
+// the given extension read from Secrets, which are not embedded into the cloud
+// config stored in ConfigMaps. k8s-kvm appends it to the cloud config when the
+// VM boots. The encryption config uses the encryption providers of the API
+// server config of the tenant cluster.
+func (c *CloudConfig) NewMasterSecretTemplate(customObject v1alpha1.KVMConfig, clusterCerts certs.Cluster, randomKeys randomkeys.Cluster, extension RoleExtension) (string, error) {
+	filesMeta := newCertFilesMeta(certs.NewFilesClusterMaster(clusterCerts))
+
+	apiServer, err := c.apiServerConfig(customObject)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var encryptionConfig string
+	if len(apiServer.EncryptionProviders) != 0 {
+		encryptionConfig = base64.StdEncoding.EncodeToString([]byte(renderEncryptionConfig(apiServer.EncryptionProviders, string(randomKeys.APIServerEncryptionKey))))
+	} else {
+		params := k8scloudconfig.DefaultParams()
+
+		params.APIServerEncryptionKey = string(randomKeys.APIServerEncryptionKey)
+		params.Cluster = customObject.Spec.Cluster
+
+		files, err := k8scloudconfig.RenderFiles(k8scloudconfig.GetIgnitionPath(c.ignitionPath), params)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		encryptionConfig = files[encryptionConfigAsset]
+	}
+
+	files := []ignition.File{
+		newIgnitionFile(k8scloudconfig.FileMetadata{
+			Path: EncryptionConfigFilePath,
+			Owner: k8scloudconfig.Owner{
+				User:  FileOwnerUser,
+				Group: FileOwnerGroup,
+			},
+			Permissions: EncryptionConfigFilePermissions,
+		}, encryptionConfig),
+	}
+
+	rendered, err := c.renderSecret(filesMeta, files, extension)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rendered, nil
+}
+
+// NewWorkerSecretTemplate generates the secret part of the worker cloud
+// config and returns it in the configured output format. It holds the
+// certificate files and the custom files of the given extension read from
+// Secrets, which are not embedded into the cloud config stored in ConfigMaps.
+// k8s-kvm appends it to the cloud config when the VM boots.
+func (c *CloudConfig) NewWorkerSecretTemplate(clusterCerts certs.Cluster, extension RoleExtension) (string, error) {
+	filesMeta := newCertFilesMeta(certs.NewFilesClusterWorker(clusterCerts))
+
+	rendered, err := c.renderSecret(filesMeta, nil, extension)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rendered, nil
+}
+
+// renderSecret returns an Ignition config writing the given files followed by
+// the custom files of the given extension read from Secrets. Custom files must
+// not overwrite the given files.
+func (c *CloudConfig) renderSecret(filesMeta []k8scloudconfig.FileMetadata, files []ignition.File, extension RoleExtension) (string, error) {
+	var config ignition.Config
+	{
+		config.Ignition.Version = ignitionVersion
+
+		for _, m := range filesMeta {
+			config.Storage.Files = append(config.Storage.Files, newIgnitionFile(m, base64.StdEncoding.EncodeToString([]byte(m.AssetContent))))
+		}
+		config.Storage.Files = append(config.Storage.Files, files...)
+
+		for _, f := range extension.Files {
+			if !f.Secret {
+				continue
+			}
+
+			for _, existing := range config.Storage.Files {
+				if existing.Path == f.Path {
+					return "", microerror.Maskf(invalidExtensionError, "file path '%s' is managed by the operator", f.Path)
+				}
+			}
+
+			m, err := f.metadata()
+			if err != nil {
+				return "", microerror.Mask(err)
+			}
+
+			config.Storage.Files = append(config.Storage.Files, newIgnitionFile(m, base64.StdEncoding.EncodeToString([]byte(f.Content))))
+		}
+	}
+
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	b = append(b, '\n')
+
+	rendered, err := c.render(string(b))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rendered, nil
+}
+
+// newCertFilesMeta returns the file metadata of the given certificate files.
+func newCertFilesMeta(files certs.Files) []k8scloudconfig.FileMetadata {
+	var filesMeta []k8scloudconfig.FileMetadata
+
+	for _, f := range files {
+		m := k8scloudconfig.FileMetadata{
+			AssetContent: string(f.Data),
+			Path:         f.AbsolutePath,
+			Owner: k8scloudconfig.Owner{
+				User:  FileOwnerUser,
+				Group: FileOwnerGroup,
+			},
+			Permissions: FilePermission,
+		}
+		filesMeta = append(filesMeta, m)
+	}
+
+	return filesMeta
+}
+
+func newIgnitionFile(m k8scloudconfig.FileMetadata, content string) ignition.File {
+	return ignition.File{
+		Contents: ignition.FileContents{
+			Source: "data:text/plain;charset=utf-8;base64," + content,
+		},
+		Filesystem: "root",
+		Group: &ignition.NodeGroup{
+			Name: m.Owner.Group,
+		},
+		Mode: m.Permissions,
+		Path: m.Path,
+		User: &ignition.NodeUser{
+			Name: m.Owner.User,
+		},
+	}
+}
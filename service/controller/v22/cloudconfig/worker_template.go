@@ -2,18 +2,28 @@ package cloudconfig
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/certs"
 	k8scloudconfig "github.com/giantswarm/k8scloudconfig/v_4_3_0"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/kubelet"
 	"github.com/giantswarm/microerror"
 )
 
 // NewWorkerTemplate generates a new worker cloud config template and returns it
-// as a base64 encoded string.
-func (c *CloudConfig) NewWorkerTemplate(customObject v1alpha1.KVMConfig, certs certs.Cluster, node v1alpha1.ClusterNode, nodeIndex int) (string, error) {
-	var err error
+// in the configured output format. The files and units of the given extension
+// are added to the ones managed by the operator. Certificates are only
+// embedded in case embedSecrets is true, which is the case for VMs not
+// mounting the secret part of their cloud config yet. See
+// NewWorkerSecretTemplate. The kubelet is configured according to the kubelet
+// settings of the worker, see the kubelet package.
+func (c *CloudConfig) NewWorkerTemplate(customObject v1alpha1.KVMConfig, certs certs.Cluster, node v1alpha1.ClusterNode, nodeIndex int, extension RoleExtension, embedSecrets bool) (string, error) {
+	kubeletSettings, err := kubelet.ForWorker(customObject, node.ID)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
 
 	var params k8scloudconfig.Params
 	{
@@ -21,11 +31,22 @@ func (c *CloudConfig) NewWorkerTemplate(customObject v1alpha1.KVMConfig, certs c
 
 		params.BaseDomain = key.BaseDomain(customObject)
 		params.Cluster = customObject.Spec.Cluster
+		if kubeletSettings.NodeLabels() != "" {
+			// The labels are appended to the ones the kubelet unit defines.
+			labels := []string{kubeletSettings.NodeLabels()}
+			if params.Cluster.Kubernetes.Kubelet.Labels != "" {
+				labels = append([]string{params.Cluster.Kubernetes.Kubelet.Labels}, labels...)
+			}
+			params.Cluster.Kubernetes.Kubelet.Labels = strings.Join(labels, ",")
+		}
 		params.Extension = &workerExtension{
 			certs:        certs,
 			customObject: customObject,
+			embedSecrets: embedSecrets,
+			extension:    extension,
 			nodeIndex:    nodeIndex,
 		}
+		params.Hyperkube.Kubelet.Docker.CommandExtraArgs = kubeletSettings.Args()
 		params.Node = node
 		params.SSOPublicKey = c.ssoPublicKey
 
@@ -40,7 +61,7 @@ func (c *CloudConfig) NewWorkerTemplate(customObject v1alpha1.KVMConfig, certs c
 	{
 		cloudConfigConfig := k8scloudconfig.DefaultCloudConfigConfig()
 		cloudConfigConfig.Params = params
-		cloudConfigConfig.Template = k8scloudconfig.WorkerTemplate
+		cloudConfigConfig.Template = c.templates.Worker
 
 		newCloudConfig, err = k8scloudconfig.NewCloudConfig(cloudConfigConfig)
 		if err != nil {
@@ -53,29 +74,28 @@ func (c *CloudConfig) NewWorkerTemplate(customObject v1alpha1.KVMConfig, certs c
 		}
 	}
 
-	return newCloudConfig.Base64(), nil
+	rendered, err := c.render(newCloudConfig.String())
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rendered, nil
 }
 
 type workerExtension struct {
 	certs        certs.Cluster
 	customObject v1alpha1.KVMConfig
+	embedSecrets bool
+	extension    RoleExtension
 	nodeIndex    int
 }
 
 func (e *workerExtension) Files() ([]k8scloudconfig.FileAsset, error) {
 	var filesMeta []k8scloudconfig.FileMetadata
 
-	for _, f := range certs.NewFilesClusterWorker(e.certs) {
-		m := k8scloudconfig.FileMetadata{
-			AssetContent: string(f.Data),
-			Path:         f.AbsolutePath,
-			Owner: k8scloudconfig.Owner{
-				User:  FileOwnerUser,
-				Group: FileOwnerGroup,
-			},
-			Permissions: FilePermission,
-		}
-		filesMeta = append(filesMeta, m)
+	certFilesMeta := newCertFilesMeta(certs.NewFilesClusterWorker(e.certs))
+	if e.embedSecrets {
+		filesMeta = append(filesMeta, certFilesMeta...)
 	}
 
 	iscsiInitiatorFile := k8scloudconfig.FileMetadata{
@@ -117,6 +137,11 @@ func (e *workerExtension) Files() ([]k8scloudconfig.FileAsset, error) {
 		newFiles = append(newFiles, fileAsset)
 	}
 
+	newFiles, err := withExtensionFiles(newFiles, certFilesMeta, e.extension, e.embedSecrets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	return newFiles, nil
 }
 
@@ -176,6 +201,11 @@ WantedBy=multi-user.target
 		newUnits = append(newUnits, unitAsset)
 	}
 
+	newUnits, err := withExtensionUnits(newUnits, e.extension)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	return newUnits, nil
 }
 
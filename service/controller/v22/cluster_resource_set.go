@@ -3,59 +3,70 @@ package v22
 import (
 	"context"
 
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
-	"github.com/giantswarm/certs"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/controller"
 	"github.com/giantswarm/operatorkit/controller/context/updateallowedcontext"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"github.com/giantswarm/randomkeys"
 	"github.com/giantswarm/statusresource"
-	"github.com/giantswarm/tenantcluster"
-	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/clusterrolebinding"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/configmap"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/deployment"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/endpointgc"
+	etcdbackupresource "github.com/giantswarm/kvm-operator/service/controller/v22/resource/etcdbackup"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/ingress"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/namespace"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/nodeindexstatus"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/pvc"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/reconciliation"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/secret"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/service"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/serviceaccount"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/validationstatus"
 )
 
-type ClusterResourceSetConfig struct {
-	CertsSearcher      certs.Interface
-	G8sClient          versioned.Interface
-	K8sClient          kubernetes.Interface
-	Logger             micrologger.Logger
-	RandomkeysSearcher randomkeys.Interface
-	TenantCluster      tenantcluster.Interface
-
-	DNSServers         string
-	IgnitionPath       string
-	OIDC               cloudconfig.OIDCConfig
-	GuestUpdateEnabled bool
-	ProjectName        string
-	SSOPublicKey       string
-}
+func NewClusterResourceSet(config registry.Config) (*controller.ResourceSet, error) {
+	err := validateSettings(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.ResourceSet, error) {
-	var err error
+	apiServer, err := newAPIServerConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	etcdBackupTarget, err := newEtcdBackupTarget(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	images, err := newImages(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	cloudConfigOutput, err := newCloudConfigOutput(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
 	var cloudConfig *cloudconfig.CloudConfig
 	{
 		c := cloudconfig.Config{
 			Logger: config.Logger,
 
+			APIServer:    apiServer,
 			IgnitionPath: config.IgnitionPath,
-			OIDC:         config.OIDC,
+			OIDC:         cloudconfig.OIDCConfig(config.OIDC),
+			Output:       cloudConfigOutput,
 			SSOPublicKey: config.SSOPublicKey,
+			Templates: cloudconfig.TemplatesConfig{
+				Master: config.Delta.CloudConfigTemplates.Master,
+				Worker: config.Delta.CloudConfigTemplates.Worker,
+			},
 		}
 
 		cloudConfig, err = cloudconfig.New(c)
@@ -118,6 +129,29 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		}
 	}
 
+	// The secret part of the cloud configs is only moved into Secrets in case
+	// the k8s-kvm image reads it. Otherwise it stays embedded in the ConfigMaps.
+	var secretResource controller.Resource
+	if config.Delta.CloudConfigSecretSupport {
+		c := secret.Config{
+			CertsSearcher: config.CertsSearcher,
+			CloudConfig:   cloudConfig,
+			K8sClient:     config.K8sClient,
+			KeyWatcher:    config.RandomkeysSearcher,
+			Logger:        config.Logger,
+		}
+
+		ops, err := secret.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		secretResource, err = toCRUDResource(config.Logger, ops)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var configMapResource controller.Resource
 	{
 		c := configmap.Config{
@@ -126,6 +160,9 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 			K8sClient:     config.K8sClient,
 			KeyWatcher:    config.RandomkeysSearcher,
 			Logger:        config.Logger,
+
+			// Settings.
+			EmbedAllSecrets: !config.Delta.CloudConfigSecretSupport,
 		}
 
 		ops, err := configmap.New(c)
@@ -143,9 +180,17 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 	{
 		c := deployment.DefaultConfig()
 
+		c.CloudConfigFormat = cloudConfigOutput.Format
+		c.CloudConfigSecrets = config.Delta.CloudConfigSecretSupport
 		c.DNSServers = config.DNSServers
+		c.EtcdBackupTarget = etcdBackupTarget
+		c.G8sClient = config.G8sClient
+		c.Images = images
 		c.K8sClient = config.K8sClient
 		c.Logger = config.Logger
+		c.ResizePolicy = newResizePolicy(config)
+		c.UpdateLimiter = config.GuestUpdateLimiter
+		c.UpdatePolicy = newUpdatePolicy(config)
 
 		ops, err := deployment.New(c)
 		if err != nil {
@@ -158,6 +203,24 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		}
 	}
 
+	var etcdBackupResource controller.Resource
+	if etcdBackupTarget != nil {
+		c := etcdbackupresource.Config{
+			CertsSearcher: config.CertsSearcher,
+			G8sClient:     config.G8sClient,
+			K8sClient:     config.K8sClient,
+			Logger:        config.Logger,
+			Target:        etcdBackupTarget,
+
+			Interval: config.Tenant.EtcdBackup.Interval,
+		}
+
+		etcdBackupResource, err = etcdbackupresource.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var ingressResource controller.Resource
 	{
 		c := ingress.DefaultConfig()
@@ -176,11 +239,26 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		}
 	}
 
+	var endpointGCResource controller.Resource
+	{
+		c := endpointgc.Config{
+			K8sClient: config.K8sClient,
+			Logger:    config.Logger,
+		}
+
+		endpointGCResource, err = endpointgc.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var nodeIndexStatusResource controller.Resource
 	{
 		c := nodeindexstatus.Config{
 			G8sClient: config.G8sClient,
 			Logger:    config.Logger,
+
+			Policy: newNodeIndexPolicy(config),
 		}
 
 		nodeIndexStatusResource, err = nodeindexstatus.New(c)
@@ -207,6 +285,18 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		}
 	}
 
+	var reconciliationResource controller.Resource
+	{
+		c := reconciliation.Config{
+			Logger: config.Logger,
+		}
+
+		reconciliationResource, err = reconciliation.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var serviceResource controller.Resource
 	{
 		c := service.DefaultConfig()
@@ -244,17 +334,56 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 		}
 	}
 
+	var validationStatusResource controller.Resource
+	{
+		c := validationstatus.Config{
+			G8sClient: config.G8sClient,
+			Logger:    config.Logger,
+		}
+
+		validationStatusResource, err = validationstatus.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// The validation status resource has to be executed first so that invalid
+	// custom objects are never seen by any of the other resources.
 	resources := []controller.Resource{
+		validationStatusResource,
 		statusResource,
 		nodeIndexStatusResource,
 		clusterRoleBindingResource,
 		namespaceResource,
 		serviceAccountResource,
+	}
+
+	if secretResource != nil {
+		resources = append(resources, secretResource)
+	}
+
+	resources = append(resources,
 		configMapResource,
 		deploymentResource,
 		ingressResource,
 		pvcResource,
 		serviceResource,
+		endpointGCResource,
+	)
+
+	// Etcd backups and restores are optional and only enabled when a backup
+	// target is configured. Backups additionally require an interval.
+	if etcdBackupResource != nil {
+		resources = append(resources, etcdBackupResource)
+	}
+
+	// The reconciliation resource must be executed last so that it only
+	// records reconciliations in which all other resources succeeded.
+	resources = append(resources, reconciliationResource)
+
+	resources, err = replaceResources(config, resources)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
 	{
@@ -283,7 +412,7 @@ func NewClusterResourceSet(config ClusterResourceSetConfig) (*controller.Resourc
 			return false
 		}
 
-		if key.VersionBundleVersion(kvmConfig) == VersionBundle().Version {
+		if key.VersionBundleVersion(kvmConfig) == config.Version {
 			return true
 		}
 
@@ -2,27 +2,20 @@ package v22
 
 import (
 	"github.com/giantswarm/microerror"
-	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/controller"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"github.com/giantswarm/tenantcluster"
-	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/node"
 )
 
-type DeleterResourceSetConfig struct {
-	K8sClient     kubernetes.Interface
-	Logger        micrologger.Logger
-	TenantCluster tenantcluster.Interface
-
-	ProjectName string
-}
-
-func NewDeleterResourceSet(config DeleterResourceSetConfig) (*controller.ResourceSet, error) {
-	var err error
+func NewDeleterResourceSet(config registry.Config) (*controller.ResourceSet, error) {
+	err := validateSettings(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
 	handlesFunc := func(obj interface{}) bool {
 		kvmConfig, err := key.ToCustomObject(obj)
@@ -30,7 +23,7 @@ func NewDeleterResourceSet(config DeleterResourceSetConfig) (*controller.Resourc
 			return false
 		}
 
-		if key.VersionBundleVersion(kvmConfig) == VersionBundle().Version {
+		if key.VersionBundleVersion(kvmConfig) == config.Version {
 			return true
 		}
 
@@ -0,0 +1,84 @@
+// Package drain implements the policy applied when tenant cluster nodes are
+// drained before their VMs are deleted.
+//
+// The operator wide defaults are configured using flags. Single clusters can
+// override them using the drain annotations of their KVMConfig.
+package drain
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+// Policy defines how tenant cluster nodes are drained.
+type Policy struct {
+	// EvictionGracePeriod is the grace period given to pods evicted from the
+	// drained node. Zero means the grace periods of the pods are used.
+	EvictionGracePeriod time.Duration
+	// ForceAfterTimeout defines whether VMs are deleted once the drain timed
+	// out even though their nodes are not drained.
+	ForceAfterTimeout bool
+	// Timeout is the maximum duration the operator waits for a node to be
+	// drained. Zero means the operator only relies on the timeout reported by
+	// the node-operator, which is why it must not be zero for the builtin
+	// drainer.
+	Timeout time.Duration
+}
+
+// FromAnnotations returns the given default policy overridden by the drain
+// annotations found in the given annotations.
+func FromAnnotations(defaults Policy, annotations map[string]string) (Policy, error) {
+	p := defaults
+
+	if v, ok := annotations[key.AnnotationDrainEvictionGracePeriod]; ok {
+		d, err := parseDuration(key.AnnotationDrainEvictionGracePeriod, v)
+		if err != nil {
+			return Policy{}, microerror.Mask(err)
+		}
+		p.EvictionGracePeriod = d
+	}
+
+	if v, ok := annotations[key.AnnotationDrainForceAfterTimeout]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Policy{}, microerror.Maskf(invalidPolicyError, "annotation %s '%s' must be a boolean", key.AnnotationDrainForceAfterTimeout, v)
+		}
+		p.ForceAfterTimeout = b
+	}
+
+	if v, ok := annotations[key.AnnotationDrainTimeout]; ok {
+		d, err := parseDuration(key.AnnotationDrainTimeout, v)
+		if err != nil {
+			return Policy{}, microerror.Mask(err)
+		}
+		p.Timeout = d
+	}
+
+	return p, nil
+}
+
+// TimedOut returns whether a drain started at the given time exceeded the
+// timeout of the policy at the given time.
+func (p Policy) TimedOut(startedAt time.Time, now time.Time) bool {
+	if p.Timeout == 0 || startedAt.IsZero() {
+		return false
+	}
+
+	return now.Sub(startedAt) >= p.Timeout
+}
+
+func parseDuration(annotation string, v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, microerror.Maskf(invalidPolicyError, "annotation %s '%s' must be a duration", annotation, v)
+	}
+	if d < 0 {
+		return 0, microerror.Maskf(invalidPolicyError, "annotation %s '%s' must not be negative", annotation, v)
+	}
+
+	return d, nil
+}
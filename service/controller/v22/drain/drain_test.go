@@ -0,0 +1,154 @@
+package drain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_FromAnnotations(t *testing.T) {
+	defaults := Policy{
+		EvictionGracePeriod: 30 * time.Second,
+		ForceAfterTimeout:   true,
+		Timeout:             0,
+	}
+
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedPolicy Policy
+		errorMatcher   func(error) bool
+	}{
+		{
+			name:           "case 0: no annotations result in the defaults",
+			annotations:    nil,
+			expectedPolicy: defaults,
+			errorMatcher:   nil,
+		},
+		{
+			name: "case 1: all annotations override the defaults",
+			annotations: map[string]string{
+				key.AnnotationDrainEvictionGracePeriod: "2m",
+				key.AnnotationDrainForceAfterTimeout:   "false",
+				key.AnnotationDrainTimeout:             "1h",
+			},
+			expectedPolicy: Policy{
+				EvictionGracePeriod: 2 * time.Minute,
+				ForceAfterTimeout:   false,
+				Timeout:             time.Hour,
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 2: single annotation overrides only its setting",
+			annotations: map[string]string{
+				key.AnnotationDrainTimeout: "15m",
+			},
+			expectedPolicy: Policy{
+				EvictionGracePeriod: 30 * time.Second,
+				ForceAfterTimeout:   true,
+				Timeout:             15 * time.Minute,
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 3: invalid duration",
+			annotations: map[string]string{
+				key.AnnotationDrainTimeout: "forever",
+			},
+			errorMatcher: IsInvalidPolicy,
+		},
+		{
+			name: "case 4: negative duration",
+			annotations: map[string]string{
+				key.AnnotationDrainEvictionGracePeriod: "-1s",
+			},
+			errorMatcher: IsInvalidPolicy,
+		},
+		{
+			name: "case 5: invalid boolean",
+			annotations: map[string]string{
+				key.AnnotationDrainForceAfterTimeout: "maybe",
+			},
+			errorMatcher: IsInvalidPolicy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := FromAnnotations(defaults, tc.annotations)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if !cmp.Equal(policy, tc.expectedPolicy) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedPolicy, policy))
+			}
+		})
+	}
+}
+
+func Test_Policy_TimedOut(t *testing.T) {
+	startedAt := time.Date(2019, 5, 15, 13, 37, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name             string
+		policy           Policy
+		startedAt        time.Time
+		now              time.Time
+		expectedTimedOut bool
+	}{
+		{
+			name:             "case 0: no timeout never times out",
+			policy:           Policy{},
+			startedAt:        startedAt,
+			now:              startedAt.Add(24 * time.Hour),
+			expectedTimedOut: false,
+		},
+		{
+			name:             "case 1: within timeout",
+			policy:           Policy{Timeout: time.Hour},
+			startedAt:        startedAt,
+			now:              startedAt.Add(59 * time.Minute),
+			expectedTimedOut: false,
+		},
+		{
+			name:             "case 2: timeout elapsed",
+			policy:           Policy{Timeout: time.Hour},
+			startedAt:        startedAt,
+			now:              startedAt.Add(time.Hour),
+			expectedTimedOut: true,
+		},
+		{
+			name:             "case 3: unknown start never times out",
+			policy:           Policy{Timeout: time.Hour},
+			startedAt:        time.Time{},
+			now:              startedAt,
+			expectedTimedOut: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			timedOut := tc.policy.TimedOut(tc.startedAt, tc.now)
+			if timedOut != tc.expectedTimedOut {
+				t.Fatalf("expected %t got %t", tc.expectedTimedOut, timedOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package drain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/giantswarm/tenantcluster"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const (
+	// annotationMirrorPod is set on static pods managed by the kubelet. They
+	// cannot be evicted using the Kubernetes API.
+	annotationMirrorPod = "kubernetes.io/config.mirror"
+)
+
+type DrainerConfig struct {
+	Logger        micrologger.Logger
+	TenantCluster tenantcluster.Interface
+}
+
+// Drainer drains tenant cluster nodes in process. It is used instead of the
+// DrainerConfig based draining of the node-operator when the operator is
+// configured to run self-contained.
+type Drainer struct {
+	logger        micrologger.Logger
+	tenantCluster tenantcluster.Interface
+}
+
+func NewDrainer(config DrainerConfig) (*Drainer, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.TenantCluster == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TenantCluster must not be empty", config)
+	}
+
+	d := &Drainer{
+		logger:        config.Logger,
+		tenantCluster: config.TenantCluster,
+	}
+
+	return d, nil
+}
+
+// Drain cordons the given tenant cluster node and evicts the pods running on
+// it. Evictions are processed by the Kubernetes API, so PodDisruptionBudgets
+// are respected and pods protected by them are retried on subsequent calls.
+// Drain returns true once no evictable pods are left on the node. Nodes which
+// do not exist anymore are considered drained.
+func (d *Drainer) Drain(ctx context.Context, clusterID, apiDomain, nodeName string, policy Policy) (bool, error) {
+	k8sClient, err := d.tenantCluster.NewK8sClient(ctx, clusterID, apiDomain)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	{
+		d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("cordoning tenant cluster node %#q", nodeName))
+
+		node, err := k8sClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("did not find tenant cluster node %#q", nodeName))
+			d.logger.LogCtx(ctx, "level", "debug", "message", "treating tenant cluster node as drained")
+
+			return true, nil
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		if node.Spec.Unschedulable {
+			d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("tenant cluster node %#q is already cordoned", nodeName))
+		} else {
+			node.Spec.Unschedulable = true
+
+			_, err := k8sClient.CoreV1().Nodes().Update(node)
+			if err != nil {
+				return false, microerror.Mask(err)
+			}
+
+			d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("cordoned tenant cluster node %#q", nodeName))
+		}
+	}
+
+	var pods []corev1.Pod
+	{
+		d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("looking for pods running on tenant cluster node %#q", nodeName))
+
+		o := metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+		}
+
+		list, err := k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(o)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		for _, p := range list.Items {
+			if p.Spec.NodeName != nodeName || !isEvictable(p) {
+				continue
+			}
+
+			pods = append(pods, p)
+		}
+
+		d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d evictable pods running on tenant cluster node %#q", len(pods), nodeName))
+	}
+
+	if len(pods) == 0 {
+		return true, nil
+	}
+
+	for _, p := range pods {
+		if p.GetDeletionTimestamp() != nil {
+			// The pod is already evicted and waits for its containers to
+			// terminate.
+			continue
+		}
+
+		err := k8sClient.PolicyV1beta1().Evictions(p.Namespace).Evict(newEviction(p, policy))
+		if apierrors.IsNotFound(err) {
+			// The pod terminated meanwhile.
+		} else if apierrors.IsTooManyRequests(err) {
+			d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("cannot evict pod %#q due to its disruption budget", p.Namespace+"/"+p.Name))
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		} else {
+			d.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("evicted pod %#q", p.Namespace+"/"+p.Name))
+		}
+	}
+
+	return false, nil
+}
+
+func newEviction(pod corev1.Pod, policy Policy) *policyv1beta1.Eviction {
+	e := &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	if policy.EvictionGracePeriod > 0 {
+		gracePeriodSeconds := int64(policy.EvictionGracePeriod.Seconds())
+		e.DeleteOptions = &metav1.DeleteOptions{
+			GracePeriodSeconds: &gracePeriodSeconds,
+		}
+	}
+
+	return e
+}
+
+// isEvictable returns whether the given pod has to be evicted for its node to
+// be drained. Pods managed by DaemonSets are rescheduled on the same node
+// anyway, mirror pods cannot be evicted and finished pods do not run anymore.
+func isEvictable(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+
+	_, ok := pod.GetAnnotations()[annotationMirrorPod]
+	if ok {
+		return false
+	}
+
+	for _, o := range pod.GetOwnerReferences() {
+		if o.Kind == "DaemonSet" {
+			return false
+		}
+	}
+
+	return true
+}
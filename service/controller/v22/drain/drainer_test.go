@@ -0,0 +1,191 @@
+package drain
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain/draintest"
+)
+
+func Test_Drainer_Drain(t *testing.T) {
+	testCases := []struct {
+		name             string
+		objects          []runtime.Object
+		protectedPods    []string
+		expectedDrained  bool
+		expectedCordoned bool
+		expectedEvicted  []string
+	}{
+		{
+			name:            "case 0: missing node is drained",
+			objects:         nil,
+			expectedDrained: true,
+		},
+		{
+			name: "case 1: node without evictable pods is drained",
+			objects: []runtime.Object{
+				newTestNode(),
+				newTestPod("kube-system", "node-exporter", "worker-1", func(p *corev1.Pod) {
+					p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter"}}
+				}),
+				newTestPod("kube-system", "kube-proxy", "worker-1", func(p *corev1.Pod) {
+					p.Annotations = map[string]string{annotationMirrorPod: "abc"}
+				}),
+				newTestPod("default", "job", "worker-1", func(p *corev1.Pod) {
+					p.Status.Phase = corev1.PodSucceeded
+				}),
+				newTestPod("default", "app", "worker-2", nil),
+			},
+			expectedDrained:  true,
+			expectedCordoned: true,
+		},
+		{
+			name: "case 2: pods are evicted",
+			objects: []runtime.Object{
+				newTestNode(),
+				newTestPod("default", "app-a", "worker-1", nil),
+				newTestPod("kube-system", "app-b", "worker-1", nil),
+				newTestPod("default", "app-c", "worker-2", nil),
+			},
+			expectedDrained:  false,
+			expectedCordoned: true,
+			expectedEvicted:  []string{"default/app-a", "kube-system/app-b"},
+		},
+		{
+			name: "case 3: pods protected by disruption budgets are retried",
+			objects: []runtime.Object{
+				newTestNode(),
+				newTestPod("default", "app-a", "worker-1", nil),
+				newTestPod("default", "app-b", "worker-1", nil),
+			},
+			protectedPods:    []string{"app-a"},
+			expectedDrained:  false,
+			expectedCordoned: true,
+			expectedEvicted:  []string{"default/app-b"},
+		},
+		{
+			name: "case 4: terminating pods are not evicted again",
+			objects: []runtime.Object{
+				newTestNode(),
+				newTestPod("default", "app-a", "worker-1", func(p *corev1.Pod) {
+					now := metav1.Now()
+					p.DeletionTimestamp = &now
+				}),
+			},
+			expectedDrained:  false,
+			expectedCordoned: true,
+			expectedEvicted:  nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8sClient := fake.NewSimpleClientset(tc.objects...)
+
+			var evicted []string
+			k8sClient.PrependReactor("post", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				name := action.(k8stesting.GetAction).GetName()
+				for _, p := range tc.protectedPods {
+					if p == name {
+						return true, nil, apierrors.NewTooManyRequests("disruption budget", 10)
+					}
+				}
+				evicted = append(evicted, action.GetNamespace()+"/"+name)
+				return true, nil, nil
+			})
+
+			var d *Drainer
+			{
+				c := DrainerConfig{
+					Logger:        microloggertest.New(),
+					TenantCluster: &draintest.TenantCluster{K8sClient: k8sClient},
+				}
+
+				var err error
+				d, err = NewDrainer(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			drained, err := d.Drain(context.Background(), "al9qy", "api.al9qy.k8s.gigantic.io", "worker-1", Policy{})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if drained != tc.expectedDrained {
+				t.Fatalf("expected drained %t got %t", tc.expectedDrained, drained)
+			}
+
+			node, err := k8sClient.CoreV1().Nodes().Get("worker-1", metav1.GetOptions{})
+			if err == nil && node.Spec.Unschedulable != tc.expectedCordoned {
+				t.Fatalf("expected cordoned %t got %t", tc.expectedCordoned, node.Spec.Unschedulable)
+			}
+
+			sort.Strings(evicted)
+			if len(evicted) != len(tc.expectedEvicted) {
+				t.Fatalf("expected evicted %#v got %#v", tc.expectedEvicted, evicted)
+			}
+			for i := range evicted {
+				if evicted[i] != tc.expectedEvicted[i] {
+					t.Fatalf("expected evicted %#v got %#v", tc.expectedEvicted, evicted)
+				}
+			}
+		})
+	}
+}
+
+func Test_newEviction_GracePeriod(t *testing.T) {
+	pod := *newTestPod("default", "app", "worker-1", nil)
+
+	e := newEviction(pod, Policy{})
+	if e.DeleteOptions != nil {
+		t.Fatalf("expected no delete options got %#v", e.DeleteOptions)
+	}
+
+	e = newEviction(pod, Policy{EvictionGracePeriod: 90 * time.Second})
+	if e.DeleteOptions == nil || *e.DeleteOptions.GracePeriodSeconds != 90 {
+		t.Fatalf("expected grace period of 90 seconds got %#v", e.DeleteOptions)
+	}
+}
+
+func newTestNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "worker-1",
+		},
+	}
+}
+
+func newTestPod(namespace, name, nodeName string, mutate func(p *corev1.Pod)) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+
+	if mutate != nil {
+		mutate(p)
+	}
+
+	return p
+}
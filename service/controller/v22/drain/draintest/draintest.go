@@ -0,0 +1,38 @@
+// Package draintest provides helpers for testing the built-in drainer against
+// fake tenant cluster clientsets.
+package draintest
+
+import (
+	"context"
+
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/helmclient"
+	"github.com/giantswarm/microerror"
+	"k8s.io/client-go/kubernetes"
+)
+
+var notImplementedError = &microerror.Error{
+	Kind: "notImplementedError",
+}
+
+// TenantCluster implements tenantcluster.Interface and returns the configured
+// Kubernetes clientset for every tenant cluster.
+type TenantCluster struct {
+	K8sClient kubernetes.Interface
+}
+
+func (t *TenantCluster) NewG8sClient(ctx context.Context, clusterID, apiDomain string) (versioned.Interface, error) {
+	return nil, microerror.Mask(notImplementedError)
+}
+
+func (t *TenantCluster) NewHelmClient(ctx context.Context, clusterID, apiDomain string) (helmclient.Interface, error) {
+	return nil, microerror.Mask(notImplementedError)
+}
+
+func (t *TenantCluster) NewK8sClient(ctx context.Context, clusterID, apiDomain string) (kubernetes.Interface, error) {
+	if t.K8sClient == nil {
+		return nil, microerror.Mask(notImplementedError)
+	}
+
+	return t.K8sClient, nil
+}
@@ -0,0 +1,23 @@
+package drain
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidPolicyError = &microerror.Error{
+	Kind: "invalidPolicyError",
+}
+
+// IsInvalidPolicy asserts invalidPolicyError.
+func IsInvalidPolicy(err error) bool {
+	return microerror.Cause(err) == invalidPolicyError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
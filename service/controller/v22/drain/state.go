@@ -0,0 +1,109 @@
+package drain
+
+import (
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	// PodConditionType is the type of the pod condition holding the drain
+	// state of VM pods. The state itself is held in the reason of the
+	// condition.
+	PodConditionType corev1.PodConditionType = "kvm-operator.giantswarm.io/Drain"
+)
+
+// State is the lifecycle state of draining the tenant cluster node of a VM
+// pod.
+type State string
+
+const (
+	// StatePending is the state of pods whose drain did not start yet. Pods
+	// without drain state are pending.
+	StatePending State = "Pending"
+	// StateDraining is the state of pods whose node is being drained.
+	StateDraining State = "Draining"
+	// StateDrained is the state of pods whose node got drained or whose
+	// containers all terminated.
+	StateDrained State = "Drained"
+	// StateTimedOut is the state of pods whose drain timed out but which are
+	// not allowed to be deleted forcefully.
+	StateTimedOut State = "TimedOut"
+	// StateForced is the state of pods which got deleted forcefully after
+	// their drain timed out.
+	StateForced State = "Forced"
+)
+
+// Final returns whether the drain finished and the VM pod can be deleted.
+func (s State) Final() bool {
+	return s == StateDrained || s == StateForced
+}
+
+func (s State) valid() bool {
+	switch s {
+	case StatePending, StateDraining, StateDrained, StateTimedOut, StateForced:
+		return true
+	}
+
+	return false
+}
+
+// StateFromPod returns the drain state of the given pod. Pods without drain
+// state condition are migrated from the drained annotation used by former
+// versions. Pods having neither are pending.
+func StateFromPod(pod *corev1.Pod) State {
+	for _, c := range pod.Status.Conditions {
+		if c.Type != PodConditionType {
+			continue
+		}
+
+		s := State(c.Reason)
+		if s.valid() {
+			return s
+		}
+	}
+
+	v, ok := pod.GetAnnotations()[key.AnnotationPodDrained]
+	if ok {
+		drained, err := strconv.ParseBool(v)
+		if err == nil && drained {
+			return StateDrained
+		}
+	}
+
+	return StatePending
+}
+
+// WithState returns a copy of the given pod having the given drain state and
+// whether the drain state changed.
+func WithState(pod *corev1.Pod, s State, now time.Time) (*corev1.Pod, bool) {
+	p := pod.DeepCopy()
+
+	condition := corev1.PodCondition{
+		Type:               PodConditionType,
+		Status:             corev1.ConditionTrue,
+		Reason:             string(s),
+		LastTransitionTime: metav1.NewTime(now),
+	}
+
+	for i, c := range p.Status.Conditions {
+		if c.Type != PodConditionType {
+			continue
+		}
+		if c.Reason == string(s) {
+			return p, false
+		}
+
+		p.Status.Conditions[i] = condition
+
+		return p, true
+	}
+
+	p.Status.Conditions = append(p.Status.Conditions, condition)
+
+	return p, true
+}
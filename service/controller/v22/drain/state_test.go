@@ -0,0 +1,130 @@
+package drain
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_StateFromPod(t *testing.T) {
+	testCases := []struct {
+		name          string
+		pod           *corev1.Pod
+		expectedState State
+	}{
+		{
+			name:          "case 0: pod without drain state is pending",
+			pod:           &corev1.Pod{},
+			expectedState: StatePending,
+		},
+		{
+			name: "case 1: drain state is read from the pod condition",
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+						{Type: PodConditionType, Status: corev1.ConditionTrue, Reason: string(StateTimedOut)},
+					},
+				},
+			},
+			expectedState: StateTimedOut,
+		},
+		{
+			name: "case 2: legacy drained annotation migrates to drained",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						key.AnnotationPodDrained: "True",
+					},
+				},
+			},
+			expectedState: StateDrained,
+		},
+		{
+			name: "case 3: legacy not drained annotation migrates to pending",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						key.AnnotationPodDrained: "False",
+					},
+				},
+			},
+			expectedState: StatePending,
+		},
+		{
+			name: "case 4: pod condition takes precedence over legacy annotation",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						key.AnnotationPodDrained: "False",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{Type: PodConditionType, Status: corev1.ConditionTrue, Reason: string(StateForced)},
+					},
+				},
+			},
+			expectedState: StateForced,
+		},
+		{
+			name: "case 5: unknown state in pod condition is ignored",
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{Type: PodConditionType, Status: corev1.ConditionTrue, Reason: "Unknown"},
+					},
+				},
+			},
+			expectedState: StatePending,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := StateFromPod(tc.pod)
+			if state != tc.expectedState {
+				t.Fatalf("expected %#q got %#q", tc.expectedState, state)
+			}
+		})
+	}
+}
+
+func Test_WithState(t *testing.T) {
+	now := time.Date(2019, 5, 15, 13, 37, 0, 0, time.UTC)
+	pod := &corev1.Pod{}
+
+	p, changed := WithState(pod, StateDraining, now)
+	if !changed {
+		t.Fatalf("expected drain state to change")
+	}
+	if len(pod.Status.Conditions) != 0 {
+		t.Fatalf("expected given pod to be unchanged")
+	}
+	if StateFromPod(p) != StateDraining {
+		t.Fatalf("expected %#q got %#q", StateDraining, StateFromPod(p))
+	}
+
+	p, changed = WithState(p, StateDraining, now.Add(time.Minute))
+	if changed {
+		t.Fatalf("expected drain state not to change")
+	}
+
+	p, changed = WithState(p, StateDrained, now.Add(time.Minute))
+	if !changed {
+		t.Fatalf("expected drain state to change")
+	}
+	if len(p.Status.Conditions) != 1 {
+		t.Fatalf("expected 1 condition got %d", len(p.Status.Conditions))
+	}
+	if !p.Status.Conditions[0].LastTransitionTime.Time.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected transition time to be updated")
+	}
+	if !StateFromPod(p).Final() {
+		t.Fatalf("expected %#q to be final", StateFromPod(p))
+	}
+}
@@ -1,29 +1,23 @@
 package v22
 
 import (
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
-	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/controller"
 	"github.com/giantswarm/operatorkit/controller/resource/metricsresource"
 	"github.com/giantswarm/operatorkit/controller/resource/retryresource"
-	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/endpoint"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/pod"
 )
 
-type DrainerResourceSetConfig struct {
-	G8sClient versioned.Interface
-	K8sClient kubernetes.Interface
-	Logger    micrologger.Logger
-
-	ProjectName string
-}
-
-func NewDrainerResourceSet(config DrainerResourceSetConfig) (*controller.ResourceSet, error) {
-	var err error
+func NewDrainerResourceSet(config registry.Config) (*controller.ResourceSet, error) {
+	err := validateSettings(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
 	handlesFunc := func(obj interface{}) bool {
 		p, err := key.ToPod(obj)
@@ -35,19 +29,46 @@ func NewDrainerResourceSet(config DrainerResourceSetConfig) (*controller.Resourc
 			return false
 		}
 
-		if v == VersionBundle().Version {
+		if v == config.Version {
 			return true
 		}
 
 		return false
 	}
 
+	builtinDrainer := config.Tenant.Drain.Builtin
+	if builtinDrainer && config.TenantCluster == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.TenantCluster must not be empty when the builtin drainer is enabled", config)
+	}
+	// The builtin drainer does not time out on its own, so without a timeout
+	// pods of tenant cluster nodes which cannot be drained would never be
+	// deleted.
+	if builtinDrainer && config.Tenant.Drain.Timeout == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Tenant.Drain.Timeout must not be zero when the builtin drainer is enabled", config)
+	}
+
+	var nodeDrainer *drain.Drainer
+	if builtinDrainer {
+		c := drain.DrainerConfig{
+			Logger:        config.Logger,
+			TenantCluster: config.TenantCluster,
+		}
+
+		nodeDrainer, err = drain.NewDrainer(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var podResource controller.Resource
 	{
 		c := pod.Config{
 			G8sClient: config.G8sClient,
 			K8sClient: config.K8sClient,
 			Logger:    config.Logger,
+
+			DrainPolicy: newDrainPolicy(config),
+			NodeDrainer: nodeDrainer,
 		}
 
 		podResource, err = pod.New(c)
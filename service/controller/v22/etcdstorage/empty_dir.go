@@ -0,0 +1,25 @@
+package etcdstorage
+
+import (
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+type emptyDir struct{}
+
+func (e *emptyDir) PVCs(cr v1alpha1.KVMConfig) ([]*corev1.PersistentVolumeClaim, error) {
+	return nil, nil
+}
+
+func (e *emptyDir) Volume(cr v1alpha1.KVMConfig, vmNumber string) (corev1.Volume, error) {
+	volume := corev1.Volume{
+		Name: key.EtcdVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+
+	return volume, nil
+}
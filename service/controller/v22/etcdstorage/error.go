@@ -0,0 +1,21 @@
+package etcdstorage
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var unknownStorageTypeError = &microerror.Error{
+	Kind: "unknownStorageTypeError",
+}
+
+// IsUnknownStorageType asserts unknownStorageTypeError.
+func IsUnknownStorageType(err error) bool {
+	return microerror.Cause(err) == unknownStorageTypeError
+}
@@ -0,0 +1,79 @@
+package etcdstorage
+
+import (
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	// TypeEmptyDir stores etcd data in an emptyDir volume. The data is lost as
+	// soon as the master pod goes away, so this must only be used for throwaway
+	// test clusters.
+	TypeEmptyDir = "emptyDir"
+	// TypeHostPath stores etcd data in a directory of the host the master pod
+	// is scheduled to.
+	TypeHostPath = "hostPath"
+	// TypeLocalVolume stores etcd data in a local persistent volume. The node
+	// affinity of the local persistent volume bound to the claim pins the master
+	// pod to the host holding the data.
+	TypeLocalVolume = "localVolume"
+	// TypePersistentVolume stores etcd data in a dynamically provisioned
+	// persistent volume.
+	TypePersistentVolume = "persistentVolume"
+)
+
+const (
+	// DefaultLocalStorageClass is the storage class local volume claims are
+	// configured with in case the cluster does not define its own.
+	DefaultLocalStorageClass = "local-storage"
+	// DefaultSize is the size persistent volume claims are configured with in
+	// case the cluster does not define its own.
+	DefaultSize = "15Gi"
+	// DefaultStorageClass is the storage class persistent volume claims are
+	// configured with in case the cluster does not define its own.
+	DefaultStorageClass = "g8s-storage"
+)
+
+// New returns the storage backend configured for the given cluster.
+func New(cr v1alpha1.KVMConfig) (Interface, error) {
+	storageType := key.StorageType(cr)
+
+	// During migration, some TPOs do not have storage type set.
+	// This specifies a default, until all TPOs have the correct storage type set.
+	// tl;dr - this shouldn't be here. If all TPOs have storageType, remove it.
+	if storageType == "" {
+		storageType = TypeHostPath
+	}
+
+	switch storageType {
+	case TypeEmptyDir:
+		return &emptyDir{}, nil
+	case TypeHostPath:
+		return &hostPath{}, nil
+	case TypeLocalVolume:
+		return &persistentVolume{defaultStorageClass: DefaultLocalStorageClass}, nil
+	case TypePersistentVolume:
+		return &persistentVolume{defaultStorageClass: DefaultStorageClass}, nil
+	}
+
+	return nil, microerror.Maskf(unknownStorageTypeError, "unknown storageType: '%s'", storageType)
+}
+
+// Size returns the storage size etcd data volumes of the given cluster are
+// configured with.
+func Size(cr v1alpha1.KVMConfig) (resource.Quantity, error) {
+	size := key.EtcdStorageSize(cr)
+	if size == "" {
+		size = DefaultSize
+	}
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return resource.Quantity{}, microerror.Maskf(invalidConfigError, "parsing etcd storage size '%s': %s", size, err)
+	}
+
+	return quantity, nil
+}
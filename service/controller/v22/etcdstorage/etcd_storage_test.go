@@ -0,0 +1,169 @@
+package etcdstorage
+
+import (
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_EtcdStorage(t *testing.T) {
+	newCustomResource := func(storageType string, annotations map[string]string) v1alpha1.KVMConfig {
+		return v1alpha1.KVMConfig{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.KVMConfigSpec{
+				Cluster: v1alpha1.Cluster{
+					ID: "al9qy",
+					Masters: []v1alpha1.ClusterNode{
+						{ID: "m1"},
+						{ID: "m2"},
+					},
+				},
+				KVM: v1alpha1.KVMConfigSpecKVM{
+					K8sKVM: v1alpha1.KVMConfigSpecKVMK8sKVM{
+						StorageType: storageType,
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                 string
+		customResource       v1alpha1.KVMConfig
+		expectedPVCCount     int
+		expectedStorageClass string
+		expectedSize         string
+		expectedVolume       func(v corev1.Volume) bool
+		errorMatcher         func(error) bool
+	}{
+		{
+			name:             "case 0: empty storage type defaults to hostPath",
+			customResource:   newCustomResource("", nil),
+			expectedPVCCount: 0,
+			expectedVolume: func(v corev1.Volume) bool {
+				return v.HostPath != nil && v.HostPath.Path == key.MasterHostPathVolumeDir("al9qy", "0")
+			},
+		},
+		{
+			name:             "case 1: hostPath",
+			customResource:   newCustomResource(TypeHostPath, nil),
+			expectedPVCCount: 0,
+			expectedVolume: func(v corev1.Volume) bool {
+				return v.HostPath != nil
+			},
+		},
+		{
+			name:             "case 2: emptyDir",
+			customResource:   newCustomResource(TypeEmptyDir, nil),
+			expectedPVCCount: 0,
+			expectedVolume: func(v corev1.Volume) bool {
+				return v.EmptyDir != nil
+			},
+		},
+		{
+			name:                 "case 3: persistentVolume with defaults",
+			customResource:       newCustomResource(TypePersistentVolume, nil),
+			expectedPVCCount:     2,
+			expectedStorageClass: DefaultStorageClass,
+			expectedSize:         DefaultSize,
+			expectedVolume: func(v corev1.Volume) bool {
+				return v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == key.EtcdPVCName("al9qy", "0")
+			},
+		},
+		{
+			name: "case 4: persistentVolume with cluster specific storage class and size",
+			customResource: newCustomResource(TypePersistentVolume, map[string]string{
+				key.AnnotationEtcdStorageClass: "fast",
+				key.AnnotationEtcdStorageSize:  "30Gi",
+			}),
+			expectedPVCCount:     2,
+			expectedStorageClass: "fast",
+			expectedSize:         "30Gi",
+			expectedVolume: func(v corev1.Volume) bool {
+				return v.PersistentVolumeClaim != nil
+			},
+		},
+		{
+			name:                 "case 5: localVolume with defaults",
+			customResource:       newCustomResource(TypeLocalVolume, nil),
+			expectedPVCCount:     2,
+			expectedStorageClass: DefaultLocalStorageClass,
+			expectedSize:         DefaultSize,
+			expectedVolume: func(v corev1.Volume) bool {
+				return v.PersistentVolumeClaim != nil
+			},
+		},
+		{
+			name:           "case 6: unknown storage type",
+			customResource: newCustomResource("nfs", nil),
+			errorMatcher:   IsUnknownStorageType,
+		},
+		{
+			name: "case 7: invalid storage size",
+			customResource: newCustomResource(TypePersistentVolume, map[string]string{
+				key.AnnotationEtcdStorageSize: "lots",
+			}),
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			var pvcs []*corev1.PersistentVolumeClaim
+			var volume corev1.Volume
+
+			s, err := New(tc.customResource)
+			if err == nil {
+				pvcs, err = s.PVCs(tc.customResource)
+			}
+			if err == nil {
+				volume, err = s.Volume(tc.customResource, key.VMNumber(0))
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if volume.Name != key.EtcdVolumeName {
+				t.Fatalf("volume name == %q, want %q", volume.Name, key.EtcdVolumeName)
+			}
+			if !tc.expectedVolume(volume) {
+				t.Fatalf("unexpected volume %#v", volume)
+			}
+
+			if len(pvcs) != tc.expectedPVCCount {
+				t.Fatalf("expected %d PVCs got %d", tc.expectedPVCCount, len(pvcs))
+			}
+			for _, p := range pvcs {
+				storageClass := p.GetAnnotations()["volume.beta.kubernetes.io/storage-class"]
+				if storageClass != tc.expectedStorageClass {
+					t.Fatalf("storage class == %q, want %q", storageClass, tc.expectedStorageClass)
+				}
+
+				size := p.Spec.Resources.Requests[corev1.ResourceStorage]
+				if size.Cmp(resource.MustParse(tc.expectedSize)) != 0 {
+					t.Fatalf("size == %q, want %q", size.String(), tc.expectedSize)
+				}
+			}
+		})
+	}
+}
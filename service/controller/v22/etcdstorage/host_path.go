@@ -0,0 +1,27 @@
+package etcdstorage
+
+import (
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+type hostPath struct{}
+
+func (h *hostPath) PVCs(cr v1alpha1.KVMConfig) ([]*corev1.PersistentVolumeClaim, error) {
+	return nil, nil
+}
+
+func (h *hostPath) Volume(cr v1alpha1.KVMConfig, vmNumber string) (corev1.Volume, error) {
+	volume := corev1.Volume{
+		Name: key.EtcdVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: key.MasterHostPathVolumeDir(key.ClusterID(cr), vmNumber),
+			},
+		},
+	}
+
+	return volume, nil
+}
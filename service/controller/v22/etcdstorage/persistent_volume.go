@@ -0,0 +1,77 @@
+package etcdstorage
+
+import (
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+type persistentVolume struct {
+	defaultStorageClass string
+}
+
+func (p *persistentVolume) PVCs(cr v1alpha1.KVMConfig) ([]*corev1.PersistentVolumeClaim, error) {
+	var persistentVolumeClaims []*corev1.PersistentVolumeClaim
+
+	quantity, err := Size(cr)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	storageClass := key.EtcdStorageClass(cr)
+	if storageClass == "" {
+		storageClass = p.defaultStorageClass
+	}
+
+	for i, masterNode := range cr.Spec.Cluster.Masters {
+		persistentVolumeClaim := &corev1.PersistentVolumeClaim{
+			TypeMeta: apismetav1.TypeMeta{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+			},
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name: key.EtcdPVCName(key.ClusterID(cr), key.VMNumber(i)),
+				Labels: map[string]string{
+					"app":      key.MasterID,
+					"cluster":  key.ClusterID(cr),
+					"customer": key.ClusterCustomer(cr),
+					"node":     masterNode.ID,
+				},
+				Annotations: map[string]string{
+					"volume.beta.kubernetes.io/storage-class": storageClass,
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{
+					corev1.ReadWriteOnce,
+				},
+				Resources: corev1.ResourceRequirements{
+					Requests: map[corev1.ResourceName]resource.Quantity{
+						corev1.ResourceStorage: quantity,
+					},
+				},
+			},
+		}
+
+		persistentVolumeClaims = append(persistentVolumeClaims, persistentVolumeClaim)
+	}
+
+	return persistentVolumeClaims, nil
+}
+
+func (p *persistentVolume) Volume(cr v1alpha1.KVMConfig, vmNumber string) (corev1.Volume, error) {
+	volume := corev1.Volume{
+		Name: key.EtcdVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: key.EtcdPVCName(key.ClusterID(cr), vmNumber),
+			},
+		},
+	}
+
+	return volume, nil
+}
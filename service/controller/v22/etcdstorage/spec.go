@@ -0,0 +1,18 @@
+package etcdstorage
+
+import (
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Interface is implemented by the different storage backends of the etcd data
+// directory of tenant cluster masters.
+type Interface interface {
+	// PVCs returns the persistent volume claims backing the etcd data volumes of
+	// all masters of the given cluster. PVCs returns nil in case the storage
+	// backend does not make use of persistent volume claims.
+	PVCs(cr v1alpha1.KVMConfig) ([]*corev1.PersistentVolumeClaim, error)
+	// Volume returns the etcd data volume of the master VM identified by the
+	// given VM number.
+	Volume(cr v1alpha1.KVMConfig, vmNumber string) (corev1.Volume, error)
+}
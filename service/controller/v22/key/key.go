@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +19,9 @@ const (
 	MasterID = "master"
 	WorkerID = "worker"
 	EtcdPort = 443
+	// DrainerConfigVersion is the version bundle version of the node-operator
+	// drainer DrainerConfigs are created for.
+	DrainerConfigVersion = "0.2.0"
 	// livenessPortBase is a baseline for computing the port for liveness probes.
 	livenessPortBase = 23000
 	// shutdownDeferrerPortBase is a baseline for computing the port for
@@ -45,6 +47,15 @@ const (
 	// Enable k8s-kvm-health check for k8s api.
 	CheckK8sApi = "true"
 
+	// ContainerNameKVM is the name of the container running the VM.
+	ContainerNameKVM = "k8s-kvm"
+	// ContainerNameKVMHealth is the name of the sidecar container checking the
+	// health of the VM.
+	ContainerNameKVMHealth = "k8s-kvm-health"
+	// ContainerNameEtcdRestore is the name of the init container of master
+	// pods restoring etcd data from a snapshot.
+	ContainerNameEtcdRestore = "etcd-restore"
+
 	// Environment variable names for Downward API use (shutdown-deferrer).
 	EnvKeyMyPodName      = "MY_POD_NAME"
 	EnvKeyMyPodNamespace = "MY_POD_NAMESPACE"
@@ -53,6 +64,7 @@ const (
 	CoreosImageDir       = "/var/lib/coreos-kvm-images"
 	CoreosVersion        = "2023.5.0"
 
+	EtcdDockerImage          = "quay.io/coreos/etcd:v3.3.12"
 	K8SEndpointUpdaterDocker = "quay.io/giantswarm/k8s-endpoint-updater:590479a6228c2c143695a268bda5382b52f7ffe1"
 	K8SKVMDockerImage        = "quay.io/giantswarm/k8s-kvm:34e40049f80ce8ea0f774e56c1b10c7f24921eca"
 	K8SKVMHealthDocker       = "quay.io/giantswarm/k8s-kvm-health:20517098a762a0d7ca2b0902316ddff487dbc7f5"
@@ -76,23 +88,65 @@ const (
 	// DefaultOSDiskSize defines the space used to partition the root FS within
 	// k8s-kvm.
 	DefaultOSDiskSize = "5G"
+
+	// CloudConfigSecretVolumeName is the name of the volume holding the
+	// secret part of the cloud configs of VMs. VMs whose deployments do not
+	// mount it yet still get their secrets embedded into their ConfigMaps.
+	CloudConfigSecretVolumeName = "cloud-config-secret"
+
+	// EtcdVolumeName is the name of the volume holding the etcd data directory
+	// of master VMs.
+	EtcdVolumeName = "etcd-data"
+
+	// EtcdRestoreSecretName is the name of the secret in the cluster namespace
+	// holding the URL of the etcd snapshot to restore. The URL is refreshed by
+	// the operator while the restore is pending and read by the etcd restore
+	// init container when it starts.
+	EtcdRestoreSecretName = "etcd-restore"
+	// EtcdRestoreSecretURLKey is the key of the snapshot URL within the etcd
+	// restore secret.
+	EtcdRestoreSecretURLKey = "url"
+
+	// ConditionEtcdRestored is the type of the resource condition in the
+	// KVMConfig status which records that the etcd restore of the cluster
+	// completed.
+	ConditionEtcdRestored = "EtcdRestored"
 )
 
 const (
-	AnnotationAPIEndpoint       = "kvm-operator.giantswarm.io/api-endpoint"
-	AnnotationEtcdDomain        = "giantswarm.io/etcd-domain"
-	AnnotationIp                = "endpoint.kvm.giantswarm.io/ip"
-	AnnotationService           = "endpoint.kvm.giantswarm.io/service"
+	AnnotationAPIEndpoint              = "kvm-operator.giantswarm.io/api-endpoint"
+	AnnotationAPIServerConfig          = "kvm-operator.giantswarm.io/apiserver-config"
+	AnnotationCloudConfigExtensions    = "kvm-operator.giantswarm.io/cloud-config-extensions"
+	AnnotationCloudConfigExtensionHash = "kvm-operator.giantswarm.io/cloud-config-extension-hash"
+	AnnotationDrainEvictionGracePeriod = "kvm-operator.giantswarm.io/drain-eviction-grace-period"
+	AnnotationDrainForceAfterTimeout   = "kvm-operator.giantswarm.io/drain-force-after-timeout"
+	AnnotationDrainStartedAt           = "kvm-operator.giantswarm.io/drain-started-at"
+	AnnotationDrainTimeout             = "kvm-operator.giantswarm.io/drain-timeout"
+	AnnotationEtcdDomain               = "giantswarm.io/etcd-domain"
+	AnnotationEtcdRestoreSnapshot      = "kvm-operator.giantswarm.io/etcd-restore-snapshot"
+	AnnotationEtcdStorageClass         = "kvm-operator.giantswarm.io/etcd-storage-class"
+	AnnotationEtcdStorageSize          = "kvm-operator.giantswarm.io/etcd-storage-size"
+	AnnotationIp                       = "endpoint.kvm.giantswarm.io/ip"
+	AnnotationKubeletConfig            = "kvm-operator.giantswarm.io/kubelet-config"
+	AnnotationKubeletConfigHash        = "kvm-operator.giantswarm.io/kubelet-config-hash"
+	AnnotationMaintenanceWindows       = "kvm-operator.giantswarm.io/maintenance-windows"
+	AnnotationService                  = "endpoint.kvm.giantswarm.io/service"
+	AnnotationUpdatedAt                = "kvm-operator.giantswarm.io/updated-at"
+	AnnotationUpdatesPaused            = "kvm-operator.giantswarm.io/updates-paused"
+	// AnnotationPodDrained is the drained annotation of pods created by former
+	// versions. It is only read to migrate their drain state. See the drain
+	// package for the drain state of VM pods.
 	AnnotationPodDrained        = "endpoint.kvm.giantswarm.io/drained"
 	AnnotationPrometheusCluster = "giantswarm.io/prometheus-cluster"
 	AnnotationVersionBundle     = "kvm-operator.giantswarm.io/version-bundle"
 
-	LabelApp           = "app"
-	LabelCluster       = "giantswarm.io/cluster"
-	LabelCustomer      = "customer"
-	LabelManagedBy     = "giantswarm.io/managed-by"
-	LabelOrganization  = "giantswarm.io/organization"
-	LabelVersionBundle = "giantswarm.io/version-bundle"
+	LabelApp               = "app"
+	LabelCloudConfigSecret = "kvm-operator.giantswarm.io/cloud-config-secret"
+	LabelCluster           = "giantswarm.io/cluster"
+	LabelCustomer          = "customer"
+	LabelManagedBy         = "giantswarm.io/managed-by"
+	LabelOrganization      = "giantswarm.io/organization"
+	LabelVersionBundle     = "giantswarm.io/version-bundle"
 
 	LegacyLabelCluster = "cluster"
 )
@@ -127,20 +181,39 @@ func AllNodes(cr v1alpha1.KVMConfig) []v1alpha1.ClusterNode {
 	return results
 }
 
-func AllocatedNodeIndexes(cr v1alpha1.KVMConfig) []int {
-	var results []int
+func BaseDomain(customObject v1alpha1.KVMConfig) string {
+	return strings.TrimPrefix(customObject.Spec.Cluster.Kubernetes.API.Domain, "api.")
+}
 
-	for _, v := range cr.Status.KVM.NodeIndexes {
-		results = append(results, v)
-	}
+// APIServerConfig returns the YAML encoded API server configuration overriding
+// the installation wide configuration for the tenant cluster. It is taken
+// from the API server config annotation and empty if not set.
+func APIServerConfig(customObject v1alpha1.KVMConfig) string {
+	return customObject.GetAnnotations()[AnnotationAPIServerConfig]
+}
 
-	sort.Ints(results)
+// CloudConfigExtensions returns the references to the ConfigMaps and Secrets
+// contributing custom files and units to the cloud configs of the tenant
+// cluster, e.g. configmap/registry-mirrors. The references are taken from the
+// comma separated list of the cloud config extensions annotation.
+func CloudConfigExtensions(customObject v1alpha1.KVMConfig) []string {
+	var refs []string
 
-	return results
+	for _, ref := range strings.Split(customObject.GetAnnotations()[AnnotationCloudConfigExtensions], ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs
 }
 
-func BaseDomain(customObject v1alpha1.KVMConfig) string {
-	return strings.TrimPrefix(customObject.Spec.Cluster.Kubernetes.API.Domain, "api.")
+// CloudConfigSecretName returns the name of the Secret holding the secret part
+// of the cloud config of the given node.
+func CloudConfigSecretName(cr v1alpha1.KVMConfig, node v1alpha1.ClusterNode, prefix string) string {
+	return fmt.Sprintf("%s-%s-%s-secret", prefix, ClusterID(cr), node.ID)
 }
 
 func ClusterAPIEndpoint(customObject v1alpha1.KVMConfig) string {
@@ -221,10 +294,60 @@ func DockerVolumeSizeFromNode(node v1alpha1.KVMConfigSpecKVMNode) string {
 	return DefaultDockerDiskSize
 }
 
+// EtcdRestoreSnapshot returns the name of the etcd snapshot the given cluster
+// should be restored from when its master data directories are empty. The
+// empty string is returned when no restore is requested.
+func EtcdRestoreSnapshot(customObject v1alpha1.KVMConfig) string {
+	return customObject.GetAnnotations()[AnnotationEtcdRestoreSnapshot]
+}
+
+// EtcdRestorePending returns whether the etcd data of the given cluster has
+// to be restored from a snapshot. Restores are only done while the cluster is
+// created for the first time and until the restore completed, so that masters
+// recreated later on never restore stale snapshots.
+func EtcdRestorePending(customObject v1alpha1.KVMConfig) bool {
+	if EtcdRestoreSnapshot(customObject) == "" {
+		return false
+	}
+	if customObject.Status.Cluster.HasCreatedCondition() {
+		return false
+	}
+
+	for _, r := range customObject.Status.Cluster.Resources {
+		for _, c := range r.Conditions {
+			if c.Type == ConditionEtcdRestored && c.Status == v1alpha1.StatusClusterStatusTrue {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// EtcdSnapshotName returns the name under which the etcd snapshot of the given
+// cluster taken at the given time is stored in the backup target.
+func EtcdSnapshotName(customObject v1alpha1.KVMConfig, t time.Time) string {
+	return fmt.Sprintf("%s/%s.db", ClusterID(customObject), t.UTC().Format("20060102-150405"))
+}
+
 func EtcdPVCName(clusterID string, vmNumber string) string {
 	return fmt.Sprintf("%s-%s-%s", "pvc-master-etcd", clusterID, vmNumber)
 }
 
+// EtcdStorageClass returns the storage class configured for the etcd data
+// volumes of the given cluster. The empty string is returned when the cluster
+// does not define its own storage class.
+func EtcdStorageClass(customObject v1alpha1.KVMConfig) string {
+	return customObject.GetAnnotations()[AnnotationEtcdStorageClass]
+}
+
+// EtcdStorageSize returns the storage size configured for the etcd data volumes
+// of the given cluster. The empty string is returned when the cluster does not
+// define its own storage size.
+func EtcdStorageSize(customObject v1alpha1.KVMConfig) string {
+	return customObject.GetAnnotations()[AnnotationEtcdStorageSize]
+}
+
 func NetworkEnvFilePath(customObject v1alpha1.KVMConfig) string {
 	return fmt.Sprintf("%s/networks/%s.env", FlannelEnvPathPrefix, NetworkBridgeName(customObject))
 }
@@ -245,36 +368,30 @@ func IsPodDeleted(pod *corev1.Pod) bool {
 	return pod.GetDeletionTimestamp() != nil
 }
 
-// IsPodDrained checks whether the pod status indicates it got drained. The pod
-// status is partially reflected by its annotations. Here we check for the
-// annotation that tells us if the pod was already drained or not. In case the
-// pod does not have any annotations an unrecoverable error is returned. Such
-// situations should actually never happen. If it happens, something really bad
-// is going on. This is nothing we can just sort right away in our code.
-//
-// TODO(xh3b4sd) handle pod status via the runtime object status primitives
-// and not via annotations.
-func IsPodDrained(pod *corev1.Pod) (bool, error) {
-	a := pod.GetAnnotations()
-	if a == nil {
-		return false, microerror.Mask(missingAnnotationError)
-	}
-	v, ok := a[AnnotationPodDrained]
-	if !ok {
-		return false, microerror.Mask(missingAnnotationError)
-	}
-	b, err := strconv.ParseBool(v)
-	if err != nil {
-		return false, microerror.Mask(err)
+// IsPodContainerReady returns whether the container with the given name of the
+// given pod is ready. Containers without status are not ready.
+func IsPodContainerReady(pod *corev1.Pod, name string) bool {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == name {
+			return cs.Ready
+		}
 	}
 
-	return b, nil
+	return false
 }
 
-func KubeletVolumeSizeFromNode(node v1alpha1.KVMConfigSpecKVMNode) string {
-	// TODO: https://github.com/giantswarm/giantswarm/issues/4105#issuecomment-421772917
-	// TODO: for now we use same value as for DockerVolumeSizeFromNode, when we have kubelet size in spec we should use that.
+// KubeletConfig returns the YAML encoded kubelet settings of the workers of
+// the tenant cluster. It is taken from the kubelet config annotation and empty
+// if not set. See the kubelet package.
+func KubeletConfig(customObject v1alpha1.KVMConfig) string {
+	return customObject.GetAnnotations()[AnnotationKubeletConfig]
+}
 
+// KubeletVolumeSizeFromNode returns the size of the kubelet volume of the given
+// node. The spec has no kubelet volume size, so the docker volume size is
+// used. Workers can be given a separate kubelet volume size with the kubelet
+// config annotation, see the kubelet package.
+func KubeletVolumeSizeFromNode(node v1alpha1.KVMConfigSpecKVMNode) string {
 	if node.DockerVolumeSizeGB != 0 {
 		return fmt.Sprintf("%dG", node.DockerVolumeSizeGB)
 	}
@@ -302,6 +419,13 @@ func LivenessPort(customObject v1alpha1.KVMConfig) int32 {
 	return int32(livenessPortBase + customObject.Spec.KVM.Network.Flannel.VNI)
 }
 
+// MaintenanceWindows returns the maintenance windows definition updates of the
+// tenant cluster are restricted to. See the maintenance package for its
+// format.
+func MaintenanceWindows(customObject v1alpha1.KVMConfig) string {
+	return customObject.GetAnnotations()[AnnotationMaintenanceWindows]
+}
+
 func MasterCount(customObject v1alpha1.KVMConfig) int {
 	return len(customObject.Spec.KVM.Masters)
 }
@@ -532,6 +656,12 @@ func ToVersionBundleVersion(v interface{}) (string, error) {
 	return VersionBundleVersion(customObject), nil
 }
 
+// UpdatesPaused returns true in case updates of the tenant cluster nodes are
+// paused manually.
+func UpdatesPaused(customObject v1alpha1.KVMConfig) bool {
+	return customObject.GetAnnotations()[AnnotationUpdatesPaused] == "true"
+}
+
 func VersionBundleVersion(customObject v1alpha1.KVMConfig) string {
 	return customObject.Spec.VersionBundle.Version
 }
@@ -0,0 +1,402 @@
+// Package kubelet implements the kubelet settings of tenant cluster workers.
+//
+// Settings are defined in the YAML encoded kubelet config annotation of the
+// KVMConfig. The default settings apply to all workers. Pools group workers by
+// their node IDs and override the default settings for them. Maps are
+// overridden key by key, all other settings as a whole. A worker must not be
+// part of more than one pool. The following example reserves resources on all
+// workers and dedicates two workers with a bigger kubelet volume to batch
+// workloads.
+//
+//	default:
+//	  kubeReserved: {cpu: 200m, memory: 512Mi}
+//	  systemReserved: {cpu: 100m, memory: 256Mi}
+//	pools:
+//	- name: batch
+//	  workers: [w1, w2]
+//	  maxPods: 50
+//	  evictionHard: {nodefs.available: 10%}
+//	  labels: {pool: batch}
+//	  taints: [dedicated=batch:NoSchedule]
+//	  volumeSizeGB: 100
+//
+// The settings are passed as flags to the kubelets, which take precedence over
+// the kubelet config file of k8scloudconfig. Labels and taints are only applied
+// when nodes register, which is why changed settings cause the worker VMs to be
+// rolled.
+package kubelet
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	// MaxPods is the maximum number of pods per worker. Workers get a /24 pod
+	// network, which has to fit the pods.
+	MaxPods = 250
+)
+
+var (
+	percentageRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?%$`)
+	taintRegexp      = regexp.MustCompile(`^([^=:]+)(=([^:]*))?:(NoSchedule|PreferNoSchedule|NoExecute)$`)
+)
+
+// Eviction thresholds of the kubelet config file of k8scloudconfig. Eviction
+// flags replace the thresholds of the config file, so they are merged with
+// the configured ones.
+var (
+	defaultEvictionHard = map[string]string{
+		"memory.available": "200Mi",
+	}
+	defaultEvictionSoft = map[string]string{
+		"memory.available": "500Mi",
+	}
+	defaultEvictionSoftGracePeriod = map[string]string{
+		"memory.available": "5s",
+	}
+)
+
+var evictionSignals = map[string]bool{
+	"imagefs.available":  true,
+	"imagefs.inodesFree": true,
+	"memory.available":   true,
+	"nodefs.available":   true,
+	"nodefs.inodesFree":  true,
+}
+
+var reservedResources = map[string]bool{
+	"cpu":               true,
+	"ephemeral-storage": true,
+	"memory":            true,
+}
+
+// reservedLabels are the node labels set by the kubelet unit of k8scloudconfig.
+var reservedLabels = map[string]bool{
+	"ip":                             true,
+	"kubernetes.io/role":             true,
+	"node-role.kubernetes.io/worker": true,
+	"node.kubernetes.io/worker":      true,
+	"role":                           true,
+}
+
+// Config holds the kubelet settings of all workers of a tenant cluster.
+type Config struct {
+	Default Settings `json:"default,omitempty"`
+	Pools   []Pool   `json:"pools,omitempty"`
+}
+
+// Pool holds the kubelet settings of the workers with the given node IDs.
+type Pool struct {
+	Name    string   `json:"name"`
+	Workers []string `json:"workers"`
+
+	Settings
+}
+
+// Settings are the kubelet settings of a worker. Eviction thresholds are given
+// per eviction signal as quantity or percentage, e.g. 10%. Reserved resources
+// are given as quantities. Taints have the form key=value:Effect.
+type Settings struct {
+	EvictionHard            map[string]string `json:"evictionHard,omitempty"`
+	EvictionSoft            map[string]string `json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+	KubeReserved            map[string]string `json:"kubeReserved,omitempty"`
+	Labels                  map[string]string `json:"labels,omitempty"`
+	MaxPods                 int               `json:"maxPods,omitempty"`
+	SystemReserved          map[string]string `json:"systemReserved,omitempty"`
+	Taints                  []string          `json:"taints,omitempty"`
+	// VolumeSizeGB is the size of the kubelet volume. Zero means the docker
+	// volume size is used.
+	VolumeSizeGB int `json:"volumeSizeGB,omitempty"`
+}
+
+// Parse parses and validates the given kubelet config. An empty config results
+// in no settings.
+func Parse(s string) (Config, error) {
+	var c Config
+
+	err := yaml.Unmarshal([]byte(s), &c)
+	if err != nil {
+		return Config{}, microerror.Maskf(invalidConfigError, "%s", err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return Config{}, microerror.Mask(err)
+	}
+
+	return c, nil
+}
+
+// ForWorker returns the kubelet settings of the worker with the given node ID
+// of the given custom object.
+func ForWorker(customObject v1alpha1.KVMConfig, nodeID string) (Settings, error) {
+	c, err := Parse(key.KubeletConfig(customObject))
+	if err != nil {
+		return Settings{}, microerror.Mask(err)
+	}
+
+	return c.Worker(nodeID), nil
+}
+
+// Validate returns an invalidConfigError in case the default settings or the
+// effective settings of any pool are invalid, or pools overlap.
+func (c Config) Validate() error {
+	err := c.Default.validate("default")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	names := map[string]bool{}
+	workers := map[string]string{}
+	for _, p := range c.Pools {
+		if p.Name == "" {
+			return microerror.Maskf(invalidConfigError, "pool names must not be empty")
+		}
+		if names[p.Name] {
+			return microerror.Maskf(invalidConfigError, "pool '%s' must not be defined twice", p.Name)
+		}
+		names[p.Name] = true
+
+		for _, w := range p.Workers {
+			other, ok := workers[w]
+			if ok {
+				return microerror.Maskf(invalidConfigError, "worker '%s' must not be part of pool '%s' and '%s'", w, other, p.Name)
+			}
+			workers[w] = p.Name
+		}
+
+		err := c.Default.merge(p.Settings).validate(fmt.Sprintf("pool '%s'", p.Name))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Worker returns the kubelet settings of the worker with the given node ID,
+// which are the default settings overridden by the ones of its pool.
+func (c Config) Worker(nodeID string) Settings {
+	for _, p := range c.Pools {
+		for _, w := range p.Workers {
+			if w == nodeID {
+				return c.Default.merge(p.Settings)
+			}
+		}
+	}
+
+	return c.Default
+}
+
+// Args returns the kubelet flags of the settings quoted for the kubelet unit
+// of k8scloudconfig. Node labels are not part of them, because the kubelet
+// unit defines its own. See NodeLabels.
+func (s Settings) Args() []string {
+	var args []string
+
+	if len(s.EvictionHard) != 0 {
+		args = append(args, quoteUnitArg(fmt.Sprintf("--eviction-hard=%s", joinMap(mergeMaps(defaultEvictionHard, s.EvictionHard), "<"))))
+	}
+	if len(s.EvictionSoft) != 0 || len(s.EvictionSoftGracePeriod) != 0 {
+		args = append(args, quoteUnitArg(fmt.Sprintf("--eviction-soft=%s", joinMap(mergeMaps(defaultEvictionSoft, s.EvictionSoft), "<"))))
+		args = append(args, fmt.Sprintf("--eviction-soft-grace-period=%s", joinMap(mergeMaps(defaultEvictionSoftGracePeriod, s.EvictionSoftGracePeriod), "=")))
+	}
+	if len(s.KubeReserved) != 0 {
+		args = append(args, fmt.Sprintf("--kube-reserved=%s", joinMap(s.KubeReserved, "=")))
+	}
+	if s.MaxPods != 0 {
+		args = append(args, fmt.Sprintf("--max-pods=%d", s.MaxPods))
+	}
+	if len(s.SystemReserved) != 0 {
+		args = append(args, fmt.Sprintf("--system-reserved=%s", joinMap(s.SystemReserved, "=")))
+	}
+	if len(s.Taints) != 0 {
+		args = append(args, fmt.Sprintf("--register-with-taints=%s", strings.Join(s.Taints, ",")))
+	}
+
+	return args
+}
+
+// Hash returns a hash of the settings rendered into the cloud config. It is
+// empty for empty settings, so that workers without settings are not rolled.
+func (s Settings) Hash() string {
+	args := s.Args()
+	labels := s.NodeLabels()
+	if len(args) == 0 && labels == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(append(args, labels), "\n"))))
+}
+
+// NodeLabels returns the comma separated node labels of the settings.
+func (s Settings) NodeLabels() string {
+	return joinMap(s.Labels, "=")
+}
+
+// VolumeSize returns the kubelet volume size of the settings in the format
+// used for the VM disks, or an empty string in case it is not set.
+func (s Settings) VolumeSize() string {
+	if s.VolumeSizeGB == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dG", s.VolumeSizeGB)
+}
+
+// merge returns the settings overridden by the given ones.
+func (s Settings) merge(o Settings) Settings {
+	merged := Settings{
+		EvictionHard:            mergeMaps(s.EvictionHard, o.EvictionHard),
+		EvictionSoft:            mergeMaps(s.EvictionSoft, o.EvictionSoft),
+		EvictionSoftGracePeriod: mergeMaps(s.EvictionSoftGracePeriod, o.EvictionSoftGracePeriod),
+		KubeReserved:            mergeMaps(s.KubeReserved, o.KubeReserved),
+		Labels:                  mergeMaps(s.Labels, o.Labels),
+		MaxPods:                 s.MaxPods,
+		SystemReserved:          mergeMaps(s.SystemReserved, o.SystemReserved),
+		Taints:                  s.Taints,
+		VolumeSizeGB:            s.VolumeSizeGB,
+	}
+
+	if o.MaxPods != 0 {
+		merged.MaxPods = o.MaxPods
+	}
+	if len(o.Taints) != 0 {
+		merged.Taints = o.Taints
+	}
+	if o.VolumeSizeGB != 0 {
+		merged.VolumeSizeGB = o.VolumeSizeGB
+	}
+
+	return merged
+}
+
+// validate returns an invalidConfigError in case the settings are invalid. The
+// given name identifies the settings in the error message.
+func (s Settings) validate(name string) error {
+	for _, m := range []map[string]string{s.EvictionHard, s.EvictionSoft} {
+		for signal, threshold := range m {
+			if !evictionSignals[signal] {
+				return microerror.Maskf(invalidConfigError, "%s: eviction signal '%s' is unknown", name, signal)
+			}
+			if !percentageRegexp.MatchString(threshold) && !isNonNegativeQuantity(threshold) {
+				return microerror.Maskf(invalidConfigError, "%s: eviction threshold '%s' of '%s' must be a quantity or percentage", name, threshold, signal)
+			}
+		}
+	}
+	for signal, period := range s.EvictionSoftGracePeriod {
+		if !evictionSignals[signal] {
+			return microerror.Maskf(invalidConfigError, "%s: eviction signal '%s' is unknown", name, signal)
+		}
+		d, err := time.ParseDuration(period)
+		if err != nil || d < 0 {
+			return microerror.Maskf(invalidConfigError, "%s: eviction grace period '%s' of '%s' must be a duration", name, period, signal)
+		}
+	}
+	// The kubelet refuses to start with soft eviction thresholds lacking a
+	// grace period.
+	soft := mergeMaps(defaultEvictionSoft, s.EvictionSoft)
+	periods := mergeMaps(defaultEvictionSoftGracePeriod, s.EvictionSoftGracePeriod)
+	for signal := range soft {
+		if _, ok := periods[signal]; !ok {
+			return microerror.Maskf(invalidConfigError, "%s: soft eviction signal '%s' must have a grace period", name, signal)
+		}
+	}
+
+	for _, m := range []map[string]string{s.KubeReserved, s.SystemReserved} {
+		for r, quantity := range m {
+			if !reservedResources[r] {
+				return microerror.Maskf(invalidConfigError, "%s: reserved resource '%s' is unknown", name, r)
+			}
+			if !isNonNegativeQuantity(quantity) {
+				return microerror.Maskf(invalidConfigError, "%s: reserved quantity '%s' of '%s' must be a quantity", name, quantity, r)
+			}
+		}
+	}
+
+	for k, v := range s.Labels {
+		if len(validation.IsQualifiedName(k)) != 0 || len(validation.IsValidLabelValue(v)) != 0 {
+			return microerror.Maskf(invalidConfigError, "%s: label '%s=%s' is invalid", name, k, v)
+		}
+		if reservedLabels[k] {
+			return microerror.Maskf(invalidConfigError, "%s: label '%s' is managed by the kubelet unit", name, k)
+		}
+	}
+
+	if s.MaxPods < 0 || s.MaxPods > MaxPods {
+		return microerror.Maskf(invalidConfigError, "%s: max pods %d must not be negative or greater than %d", name, s.MaxPods, MaxPods)
+	}
+
+	for _, t := range s.Taints {
+		m := taintRegexp.FindStringSubmatch(t)
+		if m == nil || len(validation.IsQualifiedName(m[1])) != 0 || len(validation.IsValidLabelValue(m[3])) != 0 {
+			return microerror.Maskf(invalidConfigError, "%s: taint '%s' must have the form key=value:Effect", name, t)
+		}
+	}
+
+	if s.VolumeSizeGB < 0 {
+		return microerror.Maskf(invalidConfigError, "%s: volume size %d must not be negative", name, s.VolumeSizeGB)
+	}
+
+	return nil
+}
+
+func isNonNegativeQuantity(s string) bool {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return false
+	}
+
+	return q.Sign() >= 0
+}
+
+// quoteUnitArg quotes the given flag for the kubelet unit, which runs the
+// kubelet via a shell. Otherwise the shell would treat the < of eviction
+// thresholds as redirection and systemd the % of percentages as specifier.
+func quoteUnitArg(arg string) string {
+	return "'" + strings.Replace(arg, "%", "%%", -1) + "'"
+}
+
+// joinMap returns the given map as comma separated list of key value pairs
+// sorted by key, e.g. cpu=100m,memory=256Mi.
+func joinMap(m map[string]string, sep string) string {
+	var pairs []string
+	for k, v := range m {
+		pairs = append(pairs, k+sep+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// mergeMaps returns a new map holding the entries of a overridden by the ones
+// of b. Nil is returned in case both maps are empty.
+func mergeMaps(a, b map[string]string) map[string]string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	merged := map[string]string{}
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+
+	return merged
+}
@@ -0,0 +1,196 @@
+package kubelet
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Parse(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: empty config",
+			config: "",
+		},
+		{
+			name: "case 1: default settings and pools",
+			config: `
+default:
+  kubeReserved: {cpu: 200m, memory: 512Mi}
+pools:
+- name: batch
+  workers: [w1, w2]
+  evictionHard: {nodefs.available: 10%}
+  evictionSoft: {nodefs.available: 15%}
+  evictionSoftGracePeriod: {nodefs.available: 1m}
+  labels: {pool: batch}
+  maxPods: 50
+  taints: [dedicated=batch:NoSchedule, gpu:NoExecute]
+  volumeSizeGB: 100
+`,
+		},
+		{
+			name:         "case 2: unknown eviction signal is invalid",
+			config:       `default: {evictionHard: {cpu.available: 10%}}`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 3: soft eviction signal without grace period is invalid",
+			config:       `default: {evictionSoft: {nodefs.available: 10%}}`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 4: unknown reserved resource is invalid",
+			config:       `default: {systemReserved: {gpu: "1"}}`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 5: label managed by the kubelet unit is invalid",
+			config:       `default: {labels: {role: batch}}`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 6: taint without effect is invalid",
+			config:       `default: {taints: [dedicated=batch]}`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 7: max pods exceeding the pod network is invalid",
+			config:       `default: {maxPods: 300}`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 8: worker part of two pools is invalid",
+			config: `
+pools:
+- name: a
+  workers: [w1]
+- name: b
+  workers: [w1]
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 9: invalid pool settings are invalid",
+			config: `
+pools:
+- name: a
+  workers: [w1]
+  volumeSizeGB: -1
+`,
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Config_Worker(t *testing.T) {
+	config := `
+default:
+  kubeReserved: {cpu: 200m, memory: 512Mi}
+  labels: {tier: general}
+  maxPods: 80
+pools:
+- name: batch
+  workers: [w1]
+  evictionHard: {nodefs.available: 10%}
+  evictionSoftGracePeriod: {memory.available: 30s}
+  kubeReserved: {memory: 1Gi}
+  labels: {pool: batch}
+  taints: [dedicated=batch:NoSchedule]
+  volumeSizeGB: 100
+`
+
+	testCases := []struct {
+		name               string
+		nodeID             string
+		expectedArgs       []string
+		expectedNodeLabels string
+		expectedVolumeSize string
+	}{
+		{
+			name:   "case 0: worker without pool gets the default settings",
+			nodeID: "w2",
+			expectedArgs: []string{
+				"--kube-reserved=cpu=200m,memory=512Mi",
+				"--max-pods=80",
+			},
+			expectedNodeLabels: "tier=general",
+		},
+		{
+			name:   "case 1: worker of pool gets the merged settings",
+			nodeID: "w1",
+			expectedArgs: []string{
+				"'--eviction-hard=memory.available<200Mi,nodefs.available<10%%'",
+				"'--eviction-soft=memory.available<500Mi'",
+				"--eviction-soft-grace-period=memory.available=30s",
+				"--kube-reserved=cpu=200m,memory=1Gi",
+				"--max-pods=80",
+				"--register-with-taints=dedicated=batch:NoSchedule",
+			},
+			expectedNodeLabels: "pool=batch,tier=general",
+			expectedVolumeSize: "100G",
+		},
+	}
+
+	c, err := Parse(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := c.Worker(tc.nodeID)
+
+			if diff := cmp.Diff(s.Args(), tc.expectedArgs); diff != "" {
+				t.Fatalf("args not as expected: (-got +expected)\n%s\n", diff)
+			}
+			if s.NodeLabels() != tc.expectedNodeLabels {
+				t.Fatalf("expected %#q got %#q", tc.expectedNodeLabels, s.NodeLabels())
+			}
+			if s.VolumeSize() != tc.expectedVolumeSize {
+				t.Fatalf("expected %#q got %#q", tc.expectedVolumeSize, s.VolumeSize())
+			}
+			if s.Hash() == "" {
+				t.Fatalf("expected non-empty hash")
+			}
+		})
+	}
+}
+
+func Test_Settings_Hash(t *testing.T) {
+	if (Settings{}).Hash() != "" {
+		t.Fatalf("expected empty hash of empty settings")
+	}
+	// The volume size is not rendered into the cloud config, but changes the
+	// deployment on its own.
+	if (Settings{VolumeSizeGB: 100}).Hash() != "" {
+		t.Fatalf("expected empty hash of settings only defining the volume size")
+	}
+
+	a := Settings{MaxPods: 50}
+	b := Settings{MaxPods: 60}
+	if a.Hash() == b.Hash() {
+		t.Fatalf("expected different hashes of different settings")
+	}
+}
@@ -0,0 +1,14 @@
+package maintenance
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidWindowError = &microerror.Error{
+	Kind: "invalidWindowError",
+}
+
+// IsInvalidWindow asserts invalidWindowError.
+func IsInvalidWindow(err error) bool {
+	return microerror.Cause(err) == invalidWindowError
+}
@@ -0,0 +1,217 @@
+// Package maintenance implements the maintenance windows tenant cluster
+// updates are restricted to.
+//
+// Windows are defined in the maintenance windows annotation of the KVMConfig
+// as a semicolon separated list. Each window consists of a cron schedule with
+// the five fields minute, hour, day of month, month and day of week, followed
+// by the duration of the window. The schedule defines when a window opens and
+// is evaluated in UTC. The following example allows updates on Saturdays from
+// 02:00 to 06:00 and on the first day of every month from 22:00 to 23:30.
+//
+//	0 2 * * 6 4h; 0 22 1 * * 90m
+//
+// Fields support single values, ranges like 1-5, steps like */15 or 0-30/10
+// and comma separated lists of them. Days of week are numbered from 0 to 7,
+// where both 0 and 7 mean Sunday.
+package maintenance
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// MaxDuration is the maximum duration of a single maintenance window.
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+// Windows is a set of maintenance windows.
+type Windows []Window
+
+// Window is a single maintenance window opening according to a cron schedule
+// and staying open for the configured duration.
+type Window struct {
+	Duration time.Duration
+
+	minutes     field
+	hours       field
+	daysOfMonth field
+	months      field
+	daysOfWeek  field
+}
+
+type field struct {
+	// all is true in case the field was defined as a plain wildcard.
+	all    bool
+	values map[int]bool
+}
+
+// Parse parses the given maintenance windows definition. An empty definition
+// results in no windows.
+func Parse(s string) (Windows, error) {
+	var windows Windows
+
+	for _, w := range strings.Split(s, ";") {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+
+		window, err := parseWindow(w)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+// Open returns true in case updates are allowed at the given time. This is
+// the case when no windows are defined at all or when any of the windows is
+// open at the given time.
+func (w Windows) Open(t time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+
+	for _, window := range w {
+		if window.Open(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Open returns true in case the window opened within its duration before the
+// given time.
+func (w Window) Open(t time.Time) bool {
+	t = t.UTC().Truncate(time.Minute)
+
+	for s := t; t.Sub(s) < w.Duration; s = s.Add(-time.Minute) {
+		if w.matches(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w Window) matches(t time.Time) bool {
+	if !w.minutes.values[t.Minute()] || !w.hours.values[t.Hour()] || !w.months.values[int(t.Month())] {
+		return false
+	}
+
+	dom := w.daysOfMonth.values[t.Day()]
+	dow := w.daysOfWeek.values[int(t.Weekday())]
+
+	// As in cron, a day matches either of both day fields in case both of them
+	// are restricted.
+	if !w.daysOfMonth.all && !w.daysOfWeek.all {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+func parseWindow(s string) (Window, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 6 {
+		return Window{}, microerror.Maskf(invalidWindowError, "window '%s' must consist of 5 cron fields and a duration", s)
+	}
+
+	duration, err := time.ParseDuration(fields[5])
+	if err != nil {
+		return Window{}, microerror.Maskf(invalidWindowError, "window '%s' has invalid duration: %s", s, err)
+	}
+	if duration <= 0 || duration > MaxDuration {
+		return Window{}, microerror.Maskf(invalidWindowError, "window '%s' must have a duration between 0 and %s", s, MaxDuration)
+	}
+
+	var w Window
+	{
+		w.Duration = duration
+
+		specs := []struct {
+			field *field
+			min   int
+			max   int
+		}{
+			{field: &w.minutes, min: 0, max: 59},
+			{field: &w.hours, min: 0, max: 23},
+			{field: &w.daysOfMonth, min: 1, max: 31},
+			{field: &w.months, min: 1, max: 12},
+			{field: &w.daysOfWeek, min: 0, max: 7},
+		}
+
+		for i, spec := range specs {
+			f, err := parseField(fields[i], spec.min, spec.max)
+			if err != nil {
+				return Window{}, microerror.Maskf(invalidWindowError, "window '%s': %s", s, err)
+			}
+			*spec.field = f
+		}
+
+		// Sunday can be given as 0 or 7.
+		if w.daysOfWeek.values[7] {
+			w.daysOfWeek.values[0] = true
+		}
+	}
+
+	return w, nil
+}
+
+func parseField(s string, min, max int) (field, error) {
+	f := field{
+		all:    s == "*",
+		values: map[int]bool{},
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return field{}, microerror.Maskf(invalidWindowError, "field '%s' has invalid step", s)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			var err error
+			if i := strings.Index(part, "-"); i != -1 {
+				from, err = strconv.Atoi(part[:i])
+				if err != nil {
+					return field{}, microerror.Maskf(invalidWindowError, "field '%s' has invalid range", s)
+				}
+				to, err = strconv.Atoi(part[i+1:])
+				if err != nil {
+					return field{}, microerror.Maskf(invalidWindowError, "field '%s' has invalid range", s)
+				}
+			} else {
+				from, err = strconv.Atoi(part)
+				if err != nil {
+					return field{}, microerror.Maskf(invalidWindowError, "field '%s' has invalid value", s)
+				}
+				to = from
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return field{}, microerror.Maskf(invalidWindowError, "field '%s' must be within %d and %d", s, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			f.values[v] = true
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,113 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Windows_Open(t *testing.T) {
+	testCases := []struct {
+		name         string
+		windows      string
+		time         string
+		expectedOpen bool
+	}{
+		{
+			name:         "case 0: no windows are always open",
+			windows:      "",
+			time:         "2019-05-15T13:37:00Z",
+			expectedOpen: true,
+		},
+		{
+			name:         "case 1: within Saturday night window",
+			windows:      "0 2 * * 6 4h",
+			time:         "2019-05-18T05:59:00Z",
+			expectedOpen: true,
+		},
+		{
+			name:         "case 2: after Saturday night window",
+			windows:      "0 2 * * 6 4h",
+			time:         "2019-05-18T06:00:00Z",
+			expectedOpen: false,
+		},
+		{
+			name:         "case 3: window reaching into the next day",
+			windows:      "0 22 * * 6 4h",
+			time:         "2019-05-19T01:30:00Z",
+			expectedOpen: true,
+		},
+		{
+			name:         "case 4: second window of list is open",
+			windows:      "0 2 * * 6 4h; 0 22 1 * * 90m",
+			time:         "2019-06-01T23:00:00Z",
+			expectedOpen: true,
+		},
+		{
+			name:         "case 5: time is evaluated in UTC",
+			windows:      "0 2 * * 6 1h",
+			time:         "2019-05-18T04:30:00+02:00",
+			expectedOpen: true,
+		},
+		{
+			name:         "case 6: both day fields restricted match either of them",
+			windows:      "0 0 1 * 1 1h",
+			time:         "2019-05-20T00:30:00Z",
+			expectedOpen: true,
+		},
+		{
+			name:         "case 7: weekday ranges with steps",
+			windows:      "*/30 8-17 * * 1-5 10m",
+			time:         "2019-05-18T08:05:00Z",
+			expectedOpen: false,
+		},
+		{
+			name:         "case 8: Sunday given as 7",
+			windows:      "0 3 * * 7 1h",
+			time:         "2019-05-19T03:15:00Z",
+			expectedOpen: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			windows, err := Parse(tc.windows)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			at, err := time.Parse(time.RFC3339, tc.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			open := windows.Open(at)
+			if open != tc.expectedOpen {
+				t.Fatalf("expected %t got %t", tc.expectedOpen, open)
+			}
+		})
+	}
+}
+
+func Test_Parse_Invalid(t *testing.T) {
+	testCases := []string{
+		"0 2 * * 6",
+		"0 2 * * 6 4x",
+		"0 2 * * 6 -1h",
+		"0 2 * * 6 200h",
+		"60 2 * * 6 1h",
+		"0 24 * * 6 1h",
+		"0 2 0 * * 1h",
+		"0 2 * 13 * 1h",
+		"0 2 * * 8 1h",
+		"0 5-2 * * * 1h",
+		"0 */0 * * * 1h",
+		"a 2 * * * 1h",
+	}
+
+	for i, tc := range testCases {
+		_, err := Parse(tc)
+		if !IsInvalidWindow(err) {
+			t.Fatalf("case %d: expected invalidWindowError for '%s' got %#v", i, tc, err)
+		}
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/microerror"
 	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
@@ -43,13 +45,23 @@ func (r *Resource) newConfigMaps(customResource v1alpha1.KVMConfig) ([]*apiv1.Co
 		return nil, microerror.Mask(err)
 	}
 
+	extension, err := cloudconfig.LoadExtension(r.k8sClient, customResource)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	for _, node := range customResource.Spec.Cluster.Masters {
 		nodeIdx, exists := key.NodeIndex(customResource, node.ID)
 		if !exists {
-			return nil, microerror.Maskf(notFoundError, fmt.Sprintf("node index for master (%q) is not available", node.ID))
+			return nil, microerror.Maskf(notFoundError, "node index for master (%q) is not available", node.ID)
 		}
 
-		template, err := r.cloudConfig.NewMasterTemplate(customResource, certs, node, keys, nodeIdx)
+		embedSecrets, err := r.embedSecrets(customResource, key.DeploymentName(key.MasterID, node.ID))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		template, err := r.cloudConfig.NewMasterTemplate(customResource, certs, node, keys, nodeIdx, extension.Master, embedSecrets)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -65,10 +77,15 @@ func (r *Resource) newConfigMaps(customResource v1alpha1.KVMConfig) ([]*apiv1.Co
 	for _, node := range customResource.Spec.Cluster.Workers {
 		nodeIdx, exists := key.NodeIndex(customResource, node.ID)
 		if !exists {
-			return nil, microerror.Maskf(notFoundError, fmt.Sprintf("node index for worker (%q) is not available", node.ID))
+			return nil, microerror.Maskf(notFoundError, "node index for worker (%q) is not available", node.ID)
 		}
 
-		template, err := r.cloudConfig.NewWorkerTemplate(customResource, certs, node, nodeIdx)
+		embedSecrets, err := r.embedSecrets(customResource, key.DeploymentName(key.WorkerID, node.ID))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		template, err := r.cloudConfig.NewWorkerTemplate(customResource, certs, node, nodeIdx, extension.Worker, embedSecrets)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -84,6 +101,34 @@ func (r *Resource) newConfigMaps(customResource v1alpha1.KVMConfig) ([]*apiv1.Co
 	return configMaps, nil
 }
 
+// embedSecrets returns whether secrets have to be embedded into the cloud config
+// of the VM managed by the given deployment. This is the migration path of VMs
+// created before the secret parts of cloud configs were moved into Secrets.
+// Their deployments do not mount the Secrets yet, so their ConfigMaps keep the
+// secrets until the deployments got updated. Otherwise a restarted VM would
+// boot without certificates. New VMs only get secrets embedded in case the
+// k8s-kvm image does not read the secret part of the cloud config.
+func (r *Resource) embedSecrets(customResource v1alpha1.KVMConfig, deploymentName string) (bool, error) {
+	if r.embedAllSecrets {
+		return true, nil
+	}
+
+	d, err := r.k8sClient.ExtensionsV1beta1().Deployments(key.ClusterNamespace(customResource)).Get(deploymentName, apismetav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	for _, v := range d.Spec.Template.Spec.Volumes {
+		if v.Name == key.CloudConfigSecretVolumeName {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 // newConfigMap creates a new Kubernetes configmap using the provided
 // information. customResource is used for name and label creation. params
 // serves as structure being injected into the template execution to interpolate
@@ -10,9 +10,13 @@ import (
 	"github.com/giantswarm/micrologger/microloggertest"
 	"github.com/giantswarm/randomkeys/randomkeystest"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig/cloudconfigtest"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
 func Test_Resource_CloudConfig_GetDesiredState(t *testing.T) {
@@ -222,6 +226,101 @@ func Test_Resource_CloudConfig_GetDesiredState(t *testing.T) {
 	}
 }
 
+func Test_Resource_CloudConfig_embedSecrets(t *testing.T) {
+	customResource := v1alpha1.KVMConfig{
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+			},
+		},
+	}
+
+	newDeployment := func(volumes ...string) *v1beta1.Deployment {
+		d := &v1beta1.Deployment{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name:      "worker-b",
+				Namespace: "al9qy",
+			},
+		}
+		for _, v := range volumes {
+			d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, apiv1.Volume{Name: v})
+		}
+
+		return d
+	}
+
+	testCases := []struct {
+		name                 string
+		embedAllSecrets      bool
+		objects              []runtime.Object
+		expectedEmbedSecrets bool
+	}{
+		{
+			name:                 "case 0: new VM does not embed secrets",
+			expectedEmbedSecrets: false,
+		},
+		{
+			name: "case 1: VM not mounting the secret embeds secrets",
+			objects: []runtime.Object{
+				newDeployment("cloud-config"),
+			},
+			expectedEmbedSecrets: true,
+		},
+		{
+			name: "case 2: VM mounting the secret does not embed secrets",
+			objects: []runtime.Object{
+				newDeployment("cloud-config", key.CloudConfigSecretVolumeName),
+			},
+			expectedEmbedSecrets: false,
+		},
+		{
+			name:            "case 3: VM mounting the secret embeds secrets when the k8s-kvm image does not read it",
+			embedAllSecrets: true,
+			objects: []runtime.Object{
+				newDeployment("cloud-config", key.CloudConfigSecretVolumeName),
+			},
+			expectedEmbedSecrets: true,
+		},
+		{
+			name:                 "case 4: new VM embeds secrets when the k8s-kvm image does not read them",
+			embedAllSecrets:      true,
+			expectedEmbedSecrets: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			var newResource *Resource
+			{
+				c := Config{
+					CertsSearcher: certstest.NewSearcher(certstest.Config{}),
+					CloudConfig:   cloudconfigtest.New(),
+					K8sClient:     fake.NewSimpleClientset(tc.objects...),
+					KeyWatcher:    randomkeystest.NewSearcher(),
+					Logger:        microloggertest.New(),
+
+					EmbedAllSecrets: tc.embedAllSecrets,
+				}
+
+				newResource, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			embedSecrets, err := newResource.embedSecrets(customResource, "worker-b")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if embedSecrets != tc.expectedEmbedSecrets {
+				t.Fatalf("expected %t got %t", tc.expectedEmbedSecrets, embedSecrets)
+			}
+		})
+	}
+}
+
 func testGetMasterCount(configMaps []*apiv1.ConfigMap) int {
 	var count int
 
@@ -27,6 +27,12 @@ type Config struct {
 	K8sClient     kubernetes.Interface
 	KeyWatcher    randomkeys.Interface
 	Logger        micrologger.Logger
+
+	// Settings.
+	// EmbedAllSecrets forces secrets to be embedded into the cloud configs of
+	// all VMs. It has to be set as long as the k8s-kvm image does not read the
+	// secret part of the cloud config from CLOUD_CONFIG_SECRET_PATH.
+	EmbedAllSecrets bool
 }
 
 // Resource implements the config map resource.
@@ -37,6 +43,9 @@ type Resource struct {
 	k8sClient     kubernetes.Interface
 	keyWatcher    randomkeys.Interface
 	logger        micrologger.Logger
+
+	// Settings.
+	embedAllSecrets bool
 }
 
 // New creates a new configured config map resource.
@@ -65,6 +74,9 @@ func New(config Config) (*Resource, error) {
 		k8sClient:     config.K8sClient,
 		keyWatcher:    config.KeyWatcher,
 		logger:        config.Logger,
+
+		// Settings.
+		embedAllSecrets: config.EmbedAllSecrets,
 	}
 
 	return newService, nil
@@ -9,6 +9,8 @@ import (
 	"k8s.io/api/extensions/v1beta1"
 	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
 )
 
 func Test_Resource_Deployment_newCreateChange(t *testing.T) {
@@ -218,6 +220,7 @@ func Test_Resource_Deployment_newCreateChange(t *testing.T) {
 	{
 		resourceConfig := DefaultConfig()
 		resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+		resourceConfig.G8sClient = statuspatchtest.NewClientset()
 		resourceConfig.K8sClient = fake.NewSimpleClientset()
 		resourceConfig.Logger = microloggertest.New()
 		newResource, err = New(resourceConfig)
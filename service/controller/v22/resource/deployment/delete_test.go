@@ -9,6 +9,9 @@ import (
 	"k8s.io/api/extensions/v1beta1"
 	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
+	"github.com/giantswarm/kvm-operator/service/rollout"
 )
 
 func Test_Resource_Deployment_newDeleteChange(t *testing.T) {
@@ -264,6 +267,7 @@ func Test_Resource_Deployment_newDeleteChange(t *testing.T) {
 	{
 		resourceConfig := DefaultConfig()
 		resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+		resourceConfig.G8sClient = statuspatchtest.NewClientset()
 		resourceConfig.K8sClient = fake.NewSimpleClientset()
 		resourceConfig.Logger = microloggertest.New()
 		newResource, err = New(resourceConfig)
@@ -288,3 +292,40 @@ func Test_Resource_Deployment_newDeleteChange(t *testing.T) {
 		}
 	}
 }
+
+func Test_Resource_Deployment_NewDeletePatch_ReleasesRolloutSlot(t *testing.T) {
+	limiter := rollout.NewLimiter(1)
+	limiter.Acquire("al9qy")
+
+	var err error
+	var newResource *Resource
+	{
+		resourceConfig := DefaultConfig()
+		resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+		resourceConfig.G8sClient = statuspatchtest.NewClientset()
+		resourceConfig.K8sClient = fake.NewSimpleClientset()
+		resourceConfig.Logger = microloggertest.New()
+		resourceConfig.UpdateLimiter = limiter
+		newResource, err = New(resourceConfig)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	obj := &v1alpha1.KVMConfig{
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+			},
+		},
+	}
+
+	_, err = newResource.NewDeletePatch(context.TODO(), obj, []*v1beta1.Deployment{}, []*v1beta1.Deployment{})
+	if err != nil {
+		t.Fatalf("expected %#v got %#v", nil, err)
+	}
+
+	if len(limiter.Rolling()) != 0 {
+		t.Fatalf("expected %d rolling clusters got %#v", 0, limiter.Rolling())
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
@@ -21,20 +24,109 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 	var deployments []*v1beta1.Deployment
 
 	{
-		masterDeployments, err := newMasterDeployments(customResource, r.dnsServers)
+		masterDeployments, err := newMasterDeployments(customResource, r.dnsServers, r.etcdBackupTarget, r.images)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
 		deployments = append(deployments, masterDeployments...)
 
-		workerDeployments, err := newWorkerDeployments(customResource, r.dnsServers)
+		workerDeployments, err := newWorkerDeployments(customResource, r.dnsServers, r.images)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
 		deployments = append(deployments, workerDeployments...)
 	}
 
+	// Cloud configs in the default format are read by k8s-kvm without further
+	// configuration. The environment variable is only set for other formats,
+	// so that existing VMs are not rolled.
+	if r.cloudConfigFormat != cloudconfig.OutputFormatBase64 {
+		for _, d := range deployments {
+			for i, c := range d.Spec.Template.Spec.Containers {
+				if c.Name == key.ContainerNameKVM {
+					d.Spec.Template.Spec.Containers[i].Env = append(c.Env, corev1.EnvVar{
+						Name:  "CLOUD_CONFIG_FORMAT",
+						Value: r.cloudConfigFormat,
+					})
+				}
+			}
+		}
+	}
+
+	// The secret part of the cloud configs is only moved into Secrets in case
+	// k8s-kvm reads it from CLOUD_CONFIG_SECRET_PATH. Otherwise the secrets stay
+	// embedded in the ConfigMaps and the Secrets are not mounted, so that
+	// existing VMs are not rolled.
+	if r.cloudConfigSecrets {
+		for _, d := range deployments {
+			mountCloudConfigSecret(customResource, d)
+		}
+	}
+
+	// The VMs only read their cloud configs when they boot. Changes of the
+	// cloud config extensions are therefore annotated to the pod templates, so
+	// that they show up as drift and the VMs get rolled.
+	{
+		extension, err := cloudconfig.LoadExtension(r.k8sClient, customResource)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, d := range deployments {
+			hash := extension.Worker.Hash()
+			if isMasterDeployment(d) {
+				hash = extension.Master.Hash()
+			}
+
+			if hash != "" {
+				if d.Spec.Template.Annotations == nil {
+					d.Spec.Template.Annotations = map[string]string{}
+				}
+				d.Spec.Template.Annotations[key.AnnotationCloudConfigExtensionHash] = hash
+			}
+		}
+	}
+
 	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("computed the %d new deployments", len(deployments)))
 
 	return deployments, nil
 }
+
+// mountCloudConfigSecret adds the Secret holding the secret part of the cloud
+// config of the VM managed by the given deployment to its pod template.
+func mountCloudConfigSecret(customResource v1alpha1.KVMConfig, d *v1beta1.Deployment) {
+	prefix := key.WorkerID
+	if isMasterDeployment(d) {
+		prefix = key.MasterID
+	}
+	node := v1alpha1.ClusterNode{
+		ID: d.GetLabels()["node"],
+	}
+
+	spec := &d.Spec.Template.Spec
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: key.CloudConfigSecretVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: key.CloudConfigSecretName(customResource, node, prefix),
+			},
+		},
+	})
+
+	for i, c := range spec.Containers {
+		if c.Name != key.ContainerNameKVM {
+			continue
+		}
+
+		spec.Containers[i].Env = append(c.Env, corev1.EnvVar{
+			Name:  "CLOUD_CONFIG_SECRET_PATH",
+			Value: "/cloudconfig-secret/user_data",
+		})
+		spec.Containers[i].VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      key.CloudConfigSecretVolumeName,
+			MountPath: "/cloudconfig-secret/",
+			ReadOnly:  true,
+		})
+	}
+}
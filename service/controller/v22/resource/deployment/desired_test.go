@@ -2,6 +2,7 @@ package deployment
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -11,6 +12,9 @@ import (
 	"k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
 )
 
 func Test_Resource_Deployment_GetDesiredState(t *testing.T) {
@@ -264,6 +268,7 @@ func Test_Resource_Deployment_GetDesiredState(t *testing.T) {
 	{
 		resourceConfig := DefaultConfig()
 		resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+		resourceConfig.G8sClient = statuspatchtest.NewClientset()
 		resourceConfig.K8sClient = fake.NewSimpleClientset()
 		resourceConfig.Logger = microloggertest.New()
 		newResource, err = New(resourceConfig)
@@ -319,6 +324,114 @@ func Test_Resource_Deployment_GetDesiredState(t *testing.T) {
 	}
 }
 
+func Test_Resource_Deployment_GetDesiredState_CloudConfigSecrets(t *testing.T) {
+	testCases := []struct {
+		name                string
+		cloudConfigSecrets  bool
+		expectedSecretNames []string
+	}{
+		{
+			name:                "case 0: secrets are not mounted while they are embedded",
+			cloudConfigSecrets:  false,
+			expectedSecretNames: nil,
+		},
+		{
+			name:               "case 1: secrets are mounted when k8s-kvm reads them",
+			cloudConfigSecrets: true,
+			expectedSecretNames: []string{
+				"master-al9qy-m1-secret",
+				"worker-al9qy-w1-secret",
+			},
+		},
+	}
+
+	cr := &v1alpha1.KVMConfig{
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+				Masters: []v1alpha1.ClusterNode{
+					{ID: "m1"},
+				},
+				Workers: []v1alpha1.ClusterNode{
+					{ID: "w1"},
+				},
+			},
+			KVM: v1alpha1.KVMConfigSpecKVM{
+				K8sKVM: v1alpha1.KVMConfigSpecKVMK8sKVM{
+					StorageType: "hostPath",
+				},
+				Masters: []v1alpha1.KVMConfigSpecKVMNode{
+					{CPUs: 1, Memory: "1G"},
+				},
+				Workers: []v1alpha1.KVMConfigSpecKVMNode{
+					{CPUs: 4, Memory: "8G"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			var newResource *Resource
+			{
+				resourceConfig := DefaultConfig()
+				resourceConfig.CloudConfigSecrets = tc.cloudConfigSecrets
+				resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+				resourceConfig.G8sClient = statuspatchtest.NewClientset()
+				resourceConfig.K8sClient = fake.NewSimpleClientset()
+				resourceConfig.Logger = microloggertest.New()
+				newResource, err = New(resourceConfig)
+				if err != nil {
+					t.Fatal("expected", nil, "got", err)
+				}
+			}
+
+			result, err := newResource.GetDesiredState(context.TODO(), cr)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+			deployments, err := toDeployments(result)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			var secretNames []string
+			for _, d := range deployments {
+				var mounted, pathSet bool
+				for _, c := range d.Spec.Template.Spec.Containers {
+					if c.Name != key.ContainerNameKVM {
+						continue
+					}
+					for _, m := range c.VolumeMounts {
+						if m.Name == key.CloudConfigSecretVolumeName {
+							mounted = true
+						}
+					}
+					for _, e := range c.Env {
+						if e.Name == "CLOUD_CONFIG_SECRET_PATH" {
+							pathSet = true
+						}
+					}
+				}
+				if mounted != tc.cloudConfigSecrets || pathSet != tc.cloudConfigSecrets {
+					t.Fatalf("expected secret mount and path of deployment '%s' to be %t got %t and %t", d.GetName(), tc.cloudConfigSecrets, mounted, pathSet)
+				}
+
+				for _, v := range d.Spec.Template.Spec.Volumes {
+					if v.Name == key.CloudConfigSecretVolumeName {
+						secretNames = append(secretNames, v.Secret.SecretName)
+					}
+				}
+			}
+
+			if !reflect.DeepEqual(secretNames, tc.expectedSecretNames) {
+				t.Fatalf("expected %#v got %#v", tc.expectedSecretNames, secretNames)
+			}
+		})
+	}
+}
+
 func testGetMasterCount(deployments []*v1beta1.Deployment) int {
 	return testGetCountPrefix(deployments, "master-")
 }
@@ -11,6 +11,15 @@ func IsExecutionFailed(err error) bool {
 	return microerror.Cause(err) == executionFailedError
 }
 
+var invalidAnnotationError = &microerror.Error{
+	Kind: "invalidAnnotationError",
+}
+
+// IsInvalidAnnotation asserts invalidAnnotationError.
+func IsInvalidAnnotation(err error) bool {
+	return microerror.Cause(err) == invalidAnnotationError
+}
+
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
 }
@@ -0,0 +1,135 @@
+package deployment
+
+import (
+	"path"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+const (
+	// etcdRestoreVolumeName is the name of the volume exposing the directory
+	// of a filesystem backup target to the etcd restore init container.
+	etcdRestoreVolumeName = "etcd-backup"
+	// etcdRestoreSecretVolumeName is the name of the volume exposing the etcd
+	// restore secret to the etcd restore init container.
+	etcdRestoreSecretVolumeName = "etcd-restore"
+	// etcdRestoreSecretMountPath is the path the etcd restore secret is mounted
+	// at within the etcd restore init container.
+	etcdRestoreSecretMountPath = "/etc/etcd-restore"
+)
+
+// etcdRestoreScript restores the etcd snapshot located at the URL stored in
+// SNAPSHOT_URL_FILE into the etcd data directory of the master VM. The URL is
+// read when the container starts, so that restarted containers pick up URLs
+// refreshed by the operator. Nothing is done in case the data directory
+// already holds etcd data, so that running clusters are never overwritten. The
+// member configuration matches the single member etcd cluster rendered by
+// k8scloudconfig.
+const etcdRestoreScript = `set -e
+DATA_DIR=/var/lib/etcd
+if [ -d "${DATA_DIR}/member" ]; then
+  echo "etcd data directory is not empty, skipping restore"
+  exit 0
+fi
+SNAPSHOT_URL="$(cat "${SNAPSHOT_URL_FILE}")"
+case "${SNAPSHOT_URL}" in
+  file://*) cp "${SNAPSHOT_URL#file://}" /tmp/snapshot.db ;;
+  *) wget -O /tmp/snapshot.db "${SNAPSHOT_URL}" ;;
+esac
+ETCDCTL_API=3 etcdctl snapshot restore /tmp/snapshot.db \
+  --data-dir /tmp/etcd \
+  --name etcd0 \
+  --initial-advertise-peer-urls https://127.0.0.1:2380 \
+  --initial-cluster etcd0=https://127.0.0.1:2380 \
+  --initial-cluster-token k8s-etcd-cluster
+mv /tmp/etcd/member "${DATA_DIR}/member"
+`
+
+// newEtcdRestoreInitContainers returns the init containers and additional
+// volumes restoring etcd data of master VMs from the snapshot requested via
+// the etcd restore annotation of the given custom resource. Nothing is
+// returned when no restore is pending. The snapshot URL is not part of the
+// pod spec. It is maintained in the etcd restore secret by the etcd backup
+// resource, because presigned URLs expire.
+func newEtcdRestoreInitContainers(customResource v1alpha1.KVMConfig, target etcdbackup.Target, images Images) ([]apiv1.Container, []apiv1.Volume, error) {
+	if !key.EtcdRestorePending(customResource) {
+		return nil, nil, nil
+	}
+	if target == nil {
+		return nil, nil, microerror.Maskf(invalidConfigError, "etcd restore of snapshot '%s' requested but no etcd backup target configured", key.EtcdRestoreSnapshot(customResource))
+	}
+
+	container := apiv1.Container{
+		Name:            key.ContainerNameEtcdRestore,
+		Image:           images.Etcd,
+		ImagePullPolicy: apiv1.PullIfNotPresent,
+		Command: []string{
+			"/bin/sh",
+			"-c",
+			etcdRestoreScript,
+		},
+		Env: []apiv1.EnvVar{
+			{
+				Name:  "SNAPSHOT_URL_FILE",
+				Value: path.Join(etcdRestoreSecretMountPath, key.EtcdRestoreSecretURLKey),
+			},
+		},
+		VolumeMounts: []apiv1.VolumeMount{
+			{
+				Name:      key.EtcdVolumeName,
+				MountPath: "/var/lib/etcd",
+			},
+			{
+				Name:      etcdRestoreSecretVolumeName,
+				MountPath: etcdRestoreSecretMountPath,
+				ReadOnly:  true,
+			},
+		},
+	}
+
+	volumes := []apiv1.Volume{
+		{
+			Name: etcdRestoreSecretVolumeName,
+			VolumeSource: apiv1.VolumeSource{
+				Secret: &apiv1.SecretVolumeSource{
+					SecretName: key.EtcdRestoreSecretName,
+				},
+			},
+		},
+	}
+
+	// Snapshots of filesystem targets are read from the host, which requires
+	// the backup directory to be available on all hosts, e.g. via NFS.
+	filesystem, ok := target.(*etcdbackup.Filesystem)
+	if ok {
+		container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+			Name:      etcdRestoreVolumeName,
+			MountPath: filesystem.Dir(),
+			ReadOnly:  true,
+		})
+
+		volumes = append(volumes, apiv1.Volume{
+			Name: etcdRestoreVolumeName,
+			VolumeSource: apiv1.VolumeSource{
+				HostPath: &apiv1.HostPathVolumeSource{
+					Path: filesystem.Dir(),
+				},
+			},
+		})
+	}
+
+	return []apiv1.Container{container}, volumes, nil
+}
+
+// isEtcdRestoreVolume returns whether the volume with the given name is only
+// used by the etcd restore init container. These volumes are dropped once the
+// restore completed, which must not cause the master deployments to be
+// rolled.
+func isEtcdRestoreVolume(name string) bool {
+	return name == etcdRestoreVolumeName || name == etcdRestoreSecretVolumeName
+}
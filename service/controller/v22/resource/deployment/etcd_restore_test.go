@@ -0,0 +1,134 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+func Test_newEtcdRestoreInitContainers(t *testing.T) {
+	var filesystem etcdbackup.Target
+	{
+		c := etcdbackup.FilesystemConfig{
+			Dir: "/var/lib/etcd-backups",
+		}
+
+		var err error
+		filesystem, err = etcdbackup.NewFilesystem(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newCustomResource := func(snapshot string) v1alpha1.KVMConfig {
+		cr := v1alpha1.KVMConfig{}
+		if snapshot != "" {
+			cr.ObjectMeta = apismetav1.ObjectMeta{
+				Annotations: map[string]string{
+					key.AnnotationEtcdRestoreSnapshot: snapshot,
+				},
+			}
+		}
+		return cr
+	}
+
+	withStatus := func(cr v1alpha1.KVMConfig, status v1alpha1.StatusCluster) v1alpha1.KVMConfig {
+		cr.Status.Cluster = status
+		return cr
+	}
+
+	created := v1alpha1.StatusCluster{
+		Conditions: []v1alpha1.StatusClusterCondition{
+			{
+				Status: v1alpha1.StatusClusterStatusTrue,
+				Type:   v1alpha1.StatusClusterTypeCreated,
+			},
+		},
+	}
+
+	restored := v1alpha1.StatusCluster{
+		Resources: []v1alpha1.StatusClusterResource{
+			{
+				Conditions: []v1alpha1.StatusClusterResourceCondition{
+					{
+						Status: v1alpha1.StatusClusterStatusTrue,
+						Type:   key.ConditionEtcdRestored,
+					},
+				},
+				Name: "etcdbackupv22",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		customResource     v1alpha1.KVMConfig
+		target             etcdbackup.Target
+		expectedContainers int
+		expectedVolumes    int
+		errorMatcher       func(error) bool
+	}{
+		{
+			name:               "case 0: no restore requested",
+			customResource:     newCustomResource(""),
+			target:             filesystem,
+			expectedContainers: 0,
+			expectedVolumes:    0,
+		},
+		{
+			name:           "case 1: restore requested without backup target",
+			customResource: newCustomResource("al9qy/20190501-120000.db"),
+			target:         nil,
+			errorMatcher:   IsInvalidConfig,
+		},
+		{
+			name:               "case 2: restore requested from filesystem target",
+			customResource:     newCustomResource("al9qy/20190501-120000.db"),
+			target:             filesystem,
+			expectedContainers: 1,
+			expectedVolumes:    2,
+		},
+		{
+			name:               "case 3: completed restore is not done again",
+			customResource:     withStatus(newCustomResource("al9qy/20190501-120000.db"), restored),
+			target:             filesystem,
+			expectedContainers: 0,
+			expectedVolumes:    0,
+		},
+		{
+			name:               "case 4: restore is not done for created clusters",
+			customResource:     withStatus(newCustomResource("al9qy/20190501-120000.db"), created),
+			target:             filesystem,
+			expectedContainers: 0,
+			expectedVolumes:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			containers, volumes, err := newEtcdRestoreInitContainers(tc.customResource, tc.target, DefaultConfig().Images)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if len(containers) != tc.expectedContainers {
+				t.Fatalf("expected %d init containers got %d", tc.expectedContainers, len(containers))
+			}
+			if len(volumes) != tc.expectedVolumes {
+				t.Fatalf("expected %d volumes got %d", tc.expectedVolumes, len(volumes))
+			}
+		})
+	}
+}
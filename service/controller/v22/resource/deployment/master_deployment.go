@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/etcdstorage"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
 	"github.com/giantswarm/microerror"
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
@@ -13,13 +15,23 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string) ([]*extensionsv1.Deployment, error) {
+func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string, etcdBackupTarget etcdbackup.Target, images Images) ([]*extensionsv1.Deployment, error) {
 	var deployments []*extensionsv1.Deployment
 
 	privileged := true
 	replicas := int32(1)
 	podDeletionGracePeriod := int64(key.PodDeletionGracePeriod.Seconds())
 
+	etcdStorage, err := etcdstorage.New(customResource)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	initContainers, restoreVolumes, err := newEtcdRestoreInitContainers(customResource, etcdBackupTarget, images)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	for i, masterNode := range customResource.Spec.Cluster.Masters {
 		capabilities := customResource.Spec.KVM.Masters[i]
 
@@ -33,37 +45,11 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 			return nil, microerror.Maskf(err, "creating memory quantity")
 		}
 
-		storageType := key.StorageType(customResource)
-
-		// During migration, some TPOs do not have storage type set.
-		// This specifies a default, until all TPOs have the correct storage type set.
-		// tl;dr - this shouldn't be here. If all TPOs have storageType, remove it.
-		if storageType == "" {
-			storageType = "hostPath"
+		etcdVolume, err := etcdStorage.Volume(customResource, key.VMNumber(i))
+		if err != nil {
+			return nil, microerror.Mask(err)
 		}
 
-		var etcdVolume apiv1.Volume
-		if storageType == "hostPath" {
-			etcdVolume = apiv1.Volume{
-				Name: "etcd-data",
-				VolumeSource: apiv1.VolumeSource{
-					HostPath: &apiv1.HostPathVolumeSource{
-						Path: key.MasterHostPathVolumeDir(key.ClusterID(customResource), key.VMNumber(i)),
-					},
-				},
-			}
-		} else if storageType == "persistentVolume" {
-			etcdVolume = apiv1.Volume{
-				Name: "etcd-data",
-				VolumeSource: apiv1.VolumeSource{
-					PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
-						ClaimName: key.EtcdPVCName(key.ClusterID(customResource), key.VMNumber(i)),
-					},
-				},
-			}
-		} else {
-			return nil, microerror.Maskf(wrongTypeError, "unknown storageType: '%s'", key.StorageType(customResource))
-		}
 		deployment := &extensionsv1.Deployment{
 			TypeMeta: apismetav1.TypeMeta{
 				Kind:       "deployment",
@@ -102,7 +88,6 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 							key.AnnotationAPIEndpoint:   key.ClusterAPIEndpoint(customResource),
 							key.AnnotationIp:            "",
 							key.AnnotationService:       key.MasterID,
-							key.AnnotationPodDrained:    "False",
 							key.AnnotationVersionBundle: key.VersionBundleVersion(customResource),
 						},
 						GenerateName: key.MasterID,
@@ -124,7 +109,7 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 						},
 						ServiceAccountName:            key.ServiceAccountName(customResource),
 						TerminationGracePeriodSeconds: &podDeletionGracePeriod,
-						Volumes: []apiv1.Volume{
+						Volumes: append([]apiv1.Volume{
 							{
 								Name: "cloud-config",
 								VolumeSource: apiv1.VolumeSource{
@@ -158,11 +143,12 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 									},
 								},
 							},
-						},
+						}, restoreVolumes...),
+						InitContainers: initContainers,
 						Containers: []apiv1.Container{
 							{
 								Name:            "k8s-endpoint-updater",
-								Image:           key.K8SEndpointUpdaterDocker,
+								Image:           images.K8sEndpointUpdater,
 								ImagePullPolicy: apiv1.PullIfNotPresent,
 								Command: []string{
 									"/opt/k8s-endpoint-updater",
@@ -188,8 +174,8 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 								},
 							},
 							{
-								Name:            "k8s-kvm",
-								Image:           key.K8SKVMDockerImage,
+								Name:            key.ContainerNameKVM,
+								Image:           images.K8sKVM,
 								ImagePullPolicy: apiv1.PullIfNotPresent,
 								SecurityContext: &apiv1.SecurityContext{
 									Privileged: &privileged,
@@ -304,7 +290,7 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 										MountPath: "/cloudconfig/",
 									},
 									{
-										Name:      "etcd-data",
+										Name:      key.EtcdVolumeName,
 										MountPath: "/etc/kubernetes/data/etcd/",
 									},
 									{
@@ -318,8 +304,8 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 								},
 							},
 							{
-								Name:            "k8s-kvm-health",
-								Image:           key.K8SKVMHealthDocker,
+								Name:            key.ContainerNameKVMHealth,
+								Image:           images.K8sKVMHealth,
 								ImagePullPolicy: apiv1.PullAlways,
 								Env: []apiv1.EnvVar{
 									{
@@ -347,7 +333,7 @@ func newMasterDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 							},
 							{
 								Name:            "shutdown-deferrer",
-								Image:           key.ShutdownDeferrerDocker,
+								Image:           images.ShutdownDeferrer,
 								ImagePullPolicy: apiv1.PullAlways,
 								Args: []string{
 									"daemon",
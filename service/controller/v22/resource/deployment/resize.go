@@ -0,0 +1,320 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	// ResizeStatusName is the name of the resource status within the KVMConfig
+	// status holding the resize state of the VMs of a tenant cluster. The
+	// states are recorded as condition types in the format state/nodeID,
+	// because the KVMConfig status has no per node fields for them.
+	ResizeStatusName = Name + "-resize"
+)
+
+// The states below describe the lifecycle of resizing the VM of a tenant
+// cluster node after its CPUs or memory changed in the KVMConfig. VMs are not
+// resized in place. Resizing a VM drains its node and recreates the VM with
+// the new resources.
+const (
+	// ResizeStatePending is the state of VMs waiting to be resized, e.g.
+	// because other deployments are still rolling.
+	ResizeStatePending = "Pending"
+	// ResizeStateInProgress is the state of VMs whose deployment got resized
+	// but which are not up again yet. Their nodes are drained before the VMs
+	// are recreated with the new resources.
+	ResizeStateInProgress = "InProgress"
+	// ResizeStateApplied is the state of VMs running with the resources of the
+	// latest resize.
+	ResizeStateApplied = "Applied"
+	// ResizeStateRejected is the state of VMs whose resize does not fit the
+	// allocatable capacity of any host node.
+	ResizeStateRejected = "Rejected"
+)
+
+// ResizePolicy defines how VMs of a tenant cluster are resized.
+type ResizePolicy struct {
+	// CapacityCheck defines whether resizes are rejected in case the resized
+	// VM does not fit the allocatable capacity of any schedulable host node.
+	CapacityCheck bool
+}
+
+type resizeStatus struct {
+	NodeID string
+	State  string
+}
+
+func (s resizeStatus) String() string {
+	return s.State + "/" + s.NodeID
+}
+
+// isResizeChange returns whether the given change path, as returned by
+// deploymentChanges, is caused by changed CPUs or memory of the VM.
+func isResizeChange(change string) bool {
+	p := fmt.Sprintf("spec.template.spec.containers[%s]", key.ContainerNameKVM)
+
+	switch change {
+	case p + ".env[CORES]", p + ".env[MEMORY]", p + ".resources.limits", p + ".resources.requests":
+		return true
+	}
+
+	return false
+}
+
+func hasResizeChanges(changes []string) bool {
+	for _, c := range changes {
+		if isResizeChange(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isResizeOnly returns whether all of the given changes are caused by changed
+// CPUs or memory of the VM.
+func isResizeOnly(changes []string) bool {
+	if len(changes) == 0 {
+		return false
+	}
+
+	for _, c := range changes {
+		if !isResizeChange(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rejectedResizes returns the names of the desired deployments whose resize
+// does not fit the allocatable capacity of any schedulable host node. Nothing
+// is rejected in case the capacity check of the resize policy is disabled.
+func (r *Resource) rejectedResizes(ctx context.Context, currentDeployments, desiredDeployments []*v1beta1.Deployment) (map[string]bool, error) {
+	rejected := map[string]bool{}
+
+	if !r.resizePolicy.CapacityCheck {
+		return rejected, nil
+	}
+
+	var resized []*v1beta1.Deployment
+	for _, d := range desiredDeployments {
+		c, err := getDeploymentByName(currentDeployments, d.GetName())
+		if IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if hasResizeChanges(deploymentChanges(d, c)) {
+			resized = append(resized, d)
+		}
+	}
+
+	if len(resized) == 0 {
+		return rejected, nil
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "looking for the allocatable capacity of the host nodes")
+
+	list, err := r.k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d host nodes", len(list.Items)))
+
+	for _, d := range resized {
+		if !fitsAnyNode(list.Items, d.Spec.Template.Spec) {
+			r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("rejecting resize of deployment '%s': resources do not fit the allocatable capacity of any host node", d.GetName()))
+			rejected[d.GetName()] = true
+		}
+	}
+
+	return rejected, nil
+}
+
+// fitsAnyNode returns whether the resource requests of the given pod spec fit
+// the allocatable capacity of at least one of the given schedulable nodes.
+func fitsAnyNode(nodes []corev1.Node, spec corev1.PodSpec) bool {
+	var cpu, memory resource.Quantity
+	for _, c := range spec.Containers {
+		cpu.Add(c.Resources.Requests[corev1.ResourceCPU])
+		memory.Add(c.Resources.Requests[corev1.ResourceMemory])
+	}
+
+	for _, n := range nodes {
+		if n.Spec.Unschedulable {
+			continue
+		}
+
+		allocatableCPU := n.Status.Allocatable[corev1.ResourceCPU]
+		allocatableMemory := n.Status.Allocatable[corev1.ResourceMemory]
+		if allocatableCPU.Cmp(cpu) >= 0 && allocatableMemory.Cmp(memory) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newResizeStatuses returns the resize state of every tenant cluster node
+// based on the given previous states, the current and desired deployments,
+// the deployments being updated in this reconciliation loop and the rejected
+// resizes.
+func newResizeStatuses(previous []resizeStatus, currentDeployments, desiredDeployments, updating []*v1beta1.Deployment, rejected map[string]bool) []resizeStatus {
+	var statuses []resizeStatus
+
+	for _, d := range desiredDeployments {
+		c, err := getDeploymentByName(currentDeployments, d.GetName())
+		if err != nil {
+			// New nodes are created with the desired resources right away.
+			continue
+		}
+
+		nodeID := d.GetLabels()["node"]
+		previousState := resizeStateOf(previous, nodeID)
+
+		var state string
+		switch {
+		case hasResizeChanges(deploymentChanges(d, c)) && rejected[d.GetName()]:
+			state = ResizeStateRejected
+		case hasResizeChanges(deploymentChanges(d, c)) && containsDeployment(updating, d):
+			state = ResizeStateInProgress
+		case hasResizeChanges(deploymentChanges(d, c)):
+			state = ResizeStatePending
+		case previousState == ResizeStatePending || previousState == ResizeStateInProgress:
+			if isDeploymentRolledOut(c) {
+				state = ResizeStateApplied
+			} else {
+				state = ResizeStateInProgress
+			}
+		case previousState == ResizeStateApplied:
+			state = ResizeStateApplied
+		}
+
+		if state != "" {
+			statuses = append(statuses, resizeStatus{NodeID: nodeID, State: state})
+		}
+	}
+
+	return statuses
+}
+
+// isDeploymentRolledOut returns whether the latest spec of the given
+// deployment got observed and all of its replicas are up.
+func isDeploymentRolledOut(d *v1beta1.Deployment) bool {
+	if d.Status.ObservedGeneration < d.GetGeneration() {
+		return false
+	}
+
+	return allNumbersEqual(d.Status.AvailableReplicas, d.Status.ReadyReplicas, d.Status.Replicas, d.Status.UpdatedReplicas)
+}
+
+func resizeStateOf(statuses []resizeStatus, nodeID string) string {
+	for _, s := range statuses {
+		if s.NodeID == nodeID {
+			return s.State
+		}
+	}
+
+	return ""
+}
+
+// resizeStatusesFromStatus returns the resize states recorded in the given
+// cluster status. Conditions which cannot be parsed are ignored.
+func resizeStatusesFromStatus(status v1alpha1.StatusCluster) []resizeStatus {
+	var statuses []resizeStatus
+
+	for _, r := range status.Resources {
+		if r.Name != ResizeStatusName {
+			continue
+		}
+
+		for _, c := range r.Conditions {
+			split := strings.SplitN(c.Type, "/", 2)
+			if len(split) != 2 || split[0] == "" || split[1] == "" {
+				continue
+			}
+
+			statuses = append(statuses, resizeStatus{NodeID: split[1], State: split[0]})
+		}
+	}
+
+	return statuses
+}
+
+// withResizeStatuses returns the given cluster status reflecting the given
+// resize states. Transition times of unchanged states are kept. The resource
+// status is removed in case there are no resize states.
+func withResizeStatuses(status v1alpha1.StatusCluster, statuses []resizeStatus, t time.Time) v1alpha1.StatusCluster {
+	transitionTimes := map[string]v1alpha1.DeepCopyTime{}
+
+	var resources []v1alpha1.StatusClusterResource
+	for _, r := range status.Resources {
+		if r.Name == ResizeStatusName {
+			for _, c := range r.Conditions {
+				transitionTimes[c.Type] = c.LastTransitionTime
+			}
+			continue
+		}
+		resources = append(resources, r)
+	}
+
+	if len(statuses) != 0 {
+		resizeResource := v1alpha1.StatusClusterResource{
+			Name: ResizeStatusName,
+		}
+		for _, s := range statuses {
+			transitionTime, ok := transitionTimes[s.String()]
+			if !ok {
+				transitionTime = v1alpha1.DeepCopyTime{Time: t}
+			}
+
+			resizeResource.Conditions = append(resizeResource.Conditions, v1alpha1.StatusClusterResourceCondition{
+				LastTransitionTime: transitionTime,
+				Status:             v1alpha1.StatusClusterStatusTrue,
+				Type:               s.String(),
+			})
+		}
+		resources = append(resources, resizeResource)
+	}
+
+	status.Resources = resources
+
+	return status
+}
+
+// updateResizeStatus records the given resize states of the tenant cluster
+// nodes in the KVMConfig status in case they changed.
+func (r *Resource) updateResizeStatus(ctx context.Context, cr v1alpha1.KVMConfig, statuses []resizeStatus) error {
+	if reflect.DeepEqual(resizeStatusesFromStatus(cr.Status.Cluster), statuses) {
+		return nil
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "updating status with resize states")
+
+	_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+		status.Cluster = withResizeStatuses(status.Cluster, statuses, time.Now())
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "updated status with resize states")
+
+	return nil
+}
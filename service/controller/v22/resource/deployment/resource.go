@@ -1,12 +1,22 @@
 package deployment
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+	"github.com/giantswarm/kvm-operator/service/rollout"
 )
 
 const (
@@ -18,8 +28,57 @@ const (
 type Config struct {
 	// Dependencies.
 	DNSServers string
-	K8sClient  kubernetes.Interface
-	Logger     micrologger.Logger
+	// EtcdBackupTarget is optional. It is only required to restore etcd data of
+	// master VMs from snapshots.
+	EtcdBackupTarget etcdbackup.Target
+	G8sClient        versioned.Interface
+	K8sClient        kubernetes.Interface
+	Logger           micrologger.Logger
+	// UpdateLimiter is optional. It limits the number of tenant clusters
+	// being updated at the same time.
+	UpdateLimiter *rollout.Limiter
+
+	// Settings.
+	// CloudConfigFormat is the output format of the cloud configs rendered by
+	// the configmap resource, which k8s-kvm has to know about to read them.
+	CloudConfigFormat string
+	// CloudConfigSecrets defines whether the secret part of the cloud configs
+	// is read from the Secrets of the secret resource. It must only be set in
+	// case the k8s-kvm image reads CLOUD_CONFIG_SECRET_PATH.
+	CloudConfigSecrets bool
+	Images             Images
+	ResizePolicy       ResizePolicy
+	UpdatePolicy       UpdatePolicy
+}
+
+// Images defines the container images of master and worker pods.
+type Images struct {
+	Etcd               string
+	K8sEndpointUpdater string
+	K8sKVM             string
+	K8sKVMHealth       string
+	ShutdownDeferrer   string
+}
+
+// UpdatePolicy defines how deployments of a tenant cluster are rolled when
+// they have to be updated.
+type UpdatePolicy struct {
+	// BatchSize is the maximum number of deployments being updated within a
+	// single reconciliation loop.
+	BatchSize int
+	// BatchWait is the minimum duration to wait after a batch of deployments
+	// got updated before the next batch is processed.
+	BatchWait time.Duration
+	// MastersFirst defines whether master deployments are updated before worker
+	// deployments. When true workers are only updated once all masters are up
+	// to date and ready. When false masters are updated after all workers.
+	MastersFirst bool
+	// MaxUnavailableMasters is the maximum number of master deployments being
+	// updated within a single batch.
+	MaxUnavailableMasters int
+	// MaxUnavailableWorkers is the maximum number of worker deployments being
+	// updated within a single batch.
+	MaxUnavailableWorkers int
 }
 
 // DefaultConfig provides a default configuration to create a new deployment
@@ -27,18 +86,53 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
-		DNSServers: "",
-		K8sClient:  nil,
-		Logger:     nil,
+		DNSServers:       "",
+		EtcdBackupTarget: nil,
+		G8sClient:        nil,
+		K8sClient:        nil,
+		Logger:           nil,
+		UpdateLimiter:    nil,
+
+		// Settings.
+		CloudConfigFormat:  cloudconfig.OutputFormatBase64,
+		CloudConfigSecrets: false,
+		Images: Images{
+			Etcd:               key.EtcdDockerImage,
+			K8sEndpointUpdater: key.K8SEndpointUpdaterDocker,
+			K8sKVM:             key.K8SKVMDockerImage,
+			K8sKVMHealth:       key.K8SKVMHealthDocker,
+			ShutdownDeferrer:   key.ShutdownDeferrerDocker,
+		},
+		ResizePolicy: ResizePolicy{
+			CapacityCheck: false,
+		},
+		UpdatePolicy: UpdatePolicy{
+			BatchSize:             1,
+			BatchWait:             0,
+			MastersFirst:          true,
+			MaxUnavailableMasters: 1,
+			MaxUnavailableWorkers: 1,
+		},
 	}
 }
 
 // Resource implements the deployment resource.
 type Resource struct {
 	// Dependencies.
-	dnsServers string
-	k8sClient  kubernetes.Interface
-	logger     micrologger.Logger
+	dnsServers       string
+	etcdBackupTarget etcdbackup.Target
+	g8sClient        versioned.Interface
+	k8sClient        kubernetes.Interface
+	logger           micrologger.Logger
+	statusPatcher    *statuspatch.Patcher
+	updateLimiter    *rollout.Limiter
+
+	// Settings.
+	cloudConfigFormat  string
+	cloudConfigSecrets bool
+	images             Images
+	resizePolicy       ResizePolicy
+	updatePolicy       UpdatePolicy
 }
 
 // New creates a new configured deployment resource.
@@ -47,6 +141,9 @@ func New(config Config) (*Resource, error) {
 	if config.DNSServers == "" {
 		return nil, microerror.Maskf(invalidConfigError, "config.DNSServers must not be empty")
 	}
+	if config.G8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.G8sClient must not be empty")
+	}
 	if config.K8sClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
 	}
@@ -54,11 +151,56 @@ func New(config Config) (*Resource, error) {
 		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
 	}
 
+	// Settings.
+	if config.CloudConfigFormat == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.CloudConfigFormat must not be empty")
+	}
+	if config.Images.Etcd == "" || config.Images.K8sEndpointUpdater == "" || config.Images.K8sKVM == "" || config.Images.K8sKVMHealth == "" || config.Images.ShutdownDeferrer == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Images must not be empty")
+	}
+	if config.UpdatePolicy.BatchSize < 1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.UpdatePolicy.BatchSize must be greater than 0")
+	}
+	if config.UpdatePolicy.BatchWait < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.UpdatePolicy.BatchWait must not be negative")
+	}
+	if config.UpdatePolicy.MaxUnavailableMasters < 1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.UpdatePolicy.MaxUnavailableMasters must be greater than 0")
+	}
+	if config.UpdatePolicy.MaxUnavailableWorkers < 1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.UpdatePolicy.MaxUnavailableWorkers must be greater than 0")
+	}
+
+	var statusPatcher *statuspatch.Patcher
+	{
+		c := statuspatch.Config{
+			G8sClient: config.G8sClient,
+			Logger:    config.Logger,
+		}
+
+		var err error
+		statusPatcher, err = statuspatch.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	newResource := &Resource{
 		// Dependencies.
-		dnsServers: config.DNSServers,
-		k8sClient:  config.K8sClient,
-		logger:     config.Logger,
+		dnsServers:       config.DNSServers,
+		etcdBackupTarget: config.EtcdBackupTarget,
+		g8sClient:        config.G8sClient,
+		k8sClient:        config.K8sClient,
+		logger:           config.Logger,
+		statusPatcher:    statusPatcher,
+		updateLimiter:    config.UpdateLimiter,
+
+		// Settings.
+		cloudConfigFormat:  config.CloudConfigFormat,
+		cloudConfigSecrets: config.CloudConfigSecrets,
+		images:             config.Images,
+		resizePolicy:       config.ResizePolicy,
+		updatePolicy:       config.UpdatePolicy,
 	}
 
 	return newResource, nil
@@ -104,28 +246,253 @@ func getDeploymentByName(list []*v1beta1.Deployment, name string) (*v1beta1.Depl
 	return nil, microerror.Mask(notFoundError)
 }
 
-func isDeploymentModified(a, b *v1beta1.Deployment) bool {
-	aVersion, ok := a.GetAnnotations()[key.VersionBundleVersionAnnotation]
-	if !ok {
-		return true
+// deploymentChanges returns the paths of all relevant fields which differ
+// between the desired deployment a and the current deployment b. Only fields
+// the operator renders itself are compared, so that defaults applied by the
+// Kubernetes API do not count as drift.
+func deploymentChanges(a, b *v1beta1.Deployment) []string {
+	var changes []string
+
+	aVersion := a.GetAnnotations()[key.VersionBundleVersionAnnotation]
+	bVersion := b.GetAnnotations()[key.VersionBundleVersionAnnotation]
+	if aVersion == "" || bVersion == "" || aVersion != bVersion {
+		changes = append(changes, fmt.Sprintf("metadata.annotations[%s]", key.VersionBundleVersionAnnotation))
 	}
-	if aVersion == "" {
-		return true
+
+	aHash := a.Spec.Template.GetAnnotations()[key.AnnotationCloudConfigExtensionHash]
+	bHash := b.Spec.Template.GetAnnotations()[key.AnnotationCloudConfigExtensionHash]
+	if aHash != bHash {
+		changes = append(changes, fmt.Sprintf("spec.template.metadata.annotations[%s]", key.AnnotationCloudConfigExtensionHash))
 	}
 
-	bVersion, ok := b.GetAnnotations()[key.VersionBundleVersionAnnotation]
-	if !ok {
-		return true
+	aHash = a.Spec.Template.GetAnnotations()[key.AnnotationKubeletConfigHash]
+	bHash = b.Spec.Template.GetAnnotations()[key.AnnotationKubeletConfigHash]
+	if aHash != bHash {
+		changes = append(changes, fmt.Sprintf("spec.template.metadata.annotations[%s]", key.AnnotationKubeletConfigHash))
+	}
+
+	changes = append(changes, podSpecChanges(a.Spec.Template.Spec, b.Spec.Template.Spec)...)
+
+	return changes
+}
+
+func podSpecChanges(a, b corev1.PodSpec) []string {
+	var changes []string
+
+	for _, ac := range a.Containers {
+		bc, ok := getContainerByName(b.Containers, ac.Name)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("spec.template.spec.containers[%s]", ac.Name))
+			continue
+		}
+
+		p := fmt.Sprintf("spec.template.spec.containers[%s]", ac.Name)
+
+		if ac.Image != bc.Image {
+			changes = append(changes, p+".image")
+		}
+		if !stringsEqual(ac.Args, bc.Args) {
+			changes = append(changes, p+".args")
+		}
+		if !stringsEqual(ac.Command, bc.Command) {
+			changes = append(changes, p+".command")
+		}
+		for _, name := range envChanges(ac.Env, bc.Env) {
+			changes = append(changes, fmt.Sprintf("%s.env[%s]", p, name))
+		}
+		if !resourceListsEqual(ac.Resources.Requests, bc.Resources.Requests) {
+			changes = append(changes, p+".resources.requests")
+		}
+		if !resourceListsEqual(ac.Resources.Limits, bc.Resources.Limits) {
+			changes = append(changes, p+".resources.limits")
+		}
 	}
-	if bVersion == "" {
-		return true
+
+	for _, bc := range b.Containers {
+		_, ok := getContainerByName(a.Containers, bc.Name)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("spec.template.spec.containers[%s]", bc.Name))
+		}
 	}
 
-	if aVersion != bVersion {
-		return true
+	for _, name := range volumeChanges(a.Volumes, b.Volumes) {
+		changes = append(changes, fmt.Sprintf("spec.template.spec.volumes[%s]", name))
 	}
 
-	return false
+	return changes
+}
+
+// envChanges returns the names of the environment variables which differ
+// between a and b. Variables referencing fields are compared by their field
+// path only, because the Kubernetes API defaults their API version.
+func envChanges(a, b []corev1.EnvVar) []string {
+	toMap := func(envs []corev1.EnvVar) map[string]string {
+		m := map[string]string{}
+		for _, e := range envs {
+			v := e.Value
+			if e.ValueFrom != nil && e.ValueFrom.FieldRef != nil {
+				v = "fieldRef:" + e.ValueFrom.FieldRef.FieldPath
+			}
+			m[e.Name] = v
+		}
+		return m
+	}
+
+	return mapChanges(toMap(a), toMap(b))
+}
+
+// volumeChanges returns the names of the volumes which differ between a and b.
+// Volumes are compared by the source they refer to. Volumes of the etcd
+// restore are ignored.
+func volumeChanges(a, b []corev1.Volume) []string {
+	toMap := func(volumes []corev1.Volume) map[string]string {
+		m := map[string]string{}
+		for _, v := range volumes {
+			if isEtcdRestoreVolume(v.Name) {
+				continue
+			}
+
+			var s string
+			switch {
+			case v.ConfigMap != nil:
+				s = "configMap:" + v.ConfigMap.Name
+			case v.EmptyDir != nil:
+				s = "emptyDir"
+			case v.HostPath != nil:
+				s = "hostPath:" + v.HostPath.Path
+			case v.PersistentVolumeClaim != nil:
+				s = "persistentVolumeClaim:" + v.PersistentVolumeClaim.ClaimName
+			case v.Secret != nil:
+				s = "secret:" + v.Secret.SecretName
+			}
+			m[v.Name] = s
+		}
+		return m
+	}
+
+	return mapChanges(toMap(a), toMap(b))
+}
+
+// mapChanges returns the sorted keys of all entries which are not equal in a
+// and b.
+func mapChanges(a, b map[string]string) []string {
+	var changes []string
+
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok || av != bv {
+			changes = append(changes, k)
+		}
+	}
+	for k := range b {
+		_, ok := a[k]
+		if !ok {
+			changes = append(changes, k)
+		}
+	}
+
+	sort.Strings(changes)
+
+	return changes
+}
+
+func getContainerByName(list []corev1.Container, name string) (corev1.Container, bool) {
+	for _, c := range list {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return corev1.Container{}, false
+}
+
+func resourceListsEqual(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for n, aq := range a {
+		bq, ok := b[n]
+		if !ok || aq.Cmp(bq) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isMasterDeployment(d *v1beta1.Deployment) bool {
+	return d.GetLabels()[key.LabelApp] == key.MasterID
+}
+
+// lastUpdateTime returns the most recent update timestamp annotated to the
+// given deployments. The zero time is returned when none of the deployments
+// was updated yet.
+func lastUpdateTime(deployments []*v1beta1.Deployment) (time.Time, error) {
+	var last time.Time
+
+	for _, d := range deployments {
+		v, ok := d.GetAnnotations()[key.AnnotationUpdatedAt]
+		if !ok {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, microerror.Maskf(invalidAnnotationError, "%s of deployment '%s' must be RFC3339 formatted: %s", key.AnnotationUpdatedAt, d.GetName(), err)
+		}
+
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	return last, nil
+}
+
+// sortDeploymentsForUpdate returns a copy of the given deployments ordered in
+// the way they are supposed to be updated. Masters are either put in front of
+// or behind all other deployments. The relative order is preserved otherwise.
+func sortDeploymentsForUpdate(deployments []*v1beta1.Deployment, mastersFirst bool) []*v1beta1.Deployment {
+	sorted := make([]*v1beta1.Deployment, len(deployments))
+	copy(sorted, deployments)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if mastersFirst {
+			return isMasterDeployment(sorted[i]) && !isMasterDeployment(sorted[j])
+		}
+
+		return !isMasterDeployment(sorted[i]) && isMasterDeployment(sorted[j])
+	})
+
+	return sorted
+}
+
+func toUpdateChange(v interface{}) (updateChange, error) {
+	if v == nil {
+		return updateChange{}, nil
+	}
+
+	change, ok := v.(updateChange)
+	if !ok {
+		return updateChange{}, microerror.Maskf(wrongTypeError, "expected '%T', got '%T'", updateChange{}, v)
+	}
+
+	return change, nil
 }
 
 func toDeployments(v interface{}) ([]*v1beta1.Deployment, error) {
@@ -0,0 +1,161 @@
+package deployment
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"k8s.io/api/extensions/v1beta1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_deploymentChanges(t *testing.T) {
+	newCustomResource := func(cpus int, memory string) v1alpha1.KVMConfig {
+		return v1alpha1.KVMConfig{
+			Spec: v1alpha1.KVMConfigSpec{
+				Cluster: v1alpha1.Cluster{
+					ID: "al9qy",
+					Workers: []v1alpha1.ClusterNode{
+						{ID: "w1"},
+					},
+				},
+				KVM: v1alpha1.KVMConfigSpecKVM{
+					Workers: []v1alpha1.KVMConfigSpecKVMNode{
+						{CPUs: cpus, Memory: memory},
+					},
+				},
+				VersionBundle: v1alpha1.KVMConfigSpecVersionBundle{
+					Version: "1.2.3",
+				},
+			},
+		}
+	}
+
+	newDeployment := func(customResource v1alpha1.KVMConfig, dnsServers string) *v1beta1.Deployment {
+		deployments, err := newWorkerDeployments(customResource, dnsServers, DefaultConfig().Images)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deployments[0]
+	}
+
+	testCases := []struct {
+		name            string
+		desired         *v1beta1.Deployment
+		current         *v1beta1.Deployment
+		expectedChanges []string
+	}{
+		{
+			name:            "case 0: equal deployments",
+			desired:         newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			current:         newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			expectedChanges: nil,
+		},
+		{
+			name:    "case 1: defaults applied by the Kubernetes API are not considered drift",
+			desired: newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			current: func() *v1beta1.Deployment {
+				d := newDeployment(newCustomResource(2, "2G"), "8.8.8.8")
+				for i, c := range d.Spec.Template.Spec.Containers {
+					d.Spec.Template.Spec.Containers[i].TerminationMessagePath = "/dev/termination-log"
+					for j, e := range c.Env {
+						if e.ValueFrom != nil && e.ValueFrom.FieldRef != nil {
+							d.Spec.Template.Spec.Containers[i].Env[j].ValueFrom.FieldRef.APIVersion = "v1"
+						}
+					}
+				}
+				return d
+			}(),
+			expectedChanges: nil,
+		},
+		{
+			name:    "case 2: version bundle change",
+			desired: newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			current: func() *v1beta1.Deployment {
+				cr := newCustomResource(2, "2G")
+				cr.Spec.VersionBundle.Version = "1.2.2"
+				return newDeployment(cr, "8.8.8.8")
+			}(),
+			expectedChanges: []string{
+				"metadata.annotations[giantswarm.io/version-bundle-version]",
+			},
+		},
+		{
+			name:    "case 3: CPU and memory change",
+			desired: newDeployment(newCustomResource(4, "4G"), "8.8.8.8"),
+			current: newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			expectedChanges: []string{
+				"spec.template.spec.containers[k8s-kvm].env[CORES]",
+				"spec.template.spec.containers[k8s-kvm].env[MEMORY]",
+				"spec.template.spec.containers[k8s-kvm].resources.requests",
+				"spec.template.spec.containers[k8s-kvm].resources.limits",
+			},
+		},
+		{
+			name:    "case 4: DNS servers and image change",
+			desired: newDeployment(newCustomResource(2, "2G"), "1.1.1.1"),
+			current: func() *v1beta1.Deployment {
+				d := newDeployment(newCustomResource(2, "2G"), "8.8.8.8")
+				d.Spec.Template.Spec.Containers[0].Image = "quay.io/giantswarm/k8s-endpoint-updater:old"
+				return d
+			}(),
+			expectedChanges: []string{
+				"spec.template.spec.containers[k8s-endpoint-updater].image",
+				"spec.template.spec.containers[k8s-kvm].env[DNS_SERVERS]",
+			},
+		},
+		{
+			name:    "case 5: volume change",
+			desired: newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			current: func() *v1beta1.Deployment {
+				d := newDeployment(newCustomResource(2, "2G"), "8.8.8.8")
+				d.Spec.Template.Spec.Volumes[0].ConfigMap.Name = "worker-al9qy-old"
+				return d
+			}(),
+			expectedChanges: []string{
+				"spec.template.spec.volumes[cloud-config]",
+			},
+		},
+		{
+			name: "case 6: cloud config extension change",
+			desired: func() *v1beta1.Deployment {
+				d := newDeployment(newCustomResource(2, "2G"), "8.8.8.8")
+				if d.Spec.Template.Annotations == nil {
+					d.Spec.Template.Annotations = map[string]string{}
+				}
+				d.Spec.Template.Annotations[key.AnnotationCloudConfigExtensionHash] = "abc"
+				return d
+			}(),
+			current: newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			expectedChanges: []string{
+				"spec.template.metadata.annotations[kvm-operator.giantswarm.io/cloud-config-extension-hash]",
+			},
+		},
+		{
+			name: "case 7: kubelet settings change",
+			desired: func() *v1beta1.Deployment {
+				d := newDeployment(newCustomResource(2, "2G"), "8.8.8.8")
+				if d.Spec.Template.Annotations == nil {
+					d.Spec.Template.Annotations = map[string]string{}
+				}
+				d.Spec.Template.Annotations[key.AnnotationKubeletConfigHash] = "abc"
+				return d
+			}(),
+			current: newDeployment(newCustomResource(2, "2G"), "8.8.8.8"),
+			expectedChanges: []string{
+				"spec.template.metadata.annotations[kvm-operator.giantswarm.io/kubelet-config-hash]",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := deploymentChanges(tc.desired, tc.current)
+
+			if !reflect.DeepEqual(changes, tc.expectedChanges) {
+				t.Fatalf("expected %#v got %#v", tc.expectedChanges, changes)
+			}
+		})
+	}
+}
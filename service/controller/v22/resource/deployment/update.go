@@ -3,7 +3,10 @@ package deployment
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/operatorkit/controller"
 	"github.com/giantswarm/operatorkit/controller/context/updateallowedcontext"
@@ -12,21 +15,30 @@ import (
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
+// updateChange is the update change of this resource. Next to the batch of
+// deployments to be updated it carries the resize states of the tenant
+// cluster nodes, which are computed from the same current and desired state
+// the batch got selected from.
+type updateChange struct {
+	Deployments    []*v1beta1.Deployment
+	ResizeStatuses []resizeStatus
+}
+
 func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange interface{}) error {
 	customResource, err := key.ToCustomObject(obj)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	deploymentsToUpdate, err := toDeployments(updateChange)
+	change, err := toUpdateChange(updateChange)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	if len(deploymentsToUpdate) != 0 {
+	if len(change.Deployments) != 0 {
 		r.logger.LogCtx(ctx, "level", "debug", "message", "updating the deployments in the Kubernetes API")
 
 		namespace := key.ClusterNamespace(customResource)
-		for _, deployment := range deploymentsToUpdate {
+		for _, deployment := range change.Deployments {
 			_, err := r.k8sClient.Extensions().Deployments(namespace).Update(deployment)
 			if err != nil {
 				return microerror.Mask(err)
@@ -38,6 +50,11 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		r.logger.LogCtx(ctx, "level", "debug", "message", "the deployments do not need to be updated in the Kubernetes API")
 	}
 
+	err = r.updateResizeStatus(ctx, customResource, change.ResizeStatuses)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	return nil
 }
 
@@ -64,6 +81,10 @@ func (r *Resource) NewUpdatePatch(ctx context.Context, obj, currentState, desire
 }
 
 func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
+	customResource, err := key.ToCustomObject(obj)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 	currentDeployments, err := toDeployments(currentState)
 	if err != nil {
 		return nil, microerror.Mask(err)
@@ -73,50 +94,257 @@ func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desir
 		return nil, microerror.Mask(err)
 	}
 
+	rejected, err := r.rejectedResizes(ctx, currentDeployments, desiredDeployments)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	deploymentsToUpdate, err := r.newVersionUpdateChange(ctx, customResource, currentDeployments, desiredDeployments, rejected)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	change := updateChange{
+		Deployments:    deploymentsToUpdate,
+		ResizeStatuses: newResizeStatuses(resizeStatusesFromStatus(customResource.Status.Cluster), currentDeployments, desiredDeployments, deploymentsToUpdate, rejected),
+	}
+
+	return change, nil
+}
+
+// newResizeBatch returns the batch of deployments whose VMs have to be
+// resized because only their CPUs or memory changed. Rejected resizes are
+// skipped. Resizing a VM replaces its pod, so it is rolled like any other
+// update, just before all other updates.
+func (r *Resource) newResizeBatch(ctx context.Context, currentDeployments, desiredDeployments []*v1beta1.Deployment, rejected map[string]bool) ([]*v1beta1.Deployment, bool, error) {
+	r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which deployments have to be resized")
+
+	accept := func(d *v1beta1.Deployment, changes []string) bool {
+		return isResizeOnly(changes) && !rejected[d.GetName()]
+	}
+
+	deploymentsToResize, blocked, err := r.newUpdateBatch(ctx, currentDeployments, desiredDeployments, accept)
+	if err != nil {
+		return nil, false, microerror.Mask(err)
+	}
+
+	if !blocked && len(deploymentsToResize) == 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "found no deployment that has to be resized")
+	}
+
+	return deploymentsToResize, blocked, nil
+}
+
+// newVersionUpdateChange returns the batch of deployments which have to be
+// updated, e.g. due to a new version bundle version or resized VMs. Resizes
+// are processed first. Deployments are only updated in case updates are
+// allowed and not deferred. Deployments whose resize got rejected are skipped.
+func (r *Resource) newVersionUpdateChange(ctx context.Context, customResource v1alpha1.KVMConfig, currentDeployments, desiredDeployments []*v1beta1.Deployment, rejected map[string]bool) ([]*v1beta1.Deployment, error) {
 	if updateallowedcontext.IsUpdateAllowed(ctx) {
 		r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which deployments have to be updated")
 
-		// Updates can be quite disruptive. We have to be very careful with updating
-		// resources that potentially imply disrupting customer workloads. We have
-		// to check the state of all deployments before we can safely go ahead with
-		// the update procedure.
-		for _, d := range currentDeployments {
-			allReplicasUp := allNumbersEqual(d.Status.AvailableReplicas, d.Status.ReadyReplicas, d.Status.Replicas, d.Status.UpdatedReplicas)
-			if !allReplicasUp {
-				r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("cannot update any deployment: deployment '%s' must have all replicas up", d.GetName()))
-				return nil, nil
-			}
+		accept := func(d *v1beta1.Deployment, changes []string) bool {
+			return !rejected[d.GetName()]
 		}
 
-		// We select one deployment to be updated per reconciliation loop. Therefore
-		// we have to check its state on the version bundle level to see if a
-		// deployment is already up to date. We also check if there are any other
-		// changes on the pod specs. In case there are none, we check the next one.
-		// The first one not being up to date will be chosen to be updated next and
-		// the loop will be broken immediatelly.
-		for _, currentDeployment := range currentDeployments {
-			desiredDeployment, err := getDeploymentByName(desiredDeployments, currentDeployment.Name)
-			if IsNotFound(err) {
-				// NOTE that this case indicates we should remove the current deployment
-				// eventually.
-				r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("not updating deployment '%s': no desired deployment found", currentDeployment.GetName()))
-				continue
-			} else if err != nil {
+		upToDate, err := deploymentsUpToDate(currentDeployments, desiredDeployments, accept)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if upToDate {
+			// All deployments are up to date, so the tenant cluster is not rolling
+			// anymore and nothing is deferred.
+			r.releaseRolloutSlot(customResource)
+
+			err := r.setUpdateDeferredStatus(ctx, customResource, "")
+			if err != nil {
 				return nil, microerror.Mask(err)
 			}
 
-			if !isDeploymentModified(desiredDeployment, currentDeployment) {
-				r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s': no changes found", currentDeployment.GetName()))
-				continue
+			return nil, nil
+		}
+
+		deploymentsToUpdate, blocked, err := r.newResizeBatch(ctx, currentDeployments, desiredDeployments, rejected)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !blocked && len(deploymentsToUpdate) == 0 {
+			deploymentsToUpdate, blocked, err = r.newUpdateBatch(ctx, currentDeployments, desiredDeployments, accept)
+			if err != nil {
+				return nil, microerror.Mask(err)
 			}
+		}
 
-			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found deployment '%s' that has to be updated", desiredDeployment.GetName()))
+		if blocked {
+			// The tenant cluster is still rolling, e.g. because the last batch did
+			// not settle yet. It keeps its rollout slot until it is up to date.
+			return nil, nil
+		}
+		if len(deploymentsToUpdate) == 0 {
+			// There is no batch to be updated, so there is nothing to be deferred
+			// and no rollout slot has to be acquired.
+			r.logger.LogCtx(ctx, "level", "debug", "message", "found no deployment that has to be updated")
+			return nil, nil
+		}
 
-			return []*v1beta1.Deployment{desiredDeployment}, nil
+		// Pending updates may be deferred by the tenant cluster's maintenance
+		// windows, a manual pause or the global limit of clusters rolling at
+		// once. The reason is recorded in the status so that it is visible why
+		// a tenant cluster does not get updated.
+		reason, err := r.updateDeferredReason(customResource, time.Now())
+		if err != nil {
+			return nil, microerror.Mask(err)
 		}
+
+		err = r.setUpdateDeferredStatus(ctx, customResource, reason)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if reason != "" {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("cannot update any deployment: update deferred with reason '%s'", reason))
+			return nil, nil
+		}
+
+		return deploymentsToUpdate, nil
 	} else {
 		r.logger.LogCtx(ctx, "level", "debug", "message", "not computing update state because deployments are not allowed to be updated")
 	}
 
 	return nil, nil
 }
+
+// newUpdateBatch returns the batch of deployments to be updated within this
+// reconciliation loop. Only deployments having changes accepted by the given
+// function are considered. The returned boolean is true in case no batch can
+// be processed yet because the previous one did not settle.
+func (r *Resource) newUpdateBatch(ctx context.Context, currentDeployments, desiredDeployments []*v1beta1.Deployment, accept func(d *v1beta1.Deployment, changes []string) bool) ([]*v1beta1.Deployment, bool, error) {
+	// Updates can be quite disruptive. We have to be very careful with updating
+	// resources that potentially imply disrupting customer workloads. We have
+	// to check the state of all deployments before we can safely go ahead with
+	// the update procedure.
+	for _, d := range currentDeployments {
+		allReplicasUp := allNumbersEqual(d.Status.AvailableReplicas, d.Status.ReadyReplicas, d.Status.Replicas, d.Status.UpdatedReplicas)
+		if !allReplicasUp {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("cannot update any deployment: deployment '%s' must have all replicas up", d.GetName()))
+			return nil, true, nil
+		}
+	}
+
+	// Rolling updates are processed in batches. After a batch got updated we
+	// might have to wait a configured amount of time before the next batch can
+	// be processed. This gives the tenant cluster some time to settle.
+	if r.updatePolicy.BatchWait > 0 {
+		lastUpdate, err := lastUpdateTime(currentDeployments)
+		if err != nil {
+			return nil, false, microerror.Mask(err)
+		}
+
+		if time.Since(lastUpdate) < r.updatePolicy.BatchWait {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("cannot update any deployment: last batch got updated less than %s ago", r.updatePolicy.BatchWait))
+			return nil, true, nil
+		}
+	}
+
+	// We select a batch of deployments to be updated per reconciliation loop.
+	// Therefore we have to check their state on the version bundle level to see
+	// if a deployment is already up to date. We also check if there are any
+	// other changes on the pod specs. In case there are none, we check the next
+	// one. Deployments not being up to date are added to the batch as long as
+	// the limits of the update policy are not exceeded. In case masters are
+	// updated first, workers are only updated once all masters are up to date.
+	// In case masters are updated last, masters are only updated once all
+	// workers are up to date. Deployments being rolled are not ready, which
+	// already blocks the batch above.
+	var deploymentsToUpdate []*v1beta1.Deployment
+	var masters, workers, pendingFirst int
+
+	for _, currentDeployment := range sortDeploymentsForUpdate(currentDeployments, r.updatePolicy.MastersFirst) {
+		desiredDeployment, err := getDeploymentByName(desiredDeployments, currentDeployment.Name)
+		if IsNotFound(err) {
+			// NOTE that this case indicates we should remove the current deployment
+			// eventually.
+			r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("not updating deployment '%s': no desired deployment found", currentDeployment.GetName()))
+			continue
+		} else if err != nil {
+			return nil, false, microerror.Mask(err)
+		}
+
+		changes := deploymentChanges(desiredDeployment, currentDeployment)
+		if len(changes) == 0 {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s': no changes found", currentDeployment.GetName()))
+			continue
+		}
+		if !accept(desiredDeployment, changes) {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s' in this batch: changes in %s not accepted", currentDeployment.GetName(), strings.Join(changes, ", ")))
+			continue
+		}
+
+		if isMasterDeployment(currentDeployment) != r.updatePolicy.MastersFirst && pendingFirst > 0 {
+			if r.updatePolicy.MastersFirst {
+				r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s' in this batch: masters have to be updated first", currentDeployment.GetName()))
+			} else {
+				r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s' in this batch: workers have to be updated first", currentDeployment.GetName()))
+			}
+			break
+		}
+		if isMasterDeployment(currentDeployment) == r.updatePolicy.MastersFirst {
+			pendingFirst++
+		}
+
+		if isMasterDeployment(currentDeployment) {
+			if masters >= r.updatePolicy.MaxUnavailableMasters {
+				r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s' in this batch: max unavailable masters reached", currentDeployment.GetName()))
+				continue
+			}
+			masters++
+		} else {
+			if workers >= r.updatePolicy.MaxUnavailableWorkers {
+				r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating deployment '%s' in this batch: max unavailable workers reached", currentDeployment.GetName()))
+				continue
+			}
+			workers++
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found deployment '%s' that has to be updated due to changes in %s", desiredDeployment.GetName(), strings.Join(changes, ", ")))
+
+		if r.updatePolicy.BatchWait > 0 {
+			desiredDeployment = desiredDeployment.DeepCopy()
+			if desiredDeployment.Annotations == nil {
+				desiredDeployment.Annotations = map[string]string{}
+			}
+			desiredDeployment.Annotations[key.AnnotationUpdatedAt] = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		deploymentsToUpdate = append(deploymentsToUpdate, desiredDeployment)
+
+		if len(deploymentsToUpdate) >= r.updatePolicy.BatchSize {
+			break
+		}
+	}
+
+	return deploymentsToUpdate, false, nil
+}
+
+// deploymentsUpToDate returns true in case none of the existing desired
+// deployments has changes accepted by the given function. Desired deployments
+// which do not exist yet are created by the create change and do not count as
+// pending updates.
+func deploymentsUpToDate(currentDeployments, desiredDeployments []*v1beta1.Deployment, accept func(d *v1beta1.Deployment, changes []string) bool) (bool, error) {
+	for _, desiredDeployment := range desiredDeployments {
+		currentDeployment, err := getDeploymentByName(currentDeployments, desiredDeployment.GetName())
+		if IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		changes := deploymentChanges(desiredDeployment, currentDeployment)
+		if len(changes) != 0 && accept(desiredDeployment, changes) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/maintenance"
+)
+
+// The reasons below explain why pending updates of a tenant cluster are
+// deferred. The reason is recorded as condition type in the resource status
+// of this resource within the KVMConfig status, because cluster conditions
+// cannot hold a message.
+const (
+	UpdateDeferredReasonMaintenanceWindow = "UpdateDeferredOutsideMaintenanceWindow"
+	UpdateDeferredReasonPaused            = "UpdateDeferredPaused"
+	UpdateDeferredReasonRolloutLimit      = "UpdateDeferredRolloutLimitReached"
+)
+
+// updateDeferredReason returns the reason why pending updates of the given
+// tenant cluster must not be processed at the given time. An empty reason is
+// returned in case the tenant cluster is allowed to roll. Clusters allowed to
+// roll acquire a rollout slot, which is held until they are up to date or
+// deleted, even if their maintenance window closes while they are rolling.
+func (r *Resource) updateDeferredReason(cr v1alpha1.KVMConfig, t time.Time) (string, error) {
+	if key.UpdatesPaused(cr) {
+		return UpdateDeferredReasonPaused, nil
+	}
+
+	windows, err := maintenance.Parse(key.MaintenanceWindows(cr))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if !windows.Open(t) {
+		return UpdateDeferredReasonMaintenanceWindow, nil
+	}
+
+	if r.updateLimiter != nil && !r.updateLimiter.Acquire(key.ClusterID(cr)) {
+		return UpdateDeferredReasonRolloutLimit, nil
+	}
+
+	return "", nil
+}
+
+func (r *Resource) releaseRolloutSlot(cr v1alpha1.KVMConfig) {
+	if r.updateLimiter != nil {
+		r.updateLimiter.Release(key.ClusterID(cr))
+	}
+}
+
+// setUpdateDeferredStatus records the given reason in the KVMConfig status.
+// An empty reason removes a previously recorded one.
+func (r *Resource) setUpdateDeferredStatus(ctx context.Context, cr v1alpha1.KVMConfig, reason string) error {
+	if updateDeferredStatus(cr.Status.Cluster) == reason {
+		return nil
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "updating status with update deferral reason")
+
+	_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+		status.Cluster = withUpdateDeferredStatus(status.Cluster, reason, time.Now())
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated status with update deferral reason '%s'", reason))
+
+	return nil
+}
+
+func updateDeferredStatus(status v1alpha1.StatusCluster) string {
+	for _, r := range status.Resources {
+		if r.Name != Name {
+			continue
+		}
+
+		for _, c := range r.Conditions {
+			if c.Status == v1alpha1.StatusClusterStatusTrue {
+				return c.Type
+			}
+		}
+	}
+
+	return ""
+}
+
+func withUpdateDeferredStatus(status v1alpha1.StatusCluster, reason string, t time.Time) v1alpha1.StatusCluster {
+	var resources []v1alpha1.StatusClusterResource
+	for _, r := range status.Resources {
+		if r.Name == Name {
+			continue
+		}
+		resources = append(resources, r)
+	}
+
+	if reason != "" {
+		resources = append(resources, v1alpha1.StatusClusterResource{
+			Conditions: []v1alpha1.StatusClusterResourceCondition{
+				{
+					LastTransitionTime: v1alpha1.DeepCopyTime{Time: t},
+					Status:             v1alpha1.StatusClusterStatusTrue,
+					Type:               reason,
+				},
+			},
+			Name: Name,
+		})
+	}
+
+	status.Resources = resources
+
+	return status
+}
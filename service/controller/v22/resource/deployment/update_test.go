@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/micrologger/microloggertest"
@@ -11,10 +12,13 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
+	"github.com/giantswarm/kvm-operator/service/rollout"
 )
 
 func Test_Resource_Deployment_newUpdateChange(t *testing.T) {
@@ -1210,6 +1214,7 @@ func Test_Resource_Deployment_newUpdateChange(t *testing.T) {
 	{
 		resourceConfig := DefaultConfig()
 		resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+		resourceConfig.G8sClient = statuspatchtest.NewClientset()
 		resourceConfig.K8sClient = fake.NewSimpleClientset()
 		resourceConfig.Logger = microloggertest.New()
 		newResource, err = New(resourceConfig)
@@ -1225,19 +1230,705 @@ func Test_Resource_Deployment_newUpdateChange(t *testing.T) {
 		}
 
 		t.Run("deploymentsToUpdate", func(t *testing.T) {
-			if tc.ExpectedDeploymentsToUpdate == nil {
-				if updateState != nil {
-					t.Fatalf("expected %#v got %#v", nil, updateState)
+			change, ok := updateState.(updateChange)
+			if !ok {
+				t.Fatalf("expected %T got %T", updateChange{}, updateState)
+			}
+			if !reflect.DeepEqual(change.Deployments, tc.ExpectedDeploymentsToUpdate) {
+				t.Fatalf("expected %#v got %#v", tc.ExpectedDeploymentsToUpdate, change.Deployments)
+			}
+		})
+	}
+}
+
+func Test_Resource_Deployment_newUpdateChange_UpdatePolicy(t *testing.T) {
+	newDeployment := func(name, role, version string) *v1beta1.Deployment {
+		return &v1beta1.Deployment{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name: name,
+				Annotations: map[string]string{
+					key.VersionBundleVersionAnnotation: version,
+				},
+				Labels: map[string]string{
+					key.LabelApp: role,
+				},
+			},
+			Status: v1beta1.DeploymentStatus{
+				AvailableReplicas: 1,
+				ReadyReplicas:     1,
+				Replicas:          1,
+				UpdatedReplicas:   1,
+			},
+		}
+	}
+
+	newUpdatedDeployment := func(name, role, version string, updatedAt time.Time) *v1beta1.Deployment {
+		d := newDeployment(name, role, version)
+		d.Annotations[key.AnnotationUpdatedAt] = updatedAt.Format(time.RFC3339)
+		return d
+	}
+
+	currentState := []*v1beta1.Deployment{
+		newDeployment("master-1", key.MasterID, "1.2.0"),
+		newDeployment("master-2", key.MasterID, "1.2.0"),
+		newDeployment("worker-1", key.WorkerID, "1.2.0"),
+		newDeployment("worker-2", key.WorkerID, "1.2.0"),
+		newDeployment("worker-3", key.WorkerID, "1.2.0"),
+	}
+	desiredState := []*v1beta1.Deployment{
+		newDeployment("master-1", key.MasterID, "1.3.0"),
+		newDeployment("master-2", key.MasterID, "1.3.0"),
+		newDeployment("worker-1", key.WorkerID, "1.3.0"),
+		newDeployment("worker-2", key.WorkerID, "1.3.0"),
+		newDeployment("worker-3", key.WorkerID, "1.3.0"),
+	}
+
+	testCases := []struct {
+		name                        string
+		updatePolicy                UpdatePolicy
+		currentState                []*v1beta1.Deployment
+		expectedDeploymentsToUpdate []string
+	}{
+		{
+			name: "case 0: default policy updates the first master only",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             1,
+				MastersFirst:          true,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 1,
+			},
+			currentState:                currentState,
+			expectedDeploymentsToUpdate: []string{"master-1"},
+		},
+		{
+			name: "case 1: masters last updates the first worker only",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             1,
+				MastersFirst:          false,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 1,
+			},
+			currentState:                currentState,
+			expectedDeploymentsToUpdate: []string{"worker-1"},
+		},
+		{
+			name: "case 2: masters last does not update masters while workers are pending",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             5,
+				MastersFirst:          false,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 2,
+			},
+			currentState:                currentState,
+			expectedDeploymentsToUpdate: []string{"worker-1", "worker-2"},
+		},
+		{
+			name: "case 3: batch size limits the number of deployments",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             2,
+				MastersFirst:          false,
+				MaxUnavailableMasters: 2,
+				MaxUnavailableWorkers: 3,
+			},
+			currentState:                currentState,
+			expectedDeploymentsToUpdate: []string{"worker-1", "worker-2"},
+		},
+		{
+			name: "case 4: batch wait defers the next batch",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             1,
+				BatchWait:             time.Hour,
+				MastersFirst:          true,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 1,
+			},
+			currentState: []*v1beta1.Deployment{
+				newUpdatedDeployment("master-1", key.MasterID, "1.3.0", time.Now()),
+				newDeployment("master-2", key.MasterID, "1.2.0"),
+				newDeployment("worker-1", key.WorkerID, "1.2.0"),
+			},
+			expectedDeploymentsToUpdate: nil,
+		},
+		{
+			name: "case 5: batch wait elapsed allows the next batch",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             1,
+				BatchWait:             time.Minute,
+				MastersFirst:          true,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 1,
+			},
+			currentState: []*v1beta1.Deployment{
+				newUpdatedDeployment("master-1", key.MasterID, "1.3.0", time.Now().Add(-time.Hour)),
+				newDeployment("master-2", key.MasterID, "1.2.0"),
+				newDeployment("worker-1", key.WorkerID, "1.2.0"),
+			},
+			expectedDeploymentsToUpdate: []string{"master-2"},
+		},
+		{
+			name: "case 6: masters first does not update workers while masters are pending",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             5,
+				MastersFirst:          true,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 2,
+			},
+			currentState:                currentState,
+			expectedDeploymentsToUpdate: []string{"master-1"},
+		},
+		{
+			name: "case 7: masters first updates workers once masters are up to date",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             5,
+				MastersFirst:          true,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 2,
+			},
+			currentState: []*v1beta1.Deployment{
+				newDeployment("master-1", key.MasterID, "1.3.0"),
+				newDeployment("master-2", key.MasterID, "1.3.0"),
+				newDeployment("worker-1", key.WorkerID, "1.2.0"),
+				newDeployment("worker-2", key.WorkerID, "1.2.0"),
+				newDeployment("worker-3", key.WorkerID, "1.2.0"),
+			},
+			expectedDeploymentsToUpdate: []string{"worker-1", "worker-2"},
+		},
+		{
+			name: "case 8: masters last updates masters once workers are up to date",
+			updatePolicy: UpdatePolicy{
+				BatchSize:             5,
+				MastersFirst:          false,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 2,
+			},
+			currentState: []*v1beta1.Deployment{
+				newDeployment("master-1", key.MasterID, "1.2.0"),
+				newDeployment("master-2", key.MasterID, "1.2.0"),
+				newDeployment("worker-1", key.WorkerID, "1.3.0"),
+				newDeployment("worker-2", key.WorkerID, "1.3.0"),
+				newDeployment("worker-3", key.WorkerID, "1.3.0"),
+			},
+			expectedDeploymentsToUpdate: []string{"master-1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			var newResource *Resource
+			{
+				resourceConfig := DefaultConfig()
+				resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+				resourceConfig.G8sClient = statuspatchtest.NewClientset()
+				resourceConfig.K8sClient = fake.NewSimpleClientset()
+				resourceConfig.Logger = microloggertest.New()
+				resourceConfig.UpdatePolicy = tc.updatePolicy
+				newResource, err = New(resourceConfig)
+				if err != nil {
+					t.Fatal("expected", nil, "got", err)
+				}
+			}
+
+			ctx := updateallowedcontext.NewContext(context.Background(), make(chan struct{}))
+			updateallowedcontext.SetUpdateAllowed(ctx)
+
+			updateState, err := newResource.newUpdateChange(ctx, &v1alpha1.KVMConfig{}, tc.currentState, desiredState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			change, err := toUpdateChange(updateState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			var names []string
+			for _, d := range change.Deployments {
+				names = append(names, d.GetName())
+
+				_, ok := d.GetAnnotations()[key.AnnotationUpdatedAt]
+				if tc.updatePolicy.BatchWait > 0 && !ok {
+					t.Fatalf("expected deployment '%s' to be annotated with %s", d.GetName(), key.AnnotationUpdatedAt)
+				}
+			}
+
+			if !reflect.DeepEqual(names, tc.expectedDeploymentsToUpdate) {
+				t.Fatalf("expected %#v got %#v", tc.expectedDeploymentsToUpdate, names)
+			}
+		})
+	}
+}
+
+func Test_Resource_Deployment_newUpdateChange_Deferral(t *testing.T) {
+	newDeployment := func(name, version string) *v1beta1.Deployment {
+		return &v1beta1.Deployment{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name: name,
+				Annotations: map[string]string{
+					key.VersionBundleVersionAnnotation: version,
+				},
+				Labels: map[string]string{
+					key.LabelApp: key.WorkerID,
+				},
+			},
+		}
+	}
+
+	newCustomResource := func(annotations map[string]string, reason string) *v1alpha1.KVMConfig {
+		cr := &v1alpha1.KVMConfig{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name:        "al9qy",
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.KVMConfigSpec{
+				Cluster: v1alpha1.Cluster{
+					ID: "al9qy",
+				},
+			},
+		}
+		cr.Status.Cluster = withUpdateDeferredStatus(cr.Status.Cluster, reason, time.Now())
+		return cr
+	}
+
+	testCases := []struct {
+		name                        string
+		customResource              *v1alpha1.KVMConfig
+		rollingClusters             []string
+		currentVersion              string
+		currentReplicasUp           bool
+		createdDeployments          []string
+		expectedDeploymentsToUpdate []string
+		expectedReason              string
+		expectedRolling             []string
+	}{
+		{
+			name:                        "case 0: update is not deferred and acquires a rollout slot",
+			customResource:              newCustomResource(nil, ""),
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: []string{"worker-1"},
+			expectedReason:              "",
+			expectedRolling:             []string{"al9qy"},
+		},
+		{
+			name: "case 1: paused updates are deferred",
+			customResource: newCustomResource(map[string]string{
+				key.AnnotationUpdatesPaused: "true",
+			}, ""),
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              UpdateDeferredReasonPaused,
+			expectedRolling:             nil,
+		},
+		{
+			name: "case 2: updates outside of maintenance windows are deferred",
+			customResource: newCustomResource(map[string]string{
+				key.AnnotationMaintenanceWindows: "0 0 30 2 * 1m",
+			}, ""),
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              UpdateDeferredReasonMaintenanceWindow,
+			expectedRolling:             nil,
+		},
+		{
+			name:                        "case 3: updates exceeding the rollout limit are deferred",
+			customResource:              newCustomResource(nil, ""),
+			rollingClusters:             []string{"p3m1x"},
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              UpdateDeferredReasonRolloutLimit,
+			expectedRolling:             []string{"p3m1x"},
+		},
+		{
+			name:                        "case 4: up to date cluster releases its slot and deferral reason",
+			customResource:              newCustomResource(nil, UpdateDeferredReasonPaused),
+			rollingClusters:             []string{"al9qy"},
+			currentVersion:              "1.3.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              "",
+			expectedRolling:             nil,
+		},
+		{
+			name: "case 5: cluster keeps its slot when its maintenance window closes while rolling",
+			customResource: newCustomResource(map[string]string{
+				key.AnnotationMaintenanceWindows: "0 0 30 2 * 1m",
+			}, ""),
+			rollingClusters:             []string{"al9qy"},
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              UpdateDeferredReasonMaintenanceWindow,
+			expectedRolling:             []string{"al9qy"},
+		},
+		{
+			name:                        "case 6: cluster keeps its slot while the last batch did not settle",
+			customResource:              newCustomResource(nil, ""),
+			rollingClusters:             []string{"al9qy"},
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           false,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              "",
+			expectedRolling:             []string{"al9qy"},
+		},
+		{
+			name: "case 7: paused cluster keeps its slot while rolling",
+			customResource: newCustomResource(map[string]string{
+				key.AnnotationUpdatesPaused: "true",
+			}, ""),
+			rollingClusters:             []string{"al9qy"},
+			currentVersion:              "1.2.0",
+			currentReplicasUp:           true,
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              UpdateDeferredReasonPaused,
+			expectedRolling:             []string{"al9qy"},
+		},
+		{
+			name:                        "case 8: creating deployments does not acquire a rollout slot",
+			customResource:              newCustomResource(nil, ""),
+			currentVersion:              "1.3.0",
+			currentReplicasUp:           true,
+			createdDeployments:          []string{"worker-2"},
+			expectedDeploymentsToUpdate: nil,
+			expectedReason:              "",
+			expectedRolling:             nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := rollout.NewLimiter(1)
+			for _, id := range tc.rollingClusters {
+				limiter.Acquire(id)
+			}
+
+			var err error
+			var newResource *Resource
+			{
+				resourceConfig := DefaultConfig()
+				resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+				resourceConfig.G8sClient = statuspatchtest.NewClientset(tc.customResource)
+				resourceConfig.K8sClient = fake.NewSimpleClientset()
+				resourceConfig.Logger = microloggertest.New()
+				resourceConfig.UpdateLimiter = limiter
+				newResource, err = New(resourceConfig)
+				if err != nil {
+					t.Fatal("expected", nil, "got", err)
 				}
-			} else {
-				deploymentsToUpdate, ok := updateState.([]*v1beta1.Deployment)
-				if !ok {
-					t.Fatalf("expected %T got %T", []*v1beta1.Deployment{}, updateState)
+			}
+
+			ctx := updateallowedcontext.NewContext(context.Background(), make(chan struct{}))
+			updateallowedcontext.SetUpdateAllowed(ctx)
+
+			currentState := []*v1beta1.Deployment{newDeployment("worker-1", tc.currentVersion)}
+			if !tc.currentReplicasUp {
+				currentState[0].Status.Replicas = 1
+			}
+			desiredState := []*v1beta1.Deployment{newDeployment("worker-1", "1.3.0")}
+			for _, name := range tc.createdDeployments {
+				desiredState = append(desiredState, newDeployment(name, "1.3.0"))
+			}
+
+			updateState, err := newResource.newUpdateChange(ctx, tc.customResource, currentState, desiredState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			change, err := toUpdateChange(updateState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			var names []string
+			for _, d := range change.Deployments {
+				names = append(names, d.GetName())
+			}
+			if !reflect.DeepEqual(names, tc.expectedDeploymentsToUpdate) {
+				t.Fatalf("expected %#v got %#v", tc.expectedDeploymentsToUpdate, names)
+			}
+
+			cr, err := newResource.g8sClient.ProviderV1alpha1().KVMConfigs("default").Get("al9qy", apismetav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			reason := updateDeferredStatus(cr.Status.Cluster)
+			if reason != tc.expectedReason {
+				t.Fatalf("expected reason %#q got %#q", tc.expectedReason, reason)
+			}
+
+			if !reflect.DeepEqual(limiter.Rolling(), tc.expectedRolling) {
+				t.Fatalf("expected rolling clusters %#v got %#v", tc.expectedRolling, limiter.Rolling())
+			}
+		})
+	}
+}
+
+func Test_Resource_Deployment_newUpdateChange_Resize(t *testing.T) {
+	newDeployment := func(nodeID string, cpus string, memory string) *v1beta1.Deployment {
+		return &v1beta1.Deployment{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name: "worker-" + nodeID,
+				Annotations: map[string]string{
+					key.VersionBundleVersionAnnotation: "1.2.0",
+				},
+				Labels: map[string]string{
+					key.LabelApp: key.WorkerID,
+					"node":       nodeID,
+				},
+			},
+			Spec: v1beta1.DeploymentSpec{
+				Template: apiv1.PodTemplateSpec{
+					Spec: apiv1.PodSpec{
+						Containers: []apiv1.Container{
+							{
+								Name: key.ContainerNameKVM,
+								Env: []apiv1.EnvVar{
+									{Name: "CORES", Value: cpus},
+									{Name: "MEMORY", Value: memory},
+								},
+								Resources: apiv1.ResourceRequirements{
+									Requests: apiv1.ResourceList{
+										apiv1.ResourceCPU:    resource.MustParse(cpus),
+										apiv1.ResourceMemory: resource.MustParse(memory),
+									},
+									Limits: apiv1.ResourceList{
+										apiv1.ResourceCPU:    resource.MustParse(cpus),
+										apiv1.ResourceMemory: resource.MustParse(memory),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Status: v1beta1.DeploymentStatus{
+				AvailableReplicas: 1,
+				ReadyReplicas:     1,
+				Replicas:          1,
+				UpdatedReplicas:   1,
+			},
+		}
+	}
+
+	newCustomResource := func(annotations map[string]string, statuses []resizeStatus) *v1alpha1.KVMConfig {
+		cr := &v1alpha1.KVMConfig{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name:        "al9qy",
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: v1alpha1.KVMConfigSpec{
+				Cluster: v1alpha1.Cluster{
+					ID: "al9qy",
+				},
+			},
+		}
+		cr.Status.Cluster = withResizeStatuses(cr.Status.Cluster, statuses, time.Now())
+		return cr
+	}
+
+	newHostNode := func(cpus string, memory string) *apiv1.Node {
+		return &apiv1.Node{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name: "host-" + cpus + "-" + memory,
+			},
+			Status: apiv1.NodeStatus{
+				Allocatable: apiv1.ResourceList{
+					apiv1.ResourceCPU:    resource.MustParse(cpus),
+					apiv1.ResourceMemory: resource.MustParse(memory),
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name                        string
+		customResource              *v1alpha1.KVMConfig
+		hostNodes                   []*apiv1.Node
+		resizePolicy                ResizePolicy
+		rollingClusters             []string
+		updateNotAllowed            bool
+		currentState                []*v1beta1.Deployment
+		desiredState                []*v1beta1.Deployment
+		expectedDeploymentsToUpdate []string
+		expectedStatuses            []resizeStatus
+	}{
+		{
+			name:                        "case 0: resize is applied one batch at a time",
+			customResource:              newCustomResource(nil, nil),
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G"), newDeployment("b", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G"), newDeployment("b", "4", "8G")},
+			expectedDeploymentsToUpdate: []string{"worker-a"},
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStateInProgress},
+				{NodeID: "b", State: ResizeStatePending},
+			},
+		},
+		{
+			name: "case 1: resize of paused cluster stays pending",
+			customResource: newCustomResource(map[string]string{
+				key.AnnotationUpdatesPaused: "true",
+			}, nil),
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStatePending},
+			},
+		},
+		{
+			name:                        "case 2: resize not fitting any host node is rejected",
+			customResource:              newCustomResource(nil, nil),
+			hostNodes:                   []*apiv1.Node{newHostNode("4", "6G")},
+			resizePolicy:                ResizePolicy{CapacityCheck: true},
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStateRejected},
+			},
+		},
+		{
+			name:                        "case 3: resize fitting a host node is applied",
+			customResource:              newCustomResource(nil, nil),
+			hostNodes:                   []*apiv1.Node{newHostNode("4", "6G"), newHostNode("8", "16G")},
+			resizePolicy:                ResizePolicy{CapacityCheck: true},
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: []string{"worker-a"},
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStateInProgress},
+			},
+		},
+		{
+			name: "case 4: rolled out resize is applied",
+			customResource: newCustomResource(nil, []resizeStatus{
+				{NodeID: "a", State: ResizeStateInProgress},
+			}),
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStateApplied},
+			},
+		},
+		{
+			name: "case 5: reverted rejected resize is forgotten",
+			customResource: newCustomResource(nil, []resizeStatus{
+				{NodeID: "a", State: ResizeStateRejected},
+			}),
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses:            nil,
+		},
+		{
+			name:                        "case 6: resize stays pending when updates are not allowed",
+			customResource:              newCustomResource(nil, nil),
+			updateNotAllowed:            true,
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStatePending},
+			},
+		},
+		{
+			name: "case 7: resize stays pending outside of maintenance windows",
+			customResource: newCustomResource(map[string]string{
+				key.AnnotationMaintenanceWindows: "0 0 30 2 * 1m",
+			}, nil),
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStatePending},
+			},
+		},
+		{
+			name:                        "case 8: resize stays pending when the rollout limit is reached",
+			customResource:              newCustomResource(nil, nil),
+			rollingClusters:             []string{"p3m1x"},
+			currentState:                []*v1beta1.Deployment{newDeployment("a", "2", "4G")},
+			desiredState:                []*v1beta1.Deployment{newDeployment("a", "4", "8G")},
+			expectedDeploymentsToUpdate: nil,
+			expectedStatuses: []resizeStatus{
+				{NodeID: "a", State: ResizeStatePending},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := rollout.NewLimiter(1)
+			for _, id := range tc.rollingClusters {
+				limiter.Acquire(id)
+			}
+
+			k8sClient := fake.NewSimpleClientset()
+			for _, n := range tc.hostNodes {
+				_, err := k8sClient.CoreV1().Nodes().Create(n)
+				if err != nil {
+					t.Fatal(err)
 				}
-				if !reflect.DeepEqual(deploymentsToUpdate, tc.ExpectedDeploymentsToUpdate) {
-					t.Fatalf("expected %#v got %#v", tc.ExpectedDeploymentsToUpdate, deploymentsToUpdate)
+			}
+
+			var err error
+			var newResource *Resource
+			{
+				resourceConfig := DefaultConfig()
+				resourceConfig.DNSServers = "dnsserver1,dnsserver2"
+				resourceConfig.G8sClient = statuspatchtest.NewClientset(tc.customResource)
+				resourceConfig.K8sClient = k8sClient
+				resourceConfig.Logger = microloggertest.New()
+				resourceConfig.ResizePolicy = tc.resizePolicy
+				resourceConfig.UpdateLimiter = limiter
+				newResource, err = New(resourceConfig)
+				if err != nil {
+					t.Fatal("expected", nil, "got", err)
 				}
 			}
+
+			ctx := updateallowedcontext.NewContext(context.Background(), make(chan struct{}))
+			if !tc.updateNotAllowed {
+				updateallowedcontext.SetUpdateAllowed(ctx)
+			}
+
+			updateState, err := newResource.newUpdateChange(ctx, tc.customResource, tc.currentState, tc.desiredState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			change, err := toUpdateChange(updateState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			var names []string
+			for _, d := range change.Deployments {
+				names = append(names, d.GetName())
+			}
+			if !reflect.DeepEqual(names, tc.expectedDeploymentsToUpdate) {
+				t.Fatalf("expected %#v got %#v", tc.expectedDeploymentsToUpdate, names)
+			}
+
+			err = newResource.updateResizeStatus(ctx, *tc.customResource, change.ResizeStatuses)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			cr, err := newResource.g8sClient.ProviderV1alpha1().KVMConfigs("default").Get("al9qy", apismetav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			statuses := resizeStatusesFromStatus(cr.Status.Cluster)
+			if !reflect.DeepEqual(statuses, tc.expectedStatuses) {
+				t.Fatalf("expected resize statuses %#v got %#v", tc.expectedStatuses, statuses)
+			}
 		})
 	}
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/kubelet"
 	"github.com/giantswarm/microerror"
 	apiv1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/api/extensions/v1beta1"
@@ -13,7 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string) ([]*extensionsv1.Deployment, error) {
+func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string, images Images) ([]*extensionsv1.Deployment, error) {
 	var deployments []*extensionsv1.Deployment
 
 	privileged := true
@@ -33,6 +34,16 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 			return nil, microerror.Maskf(err, "creating memory quantity")
 		}
 
+		kubeletSettings, err := kubelet.ForWorker(customResource, workerNode.ID)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		kubeletVolumeSize := key.KubeletVolumeSizeFromNode(capabilities)
+		if kubeletSettings.VolumeSize() != "" {
+			kubeletVolumeSize = kubeletSettings.VolumeSize()
+		}
+
 		deployment := &extensionsv1.Deployment{
 			TypeMeta: apismetav1.TypeMeta{
 				Kind:       "deployment",
@@ -71,7 +82,6 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 							key.AnnotationAPIEndpoint:   key.ClusterAPIEndpoint(customResource),
 							key.AnnotationIp:            "",
 							key.AnnotationService:       key.WorkerID,
-							key.AnnotationPodDrained:    "False",
 							key.AnnotationVersionBundle: key.VersionBundleVersion(customResource),
 						},
 						Name: key.WorkerID,
@@ -130,7 +140,7 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 						Containers: []apiv1.Container{
 							{
 								Name:            "k8s-endpoint-updater",
-								Image:           key.K8SEndpointUpdaterDocker,
+								Image:           images.K8sEndpointUpdater,
 								ImagePullPolicy: apiv1.PullIfNotPresent,
 								Command: []string{
 									"/opt/k8s-endpoint-updater",
@@ -156,8 +166,8 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 								},
 							},
 							{
-								Name:            "k8s-kvm",
-								Image:           key.K8SKVMDockerImage,
+								Name:            key.ContainerNameKVM,
+								Image:           images.K8sKVM,
 								ImagePullPolicy: apiv1.PullIfNotPresent,
 								SecurityContext: &apiv1.SecurityContext{
 									Privileged: &privileged,
@@ -180,7 +190,7 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 									},
 									{
 										Name:  "DISK_KUBELET",
-										Value: key.KubeletVolumeSizeFromNode(capabilities),
+										Value: kubeletVolumeSize,
 									},
 									{
 										Name:  "DISK_OS",
@@ -282,8 +292,8 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 								},
 							},
 							{
-								Name:            "k8s-kvm-health",
-								Image:           key.K8SKVMHealthDocker,
+								Name:            key.ContainerNameKVMHealth,
+								Image:           images.K8sKVMHealth,
 								ImagePullPolicy: apiv1.PullAlways,
 								Env: []apiv1.EnvVar{
 									{
@@ -307,7 +317,7 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 							},
 							{
 								Name:            "shutdown-deferrer",
-								Image:           key.ShutdownDeferrerDocker,
+								Image:           images.ShutdownDeferrer,
 								ImagePullPolicy: apiv1.PullAlways,
 								Args: []string{
 									"daemon",
@@ -359,6 +369,13 @@ func newWorkerDeployments(customResource v1alpha1.KVMConfig, dnsServers string)
 			},
 		}
 
+		// The VMs only read their cloud configs when they boot. Changes of the
+		// kubelet settings are therefore annotated to the pod template, so that
+		// they show up as drift and the VM gets rolled.
+		if hash := kubeletSettings.Hash(); hash != "" {
+			deployment.Spec.Template.Annotations[key.AnnotationKubeletConfigHash] = hash
+		}
+
 		deployments = append(deployments, deployment)
 	}
 
@@ -45,14 +45,14 @@ func (r *Resource) newCreateChange(ctx context.Context, obj, currentState, desir
 
 	var createChange *corev1.Endpoints
 	{
-		ips := ipsForCreateChange(currentEndpoint.IPs, desiredEndpoint.IPs)
+		addresses, notReadyAddresses := addressesForCreateChange(currentEndpoint, desiredEndpoint)
 
 		e := &Endpoint{
-			Addresses:        ipsToAddresses(ips),
-			IPs:              ips,
-			Ports:            currentEndpoint.Ports,
-			ServiceName:      currentEndpoint.ServiceName,
-			ServiceNamespace: currentEndpoint.ServiceNamespace,
+			Addresses:         addresses,
+			NotReadyAddresses: notReadyAddresses,
+			Ports:             currentEndpoint.Ports,
+			ServiceName:       currentEndpoint.ServiceName,
+			ServiceNamespace:  currentEndpoint.ServiceNamespace,
 		}
 
 		createChange = r.newK8sEndpoint(e)
@@ -61,18 +61,23 @@ func (r *Resource) newCreateChange(ctx context.Context, obj, currentState, desir
 	return createChange, nil
 }
 
-func ipsForCreateChange(currentIPs []string, desiredIPs []string) []string {
-	var ips []string
+// addressesForCreateChange returns the desired addresses in case the current
+// endpoint does not have any address yet. Otherwise the endpoint already
+// exists and is updated instead.
+func addressesForCreateChange(currentEndpoint, desiredEndpoint *Endpoint) ([]corev1.EndpointAddress, []corev1.EndpointAddress) {
+	if len(currentEndpoint.Addresses) > 0 || len(currentEndpoint.NotReadyAddresses) > 0 {
+		return nil, nil
+	}
 
-	for _, ip := range desiredIPs {
-		if !containsIP(ips, ip) {
-			ips = append(ips, ip)
-		}
+	var addresses []corev1.EndpointAddress
+	for _, a := range desiredEndpoint.Addresses {
+		addresses = upsertAddress(addresses, a)
 	}
 
-	if len(currentIPs) == 0 {
-		return ips
+	var notReadyAddresses []corev1.EndpointAddress
+	for _, a := range desiredEndpoint.NotReadyAddresses {
+		notReadyAddresses = upsertAddress(notReadyAddresses, a)
 	}
 
-	return nil
+	return keepMasterInRotation(desiredEndpoint, addresses, notReadyAddresses)
 }
@@ -110,8 +110,10 @@ func Test_Resource_Endpoint_newCreateChange(t *testing.T) {
 	}{
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -122,8 +124,10 @@ func Test_Resource_Endpoint_newCreateChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -150,7 +154,7 @@ func Test_Resource_Endpoint_newCreateChange(t *testing.T) {
 		},
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{},
+				Addresses: []corev1.EndpointAddress{},
 				Ports: []corev1.EndpointPort{
 					{
 						Port: 1234,
@@ -160,8 +164,10 @@ func Test_Resource_Endpoint_newCreateChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -190,6 +196,41 @@ func Test_Resource_Endpoint_newCreateChange(t *testing.T) {
 				},
 			},
 		},
+		{
+			CurrentState: &Endpoint{
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			DesiredState: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedCreateState: &corev1.Endpoints{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "TestNamespace",
+				},
+				Subsets: []corev1.EndpointSubset{
+					{
+						Addresses: []corev1.EndpointAddress{
+							{
+								IP:        "1.1.1.1",
+								TargetRef: testPodRef("pod-1"),
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for i, tc := range testCases {
 		var err error
@@ -64,7 +64,7 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 		k8sEndpoints, err := r.k8sClient.CoreV1().Endpoints(pod.GetNamespace()).Get(serviceName, metav1.GetOptions{})
 		if errors.IsNotFound(err) {
 			// In case the endpoint manifest cannot be found in the Kubernetes API we
-			// return the endpoint structure we dispatch without filling any IP.
+			// return the endpoint structure we dispatch without filling any address.
 			r.logger.LogCtx(ctx, "level", "debug", "message", "did not find endpoint")
 
 			return endpoint, nil
@@ -74,13 +74,18 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 
 		for _, endpointSubset := range k8sEndpoints.Subsets {
 			for _, endpointAddress := range endpointSubset.Addresses {
-				if !containsIP(endpoint.IPs, endpointAddress.IP) {
-					endpoint.IPs = append(endpoint.IPs, endpointAddress.IP)
+				if !containsAddress(endpoint.Addresses, endpointAddress.IP) {
+					endpoint.Addresses = append(endpoint.Addresses, endpointAddress)
+				}
+			}
+		}
+		for _, endpointSubset := range k8sEndpoints.Subsets {
+			for _, endpointAddress := range endpointSubset.NotReadyAddresses {
+				if !containsAddress(endpoint.Addresses, endpointAddress.IP) && !containsAddress(endpoint.NotReadyAddresses, endpointAddress.IP) {
+					endpoint.NotReadyAddresses = append(endpoint.NotReadyAddresses, endpointAddress)
 				}
 			}
 		}
-
-		endpoint.Addresses = ipsToAddresses(endpoint.IPs)
 
 		r.logger.LogCtx(ctx, "level", "debug", "message", "found endpoint")
 	}
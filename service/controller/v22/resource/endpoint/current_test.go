@@ -104,9 +104,6 @@ func Test_Resource_Endpoint_GetCurrentState(t *testing.T) {
 						IP: "1.1.1.1",
 					},
 				},
-				IPs: []string{
-					"1.1.1.1",
-				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
 			},
@@ -151,10 +148,6 @@ func Test_Resource_Endpoint_GetCurrentState(t *testing.T) {
 						IP: "1.2.3.4",
 					},
 				},
-				IPs: []string{
-					"1.1.1.1",
-					"1.2.3.4",
-				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
 			},
@@ -206,10 +199,6 @@ func Test_Resource_Endpoint_GetCurrentState(t *testing.T) {
 						IP: "1.2.3.4",
 					},
 				},
-				IPs: []string{
-					"1.1.1.1",
-					"1.2.3.4",
-				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
 			},
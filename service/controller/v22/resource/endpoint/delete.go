@@ -64,14 +64,14 @@ func (r *Resource) newDeleteChange(ctx context.Context, obj, currentState, desir
 
 	var deleteChange *corev1.Endpoints
 	{
-		ips := ipsForDeleteChange(currentEndpoint.IPs, desiredEndpoint.IPs)
+		addresses, notReadyAddresses := addressesForDeleteChange(currentEndpoint, desiredEndpoint)
 
 		e := &Endpoint{
-			Addresses:        ipsToAddresses(ips),
-			IPs:              ips,
-			Ports:            currentEndpoint.Ports,
-			ServiceName:      currentEndpoint.ServiceName,
-			ServiceNamespace: currentEndpoint.ServiceNamespace,
+			Addresses:         addresses,
+			NotReadyAddresses: notReadyAddresses,
+			Ports:             currentEndpoint.Ports,
+			ServiceName:       currentEndpoint.ServiceName,
+			ServiceNamespace:  currentEndpoint.ServiceNamespace,
 		}
 
 		deleteChange = r.newK8sEndpoint(e)
@@ -80,20 +80,17 @@ func (r *Resource) newDeleteChange(ctx context.Context, obj, currentState, desir
 	return deleteChange, nil
 }
 
-func ipsForDeleteChange(currentIPs []string, desiredIPs []string) []string {
-	var ips []string
-
-	for _, ip := range currentIPs {
-		if !containsIP(ips, ip) {
-			ips = append(ips, ip)
-		}
+// addressesForDeleteChange returns the current addresses without the ones of
+// the desired endpoint, regardless whether they are ready or not. All
+// addresses referencing the pod of the desired endpoint are removed as well.
+func addressesForDeleteChange(currentEndpoint, desiredEndpoint *Endpoint) ([]corev1.EndpointAddress, []corev1.EndpointAddress) {
+	ref := podRef(desiredEndpoint)
+	isDeleted := func(a corev1.EndpointAddress) bool {
+		return isPodAddress(a, ref) || containsEndpointAddress(desiredEndpoint, a.IP)
 	}
 
-	for _, ip := range desiredIPs {
-		if containsIP(ips, ip) {
-			ips = removeIP(ips, ip)
-		}
-	}
+	addresses := filterAddresses(currentEndpoint.Addresses, isDeleted)
+	notReadyAddresses := filterAddresses(currentEndpoint.NotReadyAddresses, isDeleted)
 
-	return ips
+	return addresses, notReadyAddresses
 }
@@ -159,56 +159,152 @@ func Test_Resource_Endpoint_ApplyDeleteChange(t *testing.T) {
 	}
 }
 
-func Test_Resource_Endpoint_ipsForDeleteChange(t *testing.T) {
+func Test_Resource_Endpoint_addressesForDeleteChange(t *testing.T) {
 	testCases := []struct {
-		name        string
-		base        []string
-		cutset      []string
-		expectedSet []string
+		name                      string
+		current                   *Endpoint
+		desired                   *Endpoint
+		expectedAddresses         []corev1.EndpointAddress
+		expectedNotReadyAddresses []corev1.EndpointAddress
 	}{
 		{
-			name: "case 0: Base has one more address than the cutset",
-			base: []string{
-				"1.1.1.1",
-				"0.0.0.0",
+			name: "case 0: Current has one more address than desired",
+			current: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+					{
+						IP: "0.0.0.0",
+					},
+				},
 			},
-			cutset: []string{
-				"1.1.1.1",
+			desired: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+				},
 			},
-			expectedSet: []string{
-				"0.0.0.0",
+			expectedAddresses: []corev1.EndpointAddress{
+				{
+					IP: "0.0.0.0",
+				},
 			},
 		},
 		{
-			name: "case 1: Base and cutset have the same addresses",
-			base: []string{
-				"1.1.1.1",
+			name: "case 1: Current and desired have the same addresses",
+			current: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+			desired: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+			expectedAddresses: nil,
+		},
+		{
+			name: "case 2: Desired has one more address than current",
+			current: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+				},
+			},
+			desired: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+					{
+						IP: "0.0.0.0",
+					},
+				},
+			},
+			expectedAddresses: nil,
+		},
+		{
+			name: "case 3: Not ready addresses of the desired endpoint are removed as well",
+			current: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "0.0.0.0",
+					},
+				},
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+					{
+						IP: "fd00::1",
+					},
+				},
+			},
+			desired: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+					{
+						IP: "fd00::1",
+					},
+				},
 			},
-			cutset: []string{
-				"1.1.1.1",
+			expectedAddresses: []corev1.EndpointAddress{
+				{
+					IP: "0.0.0.0",
+				},
 			},
-			expectedSet: []string{},
+			expectedNotReadyAddresses: nil,
 		},
 		{
-			name: "case 2: Cutset has one more address than base",
-			base: []string{
-				"1.1.1.1",
+			name: "case 4: Stale addresses of the desired pod are removed",
+			current: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.2",
+						TargetRef: testPodRef("pod-1"),
+					},
+					{
+						IP:        "2.2.2.2",
+						TargetRef: testPodRef("pod-2"),
+					},
+				},
+			},
+			desired: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
 			},
-			cutset: []string{
-				"1.1.1.1",
-				"0.0.0.0",
+			expectedAddresses: []corev1.EndpointAddress{
+				{
+					IP:        "2.2.2.2",
+					TargetRef: testPodRef("pod-2"),
+				},
 			},
-			expectedSet: []string{},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			addresses, notReadyAddresses := addressesForDeleteChange(tc.current, tc.desired)
 
-			resultSet := ipsForDeleteChange(tc.base, tc.cutset)
-
-			if !reflect.DeepEqual(resultSet, tc.expectedSet) {
-				t.Fatalf("resultSet == %q, want %q", resultSet, tc.expectedSet)
+			if !reflect.DeepEqual(addresses, tc.expectedAddresses) {
+				t.Fatalf("addresses == %#v, want %#v", addresses, tc.expectedAddresses)
+			}
+			if !reflect.DeepEqual(notReadyAddresses, tc.expectedNotReadyAddresses) {
+				t.Fatalf("notReadyAddresses == %#v, want %#v", notReadyAddresses, tc.expectedNotReadyAddresses)
 			}
 		})
 	}
@@ -223,8 +319,10 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 	}{
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -235,8 +333,10 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -271,9 +371,13 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 		},
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
-					"1.2.3.4",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+					{
+						IP: "1.2.3.4",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -284,8 +388,10 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -325,9 +431,13 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 		},
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"5.5.5.5",
-					"1.2.3.4",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "5.5.5.5",
+					},
+					{
+						IP: "1.2.3.4",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -338,8 +448,10 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -391,8 +503,10 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -455,3 +569,11 @@ func Test_Resource_Endpoint_newDeleteChange(t *testing.T) {
 		})
 	}
 }
+
+func testPodRef(name string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:      "Pod",
+		Namespace: "TestNamespace",
+		Name:      name,
+	}
+}
@@ -2,10 +2,11 @@ package endpoint
 
 import (
 	"context"
-	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 
 	"github.com/giantswarm/microerror"
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
 func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interface{}, error) {
@@ -14,30 +15,48 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 		return nil, microerror.Mask(err)
 	}
 
-	endpointIP, serviceName, err := getAnnotations(*pod, IPAnnotation, ServiceAnnotation)
+	ipAnnotation, serviceName, err := getAnnotations(*pod, IPAnnotation, ServiceAnnotation)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+	ips, err := parseIPs(ipAnnotation)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	desiredEndpoint := &Endpoint{
-		IPs: []string{
-			endpointIP,
-		},
 		ServiceName:      serviceName,
 		ServiceNamespace: pod.GetNamespace(),
 	}
 
-	podIsReady := false
+	// Workers which are not ready and masters which are not healthy must not
+	// receive traffic, so their addresses are tracked as not ready addresses.
+	if !isPodReady(pod) && serviceName == key.WorkerID || !isMasterHealthy(pod) && serviceName == key.MasterID {
+		desiredEndpoint.NotReadyAddresses = podAddresses(*pod, ips)
+	} else {
+		desiredEndpoint.Addresses = podAddresses(*pod, ips)
+	}
+
+	return desiredEndpoint, nil
+}
+
+// isMasterHealthy returns whether the given master pod is able to serve
+// traffic. The readiness probe of the VM container is served by the
+// k8s-kvm-health sidecar, which also checks the Kubernetes API of the master.
+// So the master is healthy when the sidecar runs and the VM container is
+// ready. The container statuses are not updated anymore once the host cluster
+// node of the pod is unreachable, so the pod must be ready as well, which is
+// set to false by the node controller in this case.
+func isMasterHealthy(pod *corev1.Pod) bool {
+	return isPodReady(pod) && key.IsPodContainerReady(pod, key.ContainerNameKVM) && key.IsPodContainerReady(pod, key.ContainerNameKVMHealth)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
 	for _, condition := range pod.Status.Conditions {
 		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
-			podIsReady = true
-			break
+			return true
 		}
 	}
 
-	// If the pod is not ready so we should not add the ip to the endpoint list.
-	if !podIsReady && serviceName == key.WorkerID {
-		desiredEndpoint.IPs = []string{}
-	}
-
-	return desiredEndpoint, nil
+	return false
 }
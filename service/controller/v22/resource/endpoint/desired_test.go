@@ -39,14 +39,224 @@ func Test_Resource_Endpoint_GetDesiredState(t *testing.T) {
 				},
 			},
 			ExpectedEndpoint: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("TestPod"),
+					},
+				},
+				ServiceName:      "TestService",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedErrorHandler: nil,
+		},
+		{
+			Obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestPod",
+					Namespace: "TestNamespace",
+					Annotations: map[string]string{
+						"endpoint.kvm.giantswarm.io/ip":      "1.1.1.1, fd00::1",
+						"endpoint.kvm.giantswarm.io/service": "TestService",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.PodReady,
+							Status: corev1.ConditionTrue,
+						},
+					},
+				},
+			},
+			ExpectedEndpoint: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("TestPod"),
+					},
+					{
+						IP:        "fd00::1",
+						TargetRef: testPodRef("TestPod"),
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
 			},
 			ExpectedErrorHandler: nil,
 		},
+		{
+			Obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestPod",
+					Namespace: "TestNamespace",
+					Annotations: map[string]string{
+						"endpoint.kvm.giantswarm.io/ip":      "1.1.1.1,fd00::1",
+						"endpoint.kvm.giantswarm.io/service": "worker",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.PodReady,
+							Status: corev1.ConditionFalse,
+						},
+					},
+				},
+			},
+			ExpectedEndpoint: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("TestPod"),
+					},
+					{
+						IP:        "fd00::1",
+						TargetRef: testPodRef("TestPod"),
+					},
+				},
+				ServiceName:      "worker",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedErrorHandler: nil,
+		},
+		{
+			Obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestPod",
+					Namespace: "TestNamespace",
+					Annotations: map[string]string{
+						"endpoint.kvm.giantswarm.io/ip":      "1.1.1.1",
+						"endpoint.kvm.giantswarm.io/service": "master",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.PodReady,
+							Status: corev1.ConditionTrue,
+						},
+					},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "k8s-kvm",
+							Ready: true,
+						},
+						{
+							Name:  "k8s-kvm-health",
+							Ready: true,
+						},
+					},
+				},
+			},
+			ExpectedEndpoint: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("TestPod"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedErrorHandler: nil,
+		},
+		{
+			Obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestPod",
+					Namespace: "TestNamespace",
+					Annotations: map[string]string{
+						"endpoint.kvm.giantswarm.io/ip":      "1.1.1.1",
+						"endpoint.kvm.giantswarm.io/service": "master",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.PodReady,
+							Status: corev1.ConditionFalse,
+						},
+					},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "k8s-kvm",
+							Ready: false,
+						},
+						{
+							Name:  "k8s-kvm-health",
+							Ready: true,
+						},
+					},
+				},
+			},
+			ExpectedEndpoint: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("TestPod"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedErrorHandler: nil,
+		},
+		{
+			Obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestPod",
+					Namespace: "TestNamespace",
+					Annotations: map[string]string{
+						"endpoint.kvm.giantswarm.io/ip":      "1.1.1.1",
+						"endpoint.kvm.giantswarm.io/service": "master",
+					},
+				},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.PodReady,
+							Status: corev1.ConditionFalse,
+						},
+					},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "k8s-kvm",
+							Ready: true,
+						},
+						{
+							Name:  "k8s-kvm-health",
+							Ready: true,
+						},
+					},
+				},
+			},
+			ExpectedEndpoint: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("TestPod"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedErrorHandler: nil,
+		},
+		{
+			Obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "TestPod",
+					Namespace: "TestNamespace",
+					Annotations: map[string]string{
+						"endpoint.kvm.giantswarm.io/ip":      "1.1.1.1,foo",
+						"endpoint.kvm.giantswarm.io/service": "TestService",
+					},
+				},
+			},
+			ExpectedErrorHandler: IsInvalidAnnotation,
+			ExpectedEndpoint:     nil,
+		},
 		{
 			Obj: &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -1,12 +1,17 @@
 package endpoint
 
 import (
+	"net"
+	"strings"
+
 	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
 const (
@@ -62,8 +67,9 @@ func (r *Resource) newK8sEndpoint(endpoint *Endpoint) *corev1.Endpoints {
 		},
 		Subsets: []corev1.EndpointSubset{
 			{
-				Addresses: endpoint.Addresses,
-				Ports:     endpoint.Ports,
+				Addresses:         endpoint.Addresses,
+				NotReadyAddresses: endpoint.NotReadyAddresses,
+				Ports:             endpoint.Ports,
 			},
 		},
 	}
@@ -71,6 +77,22 @@ func (r *Resource) newK8sEndpoint(endpoint *Endpoint) *corev1.Endpoints {
 	return k8sEndpoint
 }
 
+func containsAddress(addresses []corev1.EndpointAddress, ip string) bool {
+	for _, a := range addresses {
+		if a.IP == ip {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsEndpointAddress returns whether the given endpoint contains the
+// given IP, regardless whether it is ready or not.
+func containsEndpointAddress(endpoint *Endpoint, ip string) bool {
+	return containsAddress(endpoint.Addresses, ip) || containsAddress(endpoint.NotReadyAddresses, ip)
+}
+
 func containsIP(ips []string, ip string) bool {
 	for _, foundIP := range ips {
 		if foundIP == ip {
@@ -81,6 +103,22 @@ func containsIP(ips []string, ip string) bool {
 	return false
 }
 
+// filterAddresses returns the given addresses without the ones matching the
+// given drop function and without duplicated IPs.
+func filterAddresses(addresses []corev1.EndpointAddress, drop func(corev1.EndpointAddress) bool) []corev1.EndpointAddress {
+	var result []corev1.EndpointAddress
+
+	for _, a := range addresses {
+		if drop(a) || containsAddress(result, a.IP) {
+			continue
+		}
+
+		result = append(result, a)
+	}
+
+	return result
+}
+
 func getAnnotations(pod corev1.Pod, ipAnnotationName string, serviceAnnotationName string) (ipAnnotationValue string, serviceAnnotationValue string, err error) {
 	ipAnnotationValue, ok := pod.GetAnnotations()[ipAnnotationName]
 	if !ok {
@@ -96,40 +134,116 @@ func getAnnotations(pod corev1.Pod, ipAnnotationName string, serviceAnnotationNa
 	return ipAnnotationValue, serviceAnnotationValue, nil
 }
 
-func ipsToAddresses(ips []string) []corev1.EndpointAddress {
+func isEmptyEndpoint(endpoint *corev1.Endpoints) bool {
+	if endpoint == nil {
+		return true
+	}
+
+	for _, subset := range endpoint.Subsets {
+		if len(subset.Addresses) > 0 || len(subset.NotReadyAddresses) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPodAddress returns whether the given address references the same pod as
+// the given reference.
+func isPodAddress(address corev1.EndpointAddress, ref *corev1.ObjectReference) bool {
+	if address.TargetRef == nil || ref == nil {
+		return false
+	}
+
+	return address.TargetRef.Kind == ref.Kind && address.TargetRef.Namespace == ref.Namespace && address.TargetRef.Name == ref.Name
+}
+
+// parseIPs parses the value of the IP annotation. It may contain multiple comma
+// separated IPs, e.g. the IPv4 and IPv6 address of a dual-stack VM.
+func parseIPs(v string) ([]string, error) {
+	var ips []string
+
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if net.ParseIP(s) == nil {
+			return nil, microerror.Maskf(invalidAnnotationError, "annotation '%s' contains invalid IP '%s'", IPAnnotation, s)
+		}
+		if !containsIP(ips, s) {
+			ips = append(ips, s)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, microerror.Maskf(missingAnnotationError, "empty annotation '%s'", IPAnnotation)
+	}
+
+	return ips, nil
+}
+
+// podAddresses returns the endpoint addresses of the given IPs referencing the
+// given pod.
+func podAddresses(pod corev1.Pod, ips []string) []corev1.EndpointAddress {
 	var addresses []corev1.EndpointAddress
 
 	for _, ip := range ips {
-		k8sAddress := corev1.EndpointAddress{
+		a := corev1.EndpointAddress{
 			IP: ip,
+			TargetRef: &corev1.ObjectReference{
+				Kind:      "Pod",
+				Namespace: pod.GetNamespace(),
+				Name:      pod.GetName(),
+				UID:       pod.GetUID(),
+			},
 		}
-		addresses = append(addresses, k8sAddress)
+		addresses = append(addresses, a)
 	}
 
 	return addresses
 }
 
-func isEmptyEndpoint(endpoint *corev1.Endpoints) bool {
-	if endpoint == nil {
-		return true
+// podRef returns the reference to the pod the addresses of the given endpoint
+// belong to. Nil is returned in case the addresses do not reference a pod.
+func podRef(endpoint *Endpoint) *corev1.ObjectReference {
+	for _, a := range append(endpoint.Addresses, endpoint.NotReadyAddresses...) {
+		if a.TargetRef != nil {
+			return a.TargetRef
+		}
 	}
 
-	for _, subset := range endpoint.Subsets {
-		if len(subset.Addresses) > 0 {
-			return false
-		}
+	return nil
+}
+
+// keepMasterInRotation moves the not ready addresses of the desired master
+// back to the ready addresses in case no other master would be ready anymore.
+// Unhealthy masters are only taken out of rotation during partial failures, so
+// that the API of the tenant cluster stays reachable when all masters fail
+// their health checks, e.g. while the cluster is being created.
+func keepMasterInRotation(desiredEndpoint *Endpoint, addresses, notReadyAddresses []corev1.EndpointAddress) ([]corev1.EndpointAddress, []corev1.EndpointAddress) {
+	if desiredEndpoint.ServiceName != key.MasterID || len(addresses) > 0 {
+		return addresses, notReadyAddresses
 	}
 
-	return true
+	for _, a := range desiredEndpoint.NotReadyAddresses {
+		notReadyAddresses = removeAddress(notReadyAddresses, a.IP)
+		addresses = upsertAddress(addresses, a)
+	}
+
+	return addresses, notReadyAddresses
 }
 
-func removeIP(ips []string, ip string) []string {
-	for index, foundIP := range ips {
-		if foundIP == ip {
-			return append(ips[:index], ips[index+1:]...)
+func removeAddress(addresses []corev1.EndpointAddress, ip string) []corev1.EndpointAddress {
+	var result []corev1.EndpointAddress
+
+	for _, a := range addresses {
+		if a.IP != ip {
+			result = append(result, a)
 		}
 	}
-	return ips
+
+	return result
 }
 
 func toEndpoint(v interface{}) (*Endpoint, error) {
@@ -166,3 +280,17 @@ func toPod(v interface{}) (*corev1.Pod, error) {
 
 	return pod, nil
 }
+
+// upsertAddress replaces the address having the same IP as the given address
+// in place, so that the order of addresses stays stable. The given address is
+// appended in case its IP is not yet contained.
+func upsertAddress(addresses []corev1.EndpointAddress, address corev1.EndpointAddress) []corev1.EndpointAddress {
+	for i, a := range addresses {
+		if a.IP == address.IP {
+			addresses[i] = address
+			return addresses
+		}
+	}
+
+	return append(addresses, address)
+}
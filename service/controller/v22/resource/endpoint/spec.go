@@ -4,15 +4,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-// TODO Addresses and IPs are basically the same and we only need Addresses in
-// fact. The whole thing could be refactored and simplified a little bit later.
-//
-//     https://github.com/giantswarm/giantswarm/issues/5154
-//
+// Endpoint is the state of the endpoints of a tenant cluster service. The
+// addresses of VMs are identified by their IP and reference the pod of the VM
+// they belong to. VMs may have multiple addresses, e.g. an IPv4 and an IPv6
+// address when being dual-stack. Addresses of VMs which are not ready are
+// tracked as not ready addresses instead of being dropped.
 type Endpoint struct {
-	Addresses        []corev1.EndpointAddress
-	Ports            []corev1.EndpointPort
-	IPs              []string
-	ServiceName      string
-	ServiceNamespace string
+	Addresses         []corev1.EndpointAddress
+	NotReadyAddresses []corev1.EndpointAddress
+	Ports             []corev1.EndpointPort
+	ServiceName       string
+	ServiceNamespace  string
 }
@@ -61,14 +61,14 @@ func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desir
 
 	var updateChange *corev1.Endpoints
 	{
-		ips := ipsForUpdateChange(currentEndpoint.IPs, desiredEndpoint.IPs)
+		addresses, notReadyAddresses := addressesForUpdateChange(currentEndpoint, desiredEndpoint)
 
 		e := &Endpoint{
-			Addresses:        ipsToAddresses(ips),
-			IPs:              ips,
-			Ports:            currentEndpoint.Ports,
-			ServiceName:      currentEndpoint.ServiceName,
-			ServiceNamespace: currentEndpoint.ServiceNamespace,
+			Addresses:         addresses,
+			NotReadyAddresses: notReadyAddresses,
+			Ports:             currentEndpoint.Ports,
+			ServiceName:       currentEndpoint.ServiceName,
+			ServiceNamespace:  currentEndpoint.ServiceNamespace,
 		}
 
 		updateChange = r.newK8sEndpoint(e)
@@ -77,22 +77,33 @@ func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desir
 	return updateChange, nil
 }
 
-func ipsForUpdateChange(currentIPs []string, desiredIPs []string) []string {
-	var ips []string
-
-	for _, ip := range currentIPs {
-		ips = append(ips, ip)
+// addressesForUpdateChange merges the desired addresses of the reconciled pod
+// into the current addresses of the endpoint. Addresses of the pod move
+// between ready and not ready addresses along with the pod readiness and
+// addresses the pod does not have anymore are removed. Addresses of other pods
+// are kept. Nothing is updated in case the current endpoint does not have any
+// address yet, because it is created then.
+func addressesForUpdateChange(currentEndpoint, desiredEndpoint *Endpoint) ([]corev1.EndpointAddress, []corev1.EndpointAddress) {
+	if len(currentEndpoint.Addresses) == 0 && len(currentEndpoint.NotReadyAddresses) == 0 {
+		return nil, nil
 	}
 
-	for _, ip := range desiredIPs {
-		if !containsIP(ips, ip) {
-			ips = append(ips, ip)
-		}
+	ref := podRef(desiredEndpoint)
+	isStale := func(a corev1.EndpointAddress) bool {
+		return isPodAddress(a, ref) && !containsEndpointAddress(desiredEndpoint, a.IP)
 	}
 
-	if len(currentIPs) > 0 {
-		return ips
+	addresses := filterAddresses(currentEndpoint.Addresses, isStale)
+	notReadyAddresses := filterAddresses(currentEndpoint.NotReadyAddresses, isStale)
+
+	for _, a := range desiredEndpoint.Addresses {
+		notReadyAddresses = removeAddress(notReadyAddresses, a.IP)
+		addresses = upsertAddress(addresses, a)
+	}
+	for _, a := range desiredEndpoint.NotReadyAddresses {
+		addresses = removeAddress(addresses, a.IP)
+		notReadyAddresses = upsertAddress(notReadyAddresses, a)
 	}
 
-	return nil
+	return keepMasterInRotation(desiredEndpoint, addresses, notReadyAddresses)
 }
@@ -22,8 +22,10 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 	}{
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -34,8 +36,10 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -66,9 +70,13 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 		},
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
-					"1.2.3.4",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
+					{
+						IP: "1.2.3.4",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -79,8 +87,10 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -114,9 +124,13 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 		},
 		{
 			CurrentState: &Endpoint{
-				IPs: []string{
-					"5.5.5.5",
-					"1.2.3.4",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "5.5.5.5",
+					},
+					{
+						IP: "1.2.3.4",
+					},
 				},
 				Ports: []corev1.EndpointPort{
 					{
@@ -127,8 +141,10 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -174,8 +190,10 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 				ServiceNamespace: "TestNamespace",
 			},
 			DesiredState: &Endpoint{
-				IPs: []string{
-					"1.1.1.1",
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP: "1.1.1.1",
+					},
 				},
 				ServiceName:      "TestService",
 				ServiceNamespace: "TestNamespace",
@@ -199,6 +217,218 @@ func Test_Resource_Endpoint_newUpdateChange(t *testing.T) {
 				},
 			},
 		},
+		{
+			CurrentState: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+					{
+						IP:        "2.2.2.2",
+						TargetRef: testPodRef("pod-2"),
+					},
+				},
+				ServiceName:      "worker",
+				ServiceNamespace: "TestNamespace",
+			},
+			DesiredState: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
+				ServiceName:      "worker",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedCreateState: &corev1.Endpoints{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "worker",
+					Namespace: "TestNamespace",
+				},
+				Subsets: []corev1.EndpointSubset{
+					{
+						Addresses: []corev1.EndpointAddress{
+							{
+								IP:        "2.2.2.2",
+								TargetRef: testPodRef("pod-2"),
+							},
+						},
+						NotReadyAddresses: []corev1.EndpointAddress{
+							{
+								IP:        "1.1.1.1",
+								TargetRef: testPodRef("pod-1"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			CurrentState: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.9",
+						TargetRef: testPodRef("pod-1"),
+					},
+					{
+						IP:        "2.2.2.2",
+						TargetRef: testPodRef("pod-2"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			DesiredState: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+					{
+						IP:        "fd00::1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedCreateState: &corev1.Endpoints{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "TestNamespace",
+				},
+				Subsets: []corev1.EndpointSubset{
+					{
+						Addresses: []corev1.EndpointAddress{
+							{
+								IP:        "2.2.2.2",
+								TargetRef: testPodRef("pod-2"),
+							},
+							{
+								IP:        "1.1.1.1",
+								TargetRef: testPodRef("pod-1"),
+							},
+							{
+								IP:        "fd00::1",
+								TargetRef: testPodRef("pod-1"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			CurrentState: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+					{
+						IP:        "2.2.2.2",
+						TargetRef: testPodRef("pod-2"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			DesiredState: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedCreateState: &corev1.Endpoints{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "TestNamespace",
+				},
+				Subsets: []corev1.EndpointSubset{
+					{
+						Addresses: []corev1.EndpointAddress{
+							{
+								IP:        "2.2.2.2",
+								TargetRef: testPodRef("pod-2"),
+							},
+						},
+						NotReadyAddresses: []corev1.EndpointAddress{
+							{
+								IP:        "1.1.1.1",
+								TargetRef: testPodRef("pod-1"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			CurrentState: &Endpoint{
+				Addresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "2.2.2.2",
+						TargetRef: testPodRef("pod-2"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			DesiredState: &Endpoint{
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{
+						IP:        "1.1.1.1",
+						TargetRef: testPodRef("pod-1"),
+					},
+				},
+				ServiceName:      "master",
+				ServiceNamespace: "TestNamespace",
+			},
+			ExpectedCreateState: &corev1.Endpoints{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "TestNamespace",
+				},
+				Subsets: []corev1.EndpointSubset{
+					{
+						Addresses: []corev1.EndpointAddress{
+							{
+								IP:        "1.1.1.1",
+								TargetRef: testPodRef("pod-1"),
+							},
+						},
+						NotReadyAddresses: []corev1.EndpointAddress{
+							{
+								IP:        "2.2.2.2",
+								TargetRef: testPodRef("pod-2"),
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for i, tc := range testCases {
@@ -0,0 +1,214 @@
+package endpointgc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/metric"
+)
+
+func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
+	cr, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	namespace := key.ClusterNamespace(cr)
+
+	var pods []corev1.Pod
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "finding pods of the tenant cluster")
+
+		list, err := r.k8sClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		pods = list.Items
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d pods of the tenant cluster", len(pods)))
+	}
+
+	var readyNodes map[string]bool
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "finding nodes of the host cluster")
+
+		list, err := r.k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		readyNodes = readyNodeNames(list.Items)
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d ready nodes out of %d nodes of the host cluster", len(readyNodes), len(list.Items)))
+	}
+
+	for _, serviceName := range []string{key.MasterID, key.WorkerID} {
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("finding endpoint '%s'", serviceName))
+
+		endpoint, err := r.k8sClient.CoreV1().Endpoints(namespace).Get(serviceName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("did not find endpoint '%s'", serviceName))
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found endpoint '%s'", serviceName))
+
+		removed, changed := collectEndpoint(endpoint, livePodIPs(pods, readyNodes, serviceName))
+		if !changed {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not updating endpoint '%s'", serviceName))
+			continue
+		}
+
+		for _, a := range removed {
+			r.logger.LogCtx(ctx, "level", "info", "message", fmt.Sprintf("removing orphaned address '%s' from endpoint '%s'", a.IP, serviceName))
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updating endpoint '%s'", serviceName))
+
+		_, err = r.k8sClient.CoreV1().Endpoints(namespace).Update(endpoint)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		metric.EndpointAddressesCollected.WithLabelValues(key.ClusterID(cr), serviceName).Add(float64(len(removed)))
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated endpoint '%s'", serviceName))
+	}
+
+	return nil
+}
+
+// collectEndpoint removes all addresses from the given endpoint which are not
+// contained in the given live pod IPs. Ready addresses of pods which are not
+// ready are moved to the not ready addresses. The removed addresses are
+// returned together with the information whether the endpoint changed at all.
+func collectEndpoint(endpoint *corev1.Endpoints, ips map[string]bool) ([]corev1.EndpointAddress, bool) {
+	var changed bool
+	var removed []corev1.EndpointAddress
+
+	var subsets []corev1.EndpointSubset
+
+	for _, subset := range endpoint.Subsets {
+		var addresses []corev1.EndpointAddress
+		var notReadyAddresses []corev1.EndpointAddress
+
+		for _, a := range subset.Addresses {
+			ready, ok := ips[a.IP]
+			if !ok {
+				removed = append(removed, a)
+				changed = true
+			} else if !ready {
+				notReadyAddresses = append(notReadyAddresses, a)
+				changed = true
+			} else {
+				addresses = append(addresses, a)
+			}
+		}
+		for _, a := range subset.NotReadyAddresses {
+			_, ok := ips[a.IP]
+			if !ok {
+				removed = append(removed, a)
+				changed = true
+			} else {
+				notReadyAddresses = append(notReadyAddresses, a)
+			}
+		}
+
+		// Subsets without any address are dropped, just like the Kubernetes API
+		// does when it normalizes endpoints.
+		if len(addresses) == 0 && len(notReadyAddresses) == 0 {
+			continue
+		}
+
+		subset.Addresses = addresses
+		subset.NotReadyAddresses = notReadyAddresses
+		subsets = append(subsets, subset)
+	}
+
+	endpoint.Subsets = subsets
+
+	return removed, changed
+}
+
+// isPodLive returns whether the VM of the given pod may still serve traffic.
+// Pods being deleted are live as long as their containers are running,
+// because masters have to be drained before their addresses are removed. The
+// container statuses of pods scheduled on unreachable host cluster nodes are
+// not updated anymore though. Such pods are not live in case they are not
+// ready or being deleted, because their VMs are most likely gone.
+func isPodLive(pod corev1.Pod, readyNodes map[string]bool) bool {
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+		return false
+	}
+	if len(pod.Status.ContainerStatuses) > 0 && key.ArePodContainersTerminated(&pod) {
+		return false
+	}
+
+	nodeReachable := pod.Spec.NodeName == "" || readyNodes[pod.Spec.NodeName]
+	if !nodeReachable && (!isPodReady(pod) || pod.GetDeletionTimestamp() != nil) {
+		return false
+	}
+
+	return true
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readyNodeNames returns the names of the given nodes having the ready
+// condition set to true.
+func readyNodeNames(nodes []corev1.Node) map[string]bool {
+	names := map[string]bool{}
+
+	for _, n := range nodes {
+		for _, c := range n.Status.Conditions {
+			if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+				names[n.GetName()] = true
+			}
+		}
+	}
+
+	return names
+}
+
+// livePodIPs returns the IPs of the live pods of the given service, mapped to
+// whether their addresses are allowed to be ready. Only workers are taken out
+// of rotation when they are not ready. The endpoint resource decides about
+// masters based on their health and guarantees that one master stays in
+// rotation, so their addresses are left untouched here.
+func livePodIPs(pods []corev1.Pod, readyNodes map[string]bool, serviceName string) map[string]bool {
+	ips := map[string]bool{}
+
+	for _, pod := range pods {
+		if pod.GetAnnotations()[key.AnnotationService] != serviceName || !isPodLive(pod, readyNodes) {
+			continue
+		}
+
+		ready := serviceName != key.WorkerID || isPodReady(pod)
+
+		for _, ip := range strings.Split(pod.GetAnnotations()[key.AnnotationIp], ",") {
+			ip = strings.TrimSpace(ip)
+			if ip == "" {
+				continue
+			}
+			ips[ip] = ready
+		}
+	}
+
+	return ips
+}
@@ -0,0 +1,281 @@
+package endpointgc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_EnsureCreated(t *testing.T) {
+	testCases := []struct {
+		name              string
+		nodes             []*corev1.Node
+		pods              []*corev1.Pod
+		endpoint          *corev1.Endpoints
+		expectedEndpoints *corev1.Endpoints
+	}{
+		{
+			name: "case 0: remove address of a pod which does not exist anymore",
+			pods: []*corev1.Pod{
+				newPod("master-1", "master", "10.0.0.1", true),
+			},
+			endpoint:          newEndpoints("master", []string{"10.0.0.1", "10.0.0.2"}, nil),
+			expectedEndpoints: newEndpoints("master", []string{"10.0.0.1"}, nil),
+		},
+		{
+			name: "case 1: keep addresses of a dual-stack pod",
+			pods: []*corev1.Pod{
+				newPod("master-1", "master", "10.0.0.1,fd00::1", true),
+			},
+			endpoint:          newEndpoints("master", []string{"10.0.0.1", "fd00::1"}, nil),
+			expectedEndpoints: newEndpoints("master", []string{"10.0.0.1", "fd00::1"}, nil),
+		},
+		{
+			name: "case 2: keep address of a master which is not ready",
+			pods: []*corev1.Pod{
+				newPod("master-1", "master", "10.0.0.1", false),
+			},
+			endpoint:          newEndpoints("master", []string{"10.0.0.1"}, nil),
+			expectedEndpoints: newEndpoints("master", []string{"10.0.0.1"}, nil),
+		},
+		{
+			name: "case 3: move address of a worker which is not ready",
+			pods: []*corev1.Pod{
+				newPod("worker-1", "worker", "10.0.0.3", false),
+				newPod("worker-2", "worker", "10.0.0.4", true),
+			},
+			endpoint:          newEndpoints("worker", []string{"10.0.0.3", "10.0.0.4"}, nil),
+			expectedEndpoints: newEndpoints("worker", []string{"10.0.0.4"}, []string{"10.0.0.3"}),
+		},
+		{
+			name: "case 4: remove addresses of a pod which containers are terminated",
+			pods: []*corev1.Pod{
+				newTerminatedPod("worker-1", "worker", "10.0.0.3"),
+			},
+			endpoint: newEndpoints("worker", []string{"10.0.0.3"}, []string{"10.0.0.3"}),
+			expectedEndpoints: &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "worker",
+					Namespace: "al9qy",
+				},
+			},
+		},
+		{
+			name: "case 5: remove address of a pod belonging to another service",
+			pods: []*corev1.Pod{
+				newPod("worker-1", "worker", "10.0.0.1", true),
+			},
+			endpoint: newEndpoints("master", []string{"10.0.0.1"}, nil),
+			expectedEndpoints: &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "al9qy",
+				},
+			},
+		},
+		{
+			name: "case 6: remove address of a pod which is not ready on an unreachable node",
+			nodes: []*corev1.Node{
+				newNode("node-1", corev1.ConditionUnknown),
+			},
+			pods: []*corev1.Pod{
+				onNode(newPod("master-1", "master", "10.0.0.1", false), "node-1"),
+			},
+			endpoint: newEndpoints("master", []string{"10.0.0.1"}, nil),
+			expectedEndpoints: &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "master",
+					Namespace: "al9qy",
+				},
+			},
+		},
+		{
+			name: "case 7: remove address of a pod being deleted on a node which is not ready",
+			nodes: []*corev1.Node{
+				newNode("node-1", corev1.ConditionFalse),
+			},
+			pods: []*corev1.Pod{
+				deleting(onNode(newPod("worker-1", "worker", "10.0.0.3", true), "node-1")),
+			},
+			endpoint: newEndpoints("worker", []string{"10.0.0.3"}, nil),
+			expectedEndpoints: &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "worker",
+					Namespace: "al9qy",
+				},
+			},
+		},
+		{
+			name: "case 8: keep address of a ready pod on a node which is not ready",
+			nodes: []*corev1.Node{
+				newNode("node-1", corev1.ConditionFalse),
+			},
+			pods: []*corev1.Pod{
+				onNode(newPod("worker-1", "worker", "10.0.0.3", true), "node-1"),
+			},
+			endpoint:          newEndpoints("worker", []string{"10.0.0.3"}, nil),
+			expectedEndpoints: newEndpoints("worker", []string{"10.0.0.3"}, nil),
+		},
+		{
+			name: "case 9: keep address of a master being deleted on a ready node",
+			nodes: []*corev1.Node{
+				newNode("node-1", corev1.ConditionTrue),
+			},
+			pods: []*corev1.Pod{
+				deleting(onNode(newPod("master-1", "master", "10.0.0.1", false), "node-1")),
+			},
+			endpoint:          newEndpoints("master", []string{"10.0.0.1"}, nil),
+			expectedEndpoints: newEndpoints("master", []string{"10.0.0.1"}, nil),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []runtime.Object{tc.endpoint}
+			for _, n := range tc.nodes {
+				objects = append(objects, n)
+			}
+			for _, p := range tc.pods {
+				objects = append(objects, p)
+			}
+			k8sClient := fake.NewSimpleClientset(objects...)
+
+			c := Config{
+				K8sClient: k8sClient,
+				Logger:    microloggertest.New(),
+			}
+			r, err := New(c)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			cr := &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						ID: "al9qy",
+					},
+				},
+			}
+
+			err = r.EnsureCreated(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			endpoints, err := k8sClient.CoreV1().Endpoints("al9qy").Get(tc.endpoint.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			if !cmp.Equal(endpoints, tc.expectedEndpoints) {
+				t.Fatalf("want matching endpoints \n %s", cmp.Diff(endpoints, tc.expectedEndpoints))
+			}
+		})
+	}
+}
+
+func newAddresses(ips []string) []corev1.EndpointAddress {
+	var addresses []corev1.EndpointAddress
+
+	for _, ip := range ips {
+		addresses = append(addresses, corev1.EndpointAddress{IP: ip})
+	}
+
+	return addresses
+}
+
+func newEndpoints(name string, ips []string, notReadyIPs []string) *corev1.Endpoints {
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "al9qy",
+		},
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses:         newAddresses(ips),
+				NotReadyAddresses: newAddresses(notReadyIPs),
+				Ports: []corev1.EndpointPort{
+					{
+						Port: 443,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newPod(name, service, ips string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "al9qy",
+			Annotations: map[string]string{
+				"endpoint.kvm.giantswarm.io/ip":      ips,
+				"endpoint.kvm.giantswarm.io/service": service,
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{
+					Type:   corev1.PodReady,
+					Status: status,
+				},
+			},
+		},
+	}
+}
+
+func newTerminatedPod(name, service, ips string) *corev1.Pod {
+	pod := newPod(name, service, ips, false)
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{},
+			},
+		},
+	}
+
+	return pod
+}
+
+func newNode(name string, ready corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: ready,
+				},
+			},
+		},
+	}
+}
+
+func onNode(pod *corev1.Pod, nodeName string) *corev1.Pod {
+	pod.Spec.NodeName = nodeName
+
+	return pod
+}
+
+func deleting(pod *corev1.Pod) *corev1.Pod {
+	now := metav1.Now()
+	pod.SetDeletionTimestamp(&now)
+
+	return pod
+}
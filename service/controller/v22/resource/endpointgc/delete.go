@@ -0,0 +1,11 @@
+package endpointgc
+
+import (
+	"context"
+)
+
+// EnsureDeleted is a no-op. The endpoints are deleted together with the
+// namespace of the tenant cluster.
+func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package endpointgc
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	Name = "endpointgcv22"
+)
+
+type Config struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+}
+
+// Resource garbage collects addresses of the master and worker endpoints of a
+// tenant cluster which do not belong to any live VM pod anymore. The endpoint
+// resource of the drainer controller removes addresses when it reconciles the
+// deletion of the owning pod. Pod deletions which happen while the operator is
+// down are never reconciled though, e.g. when a VM host crashes. The
+// addresses of such pods would then stay in the endpoints forever. The
+// resource is executed on every resync of the cluster controller, so orphaned
+// addresses are removed periodically per tenant cluster namespace.
+type Resource struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+}
+
+func New(config Config) (*Resource, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	r := &Resource{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+	}
+
+	return r, nil
+}
+
+func (r *Resource) Name() string {
+	return Name
+}
@@ -0,0 +1,289 @@
+package etcdbackup
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/certs"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger/loggermeta"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+const (
+	// backupTimeout is the maximum duration a single etcd backup may take to
+	// stream the snapshot from the tenant cluster into the backup target.
+	backupTimeout = time.Hour
+)
+
+func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
+	cr, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.ensureRestore(ctx, cr)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if r.interval == 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "not taking etcd snapshot because etcd backups are disabled")
+		return nil
+	}
+
+	now := time.Now()
+
+	{
+		lastBackup, ok := lastBackupTime(cr)
+		if ok && now.Sub(lastBackup) < r.interval {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("etcd was backed up at %s", lastBackup.UTC().Format(time.RFC3339)))
+			r.logger.LogCtx(ctx, "level", "debug", "message", "not taking etcd snapshot")
+			return nil
+		}
+	}
+
+	// Snapshots of large etcd clusters take a while to be streamed into the
+	// backup target. Backups therefore run in the background, so that they do
+	// not block the reconciliation of the cluster. Only one backup per cluster
+	// runs at a time.
+	{
+		if !r.startBackup(key.ClusterID(cr)) {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "etcd backup is already in progress")
+			r.logger.LogCtx(ctx, "level", "debug", "message", "not taking etcd snapshot")
+			return nil
+		}
+
+		go r.backup(newBackupContext(ctx), cr, now)
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "started etcd backup in the background")
+	}
+
+	return nil
+}
+
+// backup writes a snapshot of the etcd cluster of the given custom object to
+// the backup target and records the result in the status of the custom object.
+// Failed backups are retried in the next reconciliation loop.
+func (r *Resource) backup(ctx context.Context, cr v1alpha1.KVMConfig, now time.Time) {
+	defer r.finishBackup(key.ClusterID(cr))
+
+	ctx, cancel := context.WithTimeout(ctx, backupTimeout)
+	defer cancel()
+
+	err := r.writeSnapshot(ctx, cr, now)
+	if err != nil {
+		r.logger.LogCtx(ctx, "level", "warning", "message", "failed backing up etcd", "stack", fmt.Sprintf("%#v", err))
+
+		// The failure is recorded in the status so that failing backups are
+		// visible.
+		_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+			status.Cluster.Resources = setCondition(status.Cluster.Resources, ConditionBackupFailed, now)
+		})
+		if err != nil {
+			r.logger.LogCtx(ctx, "level", "error", "message", "failed updating status with failed etcd backup", "stack", fmt.Sprintf("%#v", err))
+		}
+
+		return
+	}
+
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updating status with last etcd backup")
+
+		_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+			status.Cluster.Resources = setCondition(status.Cluster.Resources, ConditionBackedUp, now)
+			status.Cluster.Resources = removeCondition(status.Cluster.Resources, ConditionBackupFailed)
+		})
+		if err != nil {
+			r.logger.LogCtx(ctx, "level", "error", "message", "failed updating status with last etcd backup", "stack", fmt.Sprintf("%#v", err))
+			return
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updated status with last etcd backup")
+	}
+}
+
+// writeSnapshot streams a snapshot of the etcd cluster of the given custom
+// object into the backup target.
+func (r *Resource) writeSnapshot(ctx context.Context, cr v1alpha1.KVMConfig, now time.Time) error {
+	var client *http.Client
+	{
+		cluster, err := r.certsSearcher.SearchCluster(key.ClusterID(cr))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		client, err = newHTTPClient(cluster.EtcdServer)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "taking etcd snapshot")
+
+	snapshot, size, err := etcdbackup.Snapshot(ctx, client, "https://"+key.ClusterEtcdDomain(cr))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer snapshot.Close()
+
+	name := key.EtcdSnapshotName(cr, now)
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("writing etcd snapshot '%s' of %d bytes to backup target", name, size))
+
+	err = r.target.Put(ctx, name, snapshot, size)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("wrote etcd snapshot '%s' to backup target", name))
+
+	return nil
+}
+
+// startBackup marks a backup of the given cluster as running. It returns false
+// in case a backup of the cluster is already running.
+func (r *Resource) startBackup(clusterID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.running[clusterID] {
+		return false
+	}
+	r.running[clusterID] = true
+	r.backups.Add(1)
+
+	return true
+}
+
+func (r *Resource) finishBackup(clusterID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.running, clusterID)
+	r.backups.Done()
+}
+
+// newBackupContext returns a context for backups outliving the reconciliation
+// loop of the given context. Only the logger meta of the given context is
+// kept, so that logs of backups can still be related to their clusters.
+func newBackupContext(ctx context.Context) context.Context {
+	backupCtx := context.Background()
+
+	meta, ok := loggermeta.FromContext(ctx)
+	if ok {
+		m := loggermeta.New()
+		for k, v := range meta.KeyVals {
+			m.KeyVals[k] = v
+		}
+		backupCtx = loggermeta.NewContext(backupCtx, m)
+	}
+
+	return backupCtx
+}
+
+// lastBackupTime returns the time of the last successful etcd backup recorded
+// in the status of the given custom object. The returned boolean is false when
+// the cluster was never backed up.
+func lastBackupTime(cr v1alpha1.KVMConfig) (time.Time, bool) {
+	for _, r := range cr.Status.Cluster.Resources {
+		if r.Name != Name {
+			continue
+		}
+
+		for _, c := range r.Conditions {
+			if c.Type == ConditionBackedUp && c.Status == ConditionStatusTrue {
+				return c.LastTransitionTime.Time, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// setCondition sets the condition of the given type of this resource to true
+// at the given time in the given resource statuses. The resource status of this
+// resource is added in case it does not exist yet.
+func setCondition(resources []v1alpha1.StatusClusterResource, conditionType string, t time.Time) []v1alpha1.StatusClusterResource {
+	condition := v1alpha1.StatusClusterResourceCondition{
+		LastTransitionTime: v1alpha1.DeepCopyTime{Time: t},
+		Status:             ConditionStatusTrue,
+		Type:               conditionType,
+	}
+
+	for i, r := range resources {
+		if r.Name != Name {
+			continue
+		}
+
+		for j, c := range r.Conditions {
+			if c.Type == conditionType {
+				resources[i].Conditions[j] = condition
+				return resources
+			}
+		}
+
+		resources[i].Conditions = append(resources[i].Conditions, condition)
+		return resources
+	}
+
+	resource := v1alpha1.StatusClusterResource{
+		Conditions: []v1alpha1.StatusClusterResourceCondition{
+			condition,
+		},
+		Name: Name,
+	}
+
+	return append(resources, resource)
+}
+
+// removeCondition removes the condition of the given type of this resource
+// from the given resource statuses.
+func removeCondition(resources []v1alpha1.StatusClusterResource, conditionType string) []v1alpha1.StatusClusterResource {
+	for i, r := range resources {
+		if r.Name != Name {
+			continue
+		}
+
+		var conditions []v1alpha1.StatusClusterResourceCondition
+		for _, c := range r.Conditions {
+			if c.Type != conditionType {
+				conditions = append(conditions, c)
+			}
+		}
+		resources[i].Conditions = conditions
+	}
+
+	return resources
+}
+
+func newHTTPClient(etcdTLS certs.TLS) (*http.Client, error) {
+	certificate, err := tls.X509KeyPair(etcdTLS.Crt, etcdTLS.Key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(etcdTLS.CA) {
+		return nil, microerror.Maskf(executionFailedError, "failed to parse etcd CA")
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{certificate},
+				RootCAs:      rootCAs,
+			},
+		},
+	}
+
+	return client, nil
+}
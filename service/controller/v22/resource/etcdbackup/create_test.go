@@ -0,0 +1,211 @@
+package etcdbackup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/certs/certstest"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger/microloggertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+var certsNotFoundError = &microerror.Error{
+	Kind: "certsNotFoundError",
+}
+
+func Test_Resource_EtcdBackup_EnsureCreated(t *testing.T) {
+	testCases := []struct {
+		name               string
+		lastBackup         *time.Time
+		expectedBackupFail bool
+	}{
+		{
+			name:               "case 0: recent backup does not take a snapshot",
+			lastBackup:         timePtr(time.Now().Add(-10 * time.Minute)),
+			expectedBackupFail: false,
+		},
+		{
+			name:               "case 1: outdated backup takes a snapshot",
+			lastBackup:         timePtr(time.Now().Add(-2 * time.Hour)),
+			expectedBackupFail: true,
+		},
+		{
+			name:               "case 2: missing backup takes a snapshot",
+			lastBackup:         nil,
+			expectedBackupFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "etcdbackup")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			cr := &v1alpha1.KVMConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "al9qy",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						ID: "al9qy",
+					},
+				},
+			}
+			if tc.lastBackup != nil {
+				cr.Status.Cluster.Resources = setCondition(nil, ConditionBackedUp, *tc.lastBackup)
+			}
+
+			var target etcdbackup.Target
+			{
+				c := etcdbackup.FilesystemConfig{
+					Dir: dir,
+				}
+
+				target, err = etcdbackup.NewFilesystem(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			g8sClient := statuspatchtest.NewClientset(cr)
+
+			var r *Resource
+			{
+				c := Config{
+					// The certs searcher fails so that tests can tell whether
+					// a snapshot was attempted without reaching out to etcd.
+					CertsSearcher: certstest.NewSearcher(certstest.Config{ClusterError: certsNotFoundError}),
+					G8sClient:     g8sClient,
+					K8sClient:     fake.NewSimpleClientset(),
+					Logger:        microloggertest.New(),
+					Target:        target,
+
+					Interval: time.Hour,
+				}
+
+				r, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = r.EnsureCreated(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			// Backups run in the background and record failures in the
+			// status.
+			r.backups.Wait()
+
+			updated, err := g8sClient.ProviderV1alpha1().KVMConfigs(cr.Namespace).Get(cr.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			backupFailed := false
+			for _, r := range updated.Status.Cluster.Resources {
+				for _, c := range r.Conditions {
+					if r.Name == Name && c.Type == ConditionBackupFailed {
+						backupFailed = true
+					}
+				}
+			}
+			if backupFailed != tc.expectedBackupFail {
+				t.Fatalf("expected backup failed %t got %t", tc.expectedBackupFail, backupFailed)
+			}
+		})
+	}
+}
+
+func Test_setCondition(t *testing.T) {
+	first := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	resources := []v1alpha1.StatusClusterResource{
+		{
+			Name: "otherv22",
+		},
+	}
+
+	cr := v1alpha1.KVMConfig{}
+
+	_, ok := lastBackupTime(cr)
+	if ok {
+		t.Fatalf("expected no last backup")
+	}
+
+	cr.Status.Cluster.Resources = setCondition(resources, ConditionBackedUp, first)
+	cr.Status.Cluster.Resources = setCondition(cr.Status.Cluster.Resources, ConditionBackedUp, second)
+
+	if len(cr.Status.Cluster.Resources) != 2 {
+		t.Fatalf("expected 2 resource statuses got %d", len(cr.Status.Cluster.Resources))
+	}
+	if len(cr.Status.Cluster.Resources[1].Conditions) != 1 {
+		t.Fatalf("expected 1 condition got %d", len(cr.Status.Cluster.Resources[1].Conditions))
+	}
+
+	lastBackup, ok := lastBackupTime(cr)
+	if !ok {
+		t.Fatalf("expected last backup")
+	}
+	if !lastBackup.Equal(second) {
+		t.Fatalf("expected last backup %s got %s", second, lastBackup)
+	}
+
+	cr.Status.Cluster.Resources = setCondition(cr.Status.Cluster.Resources, ConditionBackupFailed, second)
+	if len(cr.Status.Cluster.Resources[1].Conditions) != 2 {
+		t.Fatalf("expected 2 conditions got %d", len(cr.Status.Cluster.Resources[1].Conditions))
+	}
+
+	cr.Status.Cluster.Resources = removeCondition(cr.Status.Cluster.Resources, ConditionBackupFailed)
+	if len(cr.Status.Cluster.Resources[1].Conditions) != 1 {
+		t.Fatalf("expected 1 condition got %d", len(cr.Status.Cluster.Resources[1].Conditions))
+	}
+	if cr.Status.Cluster.Resources[1].Conditions[0].Type != ConditionBackedUp {
+		t.Fatalf("expected condition %s got %s", ConditionBackedUp, cr.Status.Cluster.Resources[1].Conditions[0].Type)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func Test_Resource_EtcdBackup_startBackup(t *testing.T) {
+	r := &Resource{
+		running: map[string]bool{},
+	}
+
+	if !r.startBackup("al9qy") {
+		t.Fatalf("expected first backup of cluster to start")
+	}
+	if r.startBackup("al9qy") {
+		t.Fatalf("expected second backup of cluster not to start while the first is running")
+	}
+	if !r.startBackup("p1l6x") {
+		t.Fatalf("expected backup of other cluster to start")
+	}
+
+	r.finishBackup("al9qy")
+	r.finishBackup("p1l6x")
+
+	if !r.startBackup("al9qy") {
+		t.Fatalf("expected backup of cluster to start after the first finished")
+	}
+	r.finishBackup("al9qy")
+
+	r.backups.Wait()
+}
@@ -0,0 +1,11 @@
+package etcdbackup
+
+import (
+	"context"
+)
+
+// EnsureDeleted does nothing. Snapshots are kept in the backup target when the
+// tenant cluster is deleted so it can be restored later on.
+func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
+	return nil
+}
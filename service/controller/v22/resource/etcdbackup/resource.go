@@ -0,0 +1,128 @@
+package etcdbackup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/certs"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+const (
+	Name = "etcdbackupv22"
+)
+
+const (
+	// ConditionBackedUp is the type of the resource condition in the
+	// KVMConfig status which tracks the last successful etcd backup of the
+	// cluster. Its last transition time is the time the snapshot was taken.
+	ConditionBackedUp = "BackedUp"
+	// ConditionBackupFailed is the type of the resource condition in the
+	// KVMConfig status which tracks failed etcd backups of the cluster. Its
+	// last transition time is the time of the last failure. It is removed
+	// once a backup succeeds.
+	ConditionBackupFailed = "BackupFailed"
+	// ConditionStatusTrue is the status of the resource conditions.
+	ConditionStatusTrue = "True"
+)
+
+const (
+	// annotationRestoreURLGeneratedAt is the annotation of the etcd restore
+	// secret holding the time its snapshot URL was generated.
+	annotationRestoreURLGeneratedAt = "kvm-operator.giantswarm.io/etcd-restore-url-generated-at"
+)
+
+type Config struct {
+	CertsSearcher certs.Interface
+	G8sClient     versioned.Interface
+	K8sClient     kubernetes.Interface
+	Logger        micrologger.Logger
+	Target        etcdbackup.Target
+
+	// Interval is the minimum duration between two etcd snapshots of the same
+	// tenant cluster. Zero disables etcd backups while still allowing
+	// restores.
+	Interval time.Duration
+}
+
+// Resource takes periodic etcd snapshots of tenant cluster masters in the
+// background, streams them into the configured backup target and records the
+// last successful backup in the status of the KVMConfig. It also maintains
+// requested etcd restores of new tenant clusters. See ensureRestore.
+type Resource struct {
+	certsSearcher certs.Interface
+	g8sClient     versioned.Interface
+	k8sClient     kubernetes.Interface
+	logger        micrologger.Logger
+	statusPatcher *statuspatch.Patcher
+	target        etcdbackup.Target
+
+	interval time.Duration
+
+	// backups tracks the backups running in the background. running holds the
+	// IDs of the clusters being backed up.
+	backups sync.WaitGroup
+	mutex   sync.Mutex
+	running map[string]bool
+}
+
+func New(config Config) (*Resource, error) {
+	if config.CertsSearcher == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CertsSearcher must not be empty", config)
+	}
+	if config.G8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.G8sClient must not be empty", config)
+	}
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Target == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Target must not be empty", config)
+	}
+
+	if config.Interval < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Interval must not be negative", config)
+	}
+
+	var statusPatcher *statuspatch.Patcher
+	{
+		c := statuspatch.Config{
+			G8sClient: config.G8sClient,
+			Logger:    config.Logger,
+		}
+
+		var err error
+		statusPatcher, err = statuspatch.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	r := &Resource{
+		certsSearcher: config.CertsSearcher,
+		g8sClient:     config.G8sClient,
+		k8sClient:     config.K8sClient,
+		logger:        config.Logger,
+		statusPatcher: statusPatcher,
+		target:        config.Target,
+
+		interval: config.Interval,
+
+		running: map[string]bool{},
+	}
+
+	return r, nil
+}
+
+func (r *Resource) Name() string {
+	return Name
+}
@@ -0,0 +1,192 @@
+package etcdbackup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+const (
+	// restoreURLExpiry is the validity of presigned snapshot URLs written to
+	// the etcd restore secret. It matches the maximum expiry of S3.
+	restoreURLExpiry = 7 * 24 * time.Hour
+	// restoreURLRefresh is the age after which the snapshot URL in the etcd
+	// restore secret is replaced by a new one. It leaves the etcd restore init
+	// container plenty of time to download the snapshot with a valid URL.
+	restoreURLRefresh = 24 * time.Hour
+)
+
+// ensureRestore maintains the etcd restore of the given cluster. While the
+// restore is pending, the etcd restore secret holds a valid snapshot URL for
+// the etcd restore init containers of the masters. Once all masters restored
+// their etcd data, the completion is recorded in the status, which removes the
+// init containers from the master deployments, and the secret is deleted.
+func (r *Resource) ensureRestore(ctx context.Context, cr v1alpha1.KVMConfig) error {
+	if !key.EtcdRestorePending(cr) {
+		err := r.deleteRestoreSecret(ctx, cr)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	restored, err := r.mastersRestored(cr)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if restored {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updating status with completed etcd restore")
+
+		_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+			status.Cluster.Resources = setCondition(status.Cluster.Resources, key.ConditionEtcdRestored, time.Now())
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updated status with completed etcd restore")
+
+		err = r.deleteRestoreSecret(ctx, cr)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	err = r.ensureRestoreSecret(ctx, cr, time.Now())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// mastersRestored returns whether the etcd restore init containers of all
+// master pods of the given cluster finished successfully.
+func (r *Resource) mastersRestored(cr v1alpha1.KVMConfig) (bool, error) {
+	o := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", key.LabelApp, key.MasterID),
+	}
+
+	list, err := r.k8sClient.CoreV1().Pods(key.ClusterNamespace(cr)).List(o)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if len(list.Items) == 0 {
+		return false, nil
+	}
+
+	for _, p := range list.Items {
+		if !isRestoreFinished(p) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// ensureRestoreSecret creates the etcd restore secret or refreshes its snapshot
+// URL in case the URL was generated more than restoreURLRefresh ago.
+func (r *Resource) ensureRestoreSecret(ctx context.Context, cr v1alpha1.KVMConfig, now time.Time) error {
+	namespace := key.ClusterNamespace(cr)
+
+	current, err := r.k8sClient.CoreV1().Secrets(namespace).Get(key.EtcdRestoreSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		current = nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if current != nil {
+		generatedAt, err := time.Parse(time.RFC3339, current.GetAnnotations()[annotationRestoreURLGeneratedAt])
+		if err == nil && now.Sub(generatedAt) < restoreURLRefresh {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "etcd restore secret is up to date")
+			return nil
+		}
+	}
+
+	url, err := r.target.URL(key.EtcdRestoreSnapshot(cr), restoreURLExpiry)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.EtcdRestoreSecretName,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				annotationRestoreURLGeneratedAt: now.UTC().Format(time.RFC3339),
+			},
+			Labels: map[string]string{
+				key.LabelCluster:   key.ClusterID(cr),
+				key.LabelManagedBy: key.OperatorName,
+			},
+		},
+		StringData: map[string]string{
+			key.EtcdRestoreSecretURLKey: url,
+		},
+	}
+
+	if current == nil {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "creating etcd restore secret")
+
+		_, err = r.k8sClient.CoreV1().Secrets(namespace).Create(secret)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "created etcd restore secret")
+	} else {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "refreshing snapshot URL of etcd restore secret")
+
+		secret.ResourceVersion = current.ResourceVersion
+
+		_, err = r.k8sClient.CoreV1().Secrets(namespace).Update(secret)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "refreshed snapshot URL of etcd restore secret")
+	}
+
+	return nil
+}
+
+func (r *Resource) deleteRestoreSecret(ctx context.Context, cr v1alpha1.KVMConfig) error {
+	err := r.k8sClient.CoreV1().Secrets(key.ClusterNamespace(cr)).Delete(key.EtcdRestoreSecretName, &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "deleted etcd restore secret")
+
+	return nil
+}
+
+// isRestoreFinished returns whether the etcd restore init container of the
+// given pod terminated successfully.
+func isRestoreFinished(pod corev1.Pod) bool {
+	for _, s := range pod.Status.InitContainerStatuses {
+		if s.Name != key.ContainerNameEtcdRestore {
+			continue
+		}
+
+		return s.State.Terminated != nil && s.State.Terminated.ExitCode == 0
+	}
+
+	return false
+}
@@ -0,0 +1,210 @@
+package etcdbackup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/certs/certstest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+func Test_Resource_EtcdBackup_ensureRestore(t *testing.T) {
+	newCustomObject := func(snapshot string, status v1alpha1.StatusCluster) *v1alpha1.KVMConfig {
+		cr := &v1alpha1.KVMConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "al9qy",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.KVMConfigSpec{
+				Cluster: v1alpha1.Cluster{
+					ID: "al9qy",
+				},
+			},
+		}
+		if snapshot != "" {
+			cr.Annotations = map[string]string{
+				key.AnnotationEtcdRestoreSnapshot: snapshot,
+			}
+		}
+		cr.Status.Cluster = status
+		return cr
+	}
+
+	newMasterPod := func(exitCode *int32) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "master-1-5d8f9c7b6-x2v4k",
+				Namespace: "al9qy",
+				Labels: map[string]string{
+					key.LabelApp: key.MasterID,
+				},
+			},
+		}
+		s := corev1.ContainerStatus{
+			Name: key.ContainerNameEtcdRestore,
+		}
+		if exitCode != nil {
+			s.State.Terminated = &corev1.ContainerStateTerminated{ExitCode: *exitCode}
+		}
+		p.Status.InitContainerStatuses = []corev1.ContainerStatus{s}
+		return p
+	}
+
+	newSecret := func(generatedAt time.Time) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.EtcdRestoreSecretName,
+				Namespace: "al9qy",
+				Annotations: map[string]string{
+					annotationRestoreURLGeneratedAt: generatedAt.UTC().Format(time.RFC3339),
+				},
+			},
+			StringData: map[string]string{
+				key.EtcdRestoreSecretURLKey: "outdated",
+			},
+		}
+	}
+
+	created := v1alpha1.StatusCluster{
+		Conditions: []v1alpha1.StatusClusterCondition{
+			{
+				Status: v1alpha1.StatusClusterStatusTrue,
+				Type:   v1alpha1.StatusClusterTypeCreated,
+			},
+		},
+	}
+
+	exitCode := func(c int32) *int32 { return &c }
+
+	testCases := []struct {
+		name             string
+		customObject     *v1alpha1.KVMConfig
+		controlPlane     []runtime.Object
+		expectedURL      string
+		expectedRestored bool
+	}{
+		{
+			name:         "case 0: no restore requested deletes the secret",
+			customObject: newCustomObject("", v1alpha1.StatusCluster{}),
+			controlPlane: []runtime.Object{newSecret(time.Now())},
+		},
+		{
+			name:         "case 1: pending restore creates the secret",
+			customObject: newCustomObject("al9qy/20190501-120000.db", v1alpha1.StatusCluster{}),
+			controlPlane: []runtime.Object{newMasterPod(nil)},
+			expectedURL:  "file:///var/lib/etcd-backups/al9qy/20190501-120000.db",
+		},
+		{
+			name:         "case 2: outdated snapshot URL is refreshed",
+			customObject: newCustomObject("al9qy/20190501-120000.db", v1alpha1.StatusCluster{}),
+			controlPlane: []runtime.Object{newSecret(time.Now().Add(-2 * restoreURLRefresh))},
+			expectedURL:  "file:///var/lib/etcd-backups/al9qy/20190501-120000.db",
+		},
+		{
+			name:         "case 3: recent snapshot URL is kept",
+			customObject: newCustomObject("al9qy/20190501-120000.db", v1alpha1.StatusCluster{}),
+			controlPlane: []runtime.Object{newSecret(time.Now())},
+			expectedURL:  "outdated",
+		},
+		{
+			name:         "case 4: failed restore is retried",
+			customObject: newCustomObject("al9qy/20190501-120000.db", v1alpha1.StatusCluster{}),
+			controlPlane: []runtime.Object{newMasterPod(exitCode(1))},
+			expectedURL:  "file:///var/lib/etcd-backups/al9qy/20190501-120000.db",
+		},
+		{
+			name:             "case 5: finished restore is recorded and deletes the secret",
+			customObject:     newCustomObject("al9qy/20190501-120000.db", v1alpha1.StatusCluster{}),
+			controlPlane:     []runtime.Object{newMasterPod(exitCode(0)), newSecret(time.Now())},
+			expectedRestored: true,
+		},
+		{
+			name:         "case 6: restore of created cluster deletes the secret",
+			customObject: newCustomObject("al9qy/20190501-120000.db", created),
+			controlPlane: []runtime.Object{newSecret(time.Now())},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			var target etcdbackup.Target
+			{
+				c := etcdbackup.FilesystemConfig{
+					Dir: "/var/lib/etcd-backups",
+				}
+
+				target, err = etcdbackup.NewFilesystem(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			g8sClient := statuspatchtest.NewClientset(tc.customObject)
+			k8sClient := fake.NewSimpleClientset(tc.controlPlane...)
+
+			var r *Resource
+			{
+				c := Config{
+					CertsSearcher: certstest.NewSearcher(certstest.Config{}),
+					G8sClient:     g8sClient,
+					K8sClient:     k8sClient,
+					Logger:        microloggertest.New(),
+					Target:        target,
+				}
+
+				r, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = r.ensureRestore(context.Background(), *tc.customObject)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			secret, err := k8sClient.CoreV1().Secrets("al9qy").Get(key.EtcdRestoreSecretName, metav1.GetOptions{})
+			if tc.expectedURL == "" {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected etcd restore secret to be deleted")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if secret.StringData[key.EtcdRestoreSecretURLKey] != tc.expectedURL {
+					t.Fatalf("expected snapshot URL %q got %q", tc.expectedURL, secret.StringData[key.EtcdRestoreSecretURLKey])
+				}
+			}
+
+			cr, err := g8sClient.ProviderV1alpha1().KVMConfigs("default").Get("al9qy", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var restored bool
+			for _, r := range cr.Status.Cluster.Resources {
+				for _, c := range r.Conditions {
+					if c.Type == key.ConditionEtcdRestored {
+						restored = true
+					}
+				}
+			}
+			if restored != tc.expectedRestored {
+				t.Fatalf("expected restored condition %t got %t", tc.expectedRestored, restored)
+			}
+		})
+	}
+}
@@ -3,11 +3,13 @@ package node
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/giantswarm/errors/guest"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/tenantcluster"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/kubernetes/pkg/api/v1/node"
@@ -74,11 +76,25 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 		pods = list.Items
 	}
 
+	// Fetch the list of deployments of the control plane. A pod might be missing
+	// for a moment, e.g. while its deployment recreates it. Nodes of existing
+	// deployments must not be deleted as long as these deployments have no pod.
+	var deployments []v1beta1.Deployment
+	{
+		n := key.ClusterID(customObject)
+		list, err := r.k8sClient.Extensions().Deployments(n).List(metav1.ListOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		deployments = list.Items
+	}
+
 	// Iterate through all nodes and compare them against the pods of the control
 	// plane. Nodes being in a Ready state are fine. Nodes that belong to control
-	// plane pods are also ok. If a tenant cluster node does not have an
-	// associated control plane pod, we delete it from the tenant cluster's
-	// Kubernetes API.
+	// plane pods are also ok, as well as nodes of deployments currently having no
+	// pod at all. If a tenant cluster node does not have an associated control
+	// plane pod, we delete it from the tenant cluster's Kubernetes API. This
+	// includes nodes left behind by replaced pods of existing deployments.
 	for _, n := range nodes {
 		if node.IsNodeReady(&n) {
 			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not deleting node '%s' because it is in state 'Ready'", n.GetName()))
@@ -95,6 +111,11 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 			continue
 		}
 
+		if isNodeOfDeploymentWithoutPod(deployments, pods, n) {
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("not deleting node '%s' because its control plane deployment does exist without pod", n.GetName()))
+			continue
+		}
+
 		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("deleting node '%s' in the tenant cluster's Kubernetes API", n.GetName()))
 
 		err = k8sClient.CoreV1().Nodes().Delete(n.GetName(), nil)
@@ -118,6 +139,33 @@ func doesNodeExistAsPod(pods []corev1.Pod, n corev1.Node) bool {
 	return false
 }
 
+// isNodeOfDeploymentWithoutPod checks whether the given node belongs to one of
+// the given deployments while this deployment has none of the given pods. Nodes
+// are named after the pods running their VMs, which in turn are prefixed with
+// the name of their deployment. Every pod recreated by a deployment gets a new
+// name, so once a deployment has a pod, nodes of its former pods are stale.
+func isNodeOfDeploymentWithoutPod(deployments []v1beta1.Deployment, pods []corev1.Pod, n corev1.Node) bool {
+	for _, d := range deployments {
+		if !isOfDeployment(d, n.GetName()) {
+			continue
+		}
+
+		for _, p := range pods {
+			if isOfDeployment(d, p.GetName()) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func isOfDeployment(d v1beta1.Deployment, name string) bool {
+	return d.GetName() == name || strings.HasPrefix(name, d.GetName()+"-")
+}
+
 func isPodOfNodeRunning(pods []corev1.Pod, n corev1.Node) bool {
 	for _, p := range pods {
 		if p.GetName() == n.GetName() {
@@ -0,0 +1,188 @@
+package node
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/helmclient"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Resource_Node_EnsureCreated(t *testing.T) {
+	testCases := []struct {
+		name          string
+		controlPlane  []runtime.Object
+		tenantCluster []runtime.Object
+		expectedNodes []string
+	}{
+		{
+			name:          "case 0: ready node without control plane pod is kept",
+			controlPlane:  nil,
+			tenantCluster: []runtime.Object{newNode("worker-1", corev1.ConditionTrue)},
+			expectedNodes: []string{"worker-1"},
+		},
+		{
+			name:          "case 1: not ready node with control plane pod is kept",
+			controlPlane:  []runtime.Object{newPod("worker-1", corev1.PodPending)},
+			tenantCluster: []runtime.Object{newNode("worker-1", corev1.ConditionFalse)},
+			expectedNodes: []string{"worker-1"},
+		},
+		{
+			name:         "case 2: not ready node without control plane pod is deleted",
+			controlPlane: []runtime.Object{newPod("worker-1", corev1.PodRunning)},
+			tenantCluster: []runtime.Object{
+				newNode("worker-1", corev1.ConditionTrue),
+				newNode("worker-2", corev1.ConditionFalse),
+			},
+			expectedNodes: []string{"worker-1"},
+		},
+		{
+			name:         "case 3: not ready nodes of removed workers are deleted",
+			controlPlane: []runtime.Object{newPod("master-1", corev1.PodRunning)},
+			tenantCluster: []runtime.Object{
+				newNode("master-1", corev1.ConditionTrue),
+				newNode("worker-1", corev1.ConditionFalse),
+				newNode("worker-2", corev1.ConditionUnknown),
+			},
+			expectedNodes: []string{"master-1"},
+		},
+		{
+			name: "case 4: not ready node of replaced pod of existing deployment is deleted",
+			controlPlane: []runtime.Object{
+				newDeployment("worker-1"),
+				newPod("worker-1-7f6c5d4b3-q8r2m", corev1.PodPending),
+			},
+			tenantCluster: []runtime.Object{
+				newNode("worker-1-5d8f9c7b6-x2v4k", corev1.ConditionFalse),
+				newNode("worker-1-7f6c5d4b3-q8r2m", corev1.ConditionFalse),
+			},
+			expectedNodes: []string{"worker-1-7f6c5d4b3-q8r2m"},
+		},
+		{
+			name: "case 5: not ready node of existing deployment without pod is kept",
+			controlPlane: []runtime.Object{
+				newDeployment("worker-1"),
+				newDeployment("worker-2"),
+			},
+			tenantCluster: []runtime.Object{
+				newNode("worker-1-5d8f9c7b6-x2v4k", corev1.ConditionFalse),
+				newNode("worker-12-7c9d8b5f4-l9w2p", corev1.ConditionFalse),
+			},
+			expectedNodes: []string{"worker-1-5d8f9c7b6-x2v4k"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tenantK8sClient := fake.NewSimpleClientset(tc.tenantCluster...)
+
+			var r *Resource
+			{
+				c := Config{
+					K8sClient:     fake.NewSimpleClientset(tc.controlPlane...),
+					Logger:        microloggertest.New(),
+					TenantCluster: &tenantClusterMock{k8sClient: tenantK8sClient},
+				}
+
+				var err error
+				r, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := r.EnsureCreated(context.Background(), newCustomObject())
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			list, err := tenantK8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var nodes []string
+			for _, n := range list.Items {
+				nodes = append(nodes, n.GetName())
+			}
+			sort.Strings(nodes)
+
+			if diff := cmp.Diff(nodes, tc.expectedNodes); diff != "" {
+				t.Fatalf("expectations not met after EnsureCreated(): (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+type tenantClusterMock struct {
+	k8sClient kubernetes.Interface
+}
+
+func (t *tenantClusterMock) NewG8sClient(ctx context.Context, clusterID, apiDomain string) (versioned.Interface, error) {
+	return nil, nil
+}
+
+func (t *tenantClusterMock) NewHelmClient(ctx context.Context, clusterID, apiDomain string) (helmclient.Interface, error) {
+	return nil, nil
+}
+
+func (t *tenantClusterMock) NewK8sClient(ctx context.Context, clusterID, apiDomain string) (kubernetes.Interface, error) {
+	return t.k8sClient, nil
+}
+
+func newCustomObject() *v1alpha1.KVMConfig {
+	return &v1alpha1.KVMConfig{
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+			},
+		},
+	}
+}
+
+func newDeployment(name string) *v1beta1.Deployment {
+	return &v1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "al9qy",
+		},
+	}
+}
+
+func newNode(name string, ready corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: ready,
+				},
+			},
+		},
+	}
+}
+
+func newPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "al9qy",
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+		},
+	}
+}
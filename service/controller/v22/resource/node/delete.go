@@ -4,6 +4,10 @@ import (
 	"context"
 )
 
+// EnsureDeleted does nothing. Tenant cluster nodes of removed VMs are deleted
+// by EnsureCreated, which is executed on every resync of the deleter
+// controller. When the KVMConfig itself is deleted the whole tenant cluster
+// goes away, so there are no nodes left worth cleaning up.
 func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
 	return nil
 }
@@ -2,12 +2,14 @@ package nodeindexstatus
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sort"
+	"time"
 
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/operatorkit/controller/context/reconciliationcanceledcontext"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
@@ -18,53 +20,28 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 		return microerror.Mask(err)
 	}
 
-	var nodeIndexes map[string]int
-	{
-		var idx int
-		allocations := key.AllocatedNodeIndexes(cr)
-		nodes := key.AllNodes(cr)
-		nodeIndexes = copyMap(cr.Status.KVM.NodeIndexes)
-		if nodeIndexes == nil {
-			nodeIndexes = make(map[string]int)
-		}
-
-		// Remove node indexes for non-existent nodes.
-		for nodeID, _ := range nodeIndexes {
-			found := false
+	now := time.Now()
+	nodes := key.AllNodes(cr)
 
-			for _, n := range nodes {
-				if n.ID == nodeID {
-					found = true
-					break
-				}
-			}
+	nodeIndexes, _, exhausted := r.newNodeIndexes(nodes, cr.Status, now)
 
-			if !found {
-				delete(nodeIndexes, nodeID)
-			}
-		}
-
-		// Ensure all present nodes have node index allocation.
-		for _, n := range nodes {
-			_, exists := nodeIndexes[n.ID]
-			if !exists {
-				allocations, idx = allocateIndex(allocations)
-				nodeIndexes[n.ID] = idx
-			}
-		}
+	if exhausted {
+		r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("node indexes exhausted, all node indexes up to %d are in use or quarantined", r.policy.MaxIndex))
 	}
 
 	{
 		r.logger.LogCtx(ctx, "level", "debug", "message", "updating status with node indexes")
 
-		if !reflect.DeepEqual(cr.Status.KVM.NodeIndexes, nodeIndexes) {
-			newObj, err := r.g8sClient.ProviderV1alpha1().KVMConfigs(cr.GetNamespace()).Get(cr.GetName(), metav1.GetOptions{})
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		if !reflect.DeepEqual(cr.Status.KVM.NodeIndexes, nodeIndexes) || hasExhaustedCondition(cr.Status.Cluster) != exhausted {
+			// The node indexes are computed again based on the status given to the
+			// mutation, because the status of the KVMConfig might have changed
+			// since it was read, e.g. due to node indexes allocated concurrently.
+			_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+				nodeIndexes, history, exhausted := r.newNodeIndexes(nodes, *status, now)
 
-			newObj.Status.KVM.NodeIndexes = nodeIndexes
-			_, err = r.g8sClient.ProviderV1alpha1().KVMConfigs(newObj.GetNamespace()).UpdateStatus(newObj)
+				status.KVM.NodeIndexes = nodeIndexes
+				status.Cluster = withHistory(status.Cluster, history, exhausted, now)
+			})
 			if err != nil {
 				return microerror.Mask(err)
 			}
@@ -80,9 +57,73 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 		}
 	}
 
+	// Nodes without node index cannot be reconciled, so the reconciliation is
+	// canceled as long as node indexes are exhausted.
+	if exhausted {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "canceling reconciliation")
+		reconciliationcanceledcontext.SetCanceled(ctx)
+	}
+
 	return nil
 }
 
+// newNodeIndexes returns the node indexes of the given nodes based on the node
+// indexes and history found in the given status, together with the updated
+// history and whether node indexes are exhausted. Node indexes of nodes not
+// existing anymore are released and quarantined.
+func (r *Resource) newNodeIndexes(nodes []v1alpha1.ClusterNode, status v1alpha1.KVMConfigStatus, now time.Time) (map[string]int, []event, bool) {
+	var exhausted bool
+	var idx int
+
+	history := historyFromStatus(status.Cluster)
+	nodeIndexes := copyMap(status.KVM.NodeIndexes)
+
+	// Remove node indexes for non-existent nodes. Their release is recorded in
+	// the history, so that the node indexes are quarantined.
+	var released []string
+	for nodeID, _ := range nodeIndexes {
+		found := false
+
+		for _, n := range nodes {
+			if n.ID == nodeID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			released = append(released, nodeID)
+		}
+	}
+	sort.Strings(released)
+	for _, nodeID := range released {
+		history = append(history, event{Index: nodeIndexes[nodeID], Kind: eventReleased, NodeID: nodeID, Time: now})
+		delete(nodeIndexes, nodeID)
+	}
+
+	allocations := allocatedIndexes(status.KVM.NodeIndexes)
+	allocations = append(allocations, quarantinedIndexes(history, allocations, now, r.policy.QuarantinePeriod)...)
+
+	// Ensure all present nodes have node index allocation.
+	for _, n := range nodes {
+		_, exists := nodeIndexes[n.ID]
+		if !exists {
+			allocations, idx = allocateIndex(allocations)
+			if r.policy.MaxIndex > 0 && idx > r.policy.MaxIndex {
+				exhausted = true
+				break
+			}
+
+			nodeIndexes[n.ID] = idx
+			history = append(history, event{Index: idx, Kind: eventAllocated, NodeID: n.ID, Time: now})
+		}
+	}
+
+	history = pruneHistory(history, now, r.policy.QuarantinePeriod)
+
+	return nodeIndexes, history, exhausted
+}
+
 // allocateIndex takes existing index allocations as parameter and returns next
 // lowest number available. If there is a "hole" in the indexes, that is used.
 //
@@ -125,6 +166,19 @@ func allocateIndex(indexes []int) ([]int, int) {
 	return append(indexes, idx), idx
 }
 
+// allocatedIndexes returns the sorted node indexes of the given allocations.
+func allocatedIndexes(nodeIndexes map[string]int) []int {
+	var indexes []int
+
+	for _, v := range nodeIndexes {
+		indexes = append(indexes, v)
+	}
+
+	sort.Ints(indexes)
+
+	return indexes
+}
+
 func copyMap(v map[string]int) map[string]int {
 	m := make(map[string]int)
 	for k, v := range v {
@@ -3,17 +3,20 @@ package nodeindexstatus
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
-	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
 	"github.com/giantswarm/micrologger/microloggertest"
 	"github.com/google/go-cmp/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
 )
 
 func Test_EnsureCreated(t *testing.T) {
 	testCases := []struct {
 		name              string
+		policy            Policy
 		inputKVMConfig    *v1alpha1.KVMConfig
 		expectedKVMConfig *v1alpha1.KVMConfig
 		errorMatcher      func(error) bool
@@ -64,6 +67,7 @@ func Test_EnsureCreated(t *testing.T) {
 					},
 				},
 				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Allocated/1/a", "Allocated/2/b", "Allocated/3/c", "Allocated/4/d"),
 					KVM: v1alpha1.KVMConfigStatusKVM{
 						NodeIndexes: map[string]int{
 							"a": 1,
@@ -138,6 +142,7 @@ func Test_EnsureCreated(t *testing.T) {
 					},
 				},
 				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Allocated/5/e"),
 					KVM: v1alpha1.KVMConfigStatusKVM{
 						NodeIndexes: map[string]int{
 							"a": 1,
@@ -219,6 +224,7 @@ func Test_EnsureCreated(t *testing.T) {
 					},
 				},
 				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Allocated/3/e", "Allocated/6/f"),
 					KVM: v1alpha1.KVMConfigStatusKVM{
 						NodeIndexes: map[string]int{
 							"a": 1,
@@ -291,6 +297,7 @@ func Test_EnsureCreated(t *testing.T) {
 					},
 				},
 				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Released/4/c", "Released/6/f"),
 					KVM: v1alpha1.KVMConfigStatusKVM{
 						NodeIndexes: map[string]int{
 							"a": 1,
@@ -303,6 +310,261 @@ func Test_EnsureCreated(t *testing.T) {
 			},
 			errorMatcher: nil,
 		},
+		{
+			name: "case 4: released index is quarantined, allocate next free index",
+			policy: Policy{
+				QuarantinePeriod: 10 * time.Minute,
+			},
+			inputKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Now().Add(-time.Minute), "Released/3/c"),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+						},
+					},
+				},
+			},
+			expectedKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Released/3/c", "Allocated/4/g"),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+							"g": 4,
+						},
+					},
+				},
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 5: quarantine of released index expired, reuse index",
+			policy: Policy{
+				QuarantinePeriod: 10 * time.Minute,
+			},
+			inputKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Now().Add(-time.Hour), "Released/3/c"),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+						},
+					},
+				},
+			},
+			expectedKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Released/3/c", "Allocated/3/g"),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+							"g": 3,
+						},
+					},
+				},
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 6: indexes exhausted, do not allocate index",
+			policy: Policy{
+				MaxIndex: 2,
+			},
+			inputKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: v1alpha1.StatusCluster{},
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+						},
+					},
+				},
+			},
+			expectedKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newExhaustedStatus(),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+						},
+					},
+				},
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 7: indexes not exhausted anymore, allocate index",
+			policy: Policy{
+				MaxIndex: 3,
+			},
+			inputKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newExhaustedStatus(),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+						},
+					},
+				},
+			},
+			expectedKVMConfig: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						Masters: []v1alpha1.ClusterNode{
+							{
+								ID: "a",
+							},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{
+								ID: "b",
+							},
+							{
+								ID: "g",
+							},
+						},
+					},
+				},
+				Status: v1alpha1.KVMConfigStatus{
+					Cluster: newHistory(time.Time{}, "Allocated/3/g"),
+					KVM: v1alpha1.KVMConfigStatusKVM{
+						NodeIndexes: map[string]int{
+							"a": 1,
+							"b": 2,
+							"g": 3,
+						},
+					},
+				},
+			},
+			errorMatcher: nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -310,8 +572,10 @@ func Test_EnsureCreated(t *testing.T) {
 			var r *Resource
 			{
 				c := Config{
-					G8sClient: fake.NewSimpleClientset(tc.inputKVMConfig),
+					G8sClient: statuspatchtest.NewClientset(tc.inputKVMConfig),
 					Logger:    microloggertest.New(),
+
+					Policy: tc.policy,
 				}
 
 				var err error
@@ -339,13 +603,107 @@ func Test_EnsureCreated(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if diff := cmp.Diff(kvmConfig, tc.expectedKVMConfig); diff != "" {
+			// The resource version is increased by the fake clientset with
+			// every status patch and is not of interest here.
+			kvmConfig.ResourceVersion = ""
+
+			if diff := cmp.Diff(kvmConfig, tc.expectedKVMConfig, ignoreTime); diff != "" {
 				t.Fatalf("expectations not met after EnsureCreated(): (-got +expected)\n%s\n", diff)
 			}
 		})
 	}
 }
 
+// Test_EnsureCreated_ConcurrentAllocation ensures node indexes allocated
+// concurrently, after the KVMConfig given to the resource was read, are not
+// overwritten.
+func Test_EnsureCreated_ConcurrentAllocation(t *testing.T) {
+	spec := v1alpha1.KVMConfigSpec{
+		Cluster: v1alpha1.Cluster{
+			Masters: []v1alpha1.ClusterNode{
+				{
+					ID: "a",
+				},
+			},
+			Workers: []v1alpha1.ClusterNode{
+				{
+					ID: "b",
+				},
+				{
+					ID: "c",
+				},
+			},
+		},
+	}
+
+	staleKVMConfig := &v1alpha1.KVMConfig{
+		Spec: spec,
+		Status: v1alpha1.KVMConfigStatus{
+			Cluster: newHistory(time.Time{}, "Allocated/1/a"),
+			KVM: v1alpha1.KVMConfigStatusKVM{
+				NodeIndexes: map[string]int{
+					"a": 1,
+				},
+			},
+		},
+	}
+
+	currentKVMConfig := staleKVMConfig.DeepCopy()
+	currentKVMConfig.Status = v1alpha1.KVMConfigStatus{
+		Cluster: newHistory(time.Time{}, "Allocated/1/a", "Allocated/2/c"),
+		KVM: v1alpha1.KVMConfigStatusKVM{
+			NodeIndexes: map[string]int{
+				"a": 1,
+				"c": 2,
+			},
+		},
+	}
+
+	expectedKVMConfig := &v1alpha1.KVMConfig{
+		Spec: spec,
+		Status: v1alpha1.KVMConfigStatus{
+			Cluster: newHistory(time.Time{}, "Allocated/1/a", "Allocated/2/c", "Allocated/3/b"),
+			KVM: v1alpha1.KVMConfigStatusKVM{
+				NodeIndexes: map[string]int{
+					"a": 1,
+					"b": 3,
+					"c": 2,
+				},
+			},
+		},
+	}
+
+	var r *Resource
+	{
+		c := Config{
+			G8sClient: statuspatchtest.NewClientset(currentKVMConfig),
+			Logger:    microloggertest.New(),
+		}
+
+		var err error
+		r, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := r.EnsureCreated(context.Background(), staleKVMConfig)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	kvmConfig, err := r.g8sClient.ProviderV1alpha1().KVMConfigs(staleKVMConfig.GetNamespace()).Get(staleKVMConfig.GetName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kvmConfig.ResourceVersion = ""
+
+	if diff := cmp.Diff(kvmConfig, expectedKVMConfig, ignoreTime); diff != "" {
+		t.Fatalf("expectations not met after EnsureCreated(): (-got +expected)\n%s\n", diff)
+	}
+}
+
 func Test_allocateIndex(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -399,3 +757,40 @@ func Test_allocateIndex(t *testing.T) {
 		})
 	}
 }
+
+// ignoreTime ignores the transition times of conditions, because the events of
+// the allocation history are recorded with the current time.
+var ignoreTime = cmp.Comparer(func(a, b v1alpha1.DeepCopyTime) bool {
+	return true
+})
+
+func newExhaustedStatus() v1alpha1.StatusCluster {
+	return v1alpha1.StatusCluster{
+		Conditions: []v1alpha1.StatusClusterCondition{
+			{
+				Status: v1alpha1.StatusClusterStatusTrue,
+				Type:   StatusClusterTypeNodeIndexesExhausted,
+			},
+		},
+	}
+}
+
+func newHistory(t time.Time, events ...string) v1alpha1.StatusCluster {
+	resource := v1alpha1.StatusClusterResource{
+		Name: Name,
+	}
+
+	for _, e := range events {
+		resource.Conditions = append(resource.Conditions, v1alpha1.StatusClusterResourceCondition{
+			LastTransitionTime: v1alpha1.DeepCopyTime{Time: t},
+			Status:             v1alpha1.StatusClusterStatusTrue,
+			Type:               e,
+		})
+	}
+
+	return v1alpha1.StatusCluster{
+		Resources: []v1alpha1.StatusClusterResource{
+			resource,
+		},
+	}
+}
@@ -0,0 +1,202 @@
+package nodeindexstatus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+)
+
+const (
+	// StatusClusterTypeNodeIndexesExhausted is the type of the cluster
+	// condition set when nodes cannot be allocated a node index, because all
+	// node indexes up to the configured maximum are in use or quarantined.
+	StatusClusterTypeNodeIndexesExhausted = "NodeIndexesExhausted"
+)
+
+const (
+	eventAllocated = "Allocated"
+	eventReleased  = "Released"
+
+	// historyLimit is the number of events kept in the allocation history.
+	// Release events of quarantined node indexes are always kept, because the
+	// quarantine is computed from them.
+	historyLimit = 50
+)
+
+// event is an entry of the node index allocation history. The history is
+// recorded as conditions of the resource status of this resource, because the
+// KVM status of the custom object only holds the node indexes and has no field
+// for the history yet. The condition type of an event has the format
+// <kind>/<index>/<node ID>, e.g. Released/3/ab12c, and the transition time of
+// the condition is the time of the event.
+//
+// The format is an internal detail of this resource and not part of the API of
+// the custom object. Other consumers must not parse or write these conditions.
+// Conditions of the resource status not matching the format are ignored, so
+// the history can move to a dedicated field of the KVM status once the custom
+// object provides one.
+type event struct {
+	Index  int
+	Kind   string
+	NodeID string
+	Time   time.Time
+}
+
+func (e event) String() string {
+	return fmt.Sprintf("%s/%d/%s", e.Kind, e.Index, e.NodeID)
+}
+
+func hasExhaustedCondition(status v1alpha1.StatusCluster) bool {
+	for _, c := range status.Conditions {
+		if c.Type == StatusClusterTypeNodeIndexesExhausted && c.Status == v1alpha1.StatusClusterStatusTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// historyFromStatus returns the allocation history recorded in the resource
+// status of this resource. Conditions which are not events are ignored.
+func historyFromStatus(status v1alpha1.StatusCluster) []event {
+	var history []event
+
+	for _, r := range status.Resources {
+		if r.Name != Name {
+			continue
+		}
+
+		for _, c := range r.Conditions {
+			e, ok := parseEvent(c)
+			if ok {
+				history = append(history, e)
+			}
+		}
+	}
+
+	return history
+}
+
+func parseEvent(c v1alpha1.StatusClusterResourceCondition) (event, bool) {
+	parts := strings.SplitN(c.Type, "/", 3)
+	if len(parts) != 3 {
+		return event{}, false
+	}
+	if parts[0] != eventAllocated && parts[0] != eventReleased {
+		return event{}, false
+	}
+	idx, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return event{}, false
+	}
+
+	e := event{
+		Index:  idx,
+		Kind:   parts[0],
+		NodeID: parts[2],
+		Time:   c.LastTransitionTime.Time,
+	}
+
+	return e, true
+}
+
+// pruneHistory drops the oldest events of the given history exceeding the
+// history limit. Release events of node indexes which are still quarantined
+// are kept regardless.
+func pruneHistory(history []event, now time.Time, quarantinePeriod time.Duration) []event {
+	var pruned []event
+
+	for i := len(history) - 1; i >= 0; i-- {
+		e := history[i]
+
+		inQuarantine := e.Kind == eventReleased && now.Sub(e.Time) < quarantinePeriod
+		if len(pruned) < historyLimit || inQuarantine {
+			pruned = append([]event{e}, pruned...)
+		}
+	}
+
+	return pruned
+}
+
+// quarantinedIndexes returns the node indexes released within the given
+// quarantine period which are not allocated.
+func quarantinedIndexes(history []event, allocations []int, now time.Time, quarantinePeriod time.Duration) []int {
+	var indexes []int
+
+	for _, e := range history {
+		if e.Kind != eventReleased || now.Sub(e.Time) >= quarantinePeriod {
+			continue
+		}
+		if containsIndex(allocations, e.Index) || containsIndex(indexes, e.Index) {
+			continue
+		}
+
+		indexes = append(indexes, e.Index)
+	}
+
+	return indexes
+}
+
+// withHistory returns the given cluster status reflecting the given history
+// and whether node indexes are exhausted. The transition time of an existing
+// exhausted condition is kept.
+func withHistory(status v1alpha1.StatusCluster, history []event, exhausted bool, t time.Time) v1alpha1.StatusCluster {
+	var conditions []v1alpha1.StatusClusterCondition
+	for _, c := range status.Conditions {
+		if c.Type == StatusClusterTypeNodeIndexesExhausted {
+			if exhausted {
+				t = c.LastTransitionTime.Time
+			}
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+
+	if exhausted {
+		conditions = append(conditions, v1alpha1.StatusClusterCondition{
+			LastTransitionTime: v1alpha1.DeepCopyTime{Time: t},
+			Status:             v1alpha1.StatusClusterStatusTrue,
+			Type:               StatusClusterTypeNodeIndexesExhausted,
+		})
+	}
+
+	var resources []v1alpha1.StatusClusterResource
+	for _, r := range status.Resources {
+		if r.Name == Name {
+			continue
+		}
+		resources = append(resources, r)
+	}
+
+	if len(history) != 0 {
+		resource := v1alpha1.StatusClusterResource{
+			Name: Name,
+		}
+		for _, e := range history {
+			resource.Conditions = append(resource.Conditions, v1alpha1.StatusClusterResourceCondition{
+				LastTransitionTime: v1alpha1.DeepCopyTime{Time: e.Time},
+				Status:             v1alpha1.StatusClusterStatusTrue,
+				Type:               e.String(),
+			})
+		}
+		resources = append(resources, resource)
+	}
+
+	status.Conditions = conditions
+	status.Resources = resources
+
+	return status
+}
+
+func containsIndex(indexes []int, idx int) bool {
+	for _, i := range indexes {
+		if i == idx {
+			return true
+		}
+	}
+
+	return false
+}
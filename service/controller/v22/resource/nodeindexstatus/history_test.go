@@ -0,0 +1,31 @@
+package nodeindexstatus
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_pruneHistory(t *testing.T) {
+	now := time.Now()
+
+	var history []event
+	history = append(history, event{Index: 1, Kind: eventReleased, NodeID: "a", Time: now.Add(-time.Minute)})
+	history = append(history, event{Index: 2, Kind: eventReleased, NodeID: "b", Time: now.Add(-time.Hour)})
+	for i := 0; i < historyLimit; i++ {
+		history = append(history, event{Index: 3, Kind: eventAllocated, NodeID: "c", Time: now})
+	}
+
+	pruned := pruneHistory(history, now, 10*time.Minute)
+
+	if len(pruned) != historyLimit+1 {
+		t.Fatalf("len(pruned) == %d, want %d", len(pruned), historyLimit+1)
+	}
+	if pruned[0].NodeID != "a" {
+		t.Fatalf("pruned[0].NodeID == %q, want %q", pruned[0].NodeID, "a")
+	}
+	for _, e := range pruned {
+		if e.NodeID == "b" {
+			t.Fatalf("expected release event of node %q to be pruned", "b")
+		}
+	}
+}
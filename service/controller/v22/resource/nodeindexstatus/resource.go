@@ -1,9 +1,13 @@
 package nodeindexstatus
 
 import (
+	"time"
+
 	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch"
 )
 
 const (
@@ -13,11 +17,29 @@ const (
 type Config struct {
 	G8sClient versioned.Interface
 	Logger    micrologger.Logger
+
+	Policy Policy
+}
+
+// Policy represents the configuration of how node indexes are allocated.
+type Policy struct {
+	// MaxIndex is the highest node index allocated. Nodes are not allocated an
+	// index once all indexes up to MaxIndex are in use or quarantined. Zero
+	// means there is no upper bound.
+	MaxIndex int
+	// QuarantinePeriod is the duration a node index released by a removed node
+	// is not reused. The node index determines the iSCSI initiator name of the
+	// node and storage arrays may still have sessions of the removed node for a
+	// while. Zero means released node indexes are reused right away.
+	QuarantinePeriod time.Duration
 }
 
 type Resource struct {
-	g8sClient versioned.Interface
-	logger    micrologger.Logger
+	g8sClient     versioned.Interface
+	logger        micrologger.Logger
+	statusPatcher *statuspatch.Patcher
+
+	policy Policy
 }
 
 func New(config Config) (*Resource, error) {
@@ -28,9 +50,33 @@ func New(config Config) (*Resource, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
+	if config.Policy.MaxIndex < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Policy.MaxIndex must not be negative", config)
+	}
+	if config.Policy.QuarantinePeriod < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Policy.QuarantinePeriod must not be negative", config)
+	}
+
+	var statusPatcher *statuspatch.Patcher
+	{
+		c := statuspatch.Config{
+			G8sClient: config.G8sClient,
+			Logger:    config.Logger,
+		}
+
+		var err error
+		statusPatcher, err = statuspatch.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	r := &Resource{
-		g8sClient: config.G8sClient,
-		logger:    config.Logger,
+		g8sClient:     config.G8sClient,
+		logger:        config.Logger,
+		statusPatcher: statusPatcher,
+
+		policy: config.Policy,
 	}
 
 	return r, nil
@@ -2,6 +2,7 @@ package pod
 
 import (
 	"context"
+	"time"
 
 	corev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
 	"github.com/giantswarm/microerror"
@@ -11,6 +12,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
@@ -42,11 +44,8 @@ func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
 	}
 
 	{
-		isDrained, err := key.IsPodDrained(currentPod)
-		if err != nil {
-			return microerror.Mask(err)
-		}
-		if isDrained {
+		state := drain.StateFromPod(currentPod)
+		if state.Final() {
 			r.logger.LogCtx(ctx, "level", "debug", "message", "pod is already drained")
 			resourcecanceledcontext.SetCanceled(ctx)
 			r.logger.LogCtx(ctx, "level", "debug", "message", "canceling resource")
@@ -55,7 +54,17 @@ func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
 		}
 	}
 
-	{
+	policy, err := r.drainPolicyForPod(ctx, currentPod)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if r.nodeDrainer != nil {
+		err := r.drainInProcess(ctx, currentPod, policy)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else {
 		r.logger.LogCtx(ctx, "level", "debug", "message", "looking for the drainer config for the tenant cluster")
 
 		n := currentPod.GetNamespace()
@@ -66,7 +75,12 @@ func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
 		if apierrors.IsNotFound(err) {
 			r.logger.LogCtx(ctx, "level", "debug", "message", "did not find drainer config for tenant cluster node")
 
-			err := r.createDrainerConfig(ctx, currentPod)
+			err := r.createDrainerConfig(ctx, currentPod, policy)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			_, err = r.updateDrainState(ctx, currentPod, time.Now(), drain.StateDraining)
 			if err != nil {
 				return microerror.Mask(err)
 			}
@@ -84,30 +98,47 @@ func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
 			r.logger.LogCtx(ctx, "level", "debug", "message", "waiting for inspection of the reconciled pod")
 		}
 
+		startedAt := drainStartedAt(currentPod, drainerConfig.GetCreationTimestamp().Time)
+		timedOut := drainerConfig.Status.HasTimeoutCondition() || policy.TimedOut(startedAt, time.Now())
+
 		if drainerConfig.Status.HasDrainedCondition() {
 			r.logger.LogCtx(ctx, "level", "debug", "message", "drainer config of tenant cluster has drained condition")
 
-			err := r.finishDraining(ctx, currentPod, drainerConfig)
+			err := r.finishDraining(ctx, currentPod, startedAt, drain.StateDrained)
 			if err != nil {
 				return microerror.Mask(err)
 			}
-		} else if drainerConfig.Status.HasTimeoutCondition() {
-			r.logger.LogCtx(ctx, "level", "debug", "message", "drainer config of tenant cluster has timeout condition")
+		} else if key.ArePodContainersTerminated(currentPod) {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "pod is treated as drained")
+			r.logger.LogCtx(ctx, "level", "debug", "message", "all pod's containers are terminated")
 
-			err := r.finishDraining(ctx, currentPod, drainerConfig)
+			err := r.finishDraining(ctx, currentPod, startedAt, drain.StateDrained)
 			if err != nil {
 				return microerror.Mask(err)
 			}
-		} else if key.ArePodContainersTerminated(currentPod) {
-			r.logger.LogCtx(ctx, "level", "debug", "message", "pod is treated as drained")
-			r.logger.LogCtx(ctx, "level", "debug", "message", "all pod's containers are terminated")
+		} else if timedOut && policy.ForceAfterTimeout {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "draining of tenant cluster node timed out")
+			r.logger.LogCtx(ctx, "level", "debug", "message", "forcing deletion of the pod")
 
-			err := r.finishDraining(ctx, currentPod, drainerConfig)
+			err := r.finishDraining(ctx, currentPod, startedAt, drain.StateForced)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		} else if timedOut {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "draining of tenant cluster node timed out")
+			r.logger.LogCtx(ctx, "level", "debug", "message", "not forcing deletion of the pod because the drain policy does not allow it")
+
+			_, err := r.updateDrainState(ctx, currentPod, startedAt, drain.StateTimedOut)
 			if err != nil {
 				return microerror.Mask(err)
 			}
 		} else {
 			r.logger.LogCtx(ctx, "level", "debug", "message", "node termination is still in progress")
+
+			_, err := r.updateDrainState(ctx, currentPod, startedAt, drain.StateDraining)
+			if err != nil {
+				return microerror.Mask(err)
+			}
 		}
 	}
 
@@ -120,7 +151,7 @@ func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
 	return nil
 }
 
-func (r *Resource) createDrainerConfig(ctx context.Context, pod *corev1.Pod) error {
+func (r *Resource) createDrainerConfig(ctx context.Context, pod *corev1.Pod, policy drain.Policy) error {
 	r.logger.LogCtx(ctx, "level", "debug", "message", "creating drainer config for tenant cluster node")
 
 	apiEndpoint, err := key.ClusterAPIEndpointFromPod(pod)
@@ -132,6 +163,13 @@ func (r *Resource) createDrainerConfig(ctx context.Context, pod *corev1.Pod) err
 	c := &corev1alpha1.DrainerConfig{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: pod.GetName(),
+			// The drain policy is passed on to the drainer so that drainers
+			// supporting it can respect the eviction grace period and timeout
+			// of the tenant cluster.
+			Annotations: map[string]string{
+				key.AnnotationDrainEvictionGracePeriod: policy.EvictionGracePeriod.String(),
+				key.AnnotationDrainTimeout:             policy.Timeout.String(),
+			},
 		},
 		Spec: corev1alpha1.DrainerConfigSpec{
 			Guest: corev1alpha1.DrainerConfigSpecGuest{
@@ -146,7 +184,7 @@ func (r *Resource) createDrainerConfig(ctx context.Context, pod *corev1.Pod) err
 				},
 			},
 			VersionBundle: corev1alpha1.DrainerConfigSpecVersionBundle{
-				Version: "0.2.0",
+				Version: key.DrainerConfigVersion,
 			},
 		},
 	}
@@ -163,7 +201,7 @@ func (r *Resource) createDrainerConfig(ctx context.Context, pod *corev1.Pod) err
 	return nil
 }
 
-func (r *Resource) finishDraining(ctx context.Context, currentPod *corev1.Pod, drainerConfig *corev1alpha1.DrainerConfig) error {
+func (r *Resource) finishDraining(ctx context.Context, currentPod *corev1.Pod, startedAt time.Time, state drain.State) error {
 	var err error
 
 	{
@@ -183,36 +221,14 @@ func (r *Resource) finishDraining(ctx context.Context, currentPod *corev1.Pod, d
 		}
 	}
 
-	var podToDelete *corev1.Pod
-	{
-		podToDelete = currentPod
-
-		a := podToDelete.GetAnnotations()
-		a[key.AnnotationPodDrained] = "True"
-		podToDelete.SetAnnotations(a)
-	}
-
 	{
-		r.logger.LogCtx(ctx, "level", "debug", "message", "updating the pod in the Kubernetes API")
-
-		_, err := r.k8sClient.CoreV1().Pods(podToDelete.Namespace).Update(podToDelete)
-		if apierrors.IsConflict(err) {
-			// The reconciled pod may be updated by other processes or even humans
-			// meanwhile. In case the resource version we currently know does not
-			// match the latest existing one, we give up here and wait for the
-			// delete event to be replayed. Then we try again later until we
-			// succeed.
-			r.logger.LogCtx(ctx, "level", "debug", "message", "cannot update the pod in the Kubernetes API due to outdated resource version")
-			resourcecanceledcontext.SetCanceled(ctx)
-			finalizerskeptcontext.SetKept(ctx)
-			r.logger.LogCtx(ctx, "level", "debug", "message", "canceling reconciliation")
-
-			return nil
-		} else if err != nil {
+		updated, err := r.updateDrainState(ctx, currentPod, startedAt, state)
+		if err != nil {
 			return microerror.Mask(err)
 		}
-
-		r.logger.LogCtx(ctx, "level", "debug", "message", "updated the pod in the Kubernetes API")
+		if !updated {
+			return nil
+		}
 	}
 
 	{
@@ -222,7 +238,7 @@ func (r *Resource) finishDraining(ctx context.Context, currentPod *corev1.Pod, d
 		options := &metav1.DeleteOptions{
 			GracePeriodSeconds: &gracePeriodSeconds,
 		}
-		err = r.k8sClient.CoreV1().Pods(podToDelete.Namespace).Delete(podToDelete.Name, options)
+		err = r.k8sClient.CoreV1().Pods(currentPod.Namespace).Delete(currentPod.Name, options)
 		if err != nil {
 			return microerror.Mask(err)
 		}
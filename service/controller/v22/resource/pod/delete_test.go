@@ -0,0 +1,410 @@
+package pod
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/giantswarm/apiextensions/pkg/apis/core/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	versionedfake "github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain/draintest"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_Resource_Pod_EnsureDeleted(t *testing.T) {
+	testCases := []struct {
+		name                       string
+		drainPolicy                drain.Policy
+		clusterAnnotations         map[string]string
+		drainStartedAt             time.Duration
+		drainerConfigConditions    []corev1alpha1.DrainerConfigStatusCondition
+		existingDrainerConfig      bool
+		expectedDrainerConfig      bool
+		expectedPodDeleted         bool
+		expectedState              drain.State
+		expectedDrainerAnnotations map[string]string
+	}{
+		{
+			name: "case 0: drainer config is created and node is draining",
+			drainPolicy: drain.Policy{
+				EvictionGracePeriod: 30 * time.Second,
+				ForceAfterTimeout:   true,
+				Timeout:             time.Hour,
+			},
+			existingDrainerConfig: false,
+			expectedDrainerConfig: true,
+			expectedPodDeleted:    false,
+			expectedState:         drain.StateDraining,
+			expectedDrainerAnnotations: map[string]string{
+				key.AnnotationDrainEvictionGracePeriod: "30s",
+				key.AnnotationDrainTimeout:             "1h0m0s",
+			},
+		},
+		{
+			name: "case 1: drained node results in drained state",
+			drainPolicy: drain.Policy{
+				ForceAfterTimeout: true,
+			},
+			drainStartedAt: 10 * time.Minute,
+			drainerConfigConditions: []corev1alpha1.DrainerConfigStatusCondition{
+				corev1alpha1.DrainerConfigStatus{}.NewDrainedCondition(),
+			},
+			existingDrainerConfig: true,
+			expectedDrainerConfig: false,
+			expectedPodDeleted:    true,
+			expectedState:         drain.StateDrained,
+		},
+		{
+			name: "case 2: elapsed operator timeout forces deletion",
+			drainPolicy: drain.Policy{
+				ForceAfterTimeout: true,
+				Timeout:           time.Hour,
+			},
+			drainStartedAt:        2 * time.Hour,
+			existingDrainerConfig: true,
+			expectedDrainerConfig: false,
+			expectedPodDeleted:    true,
+			expectedState:         drain.StateForced,
+		},
+		{
+			name: "case 3: elapsed timeout without force keeps waiting",
+			drainPolicy: drain.Policy{
+				ForceAfterTimeout: false,
+				Timeout:           time.Hour,
+			},
+			drainStartedAt:        2 * time.Hour,
+			existingDrainerConfig: true,
+			expectedDrainerConfig: true,
+			expectedPodDeleted:    false,
+			expectedState:         drain.StateTimedOut,
+		},
+		{
+			name: "case 4: drainer timeout without force keeps waiting",
+			drainPolicy: drain.Policy{
+				ForceAfterTimeout: false,
+			},
+			drainStartedAt: 10 * time.Minute,
+			drainerConfigConditions: []corev1alpha1.DrainerConfigStatusCondition{
+				corev1alpha1.DrainerConfigStatus{}.NewTimeoutCondition(),
+			},
+			existingDrainerConfig: true,
+			expectedDrainerConfig: true,
+			expectedPodDeleted:    false,
+			expectedState:         drain.StateTimedOut,
+		},
+		{
+			name: "case 5: cluster annotations override the default policy",
+			drainPolicy: drain.Policy{
+				ForceAfterTimeout: false,
+			},
+			clusterAnnotations: map[string]string{
+				key.AnnotationDrainForceAfterTimeout: "true",
+				key.AnnotationDrainTimeout:           "30m",
+			},
+			drainStartedAt:        time.Hour,
+			existingDrainerConfig: true,
+			expectedDrainerConfig: false,
+			expectedPodDeleted:    true,
+			expectedState:         drain.StateForced,
+		},
+		{
+			name: "case 6: drain within timeout is draining",
+			drainPolicy: drain.Policy{
+				ForceAfterTimeout: true,
+				Timeout:           time.Hour,
+			},
+			drainStartedAt:        10 * time.Minute,
+			existingDrainerConfig: true,
+			expectedDrainerConfig: true,
+			expectedPodDeleted:    false,
+			expectedState:         drain.StateDraining,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := newTestPod()
+			if tc.drainStartedAt != 0 {
+				pod.Annotations[key.AnnotationDrainStartedAt] = time.Now().Add(-tc.drainStartedAt).UTC().Format(time.RFC3339)
+			}
+
+			g8sObjects := []runtime.Object{
+				&v1alpha1.KVMConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "al9qy",
+						Namespace:   "default",
+						Annotations: tc.clusterAnnotations,
+					},
+					Spec: v1alpha1.KVMConfigSpec{
+						Cluster: v1alpha1.Cluster{
+							ID: "al9qy",
+						},
+					},
+				},
+			}
+			if tc.existingDrainerConfig {
+				g8sObjects = append(g8sObjects, &corev1alpha1.DrainerConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      pod.Name,
+						Namespace: pod.Namespace,
+					},
+					Status: corev1alpha1.DrainerConfigStatus{
+						Conditions: tc.drainerConfigConditions,
+					},
+				})
+			}
+
+			g8sClient := versionedfake.NewSimpleClientset(g8sObjects...)
+			k8sClient := fake.NewSimpleClientset(pod)
+
+			var r *Resource
+			{
+				c := Config{
+					G8sClient: g8sClient,
+					K8sClient: k8sClient,
+					Logger:    microloggertest.New(),
+
+					DrainPolicy: tc.drainPolicy,
+				}
+
+				var err error
+				r, err = New(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			err := r.EnsureDeleted(context.Background(), pod)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			drainerConfig, err := g8sClient.CoreV1alpha1().DrainerConfigs(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if tc.expectedDrainerConfig && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !tc.expectedDrainerConfig && !apierrors.IsNotFound(err) {
+				t.Fatalf("error == %#v, want not found error", err)
+			}
+			for k, v := range tc.expectedDrainerAnnotations {
+				if drainerConfig.Annotations[k] != v {
+					t.Fatalf("expected drainer config annotation %s to be '%s' got '%s'", k, v, drainerConfig.Annotations[k])
+				}
+			}
+
+			_, err = k8sClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if tc.expectedPodDeleted && !apierrors.IsNotFound(err) {
+				t.Fatalf("error == %#v, want not found error", err)
+			}
+			if !tc.expectedPodDeleted && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			updatedPod := lastUpdatedPod(k8sClient.Actions())
+			if updatedPod == nil {
+				t.Fatalf("expected pod to be updated")
+			}
+			if updatedPod.Annotations[key.AnnotationDrainStartedAt] == "" {
+				t.Fatalf("expected annotation %s to be set", key.AnnotationDrainStartedAt)
+			}
+			state := drain.StateFromPod(updatedPod)
+			if state != tc.expectedState {
+				t.Fatalf("expected drain state %#q got %#q", tc.expectedState, state)
+			}
+		})
+	}
+}
+
+func Test_Resource_Pod_EnsureDeleted_LegacyDrained(t *testing.T) {
+	pod := newTestPod()
+	pod.Annotations[key.AnnotationPodDrained] = "True"
+
+	g8sClient := versionedfake.NewSimpleClientset()
+	k8sClient := fake.NewSimpleClientset(pod)
+
+	var r *Resource
+	{
+		c := Config{
+			G8sClient: g8sClient,
+			K8sClient: k8sClient,
+			Logger:    microloggertest.New(),
+		}
+
+		var err error
+		r, err = New(c)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	err := r.EnsureDeleted(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if lastUpdatedPod(k8sClient.Actions()) != nil {
+		t.Fatalf("expected pod drained by former versions not to be updated")
+	}
+
+	_, err = g8sClient.CoreV1alpha1().DrainerConfigs(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("error == %#v, want not found error", err)
+	}
+}
+
+func Test_Resource_Pod_EnsureDeleted_BuiltinDrainer(t *testing.T) {
+	testCases := []struct {
+		name               string
+		tenantObjects      []runtime.Object
+		expectedPodDeleted bool
+		expectedState      drain.State
+	}{
+		{
+			name:               "case 0: drained tenant cluster node results in deleted pod",
+			tenantObjects:      nil,
+			expectedPodDeleted: true,
+			expectedState:      drain.StateDrained,
+		},
+		{
+			name: "case 1: tenant cluster node with running pods is draining",
+			tenantObjects: []runtime.Object{
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "worker-1-6b9c5c9c8-xwz4q",
+					},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "app",
+						Namespace: "default",
+					},
+					Spec: corev1.PodSpec{
+						NodeName: "worker-1-6b9c5c9c8-xwz4q",
+					},
+				},
+			},
+			expectedPodDeleted: false,
+			expectedState:      drain.StateDraining,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := newTestPod()
+
+			g8sClient := versionedfake.NewSimpleClientset()
+			k8sClient := fake.NewSimpleClientset(pod)
+
+			var nodeDrainer *drain.Drainer
+			{
+				c := drain.DrainerConfig{
+					Logger:        microloggertest.New(),
+					TenantCluster: &draintest.TenantCluster{K8sClient: fake.NewSimpleClientset(tc.tenantObjects...)},
+				}
+
+				var err error
+				nodeDrainer, err = drain.NewDrainer(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			var r *Resource
+			{
+				c := Config{
+					G8sClient: g8sClient,
+					K8sClient: k8sClient,
+					Logger:    microloggertest.New(),
+
+					DrainPolicy: drain.Policy{
+						Timeout: 10 * time.Minute,
+					},
+					NodeDrainer: nodeDrainer,
+				}
+
+				var err error
+				r, err = New(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			err := r.EnsureDeleted(context.Background(), pod)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			_, err = g8sClient.CoreV1alpha1().DrainerConfigs(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if !apierrors.IsNotFound(err) {
+				t.Fatalf("error == %#v, want not found error", err)
+			}
+
+			_, err = k8sClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if tc.expectedPodDeleted && !apierrors.IsNotFound(err) {
+				t.Fatalf("error == %#v, want not found error", err)
+			}
+			if !tc.expectedPodDeleted && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			updatedPod := lastUpdatedPod(k8sClient.Actions())
+			if updatedPod == nil {
+				t.Fatalf("expected pod to be updated")
+			}
+			state := drain.StateFromPod(updatedPod)
+			if state != tc.expectedState {
+				t.Fatalf("expected drain state %#q got %#q", tc.expectedState, state)
+			}
+		})
+	}
+}
+
+func lastUpdatedPod(actions []k8stesting.Action) *corev1.Pod {
+	var pod *corev1.Pod
+
+	for _, a := range actions {
+		u, ok := a.(k8stesting.UpdateAction)
+		if !ok {
+			continue
+		}
+		p, ok := u.GetObject().(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		pod = p
+	}
+
+	return pod
+}
+
+func newTestPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "worker-1-6b9c5c9c8-xwz4q",
+			Namespace: "al9qy",
+			Annotations: map[string]string{
+				key.AnnotationAPIEndpoint: "api.al9qy.k8s.gigantic.io",
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "k8s-kvm",
+					State: corev1.ContainerState{
+						Running: &corev1.ContainerStateRunning{},
+					},
+				},
+			},
+		},
+	}
+}
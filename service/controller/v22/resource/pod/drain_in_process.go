@@ -0,0 +1,72 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+// drainInProcess drains the tenant cluster node of the given pod using the
+// built-in drainer instead of delegating the drain to the node-operator using
+// a DrainerConfig.
+func (r *Resource) drainInProcess(ctx context.Context, currentPod *corev1.Pod, policy drain.Policy) error {
+	startedAt := drainStartedAt(currentPod, time.Now())
+
+	var drained bool
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "draining tenant cluster node using the built-in drainer")
+
+		apiEndpoint, err := key.ClusterAPIEndpointFromPod(currentPod)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		drained, err = r.nodeDrainer.Drain(ctx, currentPod.GetNamespace(), apiEndpoint, currentPod.GetName(), policy)
+		if err != nil {
+			// The tenant cluster API may not be available anymore, e.g. because
+			// its masters are already deleted. We do not fail here so that the
+			// drain timeout still applies.
+			r.logger.LogCtx(ctx, "level", "warning", "message", "failed draining tenant cluster node using the built-in drainer", "stack", fmt.Sprintf("%#v", err))
+		}
+	}
+
+	if drained || key.ArePodContainersTerminated(currentPod) {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "pod is treated as drained")
+
+		err := r.finishDraining(ctx, currentPod, startedAt, drain.StateDrained)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if policy.TimedOut(startedAt, time.Now()) && policy.ForceAfterTimeout {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "draining of tenant cluster node timed out")
+		r.logger.LogCtx(ctx, "level", "debug", "message", "forcing deletion of the pod")
+
+		err := r.finishDraining(ctx, currentPod, startedAt, drain.StateForced)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if policy.TimedOut(startedAt, time.Now()) {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "draining of tenant cluster node timed out")
+		r.logger.LogCtx(ctx, "level", "debug", "message", "not forcing deletion of the pod because the drain policy does not allow it")
+
+		_, err := r.updateDrainState(ctx, currentPod, startedAt, drain.StateTimedOut)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "node termination is still in progress")
+
+		_, err := r.updateDrainState(ctx, currentPod, startedAt, drain.StateDraining)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/controller/context/finalizerskeptcontext"
+	"github.com/giantswarm/operatorkit/controller/context/resourcecanceledcontext"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+// drainPolicyForPod returns the drain policy of the tenant cluster the given
+// pod belongs to. The default policy is returned in case the KVMConfig of the
+// tenant cluster cannot be found, e.g. because it is already deleted, or its
+// drain annotations are invalid.
+func (r *Resource) drainPolicyForPod(ctx context.Context, pod *corev1.Pod) (drain.Policy, error) {
+	r.logger.LogCtx(ctx, "level", "debug", "message", "looking for the drain policy of the tenant cluster")
+
+	// KVMConfigs are named after the ID of their tenant cluster, which is also
+	// the namespace of the tenant cluster's pods. The namespace of the
+	// KVMConfig is not known here, so it is selected by name instead of
+	// listing all KVMConfigs.
+	list, err := r.g8sClient.ProviderV1alpha1().KVMConfigs("").List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", pod.GetNamespace()).String(),
+	})
+	if err != nil {
+		return drain.Policy{}, microerror.Mask(err)
+	}
+
+	for _, cr := range list.Items {
+		if key.ClusterID(cr) != pod.GetNamespace() {
+			continue
+		}
+
+		policy, err := drain.FromAnnotations(r.drainPolicy, cr.GetAnnotations())
+		if drain.IsInvalidPolicy(err) {
+			r.logger.LogCtx(ctx, "level", "warning", "message", "using default drain policy due to invalid drain annotations of the tenant cluster", "stack", fmt.Sprintf("%#v", err))
+			return r.drainPolicy, nil
+		} else if err != nil {
+			return drain.Policy{}, microerror.Mask(err)
+		}
+
+		// The builtin drainer does not time out on its own, so the annotations
+		// must not disable the timeout of the operator.
+		if r.nodeDrainer != nil && policy.Timeout == 0 {
+			r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("using default drain timeout because annotation %s must not be zero when draining in process", key.AnnotationDrainTimeout))
+			policy.Timeout = r.drainPolicy.Timeout
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "found the drain policy of the tenant cluster")
+
+		return policy, nil
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "did not find the drain policy of the tenant cluster")
+	r.logger.LogCtx(ctx, "level", "debug", "message", "using default drain policy")
+
+	return r.drainPolicy, nil
+}
+
+// drainStartedAt returns the time the drain of the given pod started. The time
+// is taken from the pod annotation and falls back to the creation time of the
+// drainer config for pods drained before the annotation was introduced.
+func drainStartedAt(pod *corev1.Pod, drainerConfigCreated time.Time) time.Time {
+	v, ok := pod.GetAnnotations()[key.AnnotationDrainStartedAt]
+	if ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err == nil {
+			return t
+		}
+	}
+
+	return drainerConfigCreated
+}
+
+// withDrainStartedAt returns a copy of the given pod having the drain start
+// time annotation and whether the annotation changed. The drain start time is
+// never overwritten once it is set.
+func withDrainStartedAt(pod *corev1.Pod, startedAt time.Time) (*corev1.Pod, bool) {
+	p := pod.DeepCopy()
+
+	_, ok := p.GetAnnotations()[key.AnnotationDrainStartedAt]
+	if ok || startedAt.IsZero() {
+		return p, false
+	}
+
+	annotations := map[string]string{}
+	for k, v := range p.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[key.AnnotationDrainStartedAt] = startedAt.UTC().Format(time.RFC3339)
+	p.SetAnnotations(annotations)
+
+	return p, true
+}
+
+// updateDrainState records the drain start time and the given drain state of
+// the given pod. The pod is only updated in case one of them changed. False is
+// returned in case the pod could not be updated because the resource version
+// we know is outdated. The reconciliation is canceled then and the drain state
+// is updated during the next reconciliation.
+func (r *Resource) updateDrainState(ctx context.Context, pod *corev1.Pod, startedAt time.Time, state drain.State) (bool, error) {
+	p, changed := withDrainStartedAt(pod, startedAt)
+	if changed {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updating drain start time of the pod in the Kubernetes API")
+
+		updated, err := r.k8sClient.CoreV1().Pods(p.Namespace).Update(p)
+		if apierrors.IsConflict(err) {
+			r.cancelOutdatedPod(ctx)
+			return false, nil
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+		p = updated
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updated drain start time of the pod in the Kubernetes API")
+	}
+
+	p, changed = drain.WithState(p, state, time.Now())
+	if changed {
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updating drain state of the pod to %#q in the Kubernetes API", state))
+
+		_, err := r.k8sClient.CoreV1().Pods(p.Namespace).UpdateStatus(p)
+		if apierrors.IsConflict(err) {
+			r.cancelOutdatedPod(ctx)
+			return false, nil
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("updated drain state of the pod to %#q in the Kubernetes API", state))
+	}
+
+	return true, nil
+}
+
+// cancelOutdatedPod cancels the reconciliation of a pod which may be updated
+// by other processes or even humans meanwhile. In case the resource version we
+// currently know does not match the latest existing one, we give up here and
+// wait for the delete event to be replayed. Then we try again later until we
+// succeed.
+func (r *Resource) cancelOutdatedPod(ctx context.Context) {
+	r.logger.LogCtx(ctx, "level", "debug", "message", "cannot update the pod in the Kubernetes API due to outdated resource version")
+	resourcecanceledcontext.SetCanceled(ctx)
+	finalizerskeptcontext.SetKept(ctx)
+	r.logger.LogCtx(ctx, "level", "debug", "message", "canceling reconciliation")
+}
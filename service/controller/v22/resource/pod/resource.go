@@ -5,6 +5,8 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
 )
 
 const (
@@ -15,12 +17,24 @@ type Config struct {
 	G8sClient versioned.Interface
 	K8sClient kubernetes.Interface
 	Logger    micrologger.Logger
+
+	// DrainPolicy is the default policy applied when draining tenant cluster
+	// nodes. It can be overridden per cluster using the drain annotations of
+	// the KVMConfig.
+	DrainPolicy drain.Policy
+	// NodeDrainer is the optional built-in drainer. When it is set tenant
+	// cluster nodes are drained in process instead of creating DrainerConfigs
+	// for the node-operator.
+	NodeDrainer *drain.Drainer
 }
 
 type Resource struct {
 	g8sClient versioned.Interface
 	k8sClient kubernetes.Interface
 	logger    micrologger.Logger
+
+	drainPolicy drain.Policy
+	nodeDrainer *drain.Drainer
 }
 
 func New(config Config) (*Resource, error) {
@@ -34,10 +48,23 @@ func New(config Config) (*Resource, error) {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
 
+	if config.DrainPolicy.EvictionGracePeriod < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DrainPolicy.EvictionGracePeriod must not be negative", config)
+	}
+	if config.DrainPolicy.Timeout < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DrainPolicy.Timeout must not be negative", config)
+	}
+	if config.NodeDrainer != nil && config.DrainPolicy.Timeout == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.DrainPolicy.Timeout must not be zero when %T.NodeDrainer is set", config, config)
+	}
+
 	r := &Resource{
 		g8sClient: config.G8sClient,
 		k8sClient: config.K8sClient,
 		logger:    config.Logger,
+
+		drainPolicy: config.DrainPolicy,
+		nodeDrainer: config.NodeDrainer,
 	}
 
 	return r, nil
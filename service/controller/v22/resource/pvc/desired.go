@@ -7,6 +7,7 @@ import (
 	"github.com/giantswarm/microerror"
 	apiv1 "k8s.io/api/core/v1"
 
+	"github.com/giantswarm/kvm-operator/service/controller/v22/etcdstorage"
 	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
@@ -17,18 +18,20 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 	}
 
 	var PVCs []*apiv1.PersistentVolumeClaim
-
-	if key.StorageType(customObject) == "persistentVolume" {
+	{
 		r.logger.LogCtx(ctx, "level", "debug", "message", "computing the new PVCs")
 
-		PVCs, err = newEtcdPVCs(customObject)
+		etcdStorage, err := etcdstorage.New(customObject)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		PVCs, err = etcdStorage.PVCs(customObject)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
 
 		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("computed the %d new PVCs", len(PVCs)))
-	} else {
-		r.logger.LogCtx(ctx, "level", "debug", "message", "not computing the new PVCs because storage type is not 'persistentVolume'")
 	}
 
 	return PVCs, nil
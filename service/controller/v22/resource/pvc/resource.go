@@ -10,9 +10,6 @@ import (
 const (
 	// Name is the identifier of the resource.
 	Name = "pvcv22"
-	// StorageClass is the storage class annotation persistent volume claims are
-	// configured with.
-	StorageClass = "g8s-storage"
 )
 
 // Config represents the configuration used to create a new PVC resource.
@@ -72,6 +69,16 @@ func containsPVC(list []*apiv1.PersistentVolumeClaim, item *apiv1.PersistentVolu
 	return false
 }
 
+func getPVCByName(list []*apiv1.PersistentVolumeClaim, name string) (*apiv1.PersistentVolumeClaim, bool) {
+	for _, l := range list {
+		if l.Name == name {
+			return l, true
+		}
+	}
+
+	return nil, false
+}
+
 func toPVCs(v interface{}) ([]*apiv1.PersistentVolumeClaim, error) {
 	if v == nil {
 		return nil, nil
@@ -2,12 +2,41 @@ package pvc
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/operatorkit/controller"
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
 )
 
 func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange interface{}) error {
+	customObject, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	pvcsToUpdate, err := toPVCs(updateChange)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(pvcsToUpdate) != 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updating the PVCs in the Kubernetes API")
+
+		namespace := key.ClusterNamespace(customObject)
+		for _, PVC := range pvcsToUpdate {
+			_, err := r.k8sClient.CoreV1().PersistentVolumeClaims(namespace).Update(PVC)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updated the PVCs in the Kubernetes API")
+	} else {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "the PVCs do not need to be updated in the Kubernetes API")
+	}
+
 	return nil
 }
 
@@ -29,6 +58,49 @@ func (r *Resource) NewUpdatePatch(ctx context.Context, obj, currentState, desire
 	return patch, nil
 }
 
+// newUpdateChange computes the PVCs which have to be expanded because the
+// requested storage size grew. Persistent volume claims cannot be shrunk, so
+// smaller desired sizes are ignored.
 func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
-	return nil, nil
+	currentPVCs, err := toPVCs(currentState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	desiredPVCs, err := toPVCs(desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which PVCs have to be updated")
+
+	var pvcsToUpdate []*apiv1.PersistentVolumeClaim
+
+	for _, currentPVC := range currentPVCs {
+		desiredPVC, ok := getPVCByName(desiredPVCs, currentPVC.Name)
+		if !ok {
+			continue
+		}
+
+		currentSize := currentPVC.Spec.Resources.Requests[apiv1.ResourceStorage]
+		desiredSize := desiredPVC.Spec.Resources.Requests[apiv1.ResourceStorage]
+
+		switch desiredSize.Cmp(currentSize) {
+		case 1:
+			r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("expanding PVC '%s' from %s to %s", currentPVC.Name, currentSize.String(), desiredSize.String()))
+
+			pvcToUpdate := currentPVC.DeepCopy()
+			if pvcToUpdate.Spec.Resources.Requests == nil {
+				pvcToUpdate.Spec.Resources.Requests = apiv1.ResourceList{}
+			}
+			pvcToUpdate.Spec.Resources.Requests[apiv1.ResourceStorage] = desiredSize
+
+			pvcsToUpdate = append(pvcsToUpdate, pvcToUpdate)
+		case -1:
+			r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("not shrinking PVC '%s' from %s to %s", currentPVC.Name, currentSize.String(), desiredSize.String()))
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d PVCs that have to be updated", len(pvcsToUpdate)))
+
+	return pvcsToUpdate, nil
 }
@@ -0,0 +1,107 @@
+package pvc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Resource_PVC_newUpdateChange(t *testing.T) {
+	newPVC := func(name, size string) *apiv1.PersistentVolumeClaim {
+		return &apiv1.PersistentVolumeClaim{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: apiv1.PersistentVolumeClaimSpec{
+				Resources: apiv1.ResourceRequirements{
+					Requests: apiv1.ResourceList{
+						apiv1.ResourceStorage: resource.MustParse(size),
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		currentState  []*apiv1.PersistentVolumeClaim
+		desiredState  []*apiv1.PersistentVolumeClaim
+		expectedSizes map[string]string
+	}{
+		{
+			name:          "case 0: equal sizes do not cause updates",
+			currentState:  []*apiv1.PersistentVolumeClaim{newPVC("pvc-etcd-al9qy-0", "15Gi")},
+			desiredState:  []*apiv1.PersistentVolumeClaim{newPVC("pvc-etcd-al9qy-0", "15Gi")},
+			expectedSizes: map[string]string{},
+		},
+		{
+			name: "case 1: grown sizes cause expansion",
+			currentState: []*apiv1.PersistentVolumeClaim{
+				newPVC("pvc-etcd-al9qy-0", "15Gi"),
+				newPVC("pvc-etcd-al9qy-1", "15Gi"),
+			},
+			desiredState: []*apiv1.PersistentVolumeClaim{
+				newPVC("pvc-etcd-al9qy-0", "30Gi"),
+				newPVC("pvc-etcd-al9qy-1", "30Gi"),
+			},
+			expectedSizes: map[string]string{
+				"pvc-etcd-al9qy-0": "30Gi",
+				"pvc-etcd-al9qy-1": "30Gi",
+			},
+		},
+		{
+			name:          "case 2: shrunk sizes are ignored",
+			currentState:  []*apiv1.PersistentVolumeClaim{newPVC("pvc-etcd-al9qy-0", "30Gi")},
+			desiredState:  []*apiv1.PersistentVolumeClaim{newPVC("pvc-etcd-al9qy-0", "15Gi")},
+			expectedSizes: map[string]string{},
+		},
+		{
+			name:          "case 3: PVCs not being desired are ignored",
+			currentState:  []*apiv1.PersistentVolumeClaim{newPVC("pvc-etcd-al9qy-0", "15Gi")},
+			desiredState:  nil,
+			expectedSizes: map[string]string{},
+		},
+	}
+
+	var err error
+	var newResource *Resource
+	{
+		resourceConfig := DefaultConfig()
+		resourceConfig.K8sClient = fake.NewSimpleClientset()
+		resourceConfig.Logger = microloggertest.New()
+		newResource, err = New(resourceConfig)
+		if err != nil {
+			t.Fatal("expected", nil, "got", err)
+		}
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := newResource.newUpdateChange(context.TODO(), &v1alpha1.KVMConfig{}, tc.currentState, tc.desiredState)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			pvcs, err := toPVCs(result)
+			if err != nil {
+				t.Fatalf("expected %#v got %#v", nil, err)
+			}
+
+			if len(pvcs) != len(tc.expectedSizes) {
+				t.Fatalf("expected %d PVCs got %d", len(tc.expectedSizes), len(pvcs))
+			}
+			for _, p := range pvcs {
+				size := p.Spec.Resources.Requests[apiv1.ResourceStorage]
+				if size.Cmp(resource.MustParse(tc.expectedSizes[p.Name])) != 0 {
+					t.Fatalf("size of PVC '%s' == %q, want %q", p.Name, size.String(), tc.expectedSizes[p.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package reconciliation
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/metric"
+)
+
+func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
+	cr, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	metric.Reconciliations.Set(key.ClusterID(cr), time.Now())
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package reconciliation
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	Name = "reconciliationv22"
+)
+
+type Config struct {
+	Logger micrologger.Logger
+}
+
+// Resource records successful reconciliations of tenant clusters so the
+// cluster collector can report the time since the last one. It has to be the
+// last resource of the resource set, because it is only reached when all
+// other resources succeeded and the reconciliation was not canceled.
+type Resource struct {
+	logger micrologger.Logger
+}
+
+func New(config Config) (*Resource, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	r := &Resource{
+		logger: config.Logger,
+	}
+
+	return r, nil
+}
+
+func (r *Resource) Name() string {
+	return Name
+}
@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange interface{}) error {
+	customResource, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	secretsToCreate, err := toSecrets(createChange)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Create the secrets in the Kubernetes API.
+	if len(secretsToCreate) != 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "creating the secrets in the Kubernetes API")
+
+		namespace := key.ClusterNamespace(customResource)
+		for _, secret := range secretsToCreate {
+			_, err := r.k8sClient.CoreV1().Secrets(namespace).Create(secret)
+			if apierrors.IsAlreadyExists(err) {
+				// fall through
+			} else if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "created the secrets in the Kubernetes API")
+	} else {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "the secrets do not need to be created in the Kubernetes API")
+	}
+
+	return nil
+}
+
+func (r *Resource) newCreateChange(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
+	currentSecrets, err := toSecrets(currentState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	desiredSecrets, err := toSecrets(desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which secrets have to be created")
+
+	var secretsToCreate []*apiv1.Secret
+
+	for _, desiredSecret := range desiredSecrets {
+		if !containsSecret(currentSecrets, desiredSecret) {
+			secretsToCreate = append(secretsToCreate, desiredSecret)
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d secrets that have to be created", len(secretsToCreate)))
+
+	return secretsToCreate, nil
+}
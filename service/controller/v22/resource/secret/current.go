@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/controller/context/resourcecanceledcontext"
+	apiv1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interface{}, error) {
+	customResource, err := key.ToCustomObject(obj)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if key.IsDeleted(customResource) {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "redirecting responsibility of deletion of secrets to namespace termination")
+		resourcecanceledcontext.SetCanceled(ctx)
+		r.logger.LogCtx(ctx, "level", "debug", "message", "canceling resource")
+
+		return nil, nil
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "looking for a list of secrets in the Kubernetes API")
+
+	// Only the cloud config secrets are listed, so that e.g. service account
+	// token secrets in the cluster namespace are never considered for deletion.
+	var currentSecrets []*apiv1.Secret
+	{
+		namespace := key.ClusterNamespace(customResource)
+		options := apismetav1.ListOptions{
+			LabelSelector: key.LabelCloudConfigSecret,
+		}
+		secretList, err := r.k8sClient.CoreV1().Secrets(namespace).List(options)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, item := range secretList.Items {
+			s := item
+			currentSecrets = append(currentSecrets, &s)
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found a list of %d secrets in the Kubernetes API", len(currentSecrets)))
+
+	return currentSecrets, nil
+}
@@ -0,0 +1,108 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/controller"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func (r *Resource) ApplyDeleteChange(ctx context.Context, obj, deleteChange interface{}) error {
+	customResource, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	secretsToDelete, err := toSecrets(deleteChange)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(secretsToDelete) != 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "deleting the secrets in the Kubernetes API")
+
+		// Delete the secrets in the Kubernetes API.
+		namespace := key.ClusterNamespace(customResource)
+		for _, secret := range secretsToDelete {
+			err := r.k8sClient.CoreV1().Secrets(namespace).Delete(secret.Name, &apismetav1.DeleteOptions{})
+			if apierrors.IsNotFound(err) {
+				// fall through
+			} else if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "deleted the secrets in the Kubernetes API")
+	} else {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "the secrets do not need to be deleted from the Kubernetes API")
+	}
+
+	return nil
+}
+
+func (r *Resource) NewDeletePatch(ctx context.Context, obj, currentState, desiredState interface{}) (*controller.Patch, error) {
+	delete, err := r.newDeleteChangeForDeletePatch(ctx, obj, currentState, desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	patch := controller.NewPatch()
+	patch.SetDeleteChange(delete)
+
+	return patch, nil
+}
+
+func (r *Resource) newDeleteChangeForDeletePatch(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
+	currentSecrets, err := toSecrets(currentState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	desiredSecrets, err := toSecrets(desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which secrets have to be deleted")
+
+	var secretsToDelete []*apiv1.Secret
+
+	for _, currentSecret := range currentSecrets {
+		if containsSecret(desiredSecrets, currentSecret) {
+			secretsToDelete = append(secretsToDelete, currentSecret)
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d secrets that have to be deleted", len(secretsToDelete)))
+
+	return secretsToDelete, nil
+}
+
+func (r *Resource) newDeleteChangeForUpdatePatch(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
+	currentSecrets, err := toSecrets(currentState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	desiredSecrets, err := toSecrets(desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which secrets have to be deleted")
+
+	var secretsToDelete []*apiv1.Secret
+
+	for _, currentSecret := range currentSecrets {
+		if !containsSecret(desiredSecrets, currentSecret) {
+			secretsToDelete = append(secretsToDelete, currentSecret)
+		}
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d secrets that have to be deleted", len(secretsToDelete)))
+
+	return secretsToDelete, nil
+}
@@ -0,0 +1,95 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	apiv1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interface{}, error) {
+	customResource, err := key.ToCustomObject(obj)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", "computing the new secrets")
+
+	secrets, err := r.newSecrets(customResource)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("computed the %d new secrets", len(secrets)))
+
+	return secrets, nil
+}
+
+func (r *Resource) newSecrets(customResource v1alpha1.KVMConfig) ([]*apiv1.Secret, error) {
+	var secrets []*apiv1.Secret
+
+	certs, err := r.certsSearcher.SearchCluster(key.ClusterID(customResource))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	keys, err := r.keyWatcher.SearchCluster(key.ClusterID(customResource))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	extension, err := cloudconfig.LoadExtension(r.k8sClient, customResource)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(customResource.Spec.Cluster.Masters) != 0 {
+		template, err := r.cloudConfig.NewMasterSecretTemplate(customResource, certs, keys, extension.Master)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, node := range customResource.Spec.Cluster.Masters {
+			secrets = append(secrets, newSecret(customResource, template, node, key.MasterID))
+		}
+	}
+
+	if len(customResource.Spec.Cluster.Workers) != 0 {
+		template, err := r.cloudConfig.NewWorkerSecretTemplate(certs, extension.Worker)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, node := range customResource.Spec.Cluster.Workers {
+			secrets = append(secrets, newSecret(customResource, template, node, key.WorkerID))
+		}
+	}
+
+	return secrets, nil
+}
+
+// newSecret creates a new Kubernetes secret holding the given secret part of
+// the cloud config of the given node. prefix can be either "master" or
+// "worker" and is used to prefix the secret name.
+func newSecret(customResource v1alpha1.KVMConfig, template string, node v1alpha1.ClusterNode, prefix string) *apiv1.Secret {
+	return &apiv1.Secret{
+		ObjectMeta: apismetav1.ObjectMeta{
+			Name: key.CloudConfigSecretName(customResource, node, prefix),
+			Labels: map[string]string{
+				"cluster":                  key.ClusterID(customResource),
+				"customer":                 key.ClusterCustomer(customResource),
+				key.LabelCloudConfigSecret: "true",
+			},
+		},
+		Data: map[string][]byte{
+			KeyUserData: []byte(template),
+		},
+		Type: apiv1.SecretTypeOpaque,
+	}
+}
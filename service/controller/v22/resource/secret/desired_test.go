@@ -0,0 +1,122 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/certs/certstest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/randomkeys/randomkeystest"
+	"github.com/google/go-cmp/cmp"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig/cloudconfigtest"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_Resource_Secret_GetDesiredState(t *testing.T) {
+	testCases := []struct {
+		name                string
+		obj                 interface{}
+		expectedSecretNames []string
+	}{
+		{
+			name: "case 0: single master, single worker",
+			obj: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						ID: "al9qy",
+						Masters: []v1alpha1.ClusterNode{
+							{ID: "a"},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{ID: "b"},
+						},
+					},
+				},
+			},
+			expectedSecretNames: []string{
+				"master-al9qy-a-secret",
+				"worker-al9qy-b-secret",
+			},
+		},
+		{
+			name: "case 1: three masters, two workers",
+			obj: &v1alpha1.KVMConfig{
+				Spec: v1alpha1.KVMConfigSpec{
+					Cluster: v1alpha1.Cluster{
+						ID: "al9qy",
+						Masters: []v1alpha1.ClusterNode{
+							{ID: "a"},
+							{ID: "b"},
+							{ID: "c"},
+						},
+						Workers: []v1alpha1.ClusterNode{
+							{ID: "d"},
+							{ID: "e"},
+						},
+					},
+				},
+			},
+			expectedSecretNames: []string{
+				"master-al9qy-a-secret",
+				"master-al9qy-b-secret",
+				"master-al9qy-c-secret",
+				"worker-al9qy-d-secret",
+				"worker-al9qy-e-secret",
+			},
+		},
+	}
+
+	var err error
+	var newResource *Resource
+	{
+		c := Config{
+			CertsSearcher: certstest.NewSearcher(certstest.Config{}),
+			CloudConfig:   cloudconfigtest.New(),
+			K8sClient:     fake.NewSimpleClientset(),
+			KeyWatcher:    randomkeystest.NewSearcher(),
+			Logger:        microloggertest.New(),
+		}
+
+		newResource, err = New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := newResource.GetDesiredState(context.Background(), tc.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			secrets, err := toSecrets(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, s := range secrets {
+				names = append(names, s.Name)
+
+				if s.Labels[key.LabelCloudConfigSecret] != "true" {
+					t.Fatalf("expected secret %#q to have label %#q", s.Name, key.LabelCloudConfigSecret)
+				}
+				if s.Type != apiv1.SecretTypeOpaque {
+					t.Fatalf("expected secret %#q to have type %#q got %#q", s.Name, apiv1.SecretTypeOpaque, s.Type)
+				}
+				if len(s.Data[KeyUserData]) == 0 {
+					t.Fatalf("expected secret %#q to have data %#q", s.Name, KeyUserData)
+				}
+			}
+
+			if diff := cmp.Diff(names, tc.expectedSecretNames); diff != "" {
+				t.Fatalf("secret names not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package secret
+
+import (
+	"reflect"
+
+	"github.com/giantswarm/certs"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/giantswarm/randomkeys"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
+)
+
+const (
+	KeyUserData = "user_data"
+	// Name is the identifier of the resource.
+	Name = "secretv22"
+)
+
+// Config represents the configuration used to create a new secret resource.
+type Config struct {
+	// Dependencies.
+	CertsSearcher certs.Interface
+	CloudConfig   *cloudconfig.CloudConfig
+	K8sClient     kubernetes.Interface
+	KeyWatcher    randomkeys.Interface
+	Logger        micrologger.Logger
+}
+
+// Resource implements the secret resource. It manages the Secrets holding the
+// secret parts of the cloud configs of the tenant cluster VMs, i.e. the
+// certificates and the API server encryption config. They are mounted into
+// the k8s-kvm containers, which append them to the cloud configs read from the
+// ConfigMaps when the VMs boot.
+type Resource struct {
+	// Dependencies.
+	certsSearcher certs.Interface
+	cloudConfig   *cloudconfig.CloudConfig
+	k8sClient     kubernetes.Interface
+	keyWatcher    randomkeys.Interface
+	logger        micrologger.Logger
+}
+
+// New creates a new configured secret resource.
+func New(config Config) (*Resource, error) {
+	// Dependencies.
+	if config.CertsSearcher == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.CertsSearcher must not be empty")
+	}
+	if config.CloudConfig == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.CloudConfig must not be empty")
+	}
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
+	}
+	if config.KeyWatcher == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.KeyWatcher must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	newResource := &Resource{
+		// Dependencies.
+		certsSearcher: config.CertsSearcher,
+		cloudConfig:   config.CloudConfig,
+		k8sClient:     config.K8sClient,
+		keyWatcher:    config.KeyWatcher,
+		logger:        config.Logger,
+	}
+
+	return newResource, nil
+}
+
+func (r *Resource) Name() string {
+	return Name
+}
+
+func containsSecret(list []*apiv1.Secret, item *apiv1.Secret) bool {
+	_, err := getSecretByName(list, item.Name)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func getSecretByName(list []*apiv1.Secret, name string) (*apiv1.Secret, error) {
+	for _, l := range list {
+		if l.Name == name {
+			return l, nil
+		}
+	}
+
+	return nil, microerror.Mask(notFoundError)
+}
+
+func isSecretModified(a, b *apiv1.Secret) bool {
+	return !reflect.DeepEqual(a.Data, b.Data)
+}
+
+func toSecrets(v interface{}) ([]*apiv1.Secret, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	secrets, ok := v.([]*apiv1.Secret)
+	if !ok {
+		return nil, microerror.Maskf(wrongTypeError, "expected '%T', got '%T'", []*apiv1.Secret{}, v)
+	}
+
+	return secrets, nil
+}
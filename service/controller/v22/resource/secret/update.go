@@ -0,0 +1,97 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/controller"
+	apiv1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange interface{}) error {
+	customResource, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	secretsToUpdate, err := toSecrets(updateChange)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(secretsToUpdate) != 0 {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updating the secrets in the Kubernetes API")
+
+		// Update the secrets in the Kubernetes API.
+		namespace := key.ClusterNamespace(customResource)
+		for _, secret := range secretsToUpdate {
+			_, err := r.k8sClient.CoreV1().Secrets(namespace).Update(secret)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updated the secrets in the Kubernetes API")
+	} else {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "the secrets do not need to be updated in the Kubernetes API")
+	}
+
+	return nil
+}
+
+func (r *Resource) NewUpdatePatch(ctx context.Context, obj, currentState, desiredState interface{}) (*controller.Patch, error) {
+	create, err := r.newCreateChange(ctx, obj, currentState, desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	delete, err := r.newDeleteChangeForUpdatePatch(ctx, obj, currentState, desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	update, err := r.newUpdateChange(ctx, obj, currentState, desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	patch := controller.NewPatch()
+	patch.SetCreateChange(create)
+	patch.SetDeleteChange(delete)
+	patch.SetUpdateChange(update)
+
+	return patch, nil
+}
+
+func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
+	currentSecrets, err := toSecrets(currentState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	desiredSecrets, err := toSecrets(desiredState)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var secretsToUpdate []*apiv1.Secret
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "finding out which secrets have to be updated")
+
+		for _, currentSecret := range currentSecrets {
+			desiredSecret, err := getSecretByName(desiredSecrets, currentSecret.Name)
+			if IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			if isSecretModified(desiredSecret, currentSecret) {
+				secretsToUpdate = append(secretsToUpdate, desiredSecret)
+			}
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("found %d secrets that have to be updated", len(secretsToUpdate)))
+	}
+
+	return secretsToUpdate, nil
+}
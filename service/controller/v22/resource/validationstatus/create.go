@@ -0,0 +1,138 @@
+package validationstatus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/controller/context/reconciliationcanceledcontext"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/validation"
+)
+
+const (
+	// StatusClusterTypeInvalid is the type of the cluster condition set when
+	// the custom object violates invariants the resources rely on. The
+	// violations are listed as conditions of the resource status of this
+	// resource, because cluster conditions cannot hold a message.
+	StatusClusterTypeInvalid = "Invalid"
+)
+
+// EnsureCreated validates the custom object before any other resource gets to
+// see it. Violations are written to the status of the custom object and the
+// reconciliation is canceled, so invalid specs neither cause resources to fail
+// in a retry loop nor to panic.
+func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
+	cr, err := key.ToCustomObject(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	violations := validation.Violations(cr)
+
+	{
+		r.logger.LogCtx(ctx, "level", "debug", "message", "updating status with validation result")
+
+		if hasInvalidCondition(cr.Status.Cluster) != (len(violations) != 0) || !reflect.DeepEqual(statusViolations(cr.Status.Cluster), violations) {
+			_, err := r.statusPatcher.Patch(ctx, cr.GetNamespace(), cr.GetName(), func(status *v1alpha1.KVMConfigStatus) {
+				status.Cluster = withViolations(status.Cluster, violations, time.Now())
+			})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			r.logger.LogCtx(ctx, "level", "debug", "message", "updated status with validation result")
+		} else {
+			r.logger.LogCtx(ctx, "level", "debug", "message", "did not update status with validation result")
+		}
+	}
+
+	if len(violations) != 0 {
+		for _, v := range violations {
+			r.logger.LogCtx(ctx, "level", "warning", "message", fmt.Sprintf("invalid custom object: %s", v))
+		}
+
+		r.logger.LogCtx(ctx, "level", "debug", "message", "canceling reconciliation")
+		reconciliationcanceledcontext.SetCanceled(ctx)
+	}
+
+	return nil
+}
+
+func hasInvalidCondition(status v1alpha1.StatusCluster) bool {
+	for _, c := range status.Conditions {
+		if c.Type == StatusClusterTypeInvalid && c.Status == v1alpha1.StatusClusterStatusTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// statusViolations returns the violations recorded in the resource status of
+// this resource.
+func statusViolations(status v1alpha1.StatusCluster) []string {
+	var violations []string
+
+	for _, r := range status.Resources {
+		if r.Name != Name {
+			continue
+		}
+
+		for _, c := range r.Conditions {
+			violations = append(violations, c.Type)
+		}
+	}
+
+	return violations
+}
+
+// withViolations returns the given cluster status reflecting the given
+// violations. The invalid condition and the resource status of this resource
+// are removed in case there are no violations.
+func withViolations(status v1alpha1.StatusCluster, violations []string, t time.Time) v1alpha1.StatusCluster {
+	var conditions []v1alpha1.StatusClusterCondition
+	for _, c := range status.Conditions {
+		if c.Type == StatusClusterTypeInvalid {
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+
+	var resources []v1alpha1.StatusClusterResource
+	for _, r := range status.Resources {
+		if r.Name == Name {
+			continue
+		}
+		resources = append(resources, r)
+	}
+
+	if len(violations) != 0 {
+		conditions = append(conditions, v1alpha1.StatusClusterCondition{
+			LastTransitionTime: v1alpha1.DeepCopyTime{Time: t},
+			Status:             v1alpha1.StatusClusterStatusTrue,
+			Type:               StatusClusterTypeInvalid,
+		})
+
+		resource := v1alpha1.StatusClusterResource{
+			Name: Name,
+		}
+		for _, v := range violations {
+			resource.Conditions = append(resource.Conditions, v1alpha1.StatusClusterResourceCondition{
+				LastTransitionTime: v1alpha1.DeepCopyTime{Time: t},
+				Status:             v1alpha1.StatusClusterStatusTrue,
+				Type:               v,
+			})
+		}
+		resources = append(resources, resource)
+	}
+
+	status.Conditions = conditions
+	status.Resources = resources
+
+	return status
+}
@@ -0,0 +1,204 @@
+package validationstatus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/controller/context/reconciliationcanceledcontext"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
+)
+
+func Test_EnsureCreated(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		inputKVMConfig        *v1alpha1.KVMConfig
+		concurrentResource    string
+		expectedCanceled      bool
+		expectedConditions    []string
+		expectedViolations    []string
+		expectedResourceNames []string
+	}{
+		{
+			name:                  "case 0: valid custom object without status is left alone",
+			inputKVMConfig:        newCustomObject(1, nil),
+			expectedCanceled:      false,
+			expectedConditions:    nil,
+			expectedViolations:    nil,
+			expectedResourceNames: nil,
+		},
+		{
+			name:             "case 1: invalid custom object gets invalid condition and violations",
+			inputKVMConfig:   newCustomObject(0, nil),
+			expectedCanceled: true,
+			expectedConditions: []string{
+				StatusClusterTypeInvalid,
+			},
+			expectedViolations: []string{
+				"spec.kvm.masters[0].cpus must be greater than 0",
+			},
+			expectedResourceNames: []string{
+				Name,
+			},
+		},
+		{
+			name: "case 2: fixed custom object gets invalid condition and violations removed",
+			inputKVMConfig: newCustomObject(1, &v1alpha1.StatusCluster{
+				Conditions: []v1alpha1.StatusClusterCondition{
+					{Status: v1alpha1.StatusClusterStatusTrue, Type: v1alpha1.StatusClusterTypeCreated},
+					{Status: v1alpha1.StatusClusterStatusTrue, Type: StatusClusterTypeInvalid},
+				},
+				Resources: []v1alpha1.StatusClusterResource{
+					{
+						Name: Name,
+						Conditions: []v1alpha1.StatusClusterResourceCondition{
+							{Status: v1alpha1.StatusClusterStatusTrue, Type: "spec.kvm.masters[0].cpus must be greater than 0"},
+						},
+					},
+				},
+			}),
+			expectedCanceled: false,
+			expectedConditions: []string{
+				v1alpha1.StatusClusterTypeCreated,
+			},
+			expectedViolations:    nil,
+			expectedResourceNames: nil,
+		},
+		{
+			name:               "case 3: status changed concurrently, keep concurrent change",
+			inputKVMConfig:     newCustomObject(0, nil),
+			concurrentResource: "deploymentv22",
+			expectedCanceled:   true,
+			expectedConditions: []string{
+				StatusClusterTypeInvalid,
+			},
+			expectedViolations: []string{
+				"spec.kvm.masters[0].cpus must be greater than 0",
+			},
+			expectedResourceNames: []string{
+				"deploymentv22",
+				Name,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := statuspatchtest.NewClientset(tc.inputKVMConfig)
+
+			if tc.concurrentResource != "" {
+				var patched bool
+				clientset.PrependReactor("patch", "kvmconfigs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					// Simulate another resource writing its status between reading
+					// and patching the status the first time.
+					if !patched {
+						patched = true
+
+						concurrent := tc.inputKVMConfig.DeepCopy()
+						concurrent.Status.Cluster.Resources = append(concurrent.Status.Cluster.Resources, v1alpha1.StatusClusterResource{
+							Name: tc.concurrentResource,
+						})
+						err := clientset.Update(concurrent)
+						if err != nil {
+							return true, nil, err
+						}
+					}
+
+					return false, nil, nil
+				})
+			}
+
+			var r *Resource
+			{
+				c := Config{
+					G8sClient: clientset,
+					Logger:    microloggertest.New(),
+				}
+
+				var err error
+				r, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx := reconciliationcanceledcontext.NewContext(context.Background(), make(chan struct{}))
+
+			err := r.EnsureCreated(ctx, tc.inputKVMConfig)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if reconciliationcanceledcontext.IsCanceled(ctx) != tc.expectedCanceled {
+				t.Fatalf("canceled == %t, want %t", reconciliationcanceledcontext.IsCanceled(ctx), tc.expectedCanceled)
+			}
+
+			kvmConfig, err := r.g8sClient.ProviderV1alpha1().KVMConfigs(tc.inputKVMConfig.GetNamespace()).Get(tc.inputKVMConfig.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var conditions []string
+			for _, c := range kvmConfig.Status.Cluster.Conditions {
+				conditions = append(conditions, c.Type)
+			}
+			if diff := cmp.Diff(conditions, tc.expectedConditions); diff != "" {
+				t.Fatalf("conditions not met after EnsureCreated(): (-got +expected)\n%s\n", diff)
+			}
+
+			var resourceNames []string
+			for _, r := range kvmConfig.Status.Cluster.Resources {
+				resourceNames = append(resourceNames, r.Name)
+			}
+			if diff := cmp.Diff(resourceNames, tc.expectedResourceNames); diff != "" {
+				t.Fatalf("resources not met after EnsureCreated(): (-got +expected)\n%s\n", diff)
+			}
+
+			if diff := cmp.Diff(statusViolations(kvmConfig.Status.Cluster), tc.expectedViolations); diff != "" {
+				t.Fatalf("violations not met after EnsureCreated(): (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func newCustomObject(masterCPUs int, status *v1alpha1.StatusCluster) *v1alpha1.KVMConfig {
+	cr := &v1alpha1.KVMConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "al9qy",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+				Masters: []v1alpha1.ClusterNode{
+					{ID: "m1"},
+				},
+			},
+			KVM: v1alpha1.KVMConfigSpecKVM{
+				Masters: []v1alpha1.KVMConfigSpecKVMNode{
+					{CPUs: masterCPUs, Memory: "4G"},
+				},
+				Network: v1alpha1.KVMConfigSpecKVMNetwork{
+					Flannel: v1alpha1.KVMConfigSpecKVMNetworkFlannel{
+						VNI: 12,
+					},
+				},
+			},
+			VersionBundle: v1alpha1.KVMConfigSpecVersionBundle{
+				Version: "3.6.0",
+			},
+		},
+	}
+
+	if status != nil {
+		cr.Status.Cluster = *status
+	}
+
+	return cr
+}
@@ -0,0 +1,11 @@
+package validationstatus
+
+import (
+	"context"
+)
+
+// EnsureDeleted does nothing. Deletion of invalid custom objects must not be
+// blocked, so the other resources are responsible for coping with them.
+func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package validationstatus
+
+import (
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch"
+)
+
+const (
+	Name = "validationstatusv22"
+)
+
+type Config struct {
+	G8sClient versioned.Interface
+	Logger    micrologger.Logger
+}
+
+type Resource struct {
+	g8sClient     versioned.Interface
+	logger        micrologger.Logger
+	statusPatcher *statuspatch.Patcher
+}
+
+func New(config Config) (*Resource, error) {
+	if config.G8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.G8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	var statusPatcher *statuspatch.Patcher
+	{
+		c := statuspatch.Config{
+			G8sClient: config.G8sClient,
+			Logger:    config.Logger,
+		}
+
+		var err error
+		statusPatcher, err = statuspatch.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	r := &Resource{
+		g8sClient:     config.G8sClient,
+		logger:        config.Logger,
+		statusPatcher: statusPatcher,
+	}
+
+	return r, nil
+}
+
+func (r *Resource) Name() string {
+	return Name
+}
@@ -0,0 +1,195 @@
+package v22
+
+import (
+	"io/ioutil"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/controller"
+
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/cloudconfig"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/deployment"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/resource/nodeindexstatus"
+	"github.com/giantswarm/kvm-operator/service/etcdbackup"
+)
+
+// The functions in this file turn the tenant cluster settings only this version
+// bundle knows about into the configuration of its resources.
+
+func validateSettings(config registry.Config) error {
+	if config.Version == "" {
+		return microerror.Maskf(invalidConfigError, "%T.Version must not be empty", config)
+	}
+
+	return nil
+}
+
+func newAPIServerConfig(config registry.Config) (cloudconfig.APIServerConfig, error) {
+	api := config.Tenant.APIServer
+
+	var auditPolicy string
+	if api.Audit.PolicyFile != "" {
+		b, err := ioutil.ReadFile(api.Audit.PolicyFile)
+		if err != nil {
+			return cloudconfig.APIServerConfig{}, microerror.Mask(err)
+		}
+
+		auditPolicy = string(b)
+	}
+
+	featureGates, err := cloudconfig.ParseFeatureGates(api.FeatureGates)
+	if err != nil {
+		return cloudconfig.APIServerConfig{}, microerror.Mask(err)
+	}
+
+	c := cloudconfig.APIServerConfig{
+		AdmissionPlugins: cloudconfig.APIServerAdmissionPlugins{
+			Disable: api.AdmissionPlugins.Disable,
+			Enable:  api.AdmissionPlugins.Enable,
+		},
+		Audit: cloudconfig.APIServerAudit{
+			LogFormat: api.Audit.LogFormat,
+			LogMode:   api.Audit.LogMode,
+			Policy:    auditPolicy,
+		},
+		EncryptionProviders: api.EncryptionProviders,
+		ExtraSANs:           api.ExtraSANs,
+		FeatureGates:        featureGates,
+	}
+
+	return c, nil
+}
+
+// newCloudConfigOutput returns the output of the cloud configs of tenant
+// cluster nodes. The format is declared by the delta of the version bundle and
+// can be overridden by the tenant cluster settings. The k8s-kvm image of this
+// version bundle only reads base64 cloud configs, so other formats require the
+// delta to declare CloudConfigSecretSupport.
+func newCloudConfigOutput(config registry.Config) (cloudconfig.OutputConfig, error) {
+	format := config.Delta.CloudConfigFormat
+	if format == "" {
+		format = cloudconfig.OutputFormatBase64
+	}
+	if config.Tenant.Ignition.Format != "" {
+		format = config.Tenant.Ignition.Format
+	}
+
+	if format != cloudconfig.OutputFormatBase64 && !config.Delta.CloudConfigSecretSupport {
+		return cloudconfig.OutputConfig{}, microerror.Maskf(invalidConfigError, "cloud config format %#q of version bundle %s requires a k8s-kvm image supporting CLOUD_CONFIG_FORMAT", format, config.Version)
+	}
+
+	c := cloudconfig.OutputConfig{
+		Format:  format,
+		MaxSize: config.Tenant.Ignition.MaxSize,
+	}
+
+	return c, nil
+}
+
+func newDrainPolicy(config registry.Config) drain.Policy {
+	return drain.Policy{
+		EvictionGracePeriod: config.Tenant.Drain.EvictionGracePeriod,
+		ForceAfterTimeout:   config.Tenant.Drain.ForceAfterTimeout,
+		Timeout:             config.Tenant.Drain.Timeout,
+	}
+}
+
+// newEtcdBackupTarget returns the configured etcd backup target or nil in case
+// etcd backups and restores are disabled.
+func newEtcdBackupTarget(config registry.Config) (etcdbackup.Target, error) {
+	backup := config.Tenant.EtcdBackup
+
+	if backup.Target == "" {
+		return nil, nil
+	}
+
+	c := etcdbackup.TargetConfig{
+		URL: backup.Target,
+
+		S3AccessKeyID:     backup.S3.AccessKeyID,
+		S3Endpoint:        backup.S3.Endpoint,
+		S3Region:          backup.S3.Region,
+		S3SecretAccessKey: backup.S3.SecretAccessKey,
+	}
+
+	target, err := etcdbackup.NewTarget(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return target, nil
+}
+
+// newImages returns the container images of master and worker pods with the
+// images declared by the delta of the version bundle applied.
+func newImages(config registry.Config) (deployment.Images, error) {
+	images := deployment.DefaultConfig().Images
+
+	for name, image := range config.Delta.Images {
+		switch name {
+		case registry.ImageEtcd:
+			images.Etcd = image
+		case registry.ImageK8sEndpointUpdater:
+			images.K8sEndpointUpdater = image
+		case registry.ImageK8sKVM:
+			images.K8sKVM = image
+		case registry.ImageK8sKVMHealth:
+			images.K8sKVMHealth = image
+		case registry.ImageShutdownDeferrer:
+			images.ShutdownDeferrer = image
+		default:
+			return deployment.Images{}, microerror.Maskf(invalidConfigError, "unknown image %#q in delta of version bundle %s", name, config.Version)
+		}
+	}
+
+	return images, nil
+}
+
+func newNodeIndexPolicy(config registry.Config) nodeindexstatus.Policy {
+	return nodeindexstatus.Policy{
+		MaxIndex:         config.Tenant.NodeIndex.MaxIndex,
+		QuarantinePeriod: config.Tenant.NodeIndex.QuarantinePeriod,
+	}
+}
+
+func newResizePolicy(config registry.Config) deployment.ResizePolicy {
+	return deployment.ResizePolicy{
+		CapacityCheck: config.Tenant.Resize.CapacityCheck,
+	}
+}
+
+func newUpdatePolicy(config registry.Config) deployment.UpdatePolicy {
+	update := config.Tenant.Update
+
+	return deployment.UpdatePolicy{
+		BatchSize:             update.BatchSize,
+		BatchWait:             update.BatchWait,
+		MastersFirst:          update.MastersFirst,
+		MaxUnavailableMasters: update.MaxUnavailableMasters,
+		MaxUnavailableWorkers: update.MaxUnavailableWorkers,
+	}
+}
+
+// replaceResources replaces the given resources with the equally named ones
+// declared by the delta of the version bundle.
+func replaceResources(config registry.Config, resources []controller.Resource) ([]controller.Resource, error) {
+	var replaced []controller.Resource
+
+	for _, r := range resources {
+		newResource, ok := config.Delta.Resources[r.Name()]
+		if !ok {
+			replaced = append(replaced, r)
+			continue
+		}
+
+		r, err := newResource(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		replaced = append(replaced, r)
+	}
+
+	return replaced, nil
+}
@@ -0,0 +1,23 @@
+package statuspatch
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var tooManyConflictsError = &microerror.Error{
+	Kind: "tooManyConflictsError",
+}
+
+// IsTooManyConflicts asserts tooManyConflictsError.
+func IsTooManyConflicts(err error) bool {
+	return microerror.Cause(err) == tooManyConflictsError
+}
@@ -0,0 +1,148 @@
+// Package statuspatch provides conflict safe status updates of KVMConfigs.
+// Several resources write different parts of the KVMConfig status, as does the
+// statusresource. Instead of updating the whole status, the Patcher sends JSON
+// merge patches of only the changed parts to the status subresource. The
+// patches are guarded by the resource version of the KVMConfig they were
+// computed from and recomputed against the current KVMConfig on conflicts.
+package statuspatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/evanphx/json-patch"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a patch is recomputed and sent
+	// again after a conflict, unless configured otherwise.
+	DefaultMaxRetries = 5
+)
+
+type Config struct {
+	G8sClient versioned.Interface
+	Logger    micrologger.Logger
+
+	// MaxRetries is the number of times a patch is recomputed and sent again
+	// after a conflict. Zero means DefaultMaxRetries.
+	MaxRetries int
+}
+
+type Patcher struct {
+	g8sClient versioned.Interface
+	logger    micrologger.Logger
+
+	maxRetries int
+}
+
+func New(config Config) (*Patcher, error) {
+	if config.G8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.G8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.MaxRetries < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxRetries must not be negative", config)
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+
+	p := &Patcher{
+		g8sClient: config.G8sClient,
+		logger:    config.Logger,
+
+		maxRetries: config.MaxRetries,
+	}
+
+	return p, nil
+}
+
+// Patch applies the given mutation to the status of the current version of
+// the KVMConfig with the given namespace and name. The mutation may be called
+// multiple times, once for every attempt, and must therefore only depend on
+// the status it is given. The returned boolean is true when the status was
+// patched and false when the mutation did not change anything.
+func (p *Patcher) Patch(ctx context.Context, namespace, name string, mutate func(status *v1alpha1.KVMConfigStatus)) (bool, error) {
+	for i := 0; i <= p.maxRetries; i++ {
+		cr, err := p.g8sClient.ProviderV1alpha1().KVMConfigs(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		patch, err := newPatch(cr, mutate)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+		if patch == nil {
+			return false, nil
+		}
+
+		_, err = p.g8sClient.ProviderV1alpha1().KVMConfigs(namespace).Patch(name, types.MergePatchType, patch, "status")
+		if errors.IsConflict(err) {
+			p.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("status of KVMConfig '%s/%s' changed concurrently, retrying", namespace, name))
+			continue
+		} else if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		return true, nil
+	}
+
+	return false, microerror.Maskf(tooManyConflictsError, "patching status of KVMConfig '%s/%s' failed %d times", namespace, name, p.maxRetries+1)
+}
+
+// newPatch returns the JSON merge patch turning the status of the given
+// KVMConfig into the mutated one. The patch carries the resource version of
+// the given KVMConfig, so it is rejected with a conflict in case the KVMConfig
+// changed in the meantime. Nil is returned when the mutation does not change
+// the status.
+func newPatch(cr *v1alpha1.KVMConfig, mutate func(status *v1alpha1.KVMConfigStatus)) ([]byte, error) {
+	modified := cr.DeepCopy()
+	mutate(&modified.Status)
+
+	if reflect.DeepEqual(cr.Status, modified.Status) {
+		return nil, nil
+	}
+
+	originalJSON, err := json.Marshal(map[string]interface{}{"status": cr.Status})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	modifiedJSON, err := json.Marshal(map[string]interface{}{"status": modified.Status})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	mergePatch, err := jsonpatch.CreateMergePatch(originalJSON, modifiedJSON)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var patch map[string]interface{}
+	err = json.Unmarshal(mergePatch, &patch)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	patch["metadata"] = map[string]interface{}{
+		"resourceVersion": cr.GetResourceVersion(),
+	}
+
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,157 @@
+package statuspatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/statuspatch/statuspatchtest"
+)
+
+func Test_Patcher_Patch(t *testing.T) {
+	testCases := []struct {
+		name             string
+		mutate           func(status *v1alpha1.KVMConfigStatus)
+		concurrentWrites int
+		expectedPatched  bool
+		expectedPatches  int
+		expectedStatus   v1alpha1.KVMConfigStatus
+		errorMatcher     func(error) bool
+	}{
+		{
+			name: "case 0: mutation does not change status, do not patch",
+			mutate: func(status *v1alpha1.KVMConfigStatus) {
+				status.KVM.NodeIndexes["a"] = 1
+			},
+			expectedPatched: false,
+			expectedPatches: 0,
+			expectedStatus:  newStatus(map[string]int{"a": 1, "b": 2}, nil),
+		},
+		{
+			name: "case 1: remove node index",
+			mutate: func(status *v1alpha1.KVMConfigStatus) {
+				delete(status.KVM.NodeIndexes, "b")
+			},
+			expectedPatched: true,
+			expectedPatches: 1,
+			expectedStatus:  newStatus(map[string]int{"a": 1}, nil),
+		},
+		{
+			name: "case 2: status changed concurrently, retry and keep concurrent change",
+			mutate: func(status *v1alpha1.KVMConfigStatus) {
+				status.KVM.NodeIndexes["c"] = 3
+			},
+			concurrentWrites: 2,
+			expectedPatched:  true,
+			expectedPatches:  3,
+			expectedStatus:   newStatus(map[string]int{"a": 1, "b": 2, "c": 3}, []string{"Concurrent"}),
+		},
+		{
+			name: "case 3: status changed concurrently too often, give up",
+			mutate: func(status *v1alpha1.KVMConfigStatus) {
+				status.KVM.NodeIndexes["c"] = 3
+			},
+			concurrentWrites: DefaultMaxRetries + 1,
+			expectedPatched:  false,
+			expectedPatches:  DefaultMaxRetries + 1,
+			expectedStatus:   newStatus(map[string]int{"a": 1, "b": 2}, []string{"Concurrent"}),
+			errorMatcher:     IsTooManyConflicts,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := &v1alpha1.KVMConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "al9qy",
+					Namespace:       "default",
+					ResourceVersion: "1",
+				},
+				Status: newStatus(map[string]int{"a": 1, "b": 2}, nil),
+			}
+			clientset := statuspatchtest.NewClientset(cr)
+
+			var patches int
+			clientset.PrependReactor("patch", "kvmconfigs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				patches++
+
+				// Simulate another writer changing the status between reading and
+				// patching it. The clientset must not be used within reactors, so
+				// the concurrent change is based on the initial custom object.
+				if patches <= tc.concurrentWrites {
+					concurrent := cr.DeepCopy()
+					concurrent.Status.Cluster = newStatus(nil, []string{"Concurrent"}).Cluster
+					err := clientset.Update(concurrent)
+					if err != nil {
+						return true, nil, err
+					}
+				}
+
+				return false, nil, nil
+			})
+
+			var p *Patcher
+			{
+				c := Config{
+					G8sClient: clientset,
+					Logger:    microloggertest.New(),
+				}
+
+				var err error
+				p, err = New(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			patched, err := p.Patch(context.Background(), "default", "al9qy", tc.mutate)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if patched != tc.expectedPatched {
+				t.Fatalf("patched == %t, want %t", patched, tc.expectedPatched)
+			}
+			if patches != tc.expectedPatches {
+				t.Fatalf("patches == %d, want %d", patches, tc.expectedPatches)
+			}
+
+			kvmConfig, err := clientset.ProviderV1alpha1().KVMConfigs("default").Get("al9qy", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(kvmConfig.Status, tc.expectedStatus); diff != "" {
+				t.Fatalf("status not as expected: (-got +expected)\n%s\n", diff)
+			}
+		})
+	}
+}
+
+func newStatus(nodeIndexes map[string]int, conditions []string) v1alpha1.KVMConfigStatus {
+	var status v1alpha1.KVMConfigStatus
+
+	status.KVM.NodeIndexes = nodeIndexes
+	for _, c := range conditions {
+		status.Cluster.Conditions = append(status.Cluster.Conditions, v1alpha1.StatusClusterCondition{
+			Status: v1alpha1.StatusClusterStatusTrue,
+			Type:   c,
+		})
+	}
+
+	return status
+}
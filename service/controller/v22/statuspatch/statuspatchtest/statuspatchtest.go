@@ -0,0 +1,166 @@
+// Package statuspatchtest provides a fake clientset for testing code using
+// the statuspatch package.
+package statuspatchtest
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/evanphx/json-patch"
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var kvmConfigResource = schema.GroupVersionResource{
+	Group:    "provider.giantswarm.io",
+	Version:  "v1alpha1",
+	Resource: "kvmconfigs",
+}
+
+// Clientset is a fake clientset like the one returned by
+// fake.NewSimpleClientset with support for JSON merge patches of KVMConfigs.
+type Clientset struct {
+	*fake.Clientset
+
+	tracker k8stesting.ObjectTracker
+}
+
+// NewClientset returns a fake clientset like fake.NewSimpleClientset. Unlike
+// the plain fake clientset it supports JSON merge patches of KVMConfigs and
+// rejects patches with a conflict in case they carry a resource version which
+// does not match the current one, as are updates. The resource version of
+// KVMConfigs is increased with every patch and update, so concurrent writers
+// can be simulated by updating KVMConfigs between reading and writing them.
+func NewClientset(objects ...runtime.Object) *Clientset {
+	scheme := runtime.NewScheme()
+	err := fake.AddToScheme(scheme)
+	if err != nil {
+		panic(err)
+	}
+
+	tracker := k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	for _, obj := range objects {
+		err := tracker.Add(obj)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	clientset := &fake.Clientset{}
+	clientset.AddReactor("*", "*", k8stesting.ObjectReaction(tracker))
+	clientset.AddWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := tracker.Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		return true, w, nil
+	})
+
+	clientset.PrependReactor("patch", "kvmconfigs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		if patchAction.GetPatchType() != types.MergePatchType {
+			return false, nil, nil
+		}
+
+		obj, err := tracker.Get(kvmConfigResource, action.GetNamespace(), patchAction.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		current := obj.(*v1alpha1.KVMConfig)
+
+		var precondition struct {
+			Metadata struct {
+				ResourceVersion *string `json:"resourceVersion"`
+			} `json:"metadata"`
+		}
+		err = json.Unmarshal(patchAction.GetPatch(), &precondition)
+		if err != nil {
+			return true, nil, err
+		}
+		rv := precondition.Metadata.ResourceVersion
+		if rv != nil && *rv != current.GetResourceVersion() {
+			return true, nil, errors.NewConflict(kvmConfigResource.GroupResource(), current.GetName(), nil)
+		}
+
+		currentJSON, err := json.Marshal(current)
+		if err != nil {
+			return true, nil, err
+		}
+		patchedJSON, err := jsonpatch.MergePatch(currentJSON, patchAction.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		patched := &v1alpha1.KVMConfig{}
+		err = json.Unmarshal(patchedJSON, patched)
+		if err != nil {
+			return true, nil, err
+		}
+		patched.SetResourceVersion(nextResourceVersion(current.GetResourceVersion()))
+
+		err = tracker.Update(kvmConfigResource, patched, action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+
+		return true, patched, nil
+	})
+
+	clientset.PrependReactor("update", "kvmconfigs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updated := action.(k8stesting.UpdateAction).GetObject().(*v1alpha1.KVMConfig).DeepCopy()
+
+		obj, err := tracker.Get(kvmConfigResource, action.GetNamespace(), updated.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		current := obj.(*v1alpha1.KVMConfig)
+
+		rv := updated.GetResourceVersion()
+		if rv != "" && rv != current.GetResourceVersion() {
+			return true, nil, errors.NewConflict(kvmConfigResource.GroupResource(), current.GetName(), nil)
+		}
+		updated.SetResourceVersion(nextResourceVersion(current.GetResourceVersion()))
+
+		err = tracker.Update(kvmConfigResource, updated, action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+
+		return true, updated, nil
+	})
+
+	c := &Clientset{
+		Clientset: clientset,
+
+		tracker: tracker,
+	}
+
+	return c
+}
+
+// Update updates the given KVMConfig like a concurrent writer would,
+// increasing its resource version. The reactors of the clientset are bypassed,
+// so Update can be used within reactors, e.g. to change the KVMConfig between
+// reading and patching it.
+func (c *Clientset) Update(cr *v1alpha1.KVMConfig) error {
+	obj, err := c.tracker.Get(kvmConfigResource, cr.GetNamespace(), cr.GetName())
+	if err != nil {
+		return err
+	}
+
+	cr = cr.DeepCopy()
+	cr.SetResourceVersion(nextResourceVersion(obj.(*v1alpha1.KVMConfig).GetResourceVersion()))
+
+	return c.tracker.Update(kvmConfigResource, cr, cr.GetNamespace())
+}
+
+func nextResourceVersion(rv string) string {
+	i, _ := strconv.Atoi(rv)
+	return strconv.Itoa(i + 1)
+}
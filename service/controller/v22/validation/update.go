@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/etcdstorage"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+// ValidateUpdate returns an invalidSpecError describing all violations of the
+// transition from the old to the new custom object. Nil is returned in case
+// the update is valid.
+func ValidateUpdate(oldCR, newCR v1alpha1.KVMConfig) error {
+	violations := UpdateViolations(oldCR, newCR)
+	if len(violations) != 0 {
+		return microerror.Maskf(invalidSpecError, "%s", strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+// UpdateViolations returns human readable descriptions of all invariants the
+// new custom object violates, including changes to fields which must not be
+// modified once the tenant cluster got created.
+func UpdateViolations(oldCR, newCR v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	violations = append(violations, Violations(newCR)...)
+	violations = append(violations, ImmutabilityViolations(oldCR, newCR)...)
+
+	return violations
+}
+
+// ImmutabilityViolations returns human readable descriptions of the changes to
+// fields which must not be modified once the tenant cluster got created. These
+// do not depend on the spec shape of a particular version bundle and hold for
+// custom objects of all version bundles.
+func ImmutabilityViolations(oldCR, newCR v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	if key.ClusterID(newCR) != key.ClusterID(oldCR) {
+		violations = append(violations, fmt.Sprintf("spec.cluster.id must not be changed from '%s' to '%s'", key.ClusterID(oldCR), key.ClusterID(newCR)))
+	}
+
+	// Removing masters breaks the etcd quorum because etcd members are never
+	// removed from the cluster.
+	if len(newCR.Spec.Cluster.Masters) < len(oldCR.Spec.Cluster.Masters) {
+		violations = append(violations, fmt.Sprintf("spec.cluster.masters must not shrink from %d to %d entries", len(oldCR.Spec.Cluster.Masters), len(newCR.Spec.Cluster.Masters)))
+	}
+
+	// The VNI defines the flannel network and the host ports of the tenant
+	// cluster VMs, which cannot be moved while the VMs are running.
+	if newCR.Spec.KVM.Network.Flannel.VNI != oldCR.Spec.KVM.Network.Flannel.VNI {
+		violations = append(violations, fmt.Sprintf("spec.kvm.network.flannel.vni must not be changed from %d to %d", oldCR.Spec.KVM.Network.Flannel.VNI, newCR.Spec.KVM.Network.Flannel.VNI))
+	}
+
+	// Persistent volume claims can only be expanded.
+	{
+		oldSize, oldErr := etcdstorage.Size(oldCR)
+		newSize, newErr := etcdstorage.Size(newCR)
+		if oldErr == nil && newErr == nil && newSize.Cmp(oldSize) < 0 {
+			violations = append(violations, fmt.Sprintf("etcd storage size must not be reduced from %s to %s", oldSize.String(), newSize.String()))
+		}
+	}
+
+	return violations
+}
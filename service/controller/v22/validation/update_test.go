@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+)
+
+func Test_UpdateViolations(t *testing.T) {
+	testCases := []struct {
+		name               string
+		mutate             func(cr *v1alpha1.KVMConfig)
+		expectedViolations []string
+	}{
+		{
+			name:               "case 0: unchanged custom object",
+			mutate:             func(cr *v1alpha1.KVMConfig) {},
+			expectedViolations: nil,
+		},
+		{
+			name: "case 1: adding workers and growing etcd storage",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.Cluster.Workers = append(cr.Spec.Cluster.Workers, v1alpha1.ClusterNode{ID: "w3"})
+				cr.Spec.KVM.Workers = append(cr.Spec.KVM.Workers, v1alpha1.KVMConfigSpecKVMNode{CPUs: 4, Memory: "8G"})
+				cr.SetAnnotations(map[string]string{key.AnnotationEtcdStorageSize: "20Gi"})
+			},
+			expectedViolations: nil,
+		},
+		{
+			name: "case 2: changing immutable fields",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.Cluster.ID = "x7k2p"
+				cr.Spec.KVM.Network.Flannel.VNI = 13
+			},
+			expectedViolations: []string{
+				"spec.cluster.id must not be changed from 'al9qy' to 'x7k2p'",
+				"spec.kvm.network.flannel.vni must not be changed from 12 to 13",
+			},
+		},
+		{
+			name: "case 3: shrinking masters and etcd storage",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.Cluster.Masters = nil
+				cr.Spec.KVM.Masters = nil
+				cr.SetAnnotations(map[string]string{key.AnnotationEtcdStorageSize: "5Gi"})
+			},
+			expectedViolations: []string{
+				"spec.cluster.masters must not be empty",
+				"spec.cluster.masters must not shrink from 1 to 0 entries",
+				"etcd storage size must not be reduced from 15Gi to 5Gi",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oldCR := newValidCustomObject()
+			oldCR.SetAnnotations(map[string]string{key.AnnotationEtcdStorageSize: "15Gi"})
+			newCR := *oldCR.DeepCopy()
+			tc.mutate(&newCR)
+
+			violations := UpdateViolations(oldCR, newCR)
+			if !reflect.DeepEqual(violations, tc.expectedViolations) {
+				t.Fatalf("expected %#v got %#v", tc.expectedViolations, violations)
+			}
+
+			err := ValidateUpdate(oldCR, newCR)
+			if len(tc.expectedViolations) == 0 && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if len(tc.expectedViolations) != 0 && !IsInvalidSpec(err) {
+				t.Fatalf("error == %#v, want invalidSpecError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Package validation checks the invariants of KVMConfig custom objects the
+// resources of this version bundle rely on. It is shared by the controller and
+// the admission webhook so that both reject the same specs.
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/etcdstorage"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/key"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/kubelet"
+	"github.com/giantswarm/kvm-operator/service/controller/v22/maintenance"
+)
+
+const (
+	// maxPort is the highest valid TCP port.
+	maxPort = 65535
+)
+
+// Validate returns an invalidSpecError describing all violations of the given
+// custom object. Nil is returned in case the custom object is valid.
+func Validate(cr v1alpha1.KVMConfig) error {
+	violations := Violations(cr)
+	if len(violations) != 0 {
+		return microerror.Maskf(invalidSpecError, "%s", strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+// Violations returns human readable descriptions of all invariants the given
+// custom object violates.
+func Violations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	violations = append(violations, clusterViolations(cr)...)
+	violations = append(violations, nodeViolations(cr)...)
+	violations = append(violations, storageViolations(cr)...)
+	violations = append(violations, drainViolations(cr)...)
+	violations = append(violations, maintenanceViolations(cr)...)
+	violations = append(violations, kubeletViolations(cr)...)
+	violations = append(violations, networkViolations(cr)...)
+
+	return violations
+}
+
+func clusterViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	if key.ClusterID(cr) == "" {
+		violations = append(violations, "spec.cluster.id must not be empty")
+	}
+	if key.VersionBundleVersion(cr) == "" {
+		violations = append(violations, "spec.versionBundle.version must not be empty")
+	}
+
+	return violations
+}
+
+func nodeViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	if len(cr.Spec.Cluster.Masters) == 0 {
+		violations = append(violations, "spec.cluster.masters must not be empty")
+	}
+	if len(cr.Spec.KVM.Masters) != len(cr.Spec.Cluster.Masters) {
+		violations = append(violations, fmt.Sprintf("spec.kvm.masters has %d entries but spec.cluster.masters has %d", len(cr.Spec.KVM.Masters), len(cr.Spec.Cluster.Masters)))
+	}
+	if len(cr.Spec.KVM.Workers) != len(cr.Spec.Cluster.Workers) {
+		violations = append(violations, fmt.Sprintf("spec.kvm.workers has %d entries but spec.cluster.workers has %d", len(cr.Spec.KVM.Workers), len(cr.Spec.Cluster.Workers)))
+	}
+
+	ids := map[string]bool{}
+	for _, n := range key.AllNodes(cr) {
+		if n.ID == "" {
+			violations = append(violations, "node IDs must not be empty")
+			continue
+		}
+		if ids[n.ID] {
+			violations = append(violations, fmt.Sprintf("node ID '%s' must be unique", n.ID))
+		}
+		ids[n.ID] = true
+	}
+
+	for i, n := range cr.Spec.KVM.Masters {
+		violations = append(violations, kvmNodeViolations(fmt.Sprintf("spec.kvm.masters[%d]", i), n)...)
+
+		_, err := key.MemoryQuantityMaster(n)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("spec.kvm.masters[%d].memory '%s' must be a valid quantity", i, n.Memory))
+		}
+	}
+	for i, n := range cr.Spec.KVM.Workers {
+		violations = append(violations, kvmNodeViolations(fmt.Sprintf("spec.kvm.workers[%d]", i), n)...)
+
+		_, err := key.MemoryQuantityWorker(n)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("spec.kvm.workers[%d].memory '%s' must be a valid quantity", i, n.Memory))
+		}
+	}
+
+	return violations
+}
+
+func kvmNodeViolations(path string, n v1alpha1.KVMConfigSpecKVMNode) []string {
+	var violations []string
+
+	if n.CPUs < 1 {
+		violations = append(violations, fmt.Sprintf("%s.cpus must be greater than 0", path))
+	}
+	if n.Disk < 0 {
+		violations = append(violations, fmt.Sprintf("%s.disk must not be negative", path))
+	}
+	if n.DockerVolumeSizeGB < 0 {
+		violations = append(violations, fmt.Sprintf("%s.dockerVolumeSizeGB must not be negative", path))
+	}
+
+	return violations
+}
+
+func storageViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	_, err := etcdstorage.New(cr)
+	if err != nil {
+		violations = append(violations, fmt.Sprintf("spec.kvm.k8sKVM.storageType '%s' is unknown", key.StorageType(cr)))
+	}
+
+	size, err := etcdstorage.Size(cr)
+	if err != nil {
+		violations = append(violations, fmt.Sprintf("annotation %s '%s' must be a valid quantity", key.AnnotationEtcdStorageSize, key.EtcdStorageSize(cr)))
+	} else if size.Sign() <= 0 {
+		violations = append(violations, fmt.Sprintf("annotation %s '%s' must be greater than 0", key.AnnotationEtcdStorageSize, key.EtcdStorageSize(cr)))
+	}
+
+	return violations
+}
+
+func drainViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	annotations := []string{
+		key.AnnotationDrainEvictionGracePeriod,
+		key.AnnotationDrainForceAfterTimeout,
+		key.AnnotationDrainTimeout,
+	}
+
+	for _, a := range annotations {
+		v, ok := cr.GetAnnotations()[a]
+		if !ok {
+			continue
+		}
+
+		_, err := drain.FromAnnotations(drain.Policy{}, map[string]string{a: v})
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("annotation %s '%s' must define a valid drain setting", a, v))
+		}
+	}
+
+	return violations
+}
+
+func maintenanceViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	_, err := maintenance.Parse(key.MaintenanceWindows(cr))
+	if err != nil {
+		violations = append(violations, fmt.Sprintf("annotation %s '%s' must define valid maintenance windows", key.AnnotationMaintenanceWindows, key.MaintenanceWindows(cr)))
+	}
+
+	return violations
+}
+
+func kubeletViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	_, err := kubelet.Parse(key.KubeletConfig(cr))
+	if err != nil {
+		violations = append(violations, fmt.Sprintf("annotation %s must define valid kubelet settings: %s", key.AnnotationKubeletConfig, err))
+	}
+
+	return violations
+}
+
+func networkViolations(cr v1alpha1.KVMConfig) []string {
+	var violations []string
+
+	// Host ports of the liveness probes and the shutdown deferrer are derived
+	// from the flannel VNI, so it has to be small enough to result in valid
+	// ports.
+	vni := cr.Spec.KVM.Network.Flannel.VNI
+	if vni < 1 {
+		violations = append(violations, fmt.Sprintf("spec.kvm.network.flannel.vni %d must be greater than 0", vni))
+	} else if int(key.LivenessPort(cr)) > maxPort || key.ShutdownDeferrerListenPort(cr) > maxPort {
+		violations = append(violations, fmt.Sprintf("spec.kvm.network.flannel.vni %d results in host ports greater than %d", vni, maxPort))
+	}
+
+	names := map[string]bool{}
+	nodePorts := map[int]bool{}
+	targetPorts := map[int]bool{}
+	for i, p := range cr.Spec.KVM.PortMappings {
+		path := fmt.Sprintf("spec.kvm.portMappings[%d]", i)
+
+		if p.Name == "" {
+			violations = append(violations, fmt.Sprintf("%s.name must not be empty", path))
+		} else if names[p.Name] {
+			violations = append(violations, fmt.Sprintf("%s.name '%s' collides with another port mapping", path, p.Name))
+		}
+		names[p.Name] = true
+
+		if p.NodePort < 0 || p.NodePort > maxPort {
+			violations = append(violations, fmt.Sprintf("%s.nodePort %d must be between 0 and %d", path, p.NodePort, maxPort))
+		} else if p.NodePort != 0 && nodePorts[p.NodePort] {
+			violations = append(violations, fmt.Sprintf("%s.nodePort %d collides with another port mapping", path, p.NodePort))
+		}
+		nodePorts[p.NodePort] = true
+
+		if p.TargetPort < 1 || p.TargetPort > maxPort {
+			violations = append(violations, fmt.Sprintf("%s.targetPort %d must be between 1 and %d", path, p.TargetPort, maxPort))
+		} else if targetPorts[p.TargetPort] {
+			violations = append(violations, fmt.Sprintf("%s.targetPort %d collides with another port mapping", path, p.TargetPort))
+		}
+		targetPorts[p.TargetPort] = true
+	}
+
+	return violations
+}
@@ -0,0 +1,183 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/apis/provider/v1alpha1"
+)
+
+func newValidCustomObject() v1alpha1.KVMConfig {
+	return v1alpha1.KVMConfig{
+		Spec: v1alpha1.KVMConfigSpec{
+			Cluster: v1alpha1.Cluster{
+				ID: "al9qy",
+				Masters: []v1alpha1.ClusterNode{
+					{ID: "m1"},
+				},
+				Workers: []v1alpha1.ClusterNode{
+					{ID: "w1"},
+					{ID: "w2"},
+				},
+			},
+			KVM: v1alpha1.KVMConfigSpecKVM{
+				Masters: []v1alpha1.KVMConfigSpecKVMNode{
+					{CPUs: 2, Memory: "4G"},
+				},
+				Network: v1alpha1.KVMConfigSpecKVMNetwork{
+					Flannel: v1alpha1.KVMConfigSpecKVMNetworkFlannel{
+						VNI: 12,
+					},
+				},
+				PortMappings: []v1alpha1.KVMConfigSpecKVMPortMappings{
+					{Name: "http", NodePort: 31010, TargetPort: 30010},
+					{Name: "https", NodePort: 31011, TargetPort: 30011},
+				},
+				Workers: []v1alpha1.KVMConfigSpecKVMNode{
+					{CPUs: 4, Memory: "8G"},
+					{CPUs: 4, Memory: "8G"},
+				},
+			},
+			VersionBundle: v1alpha1.KVMConfigSpecVersionBundle{
+				Version: "3.6.0",
+			},
+		},
+	}
+}
+
+func Test_Violations(t *testing.T) {
+	testCases := []struct {
+		name               string
+		mutate             func(cr *v1alpha1.KVMConfig)
+		expectedViolations []string
+	}{
+		{
+			name:               "case 0: valid custom object",
+			mutate:             func(cr *v1alpha1.KVMConfig) {},
+			expectedViolations: nil,
+		},
+		{
+			name: "case 1: mismatching master and worker arrays",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.KVM.Masters = append(cr.Spec.KVM.Masters, cr.Spec.KVM.Masters[0])
+				cr.Spec.KVM.Workers = cr.Spec.KVM.Workers[:1]
+			},
+			expectedViolations: []string{
+				"spec.kvm.masters has 2 entries but spec.cluster.masters has 1",
+				"spec.kvm.workers has 1 entries but spec.cluster.workers has 2",
+			},
+		},
+		{
+			name: "case 2: unparsable memory and missing CPUs",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.KVM.Masters[0].Memory = "4 gigs"
+				cr.Spec.KVM.Workers[1].CPUs = 0
+			},
+			expectedViolations: []string{
+				"spec.kvm.masters[0].memory '4 gigs' must be a valid quantity",
+				"spec.kvm.workers[1].cpus must be greater than 0",
+			},
+		},
+		{
+			name: "case 3: duplicated node IDs",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.Cluster.Workers[1].ID = "m1"
+			},
+			expectedViolations: []string{
+				"node ID 'm1' must be unique",
+			},
+		},
+		{
+			name: "case 4: unknown storage type and invalid storage size",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.KVM.K8sKVM.StorageType = "nfs"
+				cr.SetAnnotations(map[string]string{
+					"kvm-operator.giantswarm.io/etcd-storage-size": "lots",
+				})
+			},
+			expectedViolations: []string{
+				"spec.kvm.k8sKVM.storageType 'nfs' is unknown",
+				"annotation kvm-operator.giantswarm.io/etcd-storage-size 'lots' must be a valid quantity",
+			},
+		},
+		{
+			name: "case 5: colliding port mappings",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.KVM.PortMappings = append(cr.Spec.KVM.PortMappings, v1alpha1.KVMConfigSpecKVMPortMappings{
+					Name:       "http",
+					NodePort:   31011,
+					TargetPort: 30010,
+				})
+			},
+			expectedViolations: []string{
+				"spec.kvm.portMappings[2].name 'http' collides with another port mapping",
+				"spec.kvm.portMappings[2].nodePort 31011 collides with another port mapping",
+				"spec.kvm.portMappings[2].targetPort 30010 collides with another port mapping",
+			},
+		},
+		{
+			name: "case 6: flannel VNI out of range",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.Spec.KVM.Network.Flannel.VNI = 20000
+			},
+			expectedViolations: []string{
+				"spec.kvm.network.flannel.vni 20000 results in host ports greater than 65535",
+			},
+		},
+		{
+			name: "case 7: invalid maintenance windows",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.SetAnnotations(map[string]string{
+					"kvm-operator.giantswarm.io/maintenance-windows": "0 2 * * 6",
+				})
+			},
+			expectedViolations: []string{
+				"annotation kvm-operator.giantswarm.io/maintenance-windows '0 2 * * 6' must define valid maintenance windows",
+			},
+		},
+		{
+			name: "case 8: invalid drain settings",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.SetAnnotations(map[string]string{
+					"kvm-operator.giantswarm.io/drain-force-after-timeout": "maybe",
+					"kvm-operator.giantswarm.io/drain-timeout":             "-5m",
+				})
+			},
+			expectedViolations: []string{
+				"annotation kvm-operator.giantswarm.io/drain-force-after-timeout 'maybe' must define a valid drain setting",
+				"annotation kvm-operator.giantswarm.io/drain-timeout '-5m' must define a valid drain setting",
+			},
+		},
+		{
+			name: "case 9: invalid kubelet settings",
+			mutate: func(cr *v1alpha1.KVMConfig) {
+				cr.SetAnnotations(map[string]string{
+					"kvm-operator.giantswarm.io/kubelet-config": "default: {maxPods: 1000}",
+				})
+			},
+			expectedViolations: []string{
+				"annotation kvm-operator.giantswarm.io/kubelet-config must define valid kubelet settings: default: max pods 1000 must not be negative or greater than 250: invalid config error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cr := newValidCustomObject()
+			tc.mutate(&cr)
+
+			violations := Violations(cr)
+			if !reflect.DeepEqual(violations, tc.expectedViolations) {
+				t.Fatalf("expected %#v got %#v", tc.expectedViolations, violations)
+			}
+
+			err := Validate(cr)
+			if len(tc.expectedViolations) == 0 && err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if len(tc.expectedViolations) != 0 && !IsInvalidSpec(err) {
+				t.Fatalf("error == %#v, want invalidSpecError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package controller
+
+import (
+	// Version bundle packages register themselves with the registry upon
+	// import. New releases have to be added here.
+	_ "github.com/giantswarm/kvm-operator/service/controller/v14patch3"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v14patch4"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v15"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v16"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v17"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v17patch1"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v18"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v19"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v20"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v21"
+	_ "github.com/giantswarm/kvm-operator/service/controller/v22"
+)
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/giantswarm/apiextensions/pkg/clientset/versioned/fake"
+	"github.com/giantswarm/certs/certstest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/randomkeys/randomkeystest"
+	"github.com/giantswarm/tenantcluster"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
+)
+
+// Test_VersionBundles_ResourceSets ensures every registered version bundle
+// provides cluster, deleter and drainer resource sets which can be built from
+// a production-like configuration.
+func Test_VersionBundles_ResourceSets(t *testing.T) {
+	bundles, err := registry.Bundles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) == 0 {
+		t.Fatalf("expected registered version bundles")
+	}
+
+	certsSearcher := certstest.NewSearcher(certstest.Config{})
+	logger := microloggertest.New()
+
+	var tenantCluster tenantcluster.Interface
+	{
+		c := tenantcluster.Config{
+			CertsSearcher: certsSearcher,
+			Logger:        logger,
+
+			CertID: "api",
+		}
+
+		tenantCluster, err = tenantcluster.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := registry.Config{
+		CertsSearcher:      certsSearcher,
+		G8sClient:          fake.NewSimpleClientset(),
+		K8sClient:          k8sfake.NewSimpleClientset(),
+		Logger:             logger,
+		RandomkeysSearcher: randomkeystest.NewSearcher(),
+		TenantCluster:      tenantCluster,
+
+		DNSServers:   "8.8.8.8",
+		IgnitionPath: "/opt/ignition",
+		ProjectName:  "kvm-operator",
+		SSOPublicKey: "test",
+		Tenant: registry.Tenant{
+			Update: registry.TenantUpdate{
+				BatchSize:             1,
+				MastersFirst:          true,
+				MaxUnavailableMasters: 1,
+				MaxUnavailableWorkers: 1,
+			},
+		},
+	}
+
+	for _, b := range bundles {
+		version := b.VersionBundle().Version
+
+		t.Run(version, func(t *testing.T) {
+			for name, newResourceSet := range map[string]registry.ResourceSetFunc{
+				"cluster": b.NewClusterResourceSet,
+				"deleter": b.NewDeleterResourceSet,
+				"drainer": b.NewDrainerResourceSet,
+			} {
+				resourceSet, err := newResourceSet(c)
+				if err != nil {
+					t.Fatalf("expected %s resource set of version bundle %s, got error %#v", name, version, err)
+				}
+				if resourceSet == nil {
+					t.Fatalf("expected %s resource set of version bundle %s, got nil", name, version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package etcdbackup
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// FilesystemConfig represents the configuration used to create a new
+// filesystem target.
+type FilesystemConfig struct {
+	// Dir is the directory snapshots are stored in. Restoring snapshots requires
+	// the directory to be available under the same path on all hosts running
+	// tenant cluster masters, e.g. by means of a shared mount.
+	Dir string
+}
+
+// Filesystem stores snapshots as files within a directory.
+type Filesystem struct {
+	dir string
+}
+
+// NewFilesystem creates a new configured filesystem target.
+func NewFilesystem(config FilesystemConfig) (*Filesystem, error) {
+	if config.Dir == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Dir must not be empty", config)
+	}
+	if !filepath.IsAbs(config.Dir) {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Dir must be an absolute path", config)
+	}
+
+	f := &Filesystem{
+		dir: filepath.Clean(config.Dir),
+	}
+
+	return f, nil
+}
+
+// Dir returns the directory snapshots are stored in.
+func (f *Filesystem) Dir() string {
+	return f.dir
+}
+
+func (f *Filesystem) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	p, err := f.path(name)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, microerror.Maskf(notFoundError, "snapshot '%s'", name)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return file, nil
+}
+
+// Put writes the snapshot to a temporary file first and renames it afterwards,
+// so that incomplete snapshots never show up under their final name.
+func (f *Filesystem) Put(ctx context.Context, name string, snapshot io.Reader, size int64) error {
+	p, err := f.path(name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0700)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".snapshot-")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, snapshot)
+	if err != nil {
+		tmp.Close()
+		return microerror.Mask(err)
+	}
+	if n != size {
+		tmp.Close()
+		return microerror.Maskf(executionFailedError, "snapshot '%s' has %d bytes, expected %d", name, n, size)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.Rename(tmp.Name(), p)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (f *Filesystem) URL(name string, expiry time.Duration) (string, error) {
+	p, err := f.path(name)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return "file://" + p, nil
+}
+
+func (f *Filesystem) path(name string) (string, error) {
+	p := filepath.Join(f.dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(p, f.dir+string(filepath.Separator)) {
+		return "", microerror.Maskf(invalidConfigError, "snapshot name '%s' must not leave the target directory", name)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,89 @@
+package etcdbackup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Filesystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcdbackup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var target Target
+	{
+		c := TargetConfig{
+			URL: "file://" + dir,
+		}
+
+		target, err = NewTarget(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = target.Put(context.Background(), "al9qy/20190501-120000.db", strings.NewReader("snapshot"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "al9qy", "20190501-120000.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "snapshot" {
+		t.Fatalf("snapshot == %q, want %q", b, "snapshot")
+	}
+
+	b, err = readSnapshot(target, "al9qy/20190501-120000.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "snapshot" {
+		t.Fatalf("snapshot == %q, want %q", b, "snapshot")
+	}
+
+	_, err = target.Get(context.Background(), "al9qy/missing.db")
+	if !IsNotFound(err) {
+		t.Fatalf("error == %#v, want notFoundError", err)
+	}
+
+	err = target.Put(context.Background(), "al9qy/20190501-130000.db", strings.NewReader("snap"), 8)
+	if !IsExecutionFailed(err) {
+		t.Fatalf("error == %#v, want executionFailedError", err)
+	}
+	_, err = os.Stat(filepath.Join(dir, "al9qy", "20190501-130000.db"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected incomplete snapshot not to be stored")
+	}
+
+	err = target.Put(context.Background(), "../escape.db", strings.NewReader("snapshot"), 8)
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want invalidConfigError", err)
+	}
+
+	u, err := target.URL("al9qy/20190501-120000.db", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "file://"+filepath.Join(dir, "al9qy", "20190501-120000.db") {
+		t.Fatalf("unexpected URL %q", u)
+	}
+}
+
+func readSnapshot(target Target, name string) ([]byte, error) {
+	r, err := target.Get(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
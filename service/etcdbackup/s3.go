@@ -0,0 +1,142 @@
+package etcdbackup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	minio "github.com/minio/minio-go"
+)
+
+const (
+	s3DefaultEndpoint  = "https://s3.amazonaws.com"
+	s3DefaultRegion    = "us-east-1"
+	s3MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// S3Config represents the configuration used to create a new S3 target.
+type S3Config struct {
+	AccessKeyID string
+	Bucket      string
+	// Endpoint is the base URL of the S3 compatible API, e.g. the address of a
+	// MinIO server. Defaults to AWS S3.
+	Endpoint string
+	// Prefix is prepended to all object keys.
+	Prefix string
+	// Region defaults to us-east-1, which is also what MinIO expects by default.
+	Region          string
+	SecretAccessKey string
+	// Transport is used to talk to the S3 compatible API. Defaults to the
+	// transport of the S3 client.
+	Transport http.RoundTripper
+}
+
+// S3 stores snapshots as objects in a bucket of an S3 compatible object store.
+type S3 struct {
+	bucket string
+	client *minio.Client
+	prefix string
+}
+
+// NewS3 creates a new configured S3 target.
+func NewS3(config S3Config) (*S3, error) {
+	if config.AccessKeyID == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.AccessKeyID must not be empty", config)
+	}
+	if config.Bucket == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Bucket must not be empty", config)
+	}
+	if config.SecretAccessKey == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretAccessKey must not be empty", config)
+	}
+
+	if config.Endpoint == "" {
+		config.Endpoint = s3DefaultEndpoint
+	}
+	if config.Region == "" {
+		config.Region = s3DefaultRegion
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must be a valid URL: %s", config, err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must have scheme 'http' or 'https'", config)
+	}
+	if strings.Trim(endpoint.Path, "/") != "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must not have a path", config)
+	}
+
+	client, err := minio.NewWithRegion(endpoint.Host, config.AccessKeyID, config.SecretAccessKey, endpoint.Scheme == "https", config.Region)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must be a valid S3 endpoint: %s", config, err)
+	}
+	if config.Transport != nil {
+		client.SetCustomTransport(config.Transport)
+	}
+
+	s := &S3{
+		bucket: config.Bucket,
+		client: client,
+		prefix: strings.Trim(config.Prefix, "/"),
+	}
+
+	return s, nil
+}
+
+func (s *S3) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	o, err := s.client.GetObjectWithContext(ctx, s.bucket, s.objectName(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The object is only requested once it is read from, so missing snapshots
+	// are detected by fetching the object info upfront.
+	_, err = o.Stat()
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		o.Close()
+		return nil, microerror.Maskf(notFoundError, "snapshot '%s'", name)
+	} else if err != nil {
+		o.Close()
+		return nil, microerror.Maskf(executionFailedError, "getting snapshot '%s' failed: %s", name, err)
+	}
+
+	return o, nil
+}
+
+func (s *S3) Put(ctx context.Context, name string, snapshot io.Reader, size int64) error {
+	o := minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	}
+
+	_, err := s.client.PutObjectWithContext(ctx, s.bucket, s.objectName(name), snapshot, size, o)
+	if err != nil {
+		return microerror.Maskf(executionFailedError, "putting snapshot '%s' failed: %s", name, err)
+	}
+
+	return nil
+}
+
+// URL returns a presigned URL of the snapshot stored under the given name.
+func (s *S3) URL(name string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > s3MaxPresignExpiry {
+		return "", microerror.Maskf(invalidConfigError, "expiry must be between 0 and %s", s3MaxPresignExpiry)
+	}
+
+	u, err := s.client.PresignedGetObject(s.bucket, s.objectName(name), expiry, nil)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return u.String(), nil
+}
+
+func (s *S3) objectName(name string) string {
+	return path.Join(s.prefix, name)
+}
@@ -0,0 +1,154 @@
+package etcdbackup
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_S3_PutGet(t *testing.T) {
+	var mutex sync.Mutex
+	objects := map[string][]byte{}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			objects[r.URL.Path] = b
+			w.Header().Set("ETag", `"etag"`)
+		case http.MethodGet, http.MethodHead:
+			b, ok := objects[r.URL.Path]
+			if !ok {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("ETag", `"etag"`)
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+			w.Write(b)
+		}
+	}))
+	defer server.Close()
+
+	var target Target
+	{
+		c := S3Config{
+			AccessKeyID:     "access",
+			Bucket:          "backups",
+			Endpoint:        server.URL,
+			Prefix:          "etcd",
+			SecretAccessKey: "secret",
+			Transport:       server.Client().Transport,
+		}
+
+		var err error
+		target, err = NewS3(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := target.Put(context.Background(), "al9qy/20190501-120000.db", strings.NewReader("snapshot"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok := objects["/backups/etcd/al9qy/20190501-120000.db"]
+	if !ok {
+		t.Fatalf("expected object to be stored path style, got %#v", objects)
+	}
+
+	b, err := readSnapshot(target, "al9qy/20190501-120000.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "snapshot" {
+		t.Fatalf("snapshot == %q, want %q", b, "snapshot")
+	}
+
+	_, err = target.Get(context.Background(), "al9qy/missing.db")
+	if !IsNotFound(err) {
+		t.Fatalf("error == %#v, want notFoundError", err)
+	}
+
+	u, err := target.URL("al9qy/20190501-120000.db", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, server.URL+"/backups/etcd/al9qy/20190501-120000.db?") {
+		t.Fatalf("unexpected URL %q", u)
+	}
+	if !strings.Contains(u, "X-Amz-Signature=") {
+		t.Fatalf("expected URL %q to be presigned", u)
+	}
+}
+
+func Test_NewS3(t *testing.T) {
+	testCases := []struct {
+		name         string
+		endpoint     string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:     "case 0: default endpoint",
+			endpoint: "",
+		},
+		{
+			name:     "case 1: MinIO endpoint",
+			endpoint: "http://minio.example.com:9000",
+		},
+		{
+			name:         "case 2: endpoint without HTTP scheme",
+			endpoint:     "minio.example.com:9000",
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 3: endpoint with path",
+			endpoint:     "https://minio.example.com/s3",
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := S3Config{
+				AccessKeyID:     "access",
+				Bucket:          "backups",
+				Endpoint:        tc.endpoint,
+				SecretAccessKey: "secret",
+			}
+
+			_, err := NewS3(c)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package etcdbackup
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// snapshotPath is the path of the snapshot API of the etcd v3 gRPC gateway.
+	snapshotPath = "/v3beta/maintenance/snapshot"
+)
+
+type snapshotResponse struct {
+	Error  *snapshotError  `json:"error"`
+	Result *snapshotResult `json:"result"`
+}
+
+type snapshotError struct {
+	Message string `json:"message"`
+}
+
+type snapshotResult struct {
+	Blob           string `json:"blob"`
+	RemainingBytes string `json:"remaining_bytes"`
+}
+
+// Snapshot opens a stream of a snapshot of the etcd cluster served at the given
+// endpoint using the JSON gateway of the etcd v3 API. It returns the stream
+// together with the size of the snapshot in bytes. The snapshot is decoded
+// while it is read from the stream, so it is never buffered as a whole. The
+// caller has to close the returned stream. The given client has to be
+// configured with the TLS client certificates of the tenant cluster's etcd.
+func Snapshot(ctx context.Context, client *http.Client, endpoint string) (io.ReadCloser, int64, error) {
+	u := strings.TrimSuffix(endpoint, "/") + snapshotPath
+
+	req, err := http.NewRequest(http.MethodPost, u, strings.NewReader("{}"))
+	if err != nil {
+		return nil, 0, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, microerror.Mask(err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return nil, 0, microerror.Maskf(executionFailedError, "requesting snapshot failed with status code %d: %s", res.StatusCode, b)
+	}
+
+	s := &snapshotStream{
+		body:    res.Body,
+		decoder: json.NewDecoder(res.Body),
+	}
+
+	// The size of the snapshot is only known from the first message, which
+	// carries the number of bytes remaining after its own chunk.
+	remaining, err := s.next()
+	if err != nil {
+		s.Close()
+		return nil, 0, microerror.Mask(err)
+	}
+
+	size := int64(len(s.chunk)) + remaining
+	if size == 0 {
+		s.Close()
+		return nil, 0, microerror.Maskf(executionFailedError, "snapshot is empty")
+	}
+
+	return s, size, nil
+}
+
+// snapshotStream decodes the snapshot streamed by the gateway as a sequence of
+// JSON messages, each carrying a base64 encoded chunk of the snapshot.
+type snapshotStream struct {
+	body     io.ReadCloser
+	chunk    []byte
+	complete bool
+	decoder  *json.Decoder
+}
+
+func (s *snapshotStream) Close() error {
+	return s.body.Close()
+}
+
+func (s *snapshotStream) Read(p []byte) (int, error) {
+	for len(s.chunk) == 0 {
+		if s.complete {
+			return 0, io.EOF
+		}
+
+		_, err := s.next()
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+	}
+
+	n := copy(p, s.chunk)
+	s.chunk = s.chunk[n:]
+
+	return n, nil
+}
+
+// next decodes the next message of the stream into the current chunk and
+// returns the number of bytes remaining after it.
+func (s *snapshotStream) next() (int64, error) {
+	for {
+		var r snapshotResponse
+		err := s.decoder.Decode(&r)
+		if err == io.EOF {
+			return 0, microerror.Maskf(executionFailedError, "snapshot stream ended prematurely")
+		} else if err != nil {
+			return 0, microerror.Mask(err)
+		}
+
+		if r.Error != nil {
+			return 0, microerror.Maskf(executionFailedError, "streaming snapshot failed: %s", r.Error.Message)
+		}
+		if r.Result == nil {
+			continue
+		}
+
+		b, err := base64.StdEncoding.DecodeString(r.Result.Blob)
+		if err != nil {
+			return 0, microerror.Mask(err)
+		}
+
+		var remaining int64
+		if r.Result.RemainingBytes != "" {
+			remaining, err = strconv.ParseInt(r.Result.RemainingBytes, 10, 64)
+			if err != nil {
+				return 0, microerror.Mask(err)
+			}
+		}
+
+		s.chunk = b
+		s.complete = remaining == 0
+
+		return remaining, nil
+	}
+}
@@ -0,0 +1,92 @@
+package etcdbackup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Snapshot(t *testing.T) {
+	testCases := []struct {
+		name             string
+		response         string
+		expectedSnapshot string
+		expectedSize     int64
+		errorMatcher     func(error) bool
+	}{
+		{
+			name: "case 0: snapshot streamed in multiple chunks",
+			response: `{"result":{"remaining_bytes":"5","blob":"c25h"}}
+{"result":{"remaining_bytes":"0","blob":"cHNob3Q="}}`,
+			expectedSnapshot: "snapshot",
+			expectedSize:     8,
+		},
+		{
+			name:         "case 1: stream ends prematurely",
+			response:     `{"result":{"remaining_bytes":"5","blob":"c25h"}}`,
+			errorMatcher: IsExecutionFailed,
+		},
+		{
+			name: "case 2: stream reports error",
+			response: `{"result":{"remaining_bytes":"5","blob":"c25h"}}
+{"error":{"grpc_code":14,"message":"transport is closing"}}`,
+			errorMatcher: IsExecutionFailed,
+		},
+		{
+			name:         "case 3: stream is empty",
+			response:     "",
+			errorMatcher: IsExecutionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != snapshotPath {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, tc.response)
+			}))
+			defer server.Close()
+
+			var err error
+			var snapshot []byte
+			var size int64
+			{
+				var r io.ReadCloser
+				r, size, err = Snapshot(context.Background(), server.Client(), server.URL)
+				if err == nil {
+					snapshot, err = ioutil.ReadAll(r)
+					r.Close()
+				}
+			}
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if string(snapshot) != tc.expectedSnapshot {
+				t.Fatalf("snapshot == %q, want %q", snapshot, tc.expectedSnapshot)
+			}
+			if size != tc.expectedSize {
+				t.Fatalf("size == %d, want %d", size, tc.expectedSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package etcdbackup
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Target is a location etcd snapshots of tenant clusters are stored in.
+type Target interface {
+	// Get returns a stream of the snapshot stored under the given name. The
+	// caller has to close the returned stream.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// Put stores the snapshot read from the given stream under the given name.
+	// size is the number of bytes of the snapshot, so that it can be streamed
+	// into the target without being buffered as a whole.
+	Put(ctx context.Context, name string, snapshot io.Reader, size int64) error
+	// URL returns a URL the snapshot stored under the given name can be fetched
+	// from without further credentials. The URL is valid for at least the given
+	// duration. Restore init containers of tenant cluster masters use it to
+	// download snapshots.
+	URL(name string, expiry time.Duration) (string, error)
+}
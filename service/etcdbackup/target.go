@@ -0,0 +1,60 @@
+package etcdbackup
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// TargetConfig represents the configuration used to create a new target.
+type TargetConfig struct {
+	// URL defines the kind and location of the target. Supported are file URLs
+	// like file:///var/lib/etcd-backups and S3 URLs like s3://bucket/prefix.
+	URL string
+
+	S3AccessKeyID     string
+	S3Endpoint        string
+	S3Region          string
+	S3SecretAccessKey string
+}
+
+// NewTarget creates the target configured by the given URL.
+func NewTarget(config TargetConfig) (Target, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.URL must be a valid URL: %s", config, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		c := FilesystemConfig{
+			Dir: u.Path,
+		}
+
+		t, err := NewFilesystem(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return t, nil
+	case "s3":
+		c := S3Config{
+			AccessKeyID:     config.S3AccessKeyID,
+			Bucket:          u.Host,
+			Endpoint:        config.S3Endpoint,
+			Prefix:          strings.TrimPrefix(u.Path, "/"),
+			Region:          config.S3Region,
+			SecretAccessKey: config.S3SecretAccessKey,
+		}
+
+		t, err := NewS3(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return t, nil
+	}
+
+	return nil, microerror.Maskf(invalidConfigError, "%T.URL must have scheme 'file' or 's3', got '%s'", config, u.Scheme)
+}
@@ -0,0 +1,22 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// EndpointAddressesCollected counts the orphaned addresses removed from the
+// master and worker endpoints of tenant clusters by the endpoint garbage
+// collector.
+var EndpointAddressesCollected = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "endpoint_gc",
+		Name:      "removed_addresses_total",
+		Help:      "Number of orphaned endpoint addresses removed, labeled by cluster ID and service.",
+	},
+	[]string{"cluster_id", "service"},
+)
+
+func init() {
+	prometheus.MustRegister(EndpointAddressesCollected)
+}
@@ -0,0 +1,45 @@
+package metric
+
+import (
+	"sync"
+	"time"
+)
+
+// Reconciliations tracks the time of the last successful reconciliation of
+// each tenant cluster. It is written by the cluster controller and read by the
+// cluster collector.
+var Reconciliations = NewTimestamps()
+
+// Timestamps is a concurrency safe map of timestamps keyed by cluster ID.
+type Timestamps struct {
+	mutex      sync.Mutex
+	timestamps map[string]time.Time
+}
+
+func NewTimestamps() *Timestamps {
+	return &Timestamps{
+		timestamps: map[string]time.Time{},
+	}
+}
+
+func (t *Timestamps) Delete(clusterID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.timestamps, clusterID)
+}
+
+func (t *Timestamps) Get(clusterID string) (time.Time, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ts, ok := t.timestamps[clusterID]
+	return ts, ok
+}
+
+func (t *Timestamps) Set(clusterID string, ts time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.timestamps[clusterID] = ts
+}
@@ -0,0 +1,70 @@
+// Package rollout limits the number of tenant clusters being updated at the
+// same time.
+package rollout
+
+import (
+	"sort"
+	"sync"
+)
+
+// Limiter hands out a limited number of rollout slots to tenant clusters. A
+// cluster holds its slot from the moment its first node is updated until all
+// of its nodes are up to date. Slots are only tracked in memory, so the limit
+// may temporarily be exceeded after a restart of the operator until clusters
+// which were rolling before reacquired their slots.
+type Limiter struct {
+	max int
+
+	mutex    sync.Mutex
+	clusters map[string]bool
+}
+
+// NewLimiter returns a limiter allowing max clusters to roll at once. Zero or
+// a negative value means there is no limit.
+func NewLimiter(max int) *Limiter {
+	return &Limiter{
+		max: max,
+
+		clusters: map[string]bool{},
+	}
+}
+
+// Acquire returns true in case the given cluster is allowed to roll, either
+// because it already holds a slot or because a free slot was assigned to it.
+func (l *Limiter) Acquire(clusterID string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.clusters[clusterID] {
+		return true
+	}
+	if l.max > 0 && len(l.clusters) >= l.max {
+		return false
+	}
+
+	l.clusters[clusterID] = true
+
+	return true
+}
+
+// Release frees the slot of the given cluster, if any.
+func (l *Limiter) Release(clusterID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.clusters, clusterID)
+}
+
+// Rolling returns the sorted IDs of all clusters currently holding a slot.
+func (l *Limiter) Rolling() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var clusterIDs []string
+	for id := range l.clusters {
+		clusterIDs = append(clusterIDs, id)
+	}
+	sort.Strings(clusterIDs)
+
+	return clusterIDs
+}
@@ -0,0 +1,39 @@
+package rollout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Limiter(t *testing.T) {
+	l := NewLimiter(2)
+
+	if !l.Acquire("a") || !l.Acquire("b") {
+		t.Fatalf("expected first two clusters to acquire slots")
+	}
+	if !l.Acquire("a") {
+		t.Fatalf("expected cluster holding a slot to acquire it again")
+	}
+	if l.Acquire("c") {
+		t.Fatalf("expected third cluster to not acquire a slot")
+	}
+	if !reflect.DeepEqual(l.Rolling(), []string{"a", "b"}) {
+		t.Fatalf("expected %#v got %#v", []string{"a", "b"}, l.Rolling())
+	}
+
+	l.Release("a")
+
+	if !l.Acquire("c") {
+		t.Fatalf("expected third cluster to acquire released slot")
+	}
+}
+
+func Test_Limiter_Unlimited(t *testing.T) {
+	l := NewLimiter(0)
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if !l.Acquire(id) {
+			t.Fatalf("expected cluster %s to acquire a slot", id)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/giantswarm/operatorkit/client/k8srestconfig"
+	"github.com/giantswarm/statusresource"
 	"github.com/giantswarm/tenantcluster"
 	"github.com/spf13/viper"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
@@ -18,7 +20,11 @@ import (
 	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/kvm-operator/flag"
+	"github.com/giantswarm/kvm-operator/service/collector"
 	"github.com/giantswarm/kvm-operator/service/controller"
+	"github.com/giantswarm/kvm-operator/service/controller/registry"
+	"github.com/giantswarm/kvm-operator/service/rollout"
+	"github.com/giantswarm/kvm-operator/service/shutdown"
 )
 
 type Config struct {
@@ -33,12 +39,15 @@ type Config struct {
 }
 
 type Service struct {
-	Version *version.Service
+	Shutdown *shutdown.Service
+	Version  *version.Service
 
 	bootOnce          sync.Once
+	clusterCollector  *collector.Cluster
 	clusterController *controller.Cluster
 	deleterController *controller.Deleter
 	drainerController *controller.Drainer
+	statusCollector   *statusresource.Collector
 }
 
 func New(config Config) (*Service, error) {
@@ -117,6 +126,70 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	var tenant registry.Tenant
+	{
+		api := config.Flag.Service.Installation.Tenant.Kubernetes.API
+		backup := config.Flag.Service.Tenant.Etcd.Backup
+		drain := config.Flag.Service.Tenant.Drain
+		update := config.Flag.Service.Tenant.Update
+
+		tenant = registry.Tenant{
+			APIServer: registry.TenantAPIServer{
+				AdmissionPlugins: registry.TenantAPIServerAdmissionPlugins{
+					Disable: splitFlagList(config.Viper.GetString(api.Admission.DisablePlugins)),
+					Enable:  splitFlagList(config.Viper.GetString(api.Admission.EnablePlugins)),
+				},
+				Audit: registry.TenantAPIServerAudit{
+					LogFormat:  config.Viper.GetString(api.Audit.LogFormat),
+					LogMode:    config.Viper.GetString(api.Audit.LogMode),
+					PolicyFile: config.Viper.GetString(api.Audit.PolicyFile),
+				},
+				EncryptionProviders: splitFlagList(config.Viper.GetString(api.Encryption.Providers)),
+				ExtraSANs:           splitFlagList(config.Viper.GetString(api.ExtraSANs)),
+				FeatureGates:        splitFlagList(config.Viper.GetString(api.FeatureGates)),
+			},
+			Drain: registry.TenantDrain{
+				Builtin:             config.Viper.GetBool(drain.Builtin),
+				EvictionGracePeriod: config.Viper.GetDuration(drain.EvictionGracePeriod),
+				ForceAfterTimeout:   config.Viper.GetBool(drain.ForceAfterTimeout),
+				Timeout:             config.Viper.GetDuration(drain.Timeout),
+			},
+			EtcdBackup: registry.TenantEtcdBackup{
+				Interval: config.Viper.GetDuration(backup.Interval),
+				S3: registry.TenantEtcdBackupS3{
+					AccessKeyID:     config.Viper.GetString(backup.S3.AccessKeyID),
+					Endpoint:        config.Viper.GetString(backup.S3.Endpoint),
+					Region:          config.Viper.GetString(backup.S3.Region),
+					SecretAccessKey: config.Viper.GetString(backup.S3.SecretAccessKey),
+				},
+				Target: config.Viper.GetString(backup.Target),
+			},
+			Ignition: registry.TenantIgnition{
+				Format:  config.Viper.GetString(config.Flag.Service.Tenant.Ignition.Format),
+				MaxSize: config.Viper.GetInt(config.Flag.Service.Tenant.Ignition.MaxSize),
+			},
+			NodeIndex: registry.TenantNodeIndex{
+				MaxIndex:         config.Viper.GetInt(config.Flag.Service.Tenant.NodeIndex.MaxIndex),
+				QuarantinePeriod: config.Viper.GetDuration(config.Flag.Service.Tenant.NodeIndex.QuarantinePeriod),
+			},
+			Resize: registry.TenantResize{
+				CapacityCheck: config.Viper.GetBool(config.Flag.Service.Tenant.Resize.CapacityCheck),
+			},
+			Update: registry.TenantUpdate{
+				BatchSize:             config.Viper.GetInt(update.BatchSize),
+				BatchWait:             config.Viper.GetDuration(update.BatchWait),
+				MastersFirst:          config.Viper.GetBool(update.MastersFirst),
+				MaxUnavailableMasters: config.Viper.GetInt(update.MaxUnavailableMasters),
+				MaxUnavailableWorkers: config.Viper.GetInt(update.MaxUnavailableWorkers),
+			},
+		}
+	}
+
+	var guestUpdateLimiter *rollout.Limiter
+	{
+		guestUpdateLimiter = rollout.NewLimiter(config.Viper.GetInt(config.Flag.Service.Tenant.Update.MaxClusters))
+	}
+
 	var clusterController *controller.Cluster
 	{
 		c := controller.ClusterConfig{
@@ -128,18 +201,19 @@ func New(config Config) (*Service, error) {
 			TenantCluster: tenantCluster,
 
 			CRDLabelSelector:   config.Viper.GetString(config.Flag.Service.CRD.LabelSelector),
+			DNSServers:         config.Viper.GetString(config.Flag.Service.Installation.DNS.Servers),
 			GuestUpdateEnabled: config.Viper.GetBool(config.Flag.Service.Tenant.Update.Enabled),
-			ProjectName:        config.Name,
-
-			DNSServers:   config.Viper.GetString(config.Flag.Service.Installation.DNS.Servers),
-			IgnitionPath: config.Viper.GetString(config.Flag.Service.Tenant.Ignition.Path),
+			GuestUpdateLimiter: guestUpdateLimiter,
+			IgnitionPath:       config.Viper.GetString(config.Flag.Service.Tenant.Ignition.Path),
 			OIDC: controller.ClusterConfigOIDC{
 				ClientID:      config.Viper.GetString(config.Flag.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.ClientID),
 				IssuerURL:     config.Viper.GetString(config.Flag.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.IssuerURL),
 				UsernameClaim: config.Viper.GetString(config.Flag.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.UsernameClaim),
 				GroupsClaim:   config.Viper.GetString(config.Flag.Service.Installation.Tenant.Kubernetes.API.Auth.Provider.OIDC.GroupsClaim),
 			},
+			ProjectName:  config.Name,
 			SSOPublicKey: config.Viper.GetString(config.Flag.Service.Tenant.SSH.SSOPublicKey),
+			Tenant:       tenant,
 		}
 
 		clusterController, err = controller.NewCluster(c)
@@ -170,15 +244,59 @@ func New(config Config) (*Service, error) {
 	var drainerController *controller.Drainer
 	{
 		c := controller.DrainerConfig{
+			G8sClient:     g8sClient,
+			K8sClient:     k8sClient,
+			Logger:        config.Logger,
+			TenantCluster: tenantCluster,
+
+			CRDLabelSelector: config.Viper.GetString(config.Flag.Service.CRD.LabelSelector),
+			ProjectName:      config.Name,
+			Tenant:           tenant,
+		}
+
+		drainerController, err = controller.NewDrainer(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var clusterCollector *collector.Cluster
+	{
+		c := collector.ClusterConfig{
 			G8sClient: g8sClient,
 			K8sClient: k8sClient,
 			Logger:    config.Logger,
 
 			CRDLabelSelector: config.Viper.GetString(config.Flag.Service.CRD.LabelSelector),
-			ProjectName:      config.Name,
 		}
 
-		drainerController, err = controller.NewDrainer(c)
+		clusterCollector, err = collector.NewCluster(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var statusCollector *statusresource.Collector
+	{
+		c := statusresource.CollectorConfig{
+			Logger:  config.Logger,
+			Watcher: g8sClient.ProviderV1alpha1().KVMConfigs("").Watch,
+		}
+
+		statusCollector, err = statusresource.NewCollector(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var shutdownService *shutdown.Service
+	{
+		c := shutdown.Config{
+			K8sClient: k8sClient,
+			Logger:    config.Logger,
+		}
+
+		shutdownService, err = shutdown.New(c)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -201,12 +319,15 @@ func New(config Config) (*Service, error) {
 	}
 
 	newService := &Service{
-		Version: versionService,
+		Shutdown: shutdownService,
+		Version:  versionService,
 
 		bootOnce:          sync.Once{},
+		clusterCollector:  clusterCollector,
 		clusterController: clusterController,
 		deleterController: deleterController,
 		drainerController: drainerController,
+		statusCollector:   statusCollector,
 	}
 
 	return newService, nil
@@ -214,8 +335,26 @@ func New(config Config) (*Service, error) {
 
 func (s *Service) Boot() {
 	s.bootOnce.Do(func() {
+		s.clusterCollector.Boot(context.Background())
+		s.statusCollector.Boot(context.Background())
+
 		go s.clusterController.Boot(context.Background())
 		go s.deleterController.Boot(context.Background())
 		go s.drainerController.Boot(context.Background())
 	})
 }
+
+// splitFlagList returns the items of the given comma separated flag value.
+func splitFlagList(s string) []string {
+	var items []string
+
+	for _, i := range strings.Split(s, ",") {
+		i = strings.TrimSpace(i)
+		if i == "" {
+			continue
+		}
+		items = append(items, i)
+	}
+
+	return items
+}
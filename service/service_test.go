@@ -42,6 +42,9 @@ func Test_Service_New(t *testing.T) {
 				config.Viper.Set(config.Flag.Service.Kubernetes.InCluster, "false")
 				config.Viper.Set(config.Flag.Service.Tenant.Ignition.Path, "test")
 				config.Viper.Set(config.Flag.Service.Tenant.SSH.SSOPublicKey, "test")
+				config.Viper.Set(config.Flag.Service.Tenant.Update.BatchSize, 1)
+				config.Viper.Set(config.Flag.Service.Tenant.Update.MaxUnavailableMasters, 1)
+				config.Viper.Set(config.Flag.Service.Tenant.Update.MaxUnavailableWorkers, 1)
 
 				return config
 			},
@@ -0,0 +1,7 @@
+package shutdown
+
+// Request is the request asking whether the VM of a pod may shut down.
+type Request struct {
+	Namespace string
+	Pod       string
+}
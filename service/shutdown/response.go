@@ -0,0 +1,24 @@
+package shutdown
+
+const (
+	// ReasonDrainFinished is the reason of VMs allowed to shut down because
+	// their tenant cluster node finished draining.
+	ReasonDrainFinished = "DrainFinished"
+	// ReasonDrainInProgress is the reason of VMs deferring their shut down
+	// because their tenant cluster node is being drained.
+	ReasonDrainInProgress = "DrainInProgress"
+	// ReasonPodNotDeleted is the reason of VMs allowed to shut down because
+	// their pod is not deleted, e.g. because the QEMU container is restarted.
+	// Deferring the shut down does not help then since no drain happens.
+	ReasonPodNotDeleted = "PodNotDeleted"
+	// ReasonPodNotFound is the reason of VMs allowed to shut down because their
+	// pod does not exist anymore.
+	ReasonPodNotFound = "PodNotFound"
+)
+
+// Response is the decision whether the VM of a pod may shut down.
+type Response struct {
+	DrainState  string `json:"drain_state,omitempty"`
+	MayShutDown bool   `json:"may_shut_down"`
+	Reason      string `json:"reason"`
+}
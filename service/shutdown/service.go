@@ -0,0 +1,89 @@
+// Package shutdown decides whether the QEMU process of a VM pod may shut down.
+// The shutdown-deferrer sidecar and the preStop hook of the QEMU container can
+// ask the operator instead of computing the decision themselves. Shutting
+// down is deferred as long as the tenant cluster node of a deleted pod is
+// being drained.
+package shutdown
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+)
+
+type Config struct {
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+}
+
+type Service struct {
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+}
+
+func New(config Config) (*Service, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	s := &Service{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+	}
+
+	return s, nil
+}
+
+// Get returns whether the VM of the requested pod may shut down based on the
+// drain state the drainer computes for the pod.
+func (s *Service) Get(ctx context.Context, request Request) (*Response, error) {
+	if request.Namespace == "" {
+		return nil, microerror.Maskf(invalidRequestError, "namespace must not be empty")
+	}
+	if request.Pod == "" {
+		return nil, microerror.Maskf(invalidRequestError, "pod must not be empty")
+	}
+
+	pod, err := s.k8sClient.CoreV1().Pods(request.Namespace).Get(request.Pod, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		s.logger.LogCtx(ctx, "level", "debug", "message", fmt.Sprintf("did not find pod %#q", request.Namespace+"/"+request.Pod))
+
+		response := &Response{
+			MayShutDown: true,
+			Reason:      ReasonPodNotFound,
+		}
+
+		return response, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	state := drain.StateFromPod(pod)
+
+	response := &Response{
+		DrainState: string(state),
+	}
+
+	if pod.GetDeletionTimestamp() == nil {
+		response.MayShutDown = true
+		response.Reason = ReasonPodNotDeleted
+	} else if state.Final() {
+		response.MayShutDown = true
+		response.Reason = ReasonDrainFinished
+	} else {
+		response.MayShutDown = false
+		response.Reason = ReasonDrainInProgress
+	}
+
+	return response, nil
+}
@@ -0,0 +1,159 @@
+package shutdown
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kvm-operator/service/controller/v22/drain"
+)
+
+func Test_Service_Get(t *testing.T) {
+	deleted := metav1.Now()
+
+	testCases := []struct {
+		name             string
+		pod              *corev1.Pod
+		expectedResponse *Response
+	}{
+		{
+			name: "case 0: missing pod may shut down",
+			pod:  nil,
+			expectedResponse: &Response{
+				MayShutDown: true,
+				Reason:      ReasonPodNotFound,
+			},
+		},
+		{
+			name: "case 1: pod not being deleted may shut down",
+			pod:  newTestPod(nil, ""),
+			expectedResponse: &Response{
+				DrainState:  string(drain.StatePending),
+				MayShutDown: true,
+				Reason:      ReasonPodNotDeleted,
+			},
+		},
+		{
+			name: "case 2: deleted pod pending drain defers shut down",
+			pod:  newTestPod(&deleted, ""),
+			expectedResponse: &Response{
+				DrainState:  string(drain.StatePending),
+				MayShutDown: false,
+				Reason:      ReasonDrainInProgress,
+			},
+		},
+		{
+			name: "case 3: deleted pod draining defers shut down",
+			pod:  newTestPod(&deleted, drain.StateDraining),
+			expectedResponse: &Response{
+				DrainState:  string(drain.StateDraining),
+				MayShutDown: false,
+				Reason:      ReasonDrainInProgress,
+			},
+		},
+		{
+			name: "case 4: deleted pod with timed out drain defers shut down",
+			pod:  newTestPod(&deleted, drain.StateTimedOut),
+			expectedResponse: &Response{
+				DrainState:  string(drain.StateTimedOut),
+				MayShutDown: false,
+				Reason:      ReasonDrainInProgress,
+			},
+		},
+		{
+			name: "case 5: deleted pod drained may shut down",
+			pod:  newTestPod(&deleted, drain.StateDrained),
+			expectedResponse: &Response{
+				DrainState:  string(drain.StateDrained),
+				MayShutDown: true,
+				Reason:      ReasonDrainFinished,
+			},
+		},
+		{
+			name: "case 6: deleted pod forced may shut down",
+			pod:  newTestPod(&deleted, drain.StateForced),
+			expectedResponse: &Response{
+				DrainState:  string(drain.StateForced),
+				MayShutDown: true,
+				Reason:      ReasonDrainFinished,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if tc.pod != nil {
+				objects = append(objects, tc.pod)
+			}
+
+			var s *Service
+			{
+				c := Config{
+					K8sClient: fake.NewSimpleClientset(objects...),
+					Logger:    microloggertest.New(),
+				}
+
+				var err error
+				s, err = New(c)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			response, err := s.Get(context.Background(), Request{Namespace: "al9qy", Pod: "worker-1"})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if !cmp.Equal(response, tc.expectedResponse) {
+				t.Fatalf("\n\n%s\n", cmp.Diff(tc.expectedResponse, response))
+			}
+		})
+	}
+}
+
+func Test_Service_Get_InvalidRequest(t *testing.T) {
+	c := Config{
+		K8sClient: fake.NewSimpleClientset(),
+		Logger:    microloggertest.New(),
+	}
+
+	s, err := New(c)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_, err = s.Get(context.Background(), Request{Namespace: "al9qy"})
+	if !IsInvalidRequest(err) {
+		t.Fatalf("error == %#v, want invalidRequestError", err)
+	}
+}
+
+func newTestPod(deletionTimestamp *metav1.Time, state drain.State) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "worker-1",
+			Namespace:         "al9qy",
+			DeletionTimestamp: deletionTimestamp,
+		},
+	}
+
+	if state != "" {
+		p.Status.Conditions = []corev1.PodCondition{
+			{
+				Type:   drain.PodConditionType,
+				Status: corev1.ConditionTrue,
+				Reason: string(state),
+			},
+		}
+	}
+
+	return p
+}